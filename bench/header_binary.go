@@ -38,8 +38,20 @@ func (s *Header) WriteBinary(w io.Writer, order binarystruct.ByteOrder) (int, er
 
 // WriteBinaryWithMarshaler implements binarystruct.MarshalerContextWriter.
 func (s *Header) WriteBinaryWithMarshaler(ms *binarystruct.Marshaler, w io.Writer, order binarystruct.ByteOrder) (n int, err error) {
+	cg := binarystruct.NewCodegen(ms)
+	if err = cg.BeforeMarshalHook(s); err != nil {
+		return 0, err
+	}
 	order = binarystruct.LittleEndian
 	var m int
+	var efield string
+	var eoff int
+	defer func() {
+		if err != nil && efield != "" {
+			err = cg.EncodeError(eoff, efield, err)
+		}
+	}()
+	efield, eoff = "A", n
 	{
 		sbuf := make([]byte, 42)
 		sbuf[0] = byte(s.A)
@@ -68,6 +80,14 @@ func (s *Header) ReadBinary(r io.Reader, order binarystruct.ByteOrder) (int, err
 
 // ReadBinaryWithMarshaler implements binarystruct.MarshalerContextReader.
 func (s *Header) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader, order binarystruct.ByteOrder) (n int, err error) {
+	cg := binarystruct.NewCodegen(ms)
+	if cg.Salvaging() {
+		return cg.ReadStructFields(r, order, s)
+	}
+	if err = cg.EnterNested(); err != nil {
+		return 0, err
+	}
+	defer cg.LeaveNested()
 	order = binarystruct.LittleEndian
 	var m int
 	{
@@ -88,7 +108,7 @@ func (s *Header) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader
 		s.I = float32(math.Float32frombits(order.Uint32(sbuf[30:34])))
 		s.J = float64(math.Float64frombits(order.Uint64(sbuf[34:42])))
 	}
-	return n, nil
+	return n, cg.AfterUnmarshalHooks(s)
 }
 
 // MarshalBinary implements encoding.BinaryMarshaler.
@@ -119,17 +139,30 @@ func (s *IntSlice) WriteBinary(w io.Writer, order binarystruct.ByteOrder) (int,
 
 // WriteBinaryWithMarshaler implements binarystruct.MarshalerContextWriter.
 func (s *IntSlice) WriteBinaryWithMarshaler(ms *binarystruct.Marshaler, w io.Writer, order binarystruct.ByteOrder) (n int, err error) {
+	cg := binarystruct.NewCodegen(ms)
+	if err = cg.BeforeMarshalHook(s); err != nil {
+		return 0, err
+	}
 	order = binarystruct.LittleEndian
 	var tmp [8]byte
 	var m int
+	var efield string
+	var eoff int
+	defer func() {
+		if err != nil && efield != "" {
+			err = cg.EncodeError(eoff, efield, err)
+		}
+	}()
+	efield, eoff = "N", n
 	order.PutUint32(tmp[:4], uint32((len(s.Data))))
 	m, err = w.Write(tmp[:4])
 	n += m
 	if err != nil {
 		return n, err
 	}
+	efield, eoff = "Data", n
 	{
-		writeLen := int((len(s.Data)))
+		writeLen := len(s.Data)
 		sbuf := make([]byte, writeLen*4)
 		for i := 0; i < writeLen; i++ {
 			order.PutUint32(sbuf[i*4:], uint32(s.Data[i]))
@@ -150,6 +183,16 @@ func (s *IntSlice) ReadBinary(r io.Reader, order binarystruct.ByteOrder) (int, e
 
 // ReadBinaryWithMarshaler implements binarystruct.MarshalerContextReader.
 func (s *IntSlice) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader, order binarystruct.ByteOrder) (n int, err error) {
+	cg := binarystruct.NewCodegen(ms)
+	if cg.Salvaging() {
+		return cg.ReadStructFields(r, order, s)
+	}
+	cg.BeginDecode()
+	defer cg.EndDecode(&err)
+	if err = cg.EnterNested(); err != nil {
+		return 0, err
+	}
+	defer cg.LeaveNested()
 	order = binarystruct.LittleEndian
 	var tmp [8]byte
 	var m int
@@ -161,18 +204,21 @@ func (s *IntSlice) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Read
 	s.N = uint32(order.Uint32(tmp[:4]))
 	{
 		readLen := int(s.N)
-		s.Data = make([]uint32, readLen)
-		sbuf := make([]byte, readLen*4)
-		m, err = io.ReadFull(r, sbuf)
+		if err = cg.LimitSlice([]uint32(nil), readLen); err != nil {
+			return n, err
+		}
+		var sbuf []byte
+		sbuf, m, err = cg.ReadBytes(r, readLen*4)
 		n += m
 		if err != nil {
 			return n, err
 		}
+		s.Data = make([]uint32, readLen)
 		for i := 0; i < readLen; i++ {
 			s.Data[i] = uint32(order.Uint32(sbuf[i*4:]))
 		}
 	}
-	return n, nil
+	return n, cg.AfterUnmarshalHooks(s)
 }
 
 // MarshalBinary implements encoding.BinaryMarshaler.
@@ -203,28 +249,43 @@ func (s *Record) WriteBinary(w io.Writer, order binarystruct.ByteOrder) (int, er
 
 // WriteBinaryWithMarshaler implements binarystruct.MarshalerContextWriter.
 func (s *Record) WriteBinaryWithMarshaler(ms *binarystruct.Marshaler, w io.Writer, order binarystruct.ByteOrder) (n int, err error) {
+	cg := binarystruct.NewCodegen(ms)
+	if err = cg.BeforeMarshalHook(s); err != nil {
+		return 0, err
+	}
 	order = binarystruct.LittleEndian
 	var tmp [8]byte
 	var m int
+	var efield string
+	var eoff int
+	defer func() {
+		if err != nil && efield != "" {
+			err = cg.EncodeError(eoff, efield, err)
+		}
+	}()
+	efield, eoff = "Magic", n
 	m, err = w.Write([]byte{0x4d, 0x59, 0x42, 0x52})
 	n += m
 	if err != nil {
 		return n, err
 	}
+	efield, eoff = "NameLen", n
 	order.PutUint16(tmp[:2], uint16((len(s.Name))))
 	m, err = w.Write(tmp[:2])
 	n += m
 	if err != nil {
 		return n, err
 	}
+	efield, eoff = "Name", n
 	{
-		writeLen := int((len(s.Name)))
+		writeLen := len(s.Name)
 		m, err = w.Write(s.Name[:writeLen])
 		n += m
 		if err != nil {
 			return n, err
 		}
 	}
+	efield, eoff = "Seq", n
 	{
 		sbuf := make([]byte, 6)
 		order.PutUint32(sbuf[0:4], uint32(s.Seq))
@@ -235,14 +296,16 @@ func (s *Record) WriteBinaryWithMarshaler(ms *binarystruct.Marshaler, w io.Write
 			return n, err
 		}
 	}
+	efield, eoff = "PayLen", n
 	order.PutUint32(tmp[:4], uint32((len(s.Payload))))
 	m, err = w.Write(tmp[:4])
 	n += m
 	if err != nil {
 		return n, err
 	}
+	efield, eoff = "Payload", n
 	{
-		writeLen := int((len(s.Payload)))
+		writeLen := len(s.Payload)
 		m, err = w.Write(s.Payload[:writeLen])
 		n += m
 		if err != nil {
@@ -259,6 +322,16 @@ func (s *Record) ReadBinary(r io.Reader, order binarystruct.ByteOrder) (int, err
 
 // ReadBinaryWithMarshaler implements binarystruct.MarshalerContextReader.
 func (s *Record) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader, order binarystruct.ByteOrder) (n int, err error) {
+	cg := binarystruct.NewCodegen(ms)
+	if cg.Salvaging() {
+		return cg.ReadStructFields(r, order, s)
+	}
+	cg.BeginDecode()
+	defer cg.EndDecode(&err)
+	if err = cg.EnterNested(); err != nil {
+		return 0, err
+	}
+	defer cg.LeaveNested()
 	order = binarystruct.LittleEndian
 	var tmp [8]byte
 	var m int
@@ -269,7 +342,9 @@ func (s *Record) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader
 		return n, err
 	}
 	if !bytes.Equal(s.Magic[:], []byte{0x4d, 0x59, 0x42, 0x52}) {
-		return n, &binarystruct.DecodeError{Offset: voffMagic, Field: "Magic", Err: fmt.Errorf("const mismatch: %w", binarystruct.ErrValidationError)}
+		if err = cg.ValidationFailed(cg.DecodeError(voffMagic, "Magic", fmt.Sprintf("%T", s.Magic), "[4]byte,const=0x4d594252", &binarystruct.CheckError{Expected: "0x4d594252", Actual: fmt.Sprintf("%#x", s.Magic[:]), Err: fmt.Errorf("const mismatch: %w", binarystruct.ErrValidationError)})); err != nil {
+			return n, err
+		}
 	}
 	m, err = io.ReadFull(r, tmp[:2])
 	n += m
@@ -279,8 +354,10 @@ func (s *Record) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader
 	s.NameLen = uint16(order.Uint16(tmp[:2]))
 	{
 		readLen := int(s.NameLen)
-		s.Name = make([]byte, readLen)
-		m, err = io.ReadFull(r, s.Name)
+		if err = cg.LimitSlice([]byte(nil), readLen); err != nil {
+			return n, err
+		}
+		s.Name, m, err = cg.ReadBytes(r, readLen)
 		n += m
 		if err != nil {
 			return n, err
@@ -304,14 +381,16 @@ func (s *Record) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader
 	s.PayLen = uint32(order.Uint32(tmp[:4]))
 	{
 		readLen := int(s.PayLen)
-		s.Payload = make([]byte, readLen)
-		m, err = io.ReadFull(r, s.Payload)
+		if err = cg.LimitSlice([]byte(nil), readLen); err != nil {
+			return n, err
+		}
+		s.Payload, m, err = cg.ReadBytes(r, readLen)
 		n += m
 		if err != nil {
 			return n, err
 		}
 	}
-	return n, nil
+	return n, cg.AfterUnmarshalHooks(s)
 }
 
 // MarshalBinary implements encoding.BinaryMarshaler.
@@ -342,8 +421,20 @@ func (s *Inner) WriteBinary(w io.Writer, order binarystruct.ByteOrder) (int, err
 
 // WriteBinaryWithMarshaler implements binarystruct.MarshalerContextWriter.
 func (s *Inner) WriteBinaryWithMarshaler(ms *binarystruct.Marshaler, w io.Writer, order binarystruct.ByteOrder) (n int, err error) {
+	cg := binarystruct.NewCodegen(ms)
+	if err = cg.BeforeMarshalHook(s); err != nil {
+		return 0, err
+	}
 	order = binarystruct.LittleEndian
 	var m int
+	var efield string
+	var eoff int
+	defer func() {
+		if err != nil && efield != "" {
+			err = cg.EncodeError(eoff, efield, err)
+		}
+	}()
+	efield, eoff = "X", n
 	{
 		sbuf := make([]byte, 7)
 		order.PutUint32(sbuf[0:4], uint32(s.X))
@@ -365,6 +456,14 @@ func (s *Inner) ReadBinary(r io.Reader, order binarystruct.ByteOrder) (int, erro
 
 // ReadBinaryWithMarshaler implements binarystruct.MarshalerContextReader.
 func (s *Inner) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader, order binarystruct.ByteOrder) (n int, err error) {
+	cg := binarystruct.NewCodegen(ms)
+	if cg.Salvaging() {
+		return cg.ReadStructFields(r, order, s)
+	}
+	if err = cg.EnterNested(); err != nil {
+		return 0, err
+	}
+	defer cg.LeaveNested()
 	order = binarystruct.LittleEndian
 	var m int
 	{
@@ -378,7 +477,7 @@ func (s *Inner) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader,
 		s.Y = uint16(order.Uint16(sbuf[4:6]))
 		s.Z = uint8(sbuf[6])
 	}
-	return n, nil
+	return n, cg.AfterUnmarshalHooks(s)
 }
 
 // MarshalBinary implements encoding.BinaryMarshaler.
@@ -409,20 +508,36 @@ func (s *Nested) WriteBinary(w io.Writer, order binarystruct.ByteOrder) (int, er
 
 // WriteBinaryWithMarshaler implements binarystruct.MarshalerContextWriter.
 func (s *Nested) WriteBinaryWithMarshaler(ms *binarystruct.Marshaler, w io.Writer, order binarystruct.ByteOrder) (n int, err error) {
+	cg := binarystruct.NewCodegen(ms)
+	if err = cg.BeforeMarshalHook(s); err != nil {
+		return 0, err
+	}
 	order = binarystruct.LittleEndian
 	var tmp [8]byte
 	var m int
+	var efield string
+	var eoff int
+	defer func() {
+		if err != nil && efield != "" {
+			err = cg.EncodeError(eoff, efield, err)
+		}
+	}()
+	efield, eoff = "Count", n
 	order.PutUint16(tmp[:2], uint16((len(s.Items))))
 	m, err = w.Write(tmp[:2])
 	n += m
 	if err != nil {
 		return n, err
 	}
+	efield, eoff = "Items", n
 	{
-		limit := int((len(s.Items)))
+		limit := len(s.Items)
 		for i := 0; i < limit; i++ {
 			{
 				m, err = (s.Items[i]).WriteBinaryWithMarshaler(ms, w, order)
+				if err != nil {
+					err = cg.ElementError(i, n-eoff, err)
+				}
 				n += m
 				if err != nil {
 					return n, err
@@ -440,6 +555,16 @@ func (s *Nested) ReadBinary(r io.Reader, order binarystruct.ByteOrder) (int, err
 
 // ReadBinaryWithMarshaler implements binarystruct.MarshalerContextReader.
 func (s *Nested) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader, order binarystruct.ByteOrder) (n int, err error) {
+	cg := binarystruct.NewCodegen(ms)
+	if cg.Salvaging() {
+		return cg.ReadStructFields(r, order, s)
+	}
+	cg.BeginDecode()
+	defer cg.EndDecode(&err)
+	if err = cg.EnterNested(); err != nil {
+		return 0, err
+	}
+	defer cg.LeaveNested()
 	order = binarystruct.LittleEndian
 	var tmp [8]byte
 	var m int
@@ -449,18 +574,33 @@ func (s *Nested) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader
 		return n, err
 	}
 	s.Count = uint16(order.Uint16(tmp[:2]))
+	voffItems := n
 	{
 		readLen := int(s.Count)
-		s.Items = make([]Inner, readLen)
+		var sliceCap int
+		if sliceCap, err = cg.SliceCap([]Inner(nil), r, readLen, 0); err != nil {
+			return n, err
+		}
+		s.Items = make([]Inner, 0, sliceCap)
+		var zero Inner
 		for i := 0; i < readLen; i++ {
+			s.Items = append(s.Items, zero)
 			{
+				estart := n
+				cmark := cg.CollectMark()
 				m, err = (s.Items[i]).ReadBinaryWithMarshaler(ms, r, order)
+				if err != nil {
+					err = cg.DecodeError(voffItems, "Items", fmt.Sprintf("%T", s.Items), "[Count]any", cg.ElementError(i, estart-voffItems, err))
+				}
 				n += m
 				if err != nil {
 					return n, err
 				}
+				cg.WrapCollected(cmark, func(err error) error {
+					return cg.DecodeError(voffItems, "Items", fmt.Sprintf("%T", s.Items), "[Count]any", cg.ElementError(i, estart-voffItems, err))
+				})
 			}
 		}
 	}
-	return n, nil
+	return n, cg.AfterUnmarshalHooks(s)
 }
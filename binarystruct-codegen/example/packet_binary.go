@@ -38,28 +38,46 @@ func (s *Packet) WriteBinary(w io.Writer, order binarystruct.ByteOrder) (int, er
 
 // WriteBinaryWithMarshaler implements binarystruct.MarshalerContextWriter.
 func (s *Packet) WriteBinaryWithMarshaler(ms *binarystruct.Marshaler, w io.Writer, order binarystruct.ByteOrder) (n int, err error) {
+	cg := binarystruct.NewCodegen(ms)
+	if err = cg.BeforeMarshalHook(s); err != nil {
+		return 0, err
+	}
 	order = binarystruct.BigEndian
 	var tmp [8]byte
 	var m int
+	var efield string
+	var eoff int
+	defer func() {
+		if err != nil && efield != "" {
+			err = cg.EncodeError(eoff, efield, err)
+		}
+	}()
+	efield, eoff = "Magic", n
 	m, err = w.Write([]byte{0x50, 0x41, 0x4b, 0x31})
 	n += m
 	if err != nil {
 		return n, err
 	}
+	efield, eoff = "Seq", n
 	order.PutUint32(tmp[:4], uint32(s.Seq))
 	m, err = w.Write(tmp[:4])
 	n += m
 	if err != nil {
 		return n, err
 	}
+	efield, eoff = "Version", n
 	tmp[0] = byte(s.Version)
 	m, err = w.Write(tmp[:1])
 	n += m
 	if err != nil {
 		return n, err
 	}
+	efield, eoff = "Payload", n
+	if l := len(s.Payload); l > 8 {
+		return n, fmt.Errorf("array too large to fit: len %d, size %d: %w", 8, l, binarystruct.ErrValueOverflow)
+	}
 	{
-		writeLen := int(8)
+		writeLen := 8
 		m, err = w.Write(s.Payload[:writeLen])
 		n += m
 		if err != nil {
@@ -76,6 +94,16 @@ func (s *Packet) ReadBinary(r io.Reader, order binarystruct.ByteOrder) (int, err
 
 // ReadBinaryWithMarshaler implements binarystruct.MarshalerContextReader.
 func (s *Packet) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader, order binarystruct.ByteOrder) (n int, err error) {
+	cg := binarystruct.NewCodegen(ms)
+	if cg.Salvaging() {
+		return cg.ReadStructFields(r, order, s)
+	}
+	cg.BeginDecode()
+	defer cg.EndDecode(&err)
+	if err = cg.EnterNested(); err != nil {
+		return 0, err
+	}
+	defer cg.LeaveNested()
 	order = binarystruct.BigEndian
 	var tmp [8]byte
 	var m int
@@ -86,7 +114,9 @@ func (s *Packet) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader
 		return n, err
 	}
 	if !bytes.Equal(s.Magic[:], []byte{0x50, 0x41, 0x4b, 0x31}) {
-		return n, &binarystruct.DecodeError{Offset: voffMagic, Field: "Magic", Err: fmt.Errorf("const mismatch: %w", binarystruct.ErrValidationError)}
+		if err = cg.ValidationFailed(cg.DecodeError(voffMagic, "Magic", fmt.Sprintf("%T", s.Magic), "[4]byte,const=0x50414b31", &binarystruct.CheckError{Expected: "0x50414b31", Actual: fmt.Sprintf("%#x", s.Magic[:]), Err: fmt.Errorf("const mismatch: %w", binarystruct.ErrValidationError)})); err != nil {
+			return n, err
+		}
 	}
 	m, err = io.ReadFull(r, tmp[:4])
 	n += m
@@ -102,21 +132,27 @@ func (s *Packet) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader
 	}
 	s.Version = uint8(tmp[0])
 	if s.Version < 1 {
-		return n, &binarystruct.DecodeError{Offset: voffVersion, Field: "Version", Err: fmt.Errorf("value %v is out of range [1..10]: %w", s.Version, binarystruct.ErrValidationError)}
+		if err = cg.ValidationFailed(cg.DecodeError(voffVersion, "Version", fmt.Sprintf("%T", s.Version), "uint8,range=1..10", &binarystruct.CheckError{Expected: "1..10", Actual: fmt.Sprint(s.Version), Err: fmt.Errorf("value %v is out of range [1..10]: %w", s.Version, binarystruct.ErrValidationError)})); err != nil {
+			return n, err
+		}
 	}
 	if s.Version > 10 {
-		return n, &binarystruct.DecodeError{Offset: voffVersion, Field: "Version", Err: fmt.Errorf("value %v is out of range [1..10]: %w", s.Version, binarystruct.ErrValidationError)}
+		if err = cg.ValidationFailed(cg.DecodeError(voffVersion, "Version", fmt.Sprintf("%T", s.Version), "uint8,range=1..10", &binarystruct.CheckError{Expected: "1..10", Actual: fmt.Sprint(s.Version), Err: fmt.Errorf("value %v is out of range [1..10]: %w", s.Version, binarystruct.ErrValidationError)})); err != nil {
+			return n, err
+		}
 	}
 	{
-		readLen := int(8)
-		s.Payload = make([]byte, readLen)
-		m, err = io.ReadFull(r, s.Payload)
+		readLen := 8
+		if err = cg.LimitSlice([]byte(nil), readLen); err != nil {
+			return n, err
+		}
+		s.Payload, m, err = cg.ReadBytes(r, readLen)
 		n += m
 		if err != nil {
 			return n, err
 		}
 	}
-	return n, nil
+	return n, cg.AfterUnmarshalHooks(s)
 }
 
 // MarshalBinary implements encoding.BinaryMarshaler.
@@ -147,18 +183,34 @@ func (s *Chunk) WriteBinary(w io.Writer, order binarystruct.ByteOrder) (int, err
 
 // WriteBinaryWithMarshaler implements binarystruct.MarshalerContextWriter.
 func (s *Chunk) WriteBinaryWithMarshaler(ms *binarystruct.Marshaler, w io.Writer, order binarystruct.ByteOrder) (n int, err error) {
+	cg := binarystruct.NewCodegen(ms)
+	if err = cg.BeforeMarshalHook(s); err != nil {
+		return 0, err
+	}
 	order = binarystruct.BigEndian
 	var tmp [8]byte
 	var m int
+	var efield string
+	var eoff int
+	defer func() {
+		if err != nil && efield != "" {
+			err = cg.EncodeError(eoff, efield, err)
+		}
+	}()
+	efield, eoff = "Length", n
 	order.PutUint32(tmp[:4], uint32((len(s.Data))))
 	m, err = w.Write(tmp[:4])
 	n += m
 	if err != nil {
 		return n, err
 	}
+	efield, eoff = "Type", n
 	{
 		strBytes := []byte(s.Type)
-		bufLen := int(4)
+		if len(strBytes) > 4 {
+			return n, fmt.Errorf("string too long: len %d, buffer size %d: %w", len(strBytes), 4, binarystruct.ErrValueOverflow)
+		}
+		bufLen := 4
 		writeBytes := make([]byte, bufLen)
 		copy(writeBytes, strBytes)
 		m, err = w.Write(writeBytes)
@@ -167,14 +219,16 @@ func (s *Chunk) WriteBinaryWithMarshaler(ms *binarystruct.Marshaler, w io.Writer
 			return n, err
 		}
 	}
+	efield, eoff = "Data", n
 	{
-		writeLen := int((len(s.Data)))
+		writeLen := len(s.Data)
 		m, err = w.Write(s.Data[:writeLen])
 		n += m
 		if err != nil {
 			return n, err
 		}
 	}
+	efield, eoff = "CRC", n
 	if ms == nil {
 		return n, errors.New("marshaler required for valueof CRC32")
 	}
@@ -207,6 +261,16 @@ func (s *Chunk) ReadBinary(r io.Reader, order binarystruct.ByteOrder) (int, erro
 
 // ReadBinaryWithMarshaler implements binarystruct.MarshalerContextReader.
 func (s *Chunk) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader, order binarystruct.ByteOrder) (n int, err error) {
+	cg := binarystruct.NewCodegen(ms)
+	if cg.Salvaging() {
+		return cg.ReadStructFields(r, order, s)
+	}
+	cg.BeginDecode()
+	defer cg.EndDecode(&err)
+	if err = cg.EnterNested(); err != nil {
+		return 0, err
+	}
+	defer cg.LeaveNested()
 	order = binarystruct.BigEndian
 	var tmp [8]byte
 	var m int
@@ -218,9 +282,11 @@ func (s *Chunk) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader,
 	s.Length = uint32(order.Uint32(tmp[:4]))
 	{
 		var strBytes []byte
-		strLen := int(4)
-		strBytes = make([]byte, strLen)
-		m, err = io.ReadFull(r, strBytes)
+		strLen := 4
+		if err = cg.LimitString(strLen); err != nil {
+			return n, err
+		}
+		strBytes, m, err = cg.ReadBytes(r, strLen)
 		n += m
 		if err != nil {
 			return n, err
@@ -232,8 +298,10 @@ func (s *Chunk) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader,
 	}
 	{
 		readLen := int(s.Length)
-		s.Data = make([]byte, readLen)
-		m, err = io.ReadFull(r, s.Data)
+		if err = cg.LimitSlice([]byte(nil), readLen); err != nil {
+			return n, err
+		}
+		s.Data, m, err = cg.ReadBytes(r, readLen)
 		n += m
 		if err != nil {
 			return n, err
@@ -245,24 +313,28 @@ func (s *Chunk) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader,
 		return n, err
 	}
 	s.CRC = uint32(order.Uint32(tmp[:4]))
-	if ms == nil {
-		return n, errors.New("marshaler required for valueof CRC32")
-	}
-	{
-		fn := ms.GetValueOf("CRC32")
-		if fn == nil {
-			return n, fmt.Errorf("unknown valueof evaluator: %s", "CRC32")
+	if cg.VerifiesChecksums() {
+		if ms == nil {
+			return n, errors.New("marshaler required for valueof CRC32")
 		}
-		voType := make([]byte, 4)
-		copy(voType, []byte(s.Type))
-		var voVal uint64
-		voVal, err = fn(binarystruct.ValueOfContext{Struct: s, Target: "CRC", Decoding: true, Args: []binarystruct.ValueOfArg{{Name: "Type", Bytes: voType, Value: s.Type}, {Name: "Data", Bytes: s.Data, Value: s.Data}}})
-		if err != nil {
-			return n, err
-		}
-		if s.CRC != uint32(voVal) {
-			return n, &binarystruct.DecodeError{Offset: n, Field: "CRC", Err: fmt.Errorf("valueof CRC32() mismatch: %w", binarystruct.ErrValidationError)}
+		{
+			fn := ms.GetValueOf("CRC32")
+			if fn == nil {
+				return n, fmt.Errorf("unknown valueof evaluator: %s", "CRC32")
+			}
+			voType := make([]byte, 4)
+			copy(voType, []byte(s.Type))
+			var voVal uint64
+			voVal, err = fn(binarystruct.ValueOfContext{Struct: s, Target: "CRC", Decoding: true, Args: []binarystruct.ValueOfArg{{Name: "Type", Bytes: voType, Value: s.Type}, {Name: "Data", Bytes: s.Data, Value: s.Data}}})
+			if err != nil {
+				return n, err
+			}
+			if s.CRC != uint32(voVal) {
+				if err = cg.ValidationFailed(cg.DecodeError(n, "CRC", fmt.Sprintf("%T", s.CRC), "uint32,valueof=CRC32(Type, Data)", &binarystruct.CheckError{Expected: fmt.Sprintf("%#x", uint32(voVal)), Actual: fmt.Sprintf("%#x", s.CRC), Err: fmt.Errorf("valueof CRC32() mismatch: %w", binarystruct.ErrValidationError)})); err != nil {
+					return n, err
+				}
+			}
 		}
 	}
-	return n, nil
+	return n, cg.AfterUnmarshalHooks(s)
 }
@@ -4,6 +4,7 @@ package example
 
 import (
 	"bytes"
+	"fmt"
 	"github.com/mixcode/binarystruct"
 	"io"
 )
@@ -36,17 +37,30 @@ func (s *Samples) WriteBinary(w io.Writer, order binarystruct.ByteOrder) (int, e
 
 // WriteBinaryWithMarshaler implements binarystruct.MarshalerContextWriter.
 func (s *Samples) WriteBinaryWithMarshaler(ms *binarystruct.Marshaler, w io.Writer, order binarystruct.ByteOrder) (n int, err error) {
+	cg := binarystruct.NewCodegen(ms)
+	if err = cg.BeforeMarshalHook(s); err != nil {
+		return 0, err
+	}
 	order = binarystruct.LittleEndian
 	var tmp [8]byte
 	var m int
+	var efield string
+	var eoff int
+	defer func() {
+		if err != nil && efield != "" {
+			err = cg.EncodeError(eoff, efield, err)
+		}
+	}()
+	efield, eoff = "N", n
 	order.PutUint32(tmp[:4], uint32((len(s.V))))
 	m, err = w.Write(tmp[:4])
 	n += m
 	if err != nil {
 		return n, err
 	}
+	efield, eoff = "V", n
 	{
-		writeLen := int((len(s.V)))
+		writeLen := len(s.V)
 		sbuf := make([]byte, writeLen*4)
 		for i := 0; i < writeLen; i++ {
 			order.PutUint32(sbuf[i*4:], uint32(s.V[i]))
@@ -67,6 +81,16 @@ func (s *Samples) ReadBinary(r io.Reader, order binarystruct.ByteOrder) (int, er
 
 // ReadBinaryWithMarshaler implements binarystruct.MarshalerContextReader.
 func (s *Samples) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader, order binarystruct.ByteOrder) (n int, err error) {
+	cg := binarystruct.NewCodegen(ms)
+	if cg.Salvaging() {
+		return cg.ReadStructFields(r, order, s)
+	}
+	cg.BeginDecode()
+	defer cg.EndDecode(&err)
+	if err = cg.EnterNested(); err != nil {
+		return 0, err
+	}
+	defer cg.LeaveNested()
 	order = binarystruct.LittleEndian
 	var tmp [8]byte
 	var m int
@@ -78,18 +102,21 @@ func (s *Samples) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reade
 	s.N = uint32(order.Uint32(tmp[:4]))
 	{
 		readLen := int(s.N)
-		s.V = make([]uint32, readLen)
-		sbuf := make([]byte, readLen*4)
-		m, err = io.ReadFull(r, sbuf)
+		if err = cg.LimitSlice([]uint32(nil), readLen); err != nil {
+			return n, err
+		}
+		var sbuf []byte
+		sbuf, m, err = cg.ReadBytes(r, readLen*4)
 		n += m
 		if err != nil {
 			return n, err
 		}
+		s.V = make([]uint32, readLen)
 		for i := 0; i < readLen; i++ {
 			s.V[i] = uint32(order.Uint32(sbuf[i*4:]))
 		}
 	}
-	return n, nil
+	return n, cg.AfterUnmarshalHooks(s)
 }
 
 // MarshalBinary implements encoding.BinaryMarshaler.
@@ -120,20 +147,36 @@ func (s *Rec) WriteBinary(w io.Writer, order binarystruct.ByteOrder) (int, error
 
 // WriteBinaryWithMarshaler implements binarystruct.MarshalerContextWriter.
 func (s *Rec) WriteBinaryWithMarshaler(ms *binarystruct.Marshaler, w io.Writer, order binarystruct.ByteOrder) (n int, err error) {
+	cg := binarystruct.NewCodegen(ms)
+	if err = cg.BeforeMarshalHook(s); err != nil {
+		return 0, err
+	}
 	order = binarystruct.LittleEndian
 	var tmp [8]byte
 	var m int
+	var efield string
+	var eoff int
+	defer func() {
+		if err != nil && efield != "" {
+			err = cg.EncodeError(eoff, efield, err)
+		}
+	}()
+	efield, eoff = "N", n
 	order.PutUint16(tmp[:2], uint16((len(s.Items))))
 	m, err = w.Write(tmp[:2])
 	n += m
 	if err != nil {
 		return n, err
 	}
+	efield, eoff = "Items", n
 	{
-		limit := int((len(s.Items)))
+		limit := len(s.Items)
 		for i := 0; i < limit; i++ {
 			{
 				m, err = (s.Items[i]).WriteBinaryWithMarshaler(ms, w, order)
+				if err != nil {
+					err = cg.ElementError(i, n-eoff, err)
+				}
 				n += m
 				if err != nil {
 					return n, err
@@ -151,6 +194,16 @@ func (s *Rec) ReadBinary(r io.Reader, order binarystruct.ByteOrder) (int, error)
 
 // ReadBinaryWithMarshaler implements binarystruct.MarshalerContextReader.
 func (s *Rec) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader, order binarystruct.ByteOrder) (n int, err error) {
+	cg := binarystruct.NewCodegen(ms)
+	if cg.Salvaging() {
+		return cg.ReadStructFields(r, order, s)
+	}
+	cg.BeginDecode()
+	defer cg.EndDecode(&err)
+	if err = cg.EnterNested(); err != nil {
+		return 0, err
+	}
+	defer cg.LeaveNested()
 	order = binarystruct.LittleEndian
 	var tmp [8]byte
 	var m int
@@ -160,20 +213,35 @@ func (s *Rec) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader, o
 		return n, err
 	}
 	s.N = uint16(order.Uint16(tmp[:2]))
+	voffItems := n
 	{
 		readLen := int(s.N)
-		s.Items = make([]Item, readLen)
+		var sliceCap int
+		if sliceCap, err = cg.SliceCap([]Item(nil), r, readLen, 0); err != nil {
+			return n, err
+		}
+		s.Items = make([]Item, 0, sliceCap)
+		var zero Item
 		for i := 0; i < readLen; i++ {
+			s.Items = append(s.Items, zero)
 			{
+				estart := n
+				cmark := cg.CollectMark()
 				m, err = (s.Items[i]).ReadBinaryWithMarshaler(ms, r, order)
+				if err != nil {
+					err = cg.DecodeError(voffItems, "Items", fmt.Sprintf("%T", s.Items), "[N]any", cg.ElementError(i, estart-voffItems, err))
+				}
 				n += m
 				if err != nil {
 					return n, err
 				}
+				cg.WrapCollected(cmark, func(err error) error {
+					return cg.DecodeError(voffItems, "Items", fmt.Sprintf("%T", s.Items), "[N]any", cg.ElementError(i, estart-voffItems, err))
+				})
 			}
 		}
 	}
-	return n, nil
+	return n, cg.AfterUnmarshalHooks(s)
 }
 
 // MarshalBinary implements encoding.BinaryMarshaler.
@@ -204,8 +272,20 @@ func (s *Item) WriteBinary(w io.Writer, order binarystruct.ByteOrder) (int, erro
 
 // WriteBinaryWithMarshaler implements binarystruct.MarshalerContextWriter.
 func (s *Item) WriteBinaryWithMarshaler(ms *binarystruct.Marshaler, w io.Writer, order binarystruct.ByteOrder) (n int, err error) {
+	cg := binarystruct.NewCodegen(ms)
+	if err = cg.BeforeMarshalHook(s); err != nil {
+		return 0, err
+	}
 	order = binarystruct.LittleEndian
 	var m int
+	var efield string
+	var eoff int
+	defer func() {
+		if err != nil && efield != "" {
+			err = cg.EncodeError(eoff, efield, err)
+		}
+	}()
+	efield, eoff = "A", n
 	{
 		sbuf := make([]byte, 6)
 		order.PutUint32(sbuf[0:4], uint32(s.A))
@@ -226,6 +306,14 @@ func (s *Item) ReadBinary(r io.Reader, order binarystruct.ByteOrder) (int, error
 
 // ReadBinaryWithMarshaler implements binarystruct.MarshalerContextReader.
 func (s *Item) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader, order binarystruct.ByteOrder) (n int, err error) {
+	cg := binarystruct.NewCodegen(ms)
+	if cg.Salvaging() {
+		return cg.ReadStructFields(r, order, s)
+	}
+	if err = cg.EnterNested(); err != nil {
+		return 0, err
+	}
+	defer cg.LeaveNested()
 	order = binarystruct.LittleEndian
 	var m int
 	{
@@ -238,5 +326,5 @@ func (s *Item) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader,
 		s.A = uint32(order.Uint32(sbuf[0:4]))
 		s.B = uint16(order.Uint16(sbuf[4:6]))
 	}
-	return n, nil
+	return n, cg.AfterUnmarshalHooks(s)
 }
@@ -0,0 +1,860 @@
+// Copyright 2026 github.com/mixcode
+
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// Tag expressions are translated to Go source by a small recursive-descent
+// parser mirroring the runtime's tagParser (struct.go in the parent package):
+// the same tokens, the same precedence levels and the same int semantics.
+//
+// Every field reference becomes int(s.Field) — int(s.Hdr.Len) or
+// int(s.Dims[0]) for nested references — so operands of mixed widths combine
+// the way the runtime combines them (as ints); a parent./root. reference and a
+// $name parameter become Marshaler lookups where they are read (cgOuterVar,
+// cgParamVar), while $offset becomes the method's running count n and
+// $remaining a local refreshed before the field (see remainingPrologue).
+// Comparisons and the logical
+// operators produce Go bools, converted to 0/1 where an int is needed;
+// the conditional operator becomes an immediately-invoked func literal so only
+// the taken branch is evaluated. The built-ins align, min, max and abs become
+// branch-free Go (so constant arguments still fold to a Go constant), and
+// sizeof(T) becomes the literal size computed at generation time. Division,
+// modulo and shifts by a run-time value call the method's binarystruct.Codegen,
+// cg.Quo, cg.Rem, cg.Shl and cg.Shr, which fail as the interpreters do; an
+// expression that calls cg is enclosed in cgGuardOpen and cgGuardClose for
+// hoistGuarded. Other function calls are kept verbatim as s.fn(s.Arg, ...) for
+// translateValueof to rewrite.
+
+type cgTokKind int
+
+const (
+	cgTokEOF cgTokKind = iota
+	cgTokNum
+	cgTokIdent
+	cgTokOp
+	cgTokParam // $name, without the $
+)
+
+type cgTok struct {
+	kind cgTokKind
+	val  string
+}
+
+// cgExprOperators lists the operator tokens, longest first.
+var cgExprOperators = []string{
+	"<<", ">>", "<=", ">=", "==", "!=", "&&", "||",
+	"+", "-", "*", "/", "%", "&", "|", "^", "~", "!", "<", ">", "?", ":",
+	"(", ")", ",", ".", "[", "]",
+}
+
+func cgTokenize(expr string) ([]cgTok, error) {
+	var toks []cgTok
+	i, n := 0, len(expr)
+next:
+	for i < n {
+		c := expr[i]
+		if c == ' ' || c == '\t' || c == '\r' || c == '\n' {
+			i++
+			continue
+		}
+		for _, op := range cgExprOperators {
+			if strings.HasPrefix(expr[i:], op) {
+				toks = append(toks, cgTok{cgTokOp, op})
+				i += len(op)
+				continue next
+			}
+		}
+		isAlnum := func(b byte) bool {
+			return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '_'
+		}
+		switch {
+		case c >= '0' && c <= '9':
+			start := i
+			for i < n && isAlnum(expr[i]) {
+				i++
+			}
+			toks = append(toks, cgTok{cgTokNum, expr[start:i]})
+		case isAlnum(c):
+			start := i
+			for i < n && isAlnum(expr[i]) {
+				i++
+			}
+			toks = append(toks, cgTok{cgTokIdent, expr[start:i]})
+		case c == '$':
+			i++
+			start := i
+			for i < n && isAlnum(expr[i]) {
+				i++
+			}
+			if i == start {
+				return nil, fmt.Errorf("parameter name expected after $ in expression %q", expr)
+			}
+			toks = append(toks, cgTok{cgTokParam, expr[start:i]})
+		default:
+			return nil, fmt.Errorf("unexpected character %q in expression %q", c, expr)
+		}
+	}
+	return append(toks, cgTok{cgTokEOF, ""}), nil
+}
+
+// cgExprParser translates a tokenized expression. Each parse method returns
+// Go source and whether that source is a bool (true) or an int (false).
+type cgExprParser struct {
+	toks []cgTok
+	pos  int
+
+	// sizeOf resolves sizeof(TypeName); nil rejects sizeof().
+	sizeOf func(typeName string) (int, error)
+	// arrayLen returns the length of the Go array a field path such as
+	// "Hdr.Dims" denotes, false for a slice or when it is not known.
+	arrayLen func(path string) (int, bool)
+	// outerRefs collects the parent./root. references met, in order.
+	outerRefs []string
+	// params collects the $name references met (without the $), in order,
+	// including the built-in $offset and $remaining.
+	params []string
+	// fieldRefs collects the receiver's fields referenced, by top-level name.
+	fieldRefs []string
+	// offsetVar, when set, replaces n as the Go expression for $offset.
+	offsetVar string
+	// noCalls rejects calls other than the built-in functions (bytelen, count,
+	// custom evaluators), as in a check= rule.
+	noCalls bool
+	// guarded is set once the expression calls a cg method that can fail.
+	guarded bool
+}
+
+// cgGuardOpen and cgGuardClose enclose a translated expression that calls a
+// Codegen method which can fail, so that hoistGuarded can move it out of the
+// statement using it and check cg.Err before the statement runs.
+const (
+	cgGuardOpen  = "\x01"
+	cgGuardClose = "\x02"
+)
+
+// cgGuard encloses src in cgGuardOpen and cgGuardClose when guarded.
+func cgGuard(src string, guarded bool) string {
+	if !guarded {
+		return src
+	}
+	return cgGuardOpen + src + cgGuardClose
+}
+
+func (p *cgExprParser) peek() cgTok { return p.toks[p.pos] }
+
+func (p *cgExprParser) next() cgTok {
+	t := p.toks[p.pos]
+	if t.kind != cgTokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *cgExprParser) isOp(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != cgTokOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.val == op {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func cgAsInt(src string, isBool bool) string {
+	if isBool {
+		return "func() int {\n\t\tif " + src + " {\n\t\t\treturn 1\n\t\t}\n\t\treturn 0\n\t}()"
+	}
+	return src
+}
+
+func cgAsBool(src string, isBool bool) string {
+	if isBool {
+		return src
+	}
+	return "(" + src + " != 0)"
+}
+
+func (p *cgExprParser) parseExpr() (string, bool, error) {
+	cond, cb, err := p.parseLogicalOr()
+	if err != nil {
+		return "", false, err
+	}
+	if _, ok := p.isOp("?"); !ok {
+		return cond, cb, nil
+	}
+	p.next()
+	a, ab, err := p.parseExpr()
+	if err != nil {
+		return "", false, err
+	}
+	if _, ok := p.isOp(":"); !ok {
+		return "", false, fmt.Errorf("missing ':' in conditional expression")
+	}
+	p.next()
+	b, bb, err := p.parseExpr()
+	if err != nil {
+		return "", false, err
+	}
+	return fmt.Sprintf("func() int {\n\t\tif %s {\n\t\t\treturn %s\n\t\t}\n\t\treturn %s\n\t}()",
+		cgAsBool(cond, cb), cgAsInt(a, ab), cgAsInt(b, bb)), false, nil
+}
+
+func (p *cgExprParser) parseLogicalOr() (string, bool, error) {
+	l, lb, err := p.parseLogicalAnd()
+	if err != nil {
+		return "", false, err
+	}
+	for {
+		if _, ok := p.isOp("||"); !ok {
+			return l, lb, nil
+		}
+		p.next()
+		r, rb, err := p.parseLogicalAnd()
+		if err != nil {
+			return "", false, err
+		}
+		l, lb = "("+cgAsBool(l, lb)+" || "+cgAsBool(r, rb)+")", true
+	}
+}
+
+func (p *cgExprParser) parseLogicalAnd() (string, bool, error) {
+	l, lb, err := p.parseComparison()
+	if err != nil {
+		return "", false, err
+	}
+	for {
+		if _, ok := p.isOp("&&"); !ok {
+			return l, lb, nil
+		}
+		p.next()
+		r, rb, err := p.parseComparison()
+		if err != nil {
+			return "", false, err
+		}
+		l, lb = "("+cgAsBool(l, lb)+" && "+cgAsBool(r, rb)+")", true
+	}
+}
+
+func (p *cgExprParser) parseComparison() (string, bool, error) {
+	return p.parseBinary(p.parseAdditive, true, "==", "!=", "<", "<=", ">", ">=")
+}
+
+func (p *cgExprParser) parseAdditive() (string, bool, error) {
+	return p.parseBinary(p.parseTerm, false, "+", "-", "|", "^")
+}
+
+func (p *cgExprParser) parseTerm() (string, bool, error) {
+	return p.parseBinary(p.parseFactor, false, "*", "/", "%", "<<", ">>", "&")
+}
+
+// parseBinary parses a left-associative run of int operators at one precedence
+// level; yieldsBool marks comparison levels.
+func (p *cgExprParser) parseBinary(operand func() (string, bool, error), yieldsBool bool, ops ...string) (string, bool, error) {
+	l, lb, err := operand()
+	if err != nil {
+		return "", false, err
+	}
+	for {
+		op, ok := p.isOp(ops...)
+		if !ok {
+			return l, lb, nil
+		}
+		p.next()
+		r, rb, err := operand()
+		if err != nil {
+			return "", false, err
+		}
+		li, ri := cgAsInt(l, lb), cgAsInt(r, rb)
+		if fn, ok := cgGuardedOps[op]; ok {
+			if v, ok := cgGoConstInt(ri); !ok || v == 0 && (op == "/" || op == "%") || v < 0 {
+				// A divisor or shift count known only at run time, or an invalid
+				// constant one, fails through the runtime's helper as in the
+				// interpreters, rather than panicking or failing to compile.
+				l, lb = "cg."+fn+"("+li+", "+ri+")", false
+				p.guarded = true
+				continue
+			}
+		}
+		l, lb = "("+li+" "+op+" "+ri+")", yieldsBool
+	}
+}
+
+// cgGuardedOps maps the operators that can fail at run time to the Codegen
+// methods evaluating them (see binarystruct.Codegen.Quo).
+var cgGuardedOps = map[string]string{"/": "Quo", "%": "Rem", "<<": "Shl", ">>": "Shr"}
+
+// parseFieldPath parses the .Field and [N] selectors following a field name and
+// returns the reference, e.g. "Hdr.Dims[2]", and src, its Go selector on the
+// receiver s. An index into a slice, or out of an array, must first pass the
+// checks in inRange (cg.InRange), which fail as the interpreters do.
+func (p *cgExprParser) parseFieldPath(name string) (path, src string, inRange []string, err error) {
+	path, src = name, "s."+name
+	for {
+		if _, ok := p.isOp("."); ok {
+			p.next()
+			f := p.next()
+			if f.kind != cgTokIdent {
+				return "", "", nil, fmt.Errorf("field name expected after %s.", path)
+			}
+			path += "." + f.val
+			src += "." + f.val
+			continue
+		}
+		if _, ok := p.isOp("["); ok {
+			p.next()
+			n := p.next()
+			idx, err := strconv.ParseInt(n.val, 0, 64)
+			if n.kind != cgTokNum || err != nil {
+				return "", "", nil, fmt.Errorf("index of %s must be an integer literal", path)
+			}
+			if _, ok := p.isOp("]"); !ok {
+				return "", "", nil, fmt.Errorf("missing closing bracket in %s[...]", path)
+			}
+			p.next()
+			arr := src
+			path += "[" + strconv.FormatInt(idx, 10) + "]"
+			if l, ok := p.lenOf(path[:strings.LastIndexByte(path, '[')]); !ok || idx >= int64(l) {
+				inRange = append(inRange, fmt.Sprintf("cg.InRange(len(%s), %d, %q)", arr, idx, path))
+			}
+			src += "[" + strconv.FormatInt(idx, 10) + "]"
+			continue
+		}
+		return path, src, inRange, nil
+	}
+}
+
+// lenOf returns the length of the array path denotes (see arrayLen).
+func (p *cgExprParser) lenOf(path string) (int, bool) {
+	if p.arrayLen == nil || cgIsOuterRef(path) {
+		return 0, false
+	}
+	return p.arrayLen(path)
+}
+
+func (p *cgExprParser) parseFactor() (string, bool, error) {
+	t := p.next()
+	switch t.kind {
+	case cgTokNum:
+		return t.val, false, nil
+	case cgTokParam:
+		p.params = append(p.params, t.val)
+		if t.val == "offset" && p.offsetVar != "" {
+			return p.offsetVar, false, nil
+		}
+		if t.val != "offset" && t.val != "remaining" {
+			p.guarded = true
+		}
+		return cgParamVar(t.val), false, nil
+	case cgTokIdent:
+		if _, ok := p.isOp("("); !ok {
+			path, src, inRange, err := p.parseFieldPath(t.val)
+			if err != nil {
+				return "", false, err
+			}
+			if cgIsOuterRef(path) {
+				p.outerRefs = append(p.outerRefs, path)
+				p.guarded = true
+				return cgOuterVar(path), false, nil
+			}
+			p.fieldRefs = append(p.fieldRefs, t.val)
+			if len(inRange) > 0 {
+				// An index out of range reads 0, with the error left on cg.
+				p.guarded = true
+				return "func() int {\n\t\tif !(" + strings.Join(inRange, " && ") + ") {\n\t\t\treturn 0\n\t\t}\n\t\treturn int(" + src + ")\n\t}()", false, nil
+			}
+			return "int(" + src + ")", false, nil
+		}
+		if cgIsExprBuiltin(t.val) {
+			src, err := p.parseBuiltin(t.val)
+			return src, false, err
+		}
+		if p.noCalls {
+			return "", false, fmt.Errorf("function %s() is not allowed here", t.val)
+		}
+		// function call: kept as s.fn(s.A, s.B) for translateValueof.
+		p.next()
+		var args []string
+		for {
+			a := p.next()
+			if a.kind != cgTokIdent {
+				return "", false, fmt.Errorf("function %s() expects field-name arguments", t.val)
+			}
+			args = append(args, "s."+a.val)
+			if _, ok := p.isOp(","); ok {
+				p.next()
+				continue
+			}
+			break
+		}
+		if _, ok := p.isOp(")"); !ok {
+			return "", false, fmt.Errorf("missing closing parenthesis in %s(...)", t.val)
+		}
+		p.next()
+		return "s." + t.val + "(" + strings.Join(args, ", ") + ")", false, nil
+	case cgTokOp:
+		switch t.val {
+		case "+":
+			return p.parseFactor()
+		case "-", "~":
+			v, vb, err := p.parseFactor()
+			if err != nil {
+				return "", false, err
+			}
+			op := t.val
+			if op == "~" {
+				op = "^" // Go's unary bitwise complement
+			}
+			return "(" + op + cgAsInt(v, vb) + ")", false, nil
+		case "!":
+			v, vb, err := p.parseFactor()
+			if err != nil {
+				return "", false, err
+			}
+			return "!" + cgAsBool(v, vb), true, nil
+		case "(":
+			v, vb, err := p.parseExpr()
+			if err != nil {
+				return "", false, err
+			}
+			if _, ok := p.isOp(")"); !ok {
+				return "", false, fmt.Errorf("missing closing parenthesis")
+			}
+			p.next()
+			if vb {
+				return "(" + v + ")", true, nil
+			}
+			return v, false, nil
+		}
+	}
+	return "", false, fmt.Errorf("unexpected token %q", t.val)
+}
+
+// cgIsExprBuiltin mirrors the runtime's isExprBuiltin: functions of the
+// expression language itself, as opposed to valueof functions.
+func cgIsExprBuiltin(name string) bool {
+	switch name {
+	case "align", "min", "max", "abs", "sizeof":
+		return true
+	}
+	return false
+}
+
+// parseBuiltin translates a built-in call; the name has been consumed and the
+// next token is '('.
+func (p *cgExprParser) parseBuiltin(name string) (string, error) {
+	p.next() // '('
+	if name == "sizeof" {
+		arg := p.next()
+		if _, ok := p.isOp(")"); arg.kind != cgTokIdent || !ok {
+			return "", fmt.Errorf("sizeof() expects a single type name")
+		}
+		p.next()
+		if p.sizeOf == nil {
+			return "", fmt.Errorf("sizeof(%s) is not allowed here", arg.val)
+		}
+		sz, err := p.sizeOf(arg.val)
+		if err != nil {
+			return "", err
+		}
+		return strconv.Itoa(sz), nil
+	}
+
+	var args []string
+	for {
+		a, ab, err := p.parseExpr()
+		if err != nil {
+			return "", err
+		}
+		args = append(args, cgAsInt(a, ab))
+		if _, ok := p.isOp(","); !ok {
+			break
+		}
+		p.next()
+	}
+	if _, ok := p.isOp(")"); !ok {
+		return "", fmt.Errorf("missing closing parenthesis in %s(...)", name)
+	}
+	p.next()
+
+	switch name {
+	case "abs":
+		if len(args) != 1 {
+			return "", fmt.Errorf("abs() takes exactly one argument")
+		}
+		return "max(" + args[0] + ", -(" + args[0] + "))", nil
+	case "align":
+		if len(args) != 2 {
+			return "", fmt.Errorf("align() takes exactly two arguments")
+		}
+		// v rounded up to a multiple of a, for negative v too (as the runtime).
+		// An alignment known only at run time goes through the runtime's
+		// helper, which fails as the interpreters do when it is not positive.
+		if c, ok := cgGoConstInt(args[1]); !ok || c <= 0 {
+			p.guarded = true
+			return "cg.Align(" + args[0] + ", " + args[1] + ")", nil
+		}
+		v, a := "("+args[0]+")", "("+args[1]+")"
+		return "(" + v + " + (" + a + "-" + v + "%" + a + ")%" + a + ")", nil
+	default: // min, max: Go's built-ins
+		if len(args) < 2 {
+			return "", fmt.Errorf("%s() takes two or more arguments", name)
+		}
+		return name + "(" + strings.Join(args, ", ") + ")", nil
+	}
+}
+
+// cgTranslateExpr translates a tag expression into a Go int expression over the
+// receiver s. sizeOf resolves sizeof(T); when nil, sizeof() is an error.
+// arrayLen, when not nil, tells the indexes that need no bounds check.
+func cgTranslateExpr(expr string, sizeOf func(string) (int, error), arrayLen func(string) (int, bool)) (string, error) {
+	toks, err := cgTokenize(expr)
+	if err != nil {
+		return "", err
+	}
+	p := &cgExprParser{toks: toks, sizeOf: sizeOf, arrayLen: arrayLen}
+	src, isBool, err := p.parseExpr()
+	if err != nil {
+		return "", fmt.Errorf("invalid expression %q: %w", expr, err)
+	}
+	if t := p.peek(); t.kind != cgTokEOF {
+		return "", fmt.Errorf("invalid expression %q: unexpected token %q", expr, t.val)
+	}
+	return cgGuard(cgAsInt(src, isBool), p.guarded), nil
+}
+
+// cgTranslateCond translates a check= rule into a Go bool expression over the
+// receiver s. It is evaluated after its field is read, so $offset is offsetVar,
+// the field's start, rather than the running count n.
+func cgTranslateCond(expr string, sizeOf func(string) (int, error), arrayLen func(string) (int, bool), offsetVar string) (string, error) {
+	toks, err := cgTokenize(expr)
+	if err != nil {
+		return "", err
+	}
+	p := &cgExprParser{toks: toks, sizeOf: sizeOf, arrayLen: arrayLen, offsetVar: offsetVar, noCalls: true}
+	src, isBool, err := p.parseExpr()
+	if err != nil {
+		return "", fmt.Errorf("invalid expression %q: %w", expr, err)
+	}
+	if t := p.peek(); t.kind != cgTokEOF {
+		return "", fmt.Errorf("invalid expression %q: unexpected token %q", expr, t.val)
+	}
+	return cgGuard(cgAsBool(src, isBool), p.guarded), nil
+}
+
+// cgExprOuterRefs returns the parent./root. references of a tag expression.
+func cgExprOuterRefs(expr string) ([]string, error) {
+	p, err := cgParseRefs(expr)
+	if err != nil {
+		return nil, err
+	}
+	return p.outerRefs, nil
+}
+
+// cgExprFieldRefs returns the receiver fields a tag expression references, by
+// top-level name.
+func cgExprFieldRefs(expr string) ([]string, error) {
+	p, err := cgParseRefs(expr)
+	if err != nil {
+		return nil, err
+	}
+	return p.fieldRefs, nil
+}
+
+// cgExprParams returns the $name references of a tag expression, without the $.
+func cgExprParams(expr string) ([]string, error) {
+	p, err := cgParseRefs(expr)
+	if err != nil {
+		return nil, err
+	}
+	return p.params, nil
+}
+
+// cgExprUsesParam reports whether expr refers to $name; malformed expressions
+// report false (generateMethods rejects them).
+func cgExprUsesParam(expr, name string) bool {
+	params, _ := cgExprParams(expr)
+	for _, pn := range params {
+		if pn == name {
+			return true
+		}
+	}
+	return false
+}
+
+func cgParseRefs(expr string) (*cgExprParser, error) {
+	toks, err := cgTokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &cgExprParser{toks: toks, sizeOf: func(string) (int, error) { return 0, nil }}
+	if _, _, err := p.parseExpr(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// cgParamVar names the Go expression for $name. A Marshaler parameter is
+// looked up where the expression reads it (cg.Param), so one that ?:, && or
+// || skips need not be set; $offset is the running count n,
+// which is relative to the struct as the runtime's is.
+func cgParamVar(name string) string {
+	switch name {
+	case "offset":
+		return "n"
+	case "remaining":
+		return "remaining"
+	}
+	return fmt.Sprintf("cg.Param(%q)", name)
+}
+
+// cgIsOuterRef reports whether a field reference reaches into an enclosing
+// struct (parent.X, parent.parent.X, root.X).
+func cgIsOuterRef(path string) bool {
+	return strings.HasPrefix(path, "parent.") || strings.HasPrefix(path, "root.")
+}
+
+// cgOuterVar names the Go expression for a parent./root. reference, looked up
+// where the expression reads it (cg.Outer): without an enclosing
+// struct, root. is the struct itself, whose earlier fields a read method has
+// only just decoded.
+func cgOuterVar(path string) string {
+	return fmt.Sprintf("cg.Outer(s, %q)", path)
+}
+
+// sizeOf resolves sizeof(TypeName) at generation time to the fixed encoded size
+// of a struct type declared in the package. As in the runtime, the type must be
+// the one generated or reachable from its fields.
+func (g *Generator) sizeOf(name string) (int, error) {
+	if g.curType != "" && !g.usesStruct(g.curType, name, map[string]bool{}) {
+		return 0, fmt.Errorf("sizeof(%s): no struct type named %s is used by %s", name, name, g.curType)
+	}
+	sz, err := g.staticStructSize(name, map[string]bool{})
+	if err != nil {
+		return 0, fmt.Errorf("sizeof(%s): %w", name, err)
+	}
+	return sz, nil
+}
+
+// arrayLen returns the length of the Go array that a field path such as
+// "Hdr.Dims" or "Recs[0].Dims" denotes within the generated struct, and false
+// for a slice, or when the length is not a literal.
+func (g *Generator) arrayLen(path string) (int, bool) {
+	st, ok := g.structs[g.curType]
+	if !ok {
+		return 0, false
+	}
+	var t ast.Expr
+	for _, seg := range strings.Split(path, ".") {
+		name, idx, _ := strings.Cut(seg, "[")
+		if st == nil {
+			return 0, false
+		}
+		t = nil
+		for _, f := range st.Fields.List {
+			for _, id := range f.Names {
+				if id.Name == name {
+					t = f.Type
+				}
+			}
+		}
+		for ; idx != ""; _, idx, _ = strings.Cut(idx, "[") {
+			a, ok := t.(*ast.ArrayType)
+			if !ok {
+				return 0, false
+			}
+			t = a.Elt
+		}
+		st = nil
+		if id, ok := t.(*ast.Ident); ok {
+			st = g.structs[id.Name]
+		}
+	}
+	a, ok := t.(*ast.ArrayType)
+	if !ok || a.Len == nil {
+		return 0, false
+	}
+	lit, ok := a.Len.(*ast.BasicLit)
+	if !ok || lit.Kind != token.INT {
+		return 0, false
+	}
+	n, err := strconv.Atoi(lit.Value)
+	return n, err == nil
+}
+
+// usesStruct mirrors the runtime's findStructType: whether struct type name is
+// typeName or reachable from its fields through pointers, arrays and slices.
+func (g *Generator) usesStruct(typeName, name string, seen map[string]bool) bool {
+	st, ok := g.structs[typeName]
+	if !ok || seen[typeName] {
+		return false
+	}
+	seen[typeName] = true
+	if typeName == name {
+		return true
+	}
+	for _, f := range st.Fields.List {
+		t := f.Type
+		for {
+			if s, ok := t.(*ast.StarExpr); ok {
+				t = s.X
+			} else if a, ok := t.(*ast.ArrayType); ok {
+				t = a.Elt
+			} else {
+				break
+			}
+		}
+		if id, ok := t.(*ast.Ident); ok && g.usesStruct(id.Name, name, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// staticStructSize mirrors the runtime's staticStructSize over the parsed AST:
+// the encoded size of struct type name when every field has a fixed size, or
+// an error naming the first field whose size varies.
+func (g *Generator) staticStructSize(name string, visiting map[string]bool) (int, error) {
+	st, ok := g.structs[name]
+	if !ok {
+		return 0, fmt.Errorf("no struct type named %s in the package", name)
+	}
+	if visiting[name] {
+		return 0, fmt.Errorf("%s contains itself", name)
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	size := 0
+	for _, f := range st.Fields.List {
+		goType := getGoTypeName(f.Type)
+		if len(f.Names) == 0 {
+			return 0, fmt.Errorf("%s has no fixed size: embedded field %s", name, goType)
+		}
+		for _, id := range f.Names {
+			if id.Name == "_" && goType == "struct{}" || !id.IsExported() && id.Name != "_" {
+				continue // struct-level sentinel, or an unexported (skipped) field
+			}
+			sz, err := g.staticFieldSize(goType, parseFieldTag(f.Tag), visiting)
+			if err != nil {
+				return 0, fmt.Errorf("%s has no fixed size: field %s: %w", name, id.Name, err)
+			}
+			size += sz
+		}
+	}
+	return size, nil
+}
+
+// staticFieldSize returns the fixed encoded size of a field (see
+// staticStructSize).
+func (g *Generator) staticFieldSize(goType string, pt parsedFieldTag, visiting map[string]bool) (int, error) {
+	if _, ok := pt.options["ignore"]; ok || pt.binaryType == "-" || pt.binaryType == "ignore" {
+		return 0, nil
+	}
+	if _, ok := pt.options["omittable"]; ok {
+		return 0, fmt.Errorf("omittable")
+	}
+	if c := pt.options["codec"]; c != "" {
+		return 0, fmt.Errorf("custom codec %s", c)
+	}
+	if strings.HasPrefix(goType, "*") {
+		return 0, fmt.Errorf("%s may be nil", goType)
+	}
+
+	count := 1
+	dims := pt.arrayDimExprs
+	if !pt.isArray && isFixedArrayType(goType) && (pt.binaryType == "" || pt.binaryType == "any") {
+		dims = []string{""} // untagged Go array: its own length
+	}
+	for _, d := range dims {
+		if d == "" {
+			if !isFixedArrayType(goType) {
+				return 0, fmt.Errorf("array length is not constant")
+			}
+			d = goType[1:strings.IndexByte(goType, ']')]
+		}
+		n, ok := cgConstInt(d)
+		if !ok || n < 0 {
+			return 0, fmt.Errorf("array length is not constant")
+		}
+		count *= n
+		goType = cgPeelArrayLevels(goType, 1)
+	}
+
+	binType := pt.binaryType
+	if binType == "" || binType == "any" {
+		binType = goType
+	}
+	bufLen := -1
+	if pt.bufLenExpr != "" {
+		if n, ok := cgConstInt(pt.bufLenExpr); ok {
+			bufLen = n
+		}
+	}
+
+	var elem int
+	if w, ok := scalarWidth(binType); ok {
+		elem = w
+	} else if _, ok := g.structs[binType]; ok {
+		sz, err := g.staticStructSize(binType, visiting)
+		if err != nil {
+			return 0, err
+		}
+		elem = sz
+	} else {
+		switch binType {
+		case "pad":
+			elem = 1
+			if pt.bufLenExpr != "" {
+				elem = bufLen
+			}
+		case "string", "bstring", "wstring", "dwstring":
+			if pt.bufLenExpr != "" {
+				elem = bufLen
+				if elem >= 0 {
+					elem += stringPrefixWidth(binType)
+				}
+			} else {
+				elem = -1
+			}
+		default:
+			return 0, fmt.Errorf("%s has no fixed size", binType)
+		}
+		if elem < 0 {
+			return 0, fmt.Errorf("%s without a constant size", binType)
+		}
+	}
+	return count * elem, nil
+}
+
+// cgConstInt evaluates a tag expression that uses no field references (and no
+// sizeof), as the runtime's evalConstIntExpr does, by translating it to Go and
+// folding the result as a Go constant.
+func cgConstInt(expr string) (int, bool) {
+	src, err := cgTranslateExpr(expr, nil, nil)
+	if err != nil {
+		return 0, false
+	}
+	return cgGoConstInt(src)
+}
+
+// cgGoConstInt evaluates translated Go source that is an integer constant.
+func cgGoConstInt(src string) (int, bool) {
+	tv, err := types.Eval(token.NewFileSet(), nil, token.NoPos, src)
+	if err != nil || tv.Value == nil || tv.Value.Kind() != constant.Int {
+		return 0, false
+	}
+	v, exact := constant.Int64Val(tv.Value)
+	return int(v), exact
+}
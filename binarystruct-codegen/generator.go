@@ -12,6 +12,7 @@ import (
 	"go/parser"
 	"go/token"
 	"io/ioutil"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -39,6 +40,22 @@ type Generator struct {
 	// writes its magic, valueof still computes its value). Set from the -no-validate flag.
 	NoValidate bool
 
+	// ValidateEncode, when true, emits the range/match/enum/check validators in the
+	// generated write methods too, before each field is written, returning a
+	// *binarystruct.EncodeError on failure. It is baked in: unlike the runtime's
+	// Marshaler.ValidateOnEncode, it is not consulted at run time. Set from the
+	// -validate-encode flag.
+	ValidateEncode bool
+
+	// Strict, when true, emits the checks of the runtime's Marshaler.Strict: the
+	// write methods reject an integer out of its wire type's range, a float with
+	// a fractional part or out of range written as an integer, and a zstring
+	// whose terminator does not fit its size; the read methods reject a value
+	// that does not fit the Go type exactly; and UnmarshalBinary rejects input
+	// left over after the value with binarystruct.ErrTrailingData. Like
+	// ValidateEncode it is baked in. Set from the -strict flag.
+	Strict bool
+
 	// UnsafeBulk, when true, emits a raw-memory bulk path for fixed-width scalar
 	// arrays/slices whose Go element width matches the wire width: a single
 	// Write/ReadFull over the element backing store via unsafe, plus one in-place
@@ -52,6 +69,12 @@ type Generator struct {
 	// name. Populated by Generate; used to recognize nested-struct fields when
 	// translating bytelen() (case 5).
 	structs map[string]*ast.StructType
+
+	// curType is the type whose methods are being generated, the one sizeof()
+	// looks up struct types from.
+	curType string
+	// exprVars numbers the variables hoistGuarded declares in curType's methods.
+	exprVars int
 }
 
 type parsedFieldTag struct {
@@ -63,13 +86,9 @@ type parsedFieldTag struct {
 	options       map[string]string
 	numDims       int      // number of array dimensions; >1 is a multidimensional tag
 	arrayDimExprs []string // per-dimension length expressions for a multidimensional tag
+	raw           string   // the tag's text, as reflect's Tag.Get("binary") returns it
 }
 
-// Group 1 is the (possibly multi-dimensional) array bracket run "[4][2]"; group 2
-// is the binary type; group 4 is the (buflen). mTagDim splits the run per-dimension.
-var mTag = regexp.MustCompile(`^\s*((?:\[[^\]]*\])*)([^\s\(\)\[\]]*)(\(([^\)]+)\))?`)
-var mTagDim = regexp.MustCompile(`\[([^\]]*)\]`)
-
 func parseFieldTag(tag *ast.BasicLit) parsedFieldTag {
 	res := parsedFieldTag{options: make(map[string]string)}
 	if tag == nil {
@@ -89,27 +108,23 @@ func parseFieldTag(tag *ast.BasicLit) parsedFieldTag {
 	if err != nil {
 		tagStr = m[1]
 	}
+	res.raw = tagStr
 
 	tags := splitTagOptions(tagStr)
 	if len(tags) == 0 || tags[0] == "" {
 		return res
 	}
 
-	match := mTag.FindStringSubmatch(tags[0])
-	if len(match) >= 5 {
-		dims := mTagDim.FindAllStringSubmatch(match[1], -1)
-		res.isArray = len(dims) > 0
-		res.numDims = len(dims)
-		if res.isArray {
-			res.arrayDimExprs = make([]string, len(dims))
-			for i, d := range dims {
-				res.arrayDimExprs[i] = strings.TrimSpace(d[1])
-			}
-			res.arrayLenExpr = res.arrayDimExprs[0] // outermost dimension
-		}
-		res.binaryType = match[2]
-		res.bufLenExpr = match[4]
+	dimRun, typeName, bufLen := parseTypeTag(tags[0])
+	dims := parseArrayDims(dimRun)
+	res.isArray = len(dims) > 0
+	res.numDims = len(dims)
+	if res.isArray {
+		res.arrayDimExprs = dims
+		res.arrayLenExpr = dims[0] // outermost dimension
 	}
+	res.binaryType = typeName
+	res.bufLenExpr = bufLen
 
 	for _, opt := range tags[1:] {
 		parts := strings.SplitN(opt, "=", 2)
@@ -150,6 +165,12 @@ func structSentinelEndian(st *ast.StructType) (string, error) {
 			if len(kv) < 2 {
 				return "", fmt.Errorf("missing value for endian in `_` sentinel tag")
 			}
+			if cgIsOrderMarkSpec(kv[1]) {
+				return "", nil // a byte-order mark field; see structOrderMark
+			}
+			if v := strings.TrimSpace(kv[1]); v == "detect" || strings.HasPrefix(v, "detect:") {
+				return "", nil // detected from the magic; see structOrderDetect
+			}
 			switch strings.ToLower(strings.TrimSpace(kv[1])) {
 			case "big":
 				return "binarystruct.BigEndian", nil
@@ -158,6 +179,9 @@ func structSentinelEndian(st *ast.StructType) (string, error) {
 			case "inverse":
 				return "", fmt.Errorf("struct-level endian=inverse is not supported by codegen; use the runtime interpreter")
 			default:
+				if cgIsByteOrderName(strings.TrimSpace(kv[1])) {
+					return "", fmt.Errorf("named byte order endian=%s is not supported by codegen; use the runtime interpreter", strings.TrimSpace(kv[1]))
+				}
 				return "", fmt.Errorf("unknown endian value %q in `_` sentinel tag", kv[1])
 			}
 		}
@@ -191,6 +215,296 @@ func structSentinelEncoding(st *ast.StructType) string {
 	return ""
 }
 
+// cgOrderMark mirrors the runtime's orderMark: a sentinel's `endian=Field` or
+// `endian=Field:V=ORDER,…` makes Field's value choose the byte order of the
+// fields after it.
+type cgOrderMark struct {
+	field  string
+	values []cgOrderMarkValue
+}
+
+type cgOrderMarkValue struct {
+	num   uint64
+	raw   []byte // the bytes of a hex key, nil for a decimal key
+	order string // byte-order literal
+}
+
+// cgDefaultOrderMarks is the runtime's defaultOrderMarks.
+const cgDefaultOrderMarks = "0x4949=little,0x4d4d=big,0xfeff=big,0xfffe=little"
+
+// cgIsOrderMarkSpec reports whether an endian= value names a field rather than
+// a byte order.
+func cgIsOrderMarkSpec(v string) bool {
+	v = strings.TrimSpace(v)
+	switch strings.ToLower(v) {
+	case "", "big", "little", "inverse":
+		return false
+	}
+	return v[0] == '_' || v[0] >= 'A' && v[0] <= 'Z'
+}
+
+// cgIsByteOrderName mirrors the runtime's isByteOrderName: whether an endian=
+// value names a byte order registered with Marshaler.AddByteOrder.
+func cgIsByteOrderName(s string) bool {
+	switch s {
+	case "", "big", "little", "inverse", "detect":
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z':
+		case i > 0 && (c >= '0' && c <= '9' || c == '_'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// structOrderMark returns the struct's byte-order mark declaration, or nil.
+// Like the runtime, the V=ORDER pairs continue past the option commas.
+func structOrderMark(st *ast.StructType) (*cgOrderMark, error) {
+	binRe := regexp.MustCompile(`binary:"([^"]*)"`)
+	for _, field := range st.Fields.List {
+		if len(field.Names) != 1 || field.Names[0].Name != "_" || field.Tag == nil {
+			continue
+		}
+		tagVal, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			continue
+		}
+		m := binRe.FindStringSubmatch(tagVal)
+		if len(m) < 2 {
+			continue
+		}
+		segs := strings.Split(m[1], ",")
+		for i, seg := range segs {
+			kv := strings.SplitN(strings.TrimSpace(seg), "=", 2)
+			if strings.TrimSpace(kv[0]) != "endian" || len(kv) < 2 || !cgIsOrderMarkSpec(kv[1]) {
+				continue
+			}
+			name, mapping := strings.TrimSpace(kv[1]), cgDefaultOrderMarks
+			if c := strings.IndexByte(name, ':'); c >= 0 {
+				name, mapping = strings.TrimSpace(name[:c]), name[c+1:]
+				for _, more := range segs[i+1:] {
+					if t := strings.TrimSpace(more); t == "" || t[0] < '0' || t[0] > '9' {
+						break
+					}
+					mapping += "," + more
+				}
+			}
+			om := &cgOrderMark{field: name}
+			for _, pair := range strings.Split(mapping, ",") {
+				pkv := strings.SplitN(pair, "=", 2)
+				if len(pkv) != 2 {
+					return nil, fmt.Errorf("endian=%s: %q is not a VALUE=ORDER pair", name, strings.TrimSpace(pair))
+				}
+				key := strings.ReplaceAll(strings.TrimSpace(pkv[0]), "_", "")
+				num, err := strconv.ParseUint(key, 0, 64)
+				if err != nil {
+					return nil, fmt.Errorf("endian=%s: invalid mark value %q", name, strings.TrimSpace(pkv[0]))
+				}
+				v := cgOrderMarkValue{num: num}
+				switch strings.ToLower(strings.TrimSpace(pkv[1])) {
+				case "big":
+					v.order = "binarystruct.BigEndian"
+				case "little":
+					v.order = "binarystruct.LittleEndian"
+				case "inverse":
+					return nil, fmt.Errorf("endian=%s: inverse is not supported by codegen; use the runtime interpreter", name)
+				default:
+					if cgIsByteOrderName(strings.TrimSpace(pkv[1])) {
+						return nil, fmt.Errorf("endian=%s: named byte order %s is not supported by codegen; use the runtime interpreter", name, strings.TrimSpace(pkv[1]))
+					}
+					return nil, fmt.Errorf("endian=%s: unknown endian value %q", name, strings.TrimSpace(pkv[1]))
+				}
+				if h := strings.ToLower(key); strings.HasPrefix(h, "0x") && len(h)%2 == 0 {
+					v.raw, _ = hex.DecodeString(h[2:])
+				}
+				om.values = append(om.values, v)
+			}
+			return om, nil
+		}
+	}
+	return nil, nil
+}
+
+// cgOrderDetect mirrors the runtime's orderDetect: a sentinel's
+// `endian=detect:Field` makes the encoding of the magic, the first integer
+// const= field, choose the byte order, recorded in Field.
+type cgOrderDetect struct {
+	pos         int    // index of the magic among emittableFields
+	field       string // the magic field
+	goType      string
+	cexpr       string
+	big, little []byte // the magic as encoded in each order
+	record      string // the field recording the order
+}
+
+// structOrderDetect returns the struct's endian=detect declaration, or nil.
+func structOrderDetect(st *ast.StructType) (*cgOrderDetect, error) {
+	binRe := regexp.MustCompile(`binary:"([^"]*)"`)
+	var od *cgOrderDetect
+	for _, field := range st.Fields.List {
+		if len(field.Names) != 1 || field.Names[0].Name != "_" || field.Tag == nil {
+			continue
+		}
+		tagVal, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			continue
+		}
+		m := binRe.FindStringSubmatch(tagVal)
+		if len(m) < 2 {
+			continue
+		}
+		for _, seg := range strings.Split(m[1], ",") {
+			kv := strings.SplitN(strings.TrimSpace(seg), "=", 2)
+			if strings.TrimSpace(kv[0]) != "endian" || len(kv) < 2 {
+				continue
+			}
+			if v := strings.TrimSpace(kv[1]); v == "detect" || strings.HasPrefix(v, "detect:") {
+				od = &cgOrderDetect{record: strings.TrimSpace(strings.TrimPrefix(v[len("detect"):], ":"))}
+				if od.record == "" {
+					return nil, fmt.Errorf("endian=detect needs a field to record the byte order in: declare endian=detect:Field")
+				}
+			}
+		}
+	}
+	if od == nil {
+		return nil, nil
+	}
+	od.pos = -1
+	for i, f := range emittableFields(st) {
+		pt := parseFieldTag(f.Tag)
+		goType := getGoTypeName(f.Type)
+		cexpr := pt.options["const"]
+		if cexpr == "" || pt.binaryType == "-" || isCgBytesConst(goType, getEffectiveBinaryType(pt.binaryType, goType)) {
+			continue
+		}
+		od.pos, od.field, od.goType, od.cexpr = i, f.Names[0].Name, goType, cexpr
+		if _, ok := pt.options["endian"]; ok {
+			return nil, fmt.Errorf("endian=detect: the magic field %s must not declare its own endian=", od.field)
+		}
+		binType := getEffectiveBinaryType(pt.binaryType, goType)
+		w, ok := scalarWidth(binType)
+		if binType == "int" || binType == "uint" {
+			w, ok = 8, true
+		}
+		if !ok || w < 2 || binType == "float32" || binType == "float64" || strings.HasPrefix(goType, "*") {
+			return nil, fmt.Errorf("endian=detect: the magic field %s must be a multi-byte integer, not %s", od.field, binType)
+		}
+		lit := strings.ReplaceAll(strings.TrimSpace(cexpr), "_", "")
+		v, err := strconv.ParseInt(lit, 0, 64)
+		if err != nil {
+			u, uerr := strconv.ParseUint(lit, 0, 64)
+			if uerr != nil {
+				return nil, fmt.Errorf("endian=detect: codegen needs a literal magic in field %s, not %q; use the runtime interpreter", od.field, cexpr)
+			}
+			v = int64(u)
+		}
+		od.big, od.little = make([]byte, w), make([]byte, w)
+		for j := 0; j < w; j++ {
+			b := byte(uint64(v) >> (8 * uint(j)))
+			od.big[w-1-j], od.little[j] = b, b
+		}
+		if bytes.Equal(od.big, od.little) {
+			return nil, fmt.Errorf("endian=detect: the magic %s of field %s reads the same in both byte orders", cexpr, od.field)
+		}
+		break
+	}
+	if od.pos < 0 {
+		return nil, fmt.Errorf("endian=detect: no integer const= field to detect the byte order from")
+	}
+	found := false
+	for _, f := range st.Fields.List {
+		if len(f.Names) == 1 && f.Names[0].Name == od.record {
+			pt := parseFieldTag(f.Tag)
+			found = getGoTypeName(f.Type) == "binarystruct.ByteOrder" && pt.binaryType == "-"
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("endian=detect:%s: needs a field %s of type binarystruct.ByteOrder tagged `binary:\"-\"`", od.record, od.record)
+	}
+	return od, nil
+}
+
+// orderDetectWrite emits, before the magic field, the switch to the recorded
+// order when one is recorded.
+func orderDetectWrite(buf *bytes.Buffer, od *cgOrderDetect) {
+	fmt.Fprintf(buf, "\tif s.%s != nil {\n\t\torder = s.%s\n\t}\n", od.record, od.record)
+}
+
+// orderDetectRead emits the decoding of the magic field: its bytes select the
+// order, and the field is set to its constant.
+func orderDetectRead(buf *bytes.Buffer, od *cgOrderDetect) {
+	w := len(od.big)
+	fmt.Fprintf(buf, "\tm, err = io.ReadFull(r, tmp[:%d])\n\tn += m\n\tif err != nil {\n\t\treturn n, err\n\t}\n", w)
+	fmt.Fprintf(buf, "\tswitch {\n\tcase bytes.Equal(tmp[:%d], %s):\n\t\torder = binarystruct.BigEndian\n", w, goByteSliceLiteral(od.big))
+	fmt.Fprintf(buf, "\tcase bytes.Equal(tmp[:%d], %s):\n\t\torder = binarystruct.LittleEndian\n", w, goByteSliceLiteral(od.little))
+	buf.WriteString("\tdefault:\n")
+	// Not a check the Marshaler's Validation mode can skip: without the order
+	// the rest of the struct cannot be decoded.
+	fmt.Fprintf(buf, "\t\treturn n, &binarystruct.DecodeError{Offset: n - %d, Field: %q, Err: fmt.Errorf(\"endian=detect: magic 0x%%x is 0x%%x in neither byte order: %%w\", tmp[:%d], %s, binarystruct.ErrValidationError)}\n", w, od.field, w, goByteSliceLiteral(od.big))
+	buf.WriteString("\t}\n")
+	fmt.Fprintf(buf, "\ts.%s = %s(%s)\n", od.field, od.goType, od.cexpr)
+	fmt.Fprintf(buf, "\ts.%s = order\n", od.record)
+}
+
+// orderMarkSwitch emits the selection of order from the mark field's value,
+// placed before the first field after the mark.
+func orderMarkSwitch(buf *bytes.Buffer, om *cgOrderMark, goType string) error {
+	acc := "s." + om.field
+	if strings.HasPrefix(goType, "*") {
+		return fmt.Errorf("endian=%s: a pointer mark field is not supported by codegen; use the runtime interpreter", om.field)
+	}
+	var raw string
+	switch {
+	case goType == "string":
+		raw = "[]byte(" + acc + ")"
+	case goType == "[]byte" || goType == "[]uint8":
+		raw = acc
+	case isFixedArrayType(goType) && (strings.HasSuffix(goType, "]byte") || strings.HasSuffix(goType, "]uint8")):
+		raw = acc + "[:]"
+	}
+	if raw != "" {
+		buf.WriteString("\tswitch {\n")
+		for _, v := range om.values {
+			if v.raw == nil {
+				continue
+			}
+			lits := make([]string, len(v.raw))
+			for i, b := range v.raw {
+				lits[i] = fmt.Sprintf("0x%02x", b)
+			}
+			fmt.Fprintf(buf, "\tcase bytes.Equal(%s, []byte{%s}):\n\t\torder = %s\n", raw, strings.Join(lits, ", "), v.order)
+		}
+		fmt.Fprintf(buf, "\tdefault:\n\t\treturn n, fmt.Errorf(\"field <%s>: endian=%s: byte-order mark 0x%%x matches no byte order\", %s)\n\t}\n", om.field, om.field, raw)
+		return nil
+	}
+	w, ok := scalarWidth(goType)
+	if goType == "int" || goType == "uint" {
+		w, ok = 8, true
+	}
+	if !ok || goType == "float32" || goType == "float64" || goType == "bool" {
+		return fmt.Errorf("endian=%s: the field must be an integer, a byte array or slice, or a string, not %s", om.field, goType)
+	}
+	num := "uint64(" + acc + ")"
+	if strings.HasPrefix(goType, "int") && w < 8 {
+		num = fmt.Sprintf("(%s & 0x%x)", num, uint64(1)<<(8*w)-1)
+	}
+	fmt.Fprintf(buf, "\tswitch %s {\n", num)
+	seen := make(map[uint64]bool)
+	for _, v := range om.values {
+		if seen[v.num] {
+			continue // the runtime takes the first match
+		}
+		seen[v.num] = true
+		fmt.Fprintf(buf, "\tcase %#x:\n\t\torder = %s\n", v.num, v.order)
+	}
+	fmt.Fprintf(buf, "\tdefault:\n\t\treturn n, fmt.Errorf(\"field <%s>: endian=%s: byte-order mark %%#x matches no byte order\", %s)\n\t}\n", om.field, om.field, num)
+	return nil
+}
+
 // isStringBinType reports whether a binary type name is a text-string family type
 // (the kinds to which a text encoding applies).
 func isStringBinType(binType string) bool {
@@ -231,17 +545,18 @@ func getGoTypeName(expr ast.Expr) string {
 	}
 }
 
-func translateExpression(expr string) string {
+// translateExpression translates a tag expression into Go source over the
+// receiver s (see cgTranslateExpr). generateMethods validates every tag
+// expression up front, so a parse error cannot reach here.
+func (g *Generator) translateExpression(expr string) string {
 	if expr == "" {
 		return ""
 	}
-	re := regexp.MustCompile(`[a-zA-Z_][a-zA-Z0-9_\.]*`)
-	return re.ReplaceAllStringFunc(expr, func(s string) string {
-		if s == "true" || s == "false" || s == "nil" {
-			return s
-		}
-		return "s." + s
-	})
+	out, err := cgTranslateExpr(expr, g.sizeOf, g.arrayLen)
+	if err != nil {
+		return expr
+	}
+	return out
 }
 
 type cgFieldInfo struct {
@@ -263,7 +578,13 @@ type cgFieldInfo struct {
 
 var (
 	cgValueofFuncRe = regexp.MustCompile(`s\.(bytelen|count)\(s\.([a-zA-Z_][a-zA-Z0-9_]*)\)`)
-	cgIdentRe       = regexp.MustCompile(`s\.([a-zA-Z_][a-zA-Z0-9_]*)`)
+	// cgIdentRe matches a top-level field reference s.Field in translated Go;
+	// group 2 is the field name. Group 1 (the preceding character) keeps the
+	// selectors of a nested reference such as s.Hdr.s.Len from matching.
+	cgIdentRe = regexp.MustCompile(`(^|[^.\w])s\.([a-zA-Z_][a-zA-Z0-9_]*)`)
+	// cgIntRefRe matches a top-level field reference as translateExpression
+	// emits it, int(s.Field), with the groups of cgIdentRe.
+	cgIntRefRe = regexp.MustCompile(`(^|[^.\w])int\(s\.([a-zA-Z_][a-zA-Z0-9_]*)\)`)
 	// cgValueofCallRe matches a function call's name in a raw valueof expression
 	// (an identifier immediately followed by '('), used to reject custom
 	// evaluators the generator cannot resolve statically.
@@ -418,6 +739,72 @@ func splitTagOptions(s string) []string {
 	return append(out, s[start:])
 }
 
+// parseArrayDims splits an array bracket run such as "[4][2]" into its
+// per-dimension expression strings (["4","2"]); "[]" yields one empty entry.
+func parseArrayDims(bracketRun string) []string {
+	var dims []string
+	for i := 0; i < len(bracketRun); i++ {
+		if bracketRun[i] != '[' {
+			continue
+		}
+		end := closingBracket(bracketRun, i)
+		if end < 0 {
+			break
+		}
+		dims = append(dims, strings.TrimSpace(bracketRun[i+1:end]))
+		i = end
+	}
+	return dims
+}
+
+// parseTypeTag splits the leading type part of a binary tag into the array
+// bracket run, the binary type name and the (buflen) expression, counting
+// nested brackets and parentheses the same way the runtime does.
+func parseTypeTag(s string) (dimRun, typeName, bufLen string) {
+	i := 0
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\r' || s[i] == '\n') {
+		i++
+	}
+	start := i
+	for i < len(s) && s[i] == '[' {
+		end := closingBracket(s, i)
+		if end < 0 {
+			break
+		}
+		i = end + 1
+	}
+	dimRun = s[start:i]
+	start = i
+	for i < len(s) && !strings.ContainsRune(" \t\r\n()[]", rune(s[i])) {
+		i++
+	}
+	typeName = s[start:i]
+	if i < len(s) && s[i] == '(' {
+		if end := closingBracket(s, i); end > i+1 {
+			bufLen = s[i+1 : end]
+		}
+	}
+	return
+}
+
+// closingBracket returns the index of the bracket that closes the one at
+// s[open], counting nested () and [] pairs, or -1 if there is none.
+func closingBracket(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
 // isByteSequence reports whether goType is a byte-like slice or array, whose
 // element count equals its encoded byte length.
 func isByteSequence(goType string) bool {
@@ -490,6 +877,185 @@ func scalarWidth(binType string) (int, bool) {
 	return 0, false
 }
 
+// cgGoInt returns the signedness and width in bits of a Go integer type name;
+// int, uint and uintptr are taken as 64 bits.
+func cgGoInt(t string) (signed bool, bits int, ok bool) {
+	switch t {
+	case "int8":
+		return true, 8, true
+	case "int16":
+		return true, 16, true
+	case "int32", "rune":
+		return true, 32, true
+	case "int", "int64":
+		return true, 64, true
+	case "uint8", "byte":
+		return false, 8, true
+	case "uint16":
+		return false, 16, true
+	case "uint32":
+		return false, 32, true
+	case "uint", "uint64", "uintptr":
+		return false, 64, true
+	}
+	return false, 0, false
+}
+
+// cgWireInt returns the kind of an integer wire type, 'i' signed, 'u' unsigned
+// or 'b' for byte/word/dword/qword, and its width in bits.
+func cgWireInt(binType string) (kind byte, bits int, ok bool) {
+	switch binType {
+	case "int8", "int16", "int32", "int64":
+		kind = 'i'
+	case "uint8", "uint16", "uint32", "uint64":
+		kind = 'u'
+	case "byte", "word", "dword", "qword":
+		kind = 'b'
+	default:
+		return 0, 0, false
+	}
+	w, _ := scalarWidth(binType)
+	return kind, w * 8, true
+}
+
+// cgWireName is the name the runtime gives a wire type in its errors.
+func cgWireName(binType string) string {
+	return strings.ToUpper(binType[:1]) + binType[1:]
+}
+
+// cgFloatLit formats a float bound as a Go literal.
+func cgFloatLit(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// cgStrictWriteCheck returns the statements that, under -strict, reject a Go
+// value, accessor of goType, that its wire type cannot hold exactly, as the
+// runtime's Strict mode does: an integer out of the wire range, or a float
+// with a fractional part or out of range encoded as an integer. It returns ""
+// when every value converts exactly.
+func cgStrictWriteCheck(accessor, goType, binType string) string {
+	kind, wb, ok := cgWireInt(binType)
+	if !ok {
+		return ""
+	}
+	name := cgWireName(binType)
+	fail := func(cond, v, verb string) string {
+		return fmt.Sprintf("\tif v := %s(%s); %s {\n\t\treturn n, fmt.Errorf(\"value %%v %s %s: %%w\", v, binarystruct.ErrValueOverflow)\n\t}\n", v, accessor, cond, verb, name)
+	}
+	if goType == "float32" || goType == "float64" {
+		lo, hi := -math.Ldexp(1, wb-1), math.Ldexp(1, wb-1)
+		switch kind {
+		case 'u':
+			lo, hi = 0, math.Ldexp(1, wb)
+		case 'b':
+			hi = math.Ldexp(1, wb)
+		}
+		return fail("v != math.Trunc(v)", "float64", "loses precision in") +
+			fail(fmt.Sprintf("!(v >= %s && v < %s)", cgFloatLit(lo), cgFloatLit(hi)), "float64", "not fit in")
+	}
+	signed, gb, ok := cgGoInt(goType)
+	if !ok {
+		return ""
+	}
+	var cond string
+	switch {
+	case signed && kind == 'i' && gb > wb:
+		cond = fmt.Sprintf("v < math.MinInt%d || v > math.MaxInt%d", wb, wb)
+	case signed && kind == 'u':
+		cond = "v < 0"
+		if wb < gb {
+			cond += fmt.Sprintf(" || v > math.MaxUint%d", wb)
+		}
+	case signed && kind == 'b' && gb > wb:
+		cond = fmt.Sprintf("v < math.MinInt%d || v > math.MaxUint%d", wb, wb)
+	case !signed && kind == 'i' && gb >= wb:
+		cond = fmt.Sprintf("v > math.MaxInt%d", wb)
+	case !signed && kind != 'i' && gb > wb:
+		cond = fmt.Sprintf("v > math.MaxUint%d", wb)
+	default:
+		return ""
+	}
+	if signed {
+		return fail(cond, "int64", "not fit in")
+	}
+	return fail(cond, "uint64", "not fit in")
+}
+
+// cgStrictReadCheck returns the statements that, under -strict, reject a wire
+// value, wire, that the Go type cannot hold exactly, failing with fail(inner):
+// an integer out of the Go type's range, or a float with a fractional part or
+// out of range decoded into an integer. wire is the value read as a uint64, or
+// as a float64 for a float wire type. It returns "" when every value converts
+// exactly.
+func cgStrictReadCheck(wire, goType, binType string, fail func(inner string) string) string {
+	signed, gb, ok := cgGoInt(goType)
+	if !ok {
+		return ""
+	}
+	check := func(v, cond, verb string) string {
+		inner := fmt.Sprintf("fmt.Errorf(\"value %%v %s type %s: %%w\", v, binarystruct.ErrValueOverflow)", verb, goType)
+		return fmt.Sprintf("\tif v := %s; %s {\n%s\t}\n", v, cond, fail(inner))
+	}
+	if binType == "float32" || binType == "float64" {
+		lo, hi := -math.Ldexp(1, gb-1), math.Ldexp(1, gb-1)
+		if !signed {
+			lo, hi = 0, math.Ldexp(1, gb)
+		}
+		return check(wire, "v != math.Trunc(v)", "loses precision in") +
+			check(wire, fmt.Sprintf("!(v >= %s && v < %s)", cgFloatLit(lo), cgFloatLit(hi)), "not fit in")
+	}
+	kind, wb, ok := cgWireInt(binType)
+	if !ok {
+		return ""
+	}
+	// A signed wire value, or a byte/word/dword/qword decoded into a signed
+	// type, is range-checked sign-extended, as the runtime checks it.
+	if kind == 'i' || (kind == 'b' && signed) {
+		v := fmt.Sprintf("int64(int%d(%s))", wb, wire)
+		switch {
+		case signed && wb > gb:
+			return check(v, fmt.Sprintf("v < math.MinInt%d || v > math.MaxInt%d", gb, gb), "not fit in")
+		case !signed && wb > gb:
+			return check(v, fmt.Sprintf("v < 0 || v > math.MaxUint%d", gb), "not fit in")
+		case !signed:
+			return check(v, "v < 0", "not fit in")
+		}
+		return ""
+	}
+	switch {
+	case signed && wb >= gb:
+		return check(wire, fmt.Sprintf("v > math.MaxInt%d", gb), "not fit in")
+	case !signed && wb > gb:
+		return check(wire, fmt.Sprintf("v > math.MaxUint%d", gb), "not fit in")
+	}
+	return ""
+}
+
+// cgStrictChecked reports whether -strict emits a conversion check for a field
+// of goType, or for its elements, encoded as binType.
+func (g *Generator) cgStrictChecked(goType, binType string) bool {
+	return g.Strict && (cgStrictWriteCheck("x", cgStrictElem(goType), binType) != "" || g.cgStrictReadChecked(goType, binType))
+}
+
+// cgStrictReadChecked reports whether -strict emits a conversion check in the
+// read methods for a field of goType, or for its elements, encoded as binType.
+func (g *Generator) cgStrictReadChecked(goType, binType string) bool {
+	wire := "x"
+	if binType == "float32" || binType == "float64" {
+		wire = "f"
+	}
+	return g.Strict && cgStrictReadCheck(wire, cgStrictElem(goType), binType, func(string) string { return "" }) != ""
+}
+
+// cgStrictElem strips the pointers and array dimensions off a Go type name.
+func cgStrictElem(goType string) string {
+	elem := strings.TrimPrefix(goType, "*")
+	for strings.HasPrefix(elem, "[") {
+		elem = strings.TrimPrefix(elem[strings.IndexByte(elem, ']')+1:], "*")
+	}
+	return elem
+}
+
 // stringPrefixWidth returns the byte width of a length-prefixed string's prefix
 // (0 for non-prefixed forms), matching the encode path in generateFieldWrite.
 func stringPrefixWidth(binType string) int {
@@ -533,11 +1099,11 @@ func (g *Generator) translateValueof(expr string, fields map[string]cgFieldInfo,
 	// falls back to the runtime interpreter rather than emitting a call to a
 	// nonexistent method.
 	for _, fm := range cgValueofCallRe.FindAllStringSubmatch(expr, -1) {
-		if fm[1] != "bytelen" && fm[1] != "count" {
+		if fm[1] != "bytelen" && fm[1] != "count" && !cgIsExprBuiltin(fm[1]) {
 			return "", "", fmt.Errorf("codegen does not support custom valueof evaluators (%s()); use the runtime interpreter for this struct", fm[1])
 		}
 	}
-	prefixed := translateExpression(expr) // e.g. s.bytelen(s.Name)+2
+	prefixed := g.translateExpression(expr) // e.g. s.bytelen(s.Name)+2
 	var ferr error
 	var preBuf bytes.Buffer
 	measured := make(map[string]bool) // dedup hoisted measurements by field name
@@ -574,8 +1140,8 @@ func (g *Generator) translateValueof(expr string, fields map[string]cgFieldInfo,
 	// A bare reference to another valueof field cannot be resolved in generated
 	// code (it would read the field's pre-encode value, diverging from runtime).
 	for _, sm := range cgIdentRe.FindAllStringSubmatch(out, -1) {
-		if fi, ok := fields[sm[1]]; ok && fi.hasValueof {
-			return "", "", fmt.Errorf("codegen does not support a valueof expression referencing another valueof field (%q); use the runtime interpreter", sm[1])
+		if fi, ok := fields[sm[2]]; ok && fi.hasValueof {
+			return "", "", fmt.Errorf("codegen does not support a valueof expression referencing another valueof field (%q); use the runtime interpreter", sm[2])
 		}
 	}
 	return preBuf.String(), out, nil
@@ -606,12 +1172,19 @@ func (g *Generator) bytelenExpr(arg string, fi cgFieldInfo, fields map[string]cg
 		switch {
 		case fi.bufLenExpr != "":
 			// case 3: buffered string(N) -> content is exactly N bytes (padded or
-			// truncated), regardless of encoding.
+			// truncated), regardless of encoding. A terminator goes inside the
+			// buffer, overrunning it only when an unencoded string fills it.
 			bufSize, e := g.translateEncodeExpr(fi.bufLenExpr, fields, visiting)
 			if e != nil {
 				return "", "", e
 			}
-			return addExtra("int(" + bufSize + ")"), "", nil
+			if term := stringTermWidth(fi.binType); term > 0 {
+				if fi.encoding == "" {
+					return fmt.Sprintf("max(%s, len(s.%s)+%d)", bufSize, arg, term), "", nil
+				}
+				return bufSize, "", nil
+			}
+			return addExtra(bufSize), "", nil
 		case fi.encoding == "":
 			// case 1: raw, unbounded, unencoded content -> len().
 			return addExtra(fmt.Sprintf("len(s.%s)", arg)), "", nil
@@ -657,7 +1230,7 @@ func (g *Generator) bytelenExpr(arg string, fi cgFieldInfo, fields map[string]cg
 			if sizeExpr == "" {
 				sizeExpr = fmt.Sprintf("len(s.%s)", arg)
 			}
-			pre = fmt.Sprintf("\tvar %s int\n\t{\n\t\tlimit := int(%s)\n\t\tfor i := 0; i < limit; i++ {\n\t\t\tvar mm int\n\t\t\tmm, err = binarystruct.NewMarshalerOrder(order).Write(io.Discard, &s.%s[i])\n\t\t\tif err != nil {\n\t\t\t\treturn n, err\n\t\t\t}\n\t\t\t%s += mm\n\t\t}\n\t}\n", tmp, sizeExpr, arg, tmp)
+			pre = fmt.Sprintf("\tvar %s int\n\t{\n\t\tlimit := %s\n\t\tfor i := 0; i < limit; i++ {\n\t\t\tvar mm int\n\t\t\tmm, err = binarystruct.NewMarshalerOrder(order).Write(io.Discard, &s.%s[i])\n\t\t\tif err != nil {\n\t\t\t\treturn n, err\n\t\t\t}\n\t\t\t%s += mm\n\t\t}\n\t}\n", tmp, sizeExpr, arg, tmp)
 			return tmp, pre, nil
 		}
 		// A single struct value, or a Go-native slice/array of structs written as
@@ -692,15 +1265,16 @@ func (g *Generator) bytelenExpr(arg string, fi cgFieldInfo, fields map[string]cg
 // translateEncodeExpr translates a decode-side size expression for use on the
 // ENCODE path: any referenced valueof field is replaced by its computed
 // expression (so e.g. [NameLen]byte writes len(s.Name) bytes rather than the
-// stale s.NameLen). Non-valueof references become s.Field as usual.
+// stale s.NameLen). Other references stay int(s.Field).
 func (g *Generator) translateEncodeExpr(expr string, fields map[string]cgFieldInfo, visiting map[string]bool) (string, error) {
 	if expr == "" {
 		return "", nil
 	}
-	prefixed := translateExpression(expr)
+	prefixed := g.translateExpression(expr)
 	var ferr error
-	out := cgIdentRe.ReplaceAllStringFunc(prefixed, func(m string) string {
-		name := cgIdentRe.FindStringSubmatch(m)[1]
+	out := cgIntRefRe.ReplaceAllStringFunc(prefixed, func(m string) string {
+		sm := cgIntRefRe.FindStringSubmatch(m)
+		lead, name := sm[1], sm[2]
 		fi, ok := fields[name]
 		if ok && fi.hasValueof {
 			// Guard a self-referential cycle: this size expression references a
@@ -725,7 +1299,10 @@ func (g *Generator) translateEncodeExpr(expr string, fields map[string]cgFieldIn
 				ferr = fmt.Errorf("codegen cannot inline a size expression that references valueof %q (its bytelen() needs a runtime measurement); use the runtime interpreter for this struct", name)
 				return m
 			}
-			return "(" + sub + ")"
+			if m == prefixed {
+				return sub // the whole expression
+			}
+			return lead + "(" + sub + ")"
 		}
 		return m
 	})
@@ -794,6 +1371,12 @@ func (g *Generator) Generate(outPath string) error {
 		if !ok {
 			return fmt.Errorf("type %s not found in package %s", typeName, pkgName)
 		}
+		if om, _ := structOrderMark(st); om != nil {
+			needFmt = true // the unmatched-mark error
+		}
+		if od, _ := structOrderDetect(st); od != nil {
+			needFmt = true // the unmatched-magic error
+		}
 		for _, field := range st.Fields.List {
 			if len(field.Names) == 0 || field.Names[0].Name == "_" {
 				continue
@@ -811,17 +1394,41 @@ func (g *Generator) Generate(outPath string) error {
 					needMath = true
 				}
 			}
-			// const/range/match decode validation is emitted unless -no-validate.
-			if _, ok := parsedTag.options["match"]; ok && !g.NoValidate {
+			// const/range/match/check decode validation is emitted unless -no-validate;
+			// range/match/enum/check are emitted on encode too under -validate-encode.
+			checks := !g.NoValidate || g.ValidateEncode
+			if _, ok := parsedTag.options["match"]; ok && checks {
 				needRegexp = true
 				needFmt = true
 			}
-			if _, ok := parsedTag.options["range"]; ok && !g.NoValidate {
+			if _, ok := parsedTag.options["range"]; ok && checks {
 				needFmt = true
 			}
 			if cexpr, ok := parsedTag.options["const"]; ok && cexpr != "" && !g.NoValidate {
 				needFmt = true
 			}
+			if c, ok := parsedTag.options["check"]; ok && c != "" && checks {
+				needFmt = true
+			}
+			if e, ok := parsedTag.options["enum"]; ok && checks {
+				// A decode failure names the field's type with fmt.Sprintf.
+				if name, _, _ := cgParseEnum(e); name == "" || !g.NoValidate {
+					needFmt = true
+				}
+			}
+			// Encoding a value that does not fit fails with ErrValueOverflow: a
+			// sized or length-prefixed string, or an array with a declared length.
+			if _, isConst := parsedTag.options["const"]; !isConst {
+				switch {
+				case strings.Contains(binType, "string") && parsedTag.bufLenExpr != "":
+					needFmt = true
+				case binType == "bstring" || binType == "wstring" || binType == "dwstring":
+					needFmt, needMath = true, true
+				}
+				if parsedTag.isArray && parsedTag.numDims <= 1 && parsedTag.arrayLenExpr != "" {
+					needFmt = true
+				}
+			}
 			if val, ok := parsedTag.options["codec"]; ok && val != "" {
 				needErrors = true
 				needFmt = true
@@ -829,7 +1436,7 @@ func (g *Generator) Generate(outPath string) error {
 			// A custom valueof evaluator emits an ms-nil guard (errors) and an
 			// unknown-evaluator / mismatch error (fmt), like a codec.
 			if vexpr, ok := parsedTag.options["valueof"]; ok && vexpr != "" {
-				if evname, _, isCall := parseCustomValueofCall(vexpr); isCall && evname != "bytelen" && evname != "count" {
+				if evname, _, isCall := parseCustomValueofCall(vexpr); isCall && evname != "bytelen" && evname != "count" && !cgIsExprBuiltin(evname) {
 					needErrors = true
 					needFmt = true
 				}
@@ -837,6 +1444,21 @@ func (g *Generator) Generate(outPath string) error {
 			if parsedTag.isArray && parsedTag.arrayLenExpr == "" {
 				needErrors = true
 			}
+			// The failure of a nested struct, or of an operator in the size or
+			// check= expressions the read method evaluates, names the field's
+			// type.
+			readExprs := append([]string{parsedTag.bufLenExpr}, parsedTag.arrayDimExprs...)
+			if !g.NoValidate {
+				readExprs = append(readExprs, parsedTag.options["check"])
+			}
+			if cgNestedRead(binType, parsedTag) || g.exprsGuarded(readExprs) {
+				needFmt = true
+			}
+			// -strict conversion checks use fmt for the error and math for the
+			// bounds.
+			if g.cgStrictChecked(goType, binType) {
+				needFmt, needMath = true, true
+			}
 			// A scalar array/slice whose Go element width matches the wire width
 			// uses the raw-memory bulk path, which references unsafe.
 			if parsedTag.isArray && parsedTag.numDims <= 1 {
@@ -880,7 +1502,7 @@ func (g *Generator) Generate(outPath string) error {
 			}
 			fieldName := field.Names[0].Name
 			parsedTag := parseFieldTag(field.Tag)
-			if pattern, ok := parsedTag.options["match"]; ok && !g.NoValidate {
+			if pattern, ok := parsedTag.options["match"]; ok && (!g.NoValidate || g.ValidateEncode) {
 				fmt.Fprintf(&buf, "var regex_%s_%s = regexp.MustCompile(`%s`)\n", typeName, fieldName, pattern)
 			}
 		}
@@ -894,6 +1516,9 @@ func (g *Generator) Generate(outPath string) error {
 		}
 	}
 
+	if bytes.Contains(buf.Bytes(), []byte(cgGuardOpen)) {
+		return fmt.Errorf("internal error: a tag expression that can fail was not hoisted")
+	}
 	formatted, err := format.Source(buf.Bytes())
 	if err != nil {
 		// Dump unformatted code for debugging
@@ -922,6 +1547,140 @@ func emitLocalScratch(buf *bytes.Buffer, body string) {
 	}
 }
 
+// structOuterRefs returns the distinct parent./root. references in the tag
+// expressions of a struct's fields, in field order, including those of check=
+// rules when checks is set.
+func structOuterRefs(st *ast.StructType, checks bool) []string {
+	var refs []string
+	seen := make(map[string]bool)
+	for _, field := range emittableFields(st) {
+		exprs := fieldTagExprs(parseFieldTag(field.Tag))
+		if checks {
+			exprs = fieldDecodeExprs(parseFieldTag(field.Tag))
+		}
+		for _, e := range exprs {
+			rs, _ := cgExprOuterRefs(e) // malformed expressions are reported by generateMethods
+			for _, r := range rs {
+				if !seen[r] {
+					seen[r] = true
+					refs = append(refs, r)
+				}
+			}
+		}
+	}
+	return refs
+}
+
+// needsOuter reports whether encoding or decoding the generated struct typeName
+// resolves parent./root. references, in its own tags or those of a generated
+// struct nested in it. Its containers must then record themselves on the
+// Marshaler (PushStruct) around the nested call.
+func (g *Generator) needsOuter(typeName string, seen map[string]bool) bool {
+	st, ok := g.structs[typeName]
+	if !ok || seen[typeName] || !g.isGeneratedType(typeName) {
+		return false
+	}
+	seen[typeName] = true
+	if len(structOuterRefs(st, !g.NoValidate || g.ValidateEncode)) > 0 {
+		return true
+	}
+	for _, field := range emittableFields(st) {
+		if g.needsOuter(stripToElemType(getGoTypeName(field.Type)), seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// outerRefPrologue emits, at the top of a generated method, the Marshaler
+// allocation a struct needs to pass its context to nested structs. Its own
+// parent./root. references are resolved where they are read (see cgOuterVar).
+func (g *Generator) outerRefPrologue(buf *bytes.Buffer, st *ast.StructType) {
+	for _, field := range emittableFields(st) {
+		if g.needsOuter(stripToElemType(getGoTypeName(field.Type)), map[string]bool{}) {
+			buf.WriteString("\tif ms == nil {\n\t\tms = new(binarystruct.Marshaler)\n\t}\n")
+			break
+		}
+	}
+}
+
+// structParams returns the $name references in a struct's tag expressions,
+// without the $, in order of appearance, including those of check= rules when
+// checks is set.
+func structParams(st *ast.StructType, checks bool) []string {
+	var params []string
+	seen := make(map[string]bool)
+	for _, field := range emittableFields(st) {
+		exprs := fieldTagExprs(parseFieldTag(field.Tag))
+		if checks {
+			exprs = fieldDecodeExprs(parseFieldTag(field.Tag))
+		}
+		for _, e := range exprs {
+			ps, _ := cgExprParams(e) // malformed expressions are reported by generateMethods
+			for _, p := range ps {
+				if !seen[p] {
+					seen[p] = true
+					params = append(params, p)
+				}
+			}
+		}
+	}
+	return params
+}
+
+// remainingPrologue emits, at the top of a read method, the $remaining local
+// refreshed before each field that uses it. $name parameters are looked up
+// where they are read (see cgParamVar).
+func (g *Generator) remainingPrologue(buf *bytes.Buffer, st *ast.StructType) {
+	for _, p := range structParams(st, !g.NoValidate) {
+		if p == "remaining" {
+			buf.WriteString("\tvar remaining int\n")
+			return
+		}
+	}
+}
+
+// fieldTagExprs returns a field's non-empty tag expressions.
+func fieldTagExprs(pt parsedFieldTag) []string {
+	var exprs []string
+	for _, e := range append([]string{pt.bufLenExpr, pt.options["omittable"], pt.options["valueof"]}, pt.arrayDimExprs...) {
+		if e != "" {
+			exprs = append(exprs, e)
+		}
+	}
+	return exprs
+}
+
+// fieldDecodeExprs is fieldTagExprs plus the check= rule, which only the read
+// method evaluates.
+func fieldDecodeExprs(pt parsedFieldTag) []string {
+	exprs := fieldTagExprs(pt)
+	if c := pt.options["check"]; c != "" {
+		exprs = append(exprs, c)
+	}
+	return exprs
+}
+
+// encodeFieldTag mirrors the runtime's encodeMeta: an array dimension or buffer
+// length that refers to $remaining (the rest of the input, unknown while
+// encoding) is dropped, so the value's own length is written.
+func encodeFieldTag(pt parsedFieldTag) parsedFieldTag {
+	if pt.bufLenExpr != "" && pt.binaryType != "pad" && cgExprUsesParam(pt.bufLenExpr, "remaining") {
+		pt.bufLenExpr = ""
+	}
+	if len(pt.arrayDimExprs) > 0 {
+		dims := append([]string(nil), pt.arrayDimExprs...)
+		for i, d := range dims {
+			if d != "" && cgExprUsesParam(d, "remaining") {
+				dims[i] = ""
+			}
+		}
+		pt.arrayDimExprs = dims
+		pt.arrayLenExpr = dims[0]
+	}
+	return pt
+}
+
 // baseTypeName strips pointer/slice/array wrappers from a Go type expression,
 // returning the underlying identifier (e.g. "[]*Record" -> "Record", "[4]T" -> "T").
 func baseTypeName(goType string) string {
@@ -979,6 +1738,49 @@ func (g *Generator) orderedParentHint(typeName string) string {
 	return ""
 }
 
+// readAllocates reports whether a struct's read method allocates a slice or a
+// string, which is charged to the Marshaler's MaxDecodeAlloc.
+func readAllocates(st *ast.StructType) bool {
+	for _, f := range emittableFields(st) {
+		goType := strings.TrimPrefix(getGoTypeName(f.Type), "*")
+		if goType == "string" || strings.HasPrefix(goType, "[]") {
+			return true
+		}
+	}
+	return false
+}
+
+// readCollects reports whether a struct's read method brackets itself with
+// cg.BeginDecode/EndDecode: a check can fail in it or in a struct nested in it,
+// unless -no-validate strips the checks.
+func (g *Generator) readCollects(st *ast.StructType) bool {
+	if g.NoValidate {
+		return false
+	}
+	for _, f := range emittableFields(st) {
+		pt := parseFieldTag(f.Tag)
+		for _, opt := range []string{"const", "range", "match", "enum", "check", "valueof", "codec"} {
+			if _, has := pt.options[opt]; has {
+				return true
+			}
+		}
+		if _, ok := g.structs[baseTypeName(getGoTypeName(f.Type))]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// cgNestedRead reports whether generateFieldRead reads a value of binary type
+// binType as a nested struct.
+func cgNestedRead(binType string, pt parsedFieldTag) bool {
+	if _, ignore := pt.options["ignore"]; ignore || pt.binaryType == "-" || pt.options["codec"] != "" {
+		return false
+	}
+	_, scalar := scalarWidth(binType)
+	return !scalar && binType != "pad" && !isStringBinType(binType)
+}
+
 // emittableFields returns the struct's fields that produce code (named, non-`_`).
 func emittableFields(st *ast.StructType) []*ast.Field {
 	var out []*ast.Field
@@ -1004,10 +1806,10 @@ func cgFieldBatchable(f *ast.Field, structEnc string) (int, bool) {
 		return 0, false
 	}
 	// Exclude anything needing per-field handling: emit-only computed values
-	// (valueof/const), decode-time validation (const/range/match — the batch read
-	// skips it), custom codecs, omission, ignored fields, and per-field
-	// endian/encoding overrides.
-	for _, opt := range []string{"ignore", "omittable", "valueof", "const", "range", "match", "codec", "encoding", "endian"} {
+	// (valueof/const), decode-time validation (const/range/match/enum/check —
+	// the batch read skips it), custom codecs, omission, ignored fields, and
+	// per-field endian/encoding overrides.
+	for _, opt := range []string{"ignore", "omittable", "valueof", "const", "range", "match", "enum", "check", "codec", "encoding", "endian"} {
 		if _, has := pt.options[opt]; has {
 			return 0, false
 		}
@@ -1020,18 +1822,31 @@ func cgFieldBatchable(f *ast.Field, structEnc string) (int, bool) {
 }
 
 // scalarRunEnd returns the index just past the maximal run of batchable scalar
-// fields starting at i.
-func scalarRunEnd(flds []*ast.Field, i int, structEnc string) int {
+// fields starting at i. Under -strict a field with a conversion check ends it.
+func (g *Generator) scalarRunEnd(flds []*ast.Field, i int, structEnc string) int {
 	j := i
 	for j < len(flds) {
 		if _, ok := cgFieldBatchable(flds[j], structEnc); !ok {
 			break
 		}
+		goType := getGoTypeName(flds[j].Type)
+		if g.cgStrictChecked(goType, getEffectiveBinaryType(parseFieldTag(flds[j].Tag).binaryType, goType)) {
+			break
+		}
 		j++
 	}
 	return j
 }
 
+// markedRunEnd ends a scalar run starting at i just after the byte-order mark
+// field at markPos (-1 when none), so the fields after it use the new order.
+func markedRunEnd(end, i, markPos int) int {
+	if markPos >= i && end > markPos+1 {
+		return markPos + 1
+	}
+	return end
+}
+
 // batchTotalWidth sums the wire widths of a batchable run.
 func batchTotalWidth(flds []*ast.Field, structEnc string) int {
 	total := 0
@@ -1103,6 +1918,7 @@ func (g *Generator) generateScalarFieldBatchRead(buf *bytes.Buffer, flds []*ast.
 }
 
 func (g *Generator) generateMethods(buf *bytes.Buffer, typeName string, st *ast.StructType) error {
+	g.curType, g.exprVars = typeName, 0
 	// Resolve the type's byte order. A struct-level `_` sentinel declaration wins;
 	// otherwise the -endian flag supplies the order baked into the no-arg stdlib
 	// methods. If neither is present, generation fails (the stdlib encoding
@@ -1133,14 +1949,106 @@ func (g *Generator) generateMethods(buf *bytes.Buffer, typeName string, st *ast.
 	// field's parsed tag below (applyStructEncoding), mirroring the runtime.
 	structEnc := structSentinelEncoding(st)
 
+	// A byte-order mark field switches order for the fields after it; scalar
+	// batches stop at it (see markedRunEnd).
+	orderMark, err := structOrderMark(st)
+	if err != nil {
+		return fmt.Errorf("type %s: %w", typeName, err)
+	}
+	markPos, markType := -1, ""
+	if orderMark != nil {
+		for i, f := range emittableFields(st) {
+			if f.Names[0].Name == orderMark.field {
+				markPos, markType = i, getGoTypeName(f.Type)
+			}
+		}
+		if markPos < 0 {
+			return fmt.Errorf("type %s: endian=%s: no field named %s", typeName, orderMark.field, orderMark.field)
+		}
+		if err := orderMarkSwitch(&bytes.Buffer{}, orderMark, markType); err != nil {
+			return fmt.Errorf("type %s: %w", typeName, err)
+		}
+	}
+	// endian=detect: the magic's bytes choose the order on decode, and the
+	// record field's order is used on encode.
+	orderDetect, err := structOrderDetect(st)
+	if err != nil {
+		return fmt.Errorf("type %s: %w", typeName, err)
+	}
+
 	// Multidimensional array tags ([4][2]int8) are supported for scalar leaves with
 	// all-fixed or all-slice nesting; other shapes fail loud so the struct falls
 	// back to the runtime interpreter (which supports every shape).
+	declared := make(map[string]bool)
 	for _, field := range st.Fields.List {
 		if len(field.Names) == 0 || field.Names[0].Name == "_" {
 			continue
 		}
-		pt := parseFieldTag(field.Tag)
+		pt := parseFieldTag(field.Tag)
+		declared[field.Names[0].Name] = true
+		// Reject a malformed tag expression here, with the field named, rather
+		// than emitting Go that fails to compile.
+		exprs := append([]string{pt.bufLenExpr, pt.options["omittable"], pt.options["valueof"]}, pt.arrayDimExprs...)
+		for _, e := range exprs {
+			if e == "" {
+				continue
+			}
+			if _, err := cgTranslateExpr(e, g.sizeOf, g.arrayLen); err != nil {
+				return fmt.Errorf("type %s: field %s: %w", typeName, field.Names[0].Name, err)
+			}
+		}
+		if e := strings.TrimSpace(pt.options["endian"]); cgIsByteOrderName(e) {
+			return fmt.Errorf("type %s: field %s: named byte order endian=%s is not supported by codegen; use the runtime interpreter", typeName, field.Names[0].Name, e)
+		}
+		if v := pt.options["valueof"]; v != "" && cgExprUsesParam(v, "remaining") {
+			return fmt.Errorf("type %s: field %s: $remaining is only available when decoding, so a valueof cannot use it", typeName, field.Names[0].Name)
+		}
+		if ept := encodeFieldTag(pt); ept.bufLenExpr != "" && cgExprUsesParam(ept.bufLenExpr, "remaining") {
+			return fmt.Errorf("type %s: field %s: $remaining is only available when decoding, so a pad cannot be sized by it", typeName, field.Names[0].Name)
+		}
+		if e, ok := pt.options["enum"]; ok {
+			if _, _, err := cgParseEnum(e); err != nil {
+				return fmt.Errorf("type %s: field %s: %w", typeName, field.Names[0].Name, err)
+			}
+			goType := getGoTypeName(field.Type)
+			binType := getEffectiveBinaryType(pt.binaryType, goType)
+			if _, ok := scalarWidth(binType); !ok || strings.HasPrefix(binType, "float") {
+				return fmt.Errorf("type %s: field %s: enum requires an integer field type, got %s", typeName, field.Names[0].Name, binType)
+			}
+		}
+		if c := pt.options["check"]; c != "" {
+			// As in the runtime, a rule runs once its field is read, so it may
+			// only use that field and the ones before it.
+			if _, err := cgTranslateCond(c, g.sizeOf, g.arrayLen, "n"); err != nil {
+				return fmt.Errorf("type %s: field %s: check: %w", typeName, field.Names[0].Name, err)
+			}
+			refs, _ := cgExprFieldRefs(c)
+			for _, r := range refs {
+				if !declared[r] {
+					return fmt.Errorf("type %s: field %s: check references %s, which is decoded later; put the rule on the last field it uses", typeName, field.Names[0].Name, r)
+				}
+			}
+		}
+		if d, ok := pt.options["default"]; ok {
+			goType := getGoTypeName(field.Type)
+			if strings.HasPrefix(goType, "*") || goType == "interface{}" || goType == "any" {
+				return fmt.Errorf("type %s: field %s: default is not allowed on a pointer or interface field; an omitted one is nil", typeName, field.Names[0].Name)
+			}
+			if _, hasConst := pt.options["const"]; hasConst || pt.options["valueof"] != "" {
+				return fmt.Errorf("type %s: field %s: default cannot be combined with const or valueof", typeName, field.Names[0].Name)
+			}
+			if _, err := cgDefaultLiteral(goType, getEffectiveBinaryType(pt.binaryType, goType), d); err != nil {
+				return fmt.Errorf("type %s: field %s: %w", typeName, field.Names[0].Name, err)
+			}
+		}
+		if _, ok := pt.options["omitdefault"]; ok {
+			if _, hasDefault := pt.options["default"]; !hasDefault {
+				return fmt.Errorf("type %s: field %s: omitdefault requires a default", typeName, field.Names[0].Name)
+			}
+			if o, ok := pt.options["omittable"]; !ok || o != "" {
+				return fmt.Errorf("type %s: field %s: omitdefault requires a plain omittable field (one that ends at the end of the input)", typeName, field.Names[0].Name)
+			}
+		}
 		if pt.numDims > 1 {
 			goType := getGoTypeName(field.Type)
 			binType := getEffectiveBinaryType(pt.binaryType, goType)
@@ -1170,7 +2078,14 @@ func (g *Generator) generateMethods(buf *bytes.Buffer, typeName string, st *ast.
 	fmt.Fprintf(buf, "// UnmarshalBinary implements encoding.BinaryUnmarshaler.\n")
 	fmt.Fprintf(buf, "func (s *%s) UnmarshalBinary(data []byte) error {\n", typeName)
 	buf.WriteString("\tr := bytes.NewReader(data)\n")
-	fmt.Fprintf(buf, "\t_, err := s.ReadBinary(r, %s)\n", bakedLit)
+	if g.Strict {
+		// Input left over after the value fails, as Marshaler.Unmarshal does
+		// under Strict.
+		fmt.Fprintf(buf, "\tn, err := s.ReadBinary(r, %s)\n", bakedLit)
+		buf.WriteString("\tif err == nil {\n\t\terr = binarystruct.NewCodegen(nil).CheckTrailingData(n, len(data))\n\t}\n")
+	} else {
+		fmt.Fprintf(buf, "\t_, err := s.ReadBinary(r, %s)\n", bakedLit)
+	}
 	buf.WriteString("\treturn err\n")
 	buf.WriteString("}\n\n")
 
@@ -1190,7 +2105,7 @@ func (g *Generator) generateMethods(buf *bytes.Buffer, typeName string, st *ast.
 		if len(field.Names) == 0 || field.Names[0].Name == "_" {
 			continue
 		}
-		pt := parseFieldTag(field.Tag)
+		pt := encodeFieldTag(parseFieldTag(field.Tag))
 		applyStructEncoding(pt, structEnc)
 		vexpr, hasV := pt.options["valueof"]
 		goType := getGoTypeName(field.Type)
@@ -1211,8 +2126,41 @@ func (g *Generator) generateMethods(buf *bytes.Buffer, typeName string, st *ast.
 	if err := func() error {
 		buf := &writeBody
 		flds := emittableFields(st)
+		// A trailing run of omitdefault fields left at their defaults is not
+		// written: omit<Field> is true when the field and all after it are.
+		omitDefault := make(map[string]bool)
+		prev := ""
+		for fi := len(flds) - 1; fi >= 0; fi-- {
+			pt := parseFieldTag(flds[fi].Tag)
+			if _, ok := pt.options["ignore"]; ok || pt.binaryType == "-" {
+				continue
+			}
+			if _, ok := pt.options["omitdefault"]; !ok {
+				break
+			}
+			fieldName := flds[fi].Names[0].Name
+			goType := getGoTypeName(flds[fi].Type)
+			lit, _ := cgDefaultLiteral(goType, getEffectiveBinaryType(pt.binaryType, goType), pt.options["default"])
+			cond := fmt.Sprintf("s.%s == %s", fieldName, lit)
+			if strings.HasPrefix(goType, "[]") {
+				cond = fmt.Sprintf("bytes.Equal(s.%s, %s)", fieldName, lit)
+			}
+			if prev != "" {
+				cond = prev + " && " + cond
+			}
+			fmt.Fprintf(buf, "\tomit%s := %s\n", fieldName, cond)
+			omitDefault[fieldName] = true
+			prev = "omit" + fieldName
+		}
 		for fi := 0; fi < len(flds); fi++ {
-			if rj := scalarRunEnd(flds, fi, structEnc); rj-fi >= 2 {
+			if markPos >= 0 && fi == markPos+1 {
+				orderMarkSwitch(buf, orderMark, markType)
+			}
+			if orderDetect != nil && fi == orderDetect.pos {
+				orderDetectWrite(buf, orderDetect)
+			}
+			if rj := markedRunEnd(g.scalarRunEnd(flds, fi, structEnc), fi, markPos); rj-fi >= 2 {
+				fmt.Fprintf(buf, "\tefield, eoff = %q, n\n", flds[fi].Names[0].Name)
 				g.generateScalarFieldBatchWrite(buf, flds[fi:rj], structEnc)
 				fi = rj - 1
 				continue
@@ -1220,29 +2168,36 @@ func (g *Generator) generateMethods(buf *bytes.Buffer, typeName string, st *ast.
 			field := flds[fi]
 			fieldName := field.Names[0].Name
 			goType := getGoTypeName(field.Type)
-			parsedTag := parseFieldTag(field.Tag)
+			parsedTag := encodeFieldTag(parseFieldTag(field.Tag))
 			applyStructEncoding(parsedTag, structEnc)
 
 			if _, ok := parsedTag.options["ignore"]; ok || parsedTag.binaryType == "-" {
 				continue
 			}
+			if omitDefault[fieldName] {
+				fmt.Fprintf(buf, "\tif omit%s {\n\t\treturn n, nil\n\t}\n", fieldName)
+			}
 
-			// Handle omittable with expression
+			// Handle omittable with expression. One that uses $remaining has no
+			// value while encoding and never omits, as in the runtime.
 			if omittableExpr, ok := parsedTag.options["omittable"]; ok && omittableExpr != "" {
-				fmt.Fprintf(buf, "\tif n >= int(%s) {\n\t\treturn n, nil\n\t}\n", translateExpression(omittableExpr))
+				if !cgExprUsesParam(omittableExpr, "remaining") {
+					fmt.Fprintf(buf, "\tif %s {\n\t\treturn n, nil\n\t}\n", g.omittedCond(omittableExpr))
+				}
 			} else if ok && strings.HasPrefix(goType, "*") {
 				// EOF-based omission (pointer)
 				fmt.Fprintf(buf, "\tif s.%s == nil {\n\t\treturn n, nil\n\t}\n", fieldName)
 			}
 
 			binType := getEffectiveBinaryType(parsedTag.binaryType, goType)
+			fmt.Fprintf(buf, "\tefield, eoff = %q, n\n", fieldName)
 
 			// valueof: write a value computed from other fields instead of the
 			// field's own (emit-only). Validated as an integer scalar upstream.
 			if vexpr, ok := parsedTag.options["valueof"]; ok && vexpr != "" {
 				// A custom evaluator (a single NAME(...) call whose NAME is not a
 				// built-in) is resolved on the Marshaler at run time, like a codec.
-				if evname, cargs, isCall := parseCustomValueofCall(vexpr); isCall && evname != "bytelen" && evname != "count" {
+				if evname, cargs, isCall := parseCustomValueofCall(vexpr); isCall && evname != "bytelen" && evname != "count" && !cgIsExprBuiltin(evname) {
 					if err := g.generateCustomValueofWrite(buf, fieldName, evname, cargs, goType, binType, parsedTag, fieldInfo, endianStr); err != nil {
 						return fmt.Errorf("field %s: %w", fieldName, err)
 					}
@@ -1267,6 +2222,12 @@ func (g *Generator) generateMethods(buf *bytes.Buffer, typeName string, st *ast.
 				continue
 			}
 
+			if g.ValidateEncode {
+				if err := g.generateEncodeValidate(buf, typeName, fieldName, goType, parsedTag, fieldInfo); err != nil {
+					return fmt.Errorf("field %s: %w", fieldName, err)
+				}
+			}
+
 			if parsedTag.isArray {
 				if err := g.generateArrayWrite(buf, fieldName, goType, binType, parsedTag, fieldInfo); err != nil {
 					return fmt.Errorf("field %s: %w", fieldName, err)
@@ -1281,13 +2242,30 @@ func (g *Generator) generateMethods(buf *bytes.Buffer, typeName string, st *ast.
 	}(); err != nil {
 		return err
 	}
+	// A failed tag expression is returned as the error of the field, wrapped
+	// by the deferred EncodeError below.
+	body, err := g.hoistGuarded(writeBody.String(), "err")
+	if err != nil {
+		return err
+	}
+	g.outerRefPrologue(buf, st)
+	buf.WriteString("\tcg := binarystruct.NewCodegen(ms)\n")
+	// Lifecycle hooks run here as the runtime runs them for a struct: before
+	// encoding, and (in the read method) after decoding, including when an
+	// omittable field ends the input early.
+	buf.WriteString("\tif err = cg.BeforeMarshalHook(s); err != nil {\n\t\treturn 0, err\n\t}\n")
 	if structLit != "" {
 		// A struct-declared order wins over the order the caller passed in (the
 		// runtime fast-paths here before seeding the struct order, so we seed it).
 		fmt.Fprintf(buf, "\torder = %s\n", structLit)
 	}
-	emitLocalScratch(buf, writeBody.String())
-	buf.Write(writeBody.Bytes())
+	emitLocalScratch(buf, body)
+	if strings.Contains(body, "efield, eoff = ") {
+		// A field's failure is an *EncodeError naming it, as in the runtime.
+		buf.WriteString("\tvar efield string\n\tvar eoff int\n")
+		buf.WriteString("\tdefer func() {\n\t\tif err != nil && efield != \"\" {\n\t\t\terr = cg.EncodeError(eoff, efield, err)\n\t\t}\n\t}()\n")
+	}
+	buf.WriteString(body)
 	buf.WriteString("\treturn n, nil\n")
 	buf.WriteString("}\n\n")
 
@@ -1300,13 +2278,40 @@ func (g *Generator) generateMethods(buf *bytes.Buffer, typeName string, st *ast.
 	// 4. ReadBinaryWithMarshaler (Context-aware)
 	fmt.Fprintf(buf, "// ReadBinaryWithMarshaler implements binarystruct.MarshalerContextReader.\n")
 	fmt.Fprintf(buf, "func (s *%s) ReadBinaryWithMarshaler(ms *binarystruct.Marshaler, r io.Reader, order binarystruct.ByteOrder) (n int, err error) {\n", typeName)
+	g.outerRefPrologue(buf, st)
+	buf.WriteString("\tcg := binarystruct.NewCodegen(ms)\n")
+	// Under Salvage the interpreter reads the fields, recording those it read
+	// and restoring the rest when one fails.
+	buf.WriteString("\tif cg.Salvaging() {\n\t\treturn cg.ReadStructFields(r, order, s)\n\t}\n")
+	if g.readCollects(st) || readAllocates(st) {
+		// Under ValidateCollect the outermost decode reports every failed check,
+		// and counts the memory charged to MaxDecodeAlloc.
+		buf.WriteString("\tcg.BeginDecode()\n\tdefer cg.EndDecode(&err)\n")
+	}
+	buf.WriteString("\tif err = cg.EnterNested(); err != nil {\n\t\treturn 0, err\n\t}\n\tdefer cg.LeaveNested()\n")
 
 	var readBody bytes.Buffer
 	if err := func() error {
 		buf := &readBody
 		flds := emittableFields(st)
+		// default=: a field the input ends before keeps its default.
+		for _, field := range flds {
+			pt := parseFieldTag(field.Tag)
+			if d, ok := pt.options["default"]; ok {
+				goType := getGoTypeName(field.Type)
+				lit, _ := cgDefaultLiteral(goType, getEffectiveBinaryType(pt.binaryType, goType), d)
+				fmt.Fprintf(buf, "\ts.%s = %s\n", field.Names[0].Name, lit)
+			}
+		}
 		for fi := 0; fi < len(flds); fi++ {
-			if rj := scalarRunEnd(flds, fi, structEnc); rj-fi >= 2 {
+			if markPos >= 0 && fi == markPos+1 {
+				orderMarkSwitch(buf, orderMark, markType)
+			}
+			if orderDetect != nil && fi == orderDetect.pos {
+				orderDetectRead(buf, orderDetect)
+				continue
+			}
+			if rj := markedRunEnd(g.scalarRunEnd(flds, fi, structEnc), fi, markPos); rj-fi >= 2 {
 				g.generateScalarFieldBatchRead(buf, flds[fi:rj], structEnc)
 				fi = rj - 1
 				continue
@@ -1323,10 +2328,18 @@ func (g *Generator) generateMethods(buf *bytes.Buffer, typeName string, st *ast.
 
 			binType := getEffectiveBinaryType(parsedTag.binaryType, goType)
 
+			for _, e := range fieldTagExprs(parsedTag) {
+				if cgExprUsesParam(e, "remaining") {
+					buf.WriteString("\tremaining, err = cg.RemainingLen(r)\n\tif err != nil {\n\t\treturn n, err\n\t}\n")
+					break
+				}
+			}
+			chunk := buf.Len()
+
 			// Handle omittable
 			if omittableExpr, ok := parsedTag.options["omittable"]; ok {
 				if omittableExpr != "" {
-					fmt.Fprintf(buf, "\tif n >= int(%s) {\n\t\treturn n, nil\n\t}\n", translateExpression(omittableExpr))
+					fmt.Fprintf(buf, "\tif %s {\n\t\treturn n, cg.AfterUnmarshalHooks(s)\n\t}\n", g.omittedCond(omittableExpr))
 				} else {
 					// EOF-based omission
 					buf.WriteString("\t// EOF check for omittable\n")
@@ -1334,7 +2347,7 @@ func (g *Generator) generateMethods(buf *bytes.Buffer, typeName string, st *ast.
 					buf.WriteString("\t\tvar peek [1]byte\n")
 					buf.WriteString("\t\t_, peekErr := io.ReadFull(r, peek[:])\n")
 					buf.WriteString("\t\tif peekErr == io.EOF || peekErr == io.ErrUnexpectedEOF {\n")
-					buf.WriteString("\t\t\treturn n, nil\n")
+					buf.WriteString("\t\t\treturn n, cg.AfterUnmarshalHooks(s)\n")
 					buf.WriteString("\t\t}\n")
 					buf.WriteString("\t\t// Restore the byte\n")
 					buf.WriteString("\t\tr = io.MultiReader(bytes.NewReader(peek[:]), r)\n")
@@ -1345,16 +2358,22 @@ func (g *Generator) generateMethods(buf *bytes.Buffer, typeName string, st *ast.
 			// Capture the field's start offset before reading it, so a validation
 			// failure reports the same byte offset as the runtime interpreter
 			// (which records n before advancing past the field). Only emitted for
-			// fields that actually validate, to avoid an unused variable.
+			// fields that actually validate, convert under -strict or hold nested
+			// structs, whose failures are wrapped, to avoid an unused variable.
 			offExpr := "n"
+			validates := g.cgStrictReadChecked(goType, binType) || cgNestedRead(binType, parsedTag)
 			if !g.NoValidate {
 				_, hasConst := parsedTag.options["const"]
 				_, hasRange := parsedTag.options["range"]
 				_, hasMatch := parsedTag.options["match"]
-				if hasConst || hasRange || hasMatch {
-					offExpr = "voff" + fieldName
-					fmt.Fprintf(buf, "\t%s := n\n", offExpr)
-				}
+				_, hasCheck := parsedTag.options["check"]
+				_, hasEnum := parsedTag.options["enum"]
+				_, reserved := parsedTag.options["reserved"]
+				validates = validates || hasConst || hasRange || hasMatch || hasCheck || hasEnum || reserved
+			}
+			if validates {
+				offExpr = "voff" + fieldName
+				fmt.Fprintf(buf, "\t%s := n\n", offExpr)
 			}
 
 			if parsedTag.isArray {
@@ -1366,10 +2385,23 @@ func (g *Generator) generateMethods(buf *bytes.Buffer, typeName string, st *ast.
 			// const: validate the field equals its fixed value after reading
 			// (unless -no-validate strips decode validation).
 			if cexpr, ok := parsedTag.options["const"]; ok && cexpr != "" && !g.NoValidate {
-				if err := g.generateConstValidate(buf, fieldName, goType, binType, cexpr, offExpr); err != nil {
+				if err := g.generateConstValidate(buf, fieldName, goType, binType, cexpr, parsedTag.raw, offExpr); err != nil {
+					return fmt.Errorf("field %s: %w", fieldName, err)
+				}
+			}
+			// check: the field's cross-field rule, once it is read.
+			if c, ok := parsedTag.options["check"]; ok && c != "" && !g.NoValidate {
+				if err := g.generateCheckValidate(buf, fieldName, c, parsedTag.raw, offExpr); err != nil {
 					return fmt.Errorf("field %s: %w", fieldName, err)
 				}
 			}
+			// A failed tag expression is reported for the field, at its start,
+			// as in the runtime.
+			doff := "doff" + fieldName
+			fail := fmt.Sprintf("cg.DecodeError(%s, %q, fmt.Sprintf(\"%%T\", s.%s), %q, err)", doff, fieldName, fieldName, parsedTag.raw)
+			if err := g.hoistGuardedFrom(buf, chunk, "\t"+doff+" := n\n", fail); err != nil {
+				return fmt.Errorf("field %s: %w", fieldName, err)
+			}
 		}
 		// Post-decode validation of custom valueof evaluators. Run after all
 		// fields are read so a checksum may reference fields declared after it.
@@ -1388,10 +2420,10 @@ func (g *Generator) generateMethods(buf *bytes.Buffer, typeName string, st *ast.
 					continue
 				}
 				evname, cargs, isCall := parseCustomValueofCall(vexpr)
-				if !isCall || evname == "bytelen" || evname == "count" {
+				if !isCall || evname == "bytelen" || evname == "count" || cgIsExprBuiltin(evname) {
 					continue
 				}
-				if err := g.generateCustomValueofValidate(buf, fieldName, evname, cargs, goType, fieldInfo, endianStr); err != nil {
+				if err := g.generateCustomValueofValidate(buf, fieldName, evname, cargs, goType, parsedTag.raw, fieldInfo, endianStr); err != nil {
 					return fmt.Errorf("field %s: %w", fieldName, err)
 				}
 			}
@@ -1403,14 +2435,94 @@ func (g *Generator) generateMethods(buf *bytes.Buffer, typeName string, st *ast.
 	if structLit != "" {
 		fmt.Fprintf(buf, "\torder = %s\n", structLit)
 	}
+	g.remainingPrologue(buf, st)
 	emitLocalScratch(buf, readBody.String())
 	buf.Write(readBody.Bytes())
-	buf.WriteString("\treturn n, nil\n")
+	buf.WriteString("\treturn n, cg.AfterUnmarshalHooks(s)\n")
 	buf.WriteString("}\n\n")
 
 	return nil
 }
 
+// omittedCond is the condition that an omittable= expression omits the field.
+// A guarded operator that fails leaves the field in, as in the runtime.
+func (g *Generator) omittedCond(expr string) string {
+	if g.exprsGuarded([]string{expr}) {
+		return fmt.Sprintf("cg.Omitted(n, %s)", cgUnguard(g.translateExpression(expr)))
+	}
+	return "n >= " + g.translateExpression(expr)
+}
+
+// cgUnguard removes the cgGuardOpen and cgGuardClose marks from src.
+func cgUnguard(src string) string {
+	return strings.NewReplacer(cgGuardOpen, "", cgGuardClose, "").Replace(src)
+}
+
+// hoistGuarded moves each outermost expression of body enclosed in cgGuardOpen
+// and cgGuardClose into a variable assigned just before the statement using it,
+// followed by a check of cg.Err that returns n and fail, so that the statement
+// runs only when every operator of the expression succeeded.
+func (g *Generator) hoistGuarded(body, fail string) (string, error) {
+	var out strings.Builder
+	for {
+		open := strings.Index(body, cgGuardOpen)
+		if open < 0 {
+			out.WriteString(body)
+			return out.String(), nil
+		}
+		end, depth := -1, 0
+		for i := open; i < len(body) && end < 0; i++ {
+			switch body[i] {
+			case cgGuardOpen[0]:
+				depth++
+			case cgGuardClose[0]:
+				if depth--; depth == 0 {
+					end = i
+				}
+			}
+		}
+		line := strings.LastIndexByte(body[:open], '\n') + 1
+		stmt := strings.TrimLeft(body[line:open], "\t")
+		if end < 0 || strings.HasPrefix(stmt, "}") || strings.HasPrefix(stmt, "case ") {
+			return "", fmt.Errorf("internal error: cannot hoist the tag expression in %q", cgUnguard(body[line:open]))
+		}
+		indent := body[line : open-len(stmt)]
+		g.exprVars++
+		v := fmt.Sprintf("expr%d", g.exprVars)
+		out.WriteString(body[:line])
+		fmt.Fprintf(&out, "%s%s := %s\n%sif err = cg.Err(); err != nil {\n%s\treturn n, %s\n%s}\n", indent, v, cgUnguard(body[open+1:end]), indent, indent, fail, indent)
+		body = body[line:open] + v + body[end+1:]
+	}
+}
+
+// hoistGuardedFrom runs hoistGuarded on the part of buf from start, the code of
+// one field, which then begins with pre when it hoisted an expression.
+func (g *Generator) hoistGuardedFrom(buf *bytes.Buffer, start int, pre, fail string) error {
+	part := buf.String()[start:]
+	if !strings.Contains(part, cgGuardOpen) {
+		return nil
+	}
+	out, err := g.hoistGuarded(part, fail)
+	if err != nil {
+		return err
+	}
+	buf.Truncate(start)
+	buf.WriteString(pre)
+	buf.WriteString(out)
+	return nil
+}
+
+// exprsGuarded reports whether any of the tag expressions calls a cg method
+// that can fail at run time (see cgGuard).
+func (g *Generator) exprsGuarded(exprs []string) bool {
+	for _, e := range exprs {
+		if strings.Contains(g.translateExpression(e), cgGuardOpen) {
+			return true
+		}
+	}
+	return false
+}
+
 // parseCgConstBytes decodes a byte-sequence const hex blob (e.g. 0x504b0304).
 func parseCgConstBytes(s string) ([]byte, error) {
 	t := strings.ReplaceAll(strings.TrimSpace(s), "_", "")
@@ -1428,6 +2540,80 @@ func parseCgConstBytes(s string) ([]byte, error) {
 	return out, nil
 }
 
+// cgDefaultLiteral returns the Go literal for a default= value, checked as the
+// runtime checks it against the field's Go type. A named type is checked by its
+// binary type, since its underlying type is not known here.
+func cgDefaultLiteral(goType, binType, s string) (string, error) {
+	if strings.HasPrefix(goType, "[") {
+		if !strings.HasSuffix(goType, "]byte") && !strings.HasSuffix(goType, "]uint8") {
+			return "", fmt.Errorf("default is not supported on type %s", goType)
+		}
+		b, err := parseCgConstBytes(s)
+		if err != nil {
+			return "", err
+		}
+		if isFixedArrayType(goType) {
+			if n, err := strconv.Atoi(goType[1:strings.Index(goType, "]")]); err != nil || n != len(b) {
+				return "", fmt.Errorf("default %q is %d bytes; %s needs its length", s, len(b), goType)
+			}
+		}
+		elems := make([]string, len(b))
+		for i, c := range b {
+			elems[i] = fmt.Sprintf("0x%02x", c)
+		}
+		return goType + "{" + strings.Join(elems, ", ") + "}", nil
+	}
+	kind := goType
+	switch goType {
+	case "string", "bool", "float32", "float64", "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64", "byte":
+	default:
+		kind = binType
+	}
+	switch kind {
+	case "bool":
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return "", fmt.Errorf("invalid default %q for %s", s, goType)
+		}
+		return strconv.FormatBool(b), nil
+	case "float32", "float64":
+		bits := 64
+		if kind == "float32" {
+			bits = 32
+		}
+		f, err := strconv.ParseFloat(s, bits)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", fmt.Errorf("invalid default %q for %s; it must be a finite number", s, goType)
+		}
+		return strconv.FormatFloat(f, 'g', -1, 64), nil
+	case "int", "int8", "int16", "int32", "int64":
+		bits := 64
+		if w, ok := scalarWidth(kind); ok {
+			bits = w * 8
+		}
+		i, err := strconv.ParseInt(s, 0, bits)
+		if err != nil {
+			return "", fmt.Errorf("invalid default %q for %s", s, goType)
+		}
+		return strconv.FormatInt(i, 10), nil
+	case "uint", "uint8", "uint16", "uint32", "uint64", "byte", "word", "dword", "qword":
+		bits := 64
+		if w, ok := scalarWidth(kind); ok {
+			bits = w * 8
+		}
+		u, err := strconv.ParseUint(s, 0, bits)
+		if err != nil {
+			return "", fmt.Errorf("invalid default %q for %s", s, goType)
+		}
+		return strconv.FormatUint(u, 10), nil
+	}
+	if goType == "string" || isStringBinType(binType) {
+		return strconv.Quote(s), nil
+	}
+	return "", fmt.Errorf("default is not supported on type %s", goType)
+}
+
 // isFixedArrayType reports whether goType is a fixed-size array ([N]T) rather
 // than a slice ([]T).
 func isFixedArrayType(goType string) bool {
@@ -1542,19 +2728,72 @@ func (g *Generator) generateConstWrite(buf *bytes.Buffer, goType, binType string
 	return g.generateFieldWrite(buf, "("+cexpr+")", goType, binType, parsedTag, fields)
 }
 
-// cgValidationErr formats a `return n, &binarystruct.DecodeError{...}` statement
-// that mirrors the runtime interpreter's validation failures: a *DecodeError with
-// the field's start offset (offExpr) and field name, wrapping an inner error that
-// itself wraps ErrValidationError. inner is the Go expression for that inner error
-// (e.g. `fmt.Errorf("const mismatch: %w", binarystruct.ErrValidationError)`).
-func cgValidationErr(offExpr, fieldName, inner string) string {
-	return fmt.Sprintf("\t\treturn n, &binarystruct.DecodeError{Offset: %s, Field: %q, Err: %s}\n", offExpr, fieldName, inner)
+// cgValidationErr formats the statements that report a failed check as the
+// runtime interpreter does: the *DecodeError of cg.DecodeError with
+// the field's start offset (offExpr), name, Go type and tag, wrapping an inner
+// error that itself wraps ErrValidationError, passed through cg.ValidationFailed
+// so the Marshaler's Validation mode applies. inner is the Go expression for
+// that inner error, a *binarystruct.CheckError. An array element, fieldName
+// "F[i]", is reported as the field F, as the runtime reports it.
+func cgValidationErr(offExpr, fieldName, tag, inner string) string {
+	fieldName = strings.TrimSuffix(fieldName, "[i]")
+	return fmt.Sprintf("\t\tif err = cg.ValidationFailed(cg.DecodeError(%s, %q, fmt.Sprintf(\"%%T\", s.%s), %q, %s)); err != nil {\n\t\t\treturn n, err\n\t\t}\n", offExpr, fieldName, fieldName, tag, inner)
+}
+
+// cgDecodeErr formats the statements that return inner, an error decoding the
+// field, as a *binarystruct.DecodeError, like cgValidationErr but not subject
+// to the Marshaler's Validation mode.
+func cgDecodeErr(offExpr, fieldName, tag, inner string) string {
+	fieldName = strings.TrimSuffix(fieldName, "[i]")
+	return fmt.Sprintf("\t\treturn n, cg.DecodeError(%s, %q, fmt.Sprintf(\"%%T\", s.%s), %q, %s)\n", offExpr, fieldName, fieldName, tag, inner)
+}
+
+// cgFill returns the byte of a tag's fill= or padchar= option, 0 when it has
+// neither. As in the runtime, padchar= is an alias of fill=, and a tag that
+// gives the byte more than once is an error.
+func cgFill(parsedTag parsedFieldTag) (byte, error) {
+	given := 0
+	for _, opt := range splitTagOptions(parsedTag.raw) {
+		if k, _, _ := strings.Cut(opt, "="); k == "fill" || k == "padchar" {
+			given++
+		}
+	}
+	if given > 1 {
+		return 0, fmt.Errorf("fill= and padchar= set the same byte; give one of them once")
+	}
+	name := "padchar"
+	s, ok := parsedTag.options[name]
+	if !ok {
+		name = "fill"
+		if s, ok = parsedTag.options[name]; !ok {
+			return 0, nil
+		}
+	}
+	v, err := strconv.ParseUint(strings.TrimSpace(s), 0, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value %q: must be a byte", name, s)
+	}
+	return byte(v), nil
+}
+
+// cgTrim returns a string tag's trim= mode: right (the default), none or both.
+func cgTrim(parsedTag parsedFieldTag) string {
+	if s, ok := parsedTag.options["trim"]; ok {
+		return strings.TrimSpace(s)
+	}
+	return "right"
+}
+
+// cgCheckError formats the *binarystruct.CheckError of a failed check, given
+// the Go expressions of its Expected and Actual strings and of its Err.
+func cgCheckError(expected, actual, inner string) string {
+	return fmt.Sprintf("&binarystruct.CheckError{Expected: %s, Actual: %s, Err: %s}", expected, actual, inner)
 }
 
 // generateConstValidate emits a post-read check that the field equals its const.
-func (g *Generator) generateConstValidate(buf *bytes.Buffer, fieldName, goType, binType, cexpr, offExpr string) error {
+func (g *Generator) generateConstValidate(buf *bytes.Buffer, fieldName, goType, binType, cexpr, tag, offExpr string) error {
 	accessor := "s." + fieldName
-	inner := `fmt.Errorf("const mismatch: %w", binarystruct.ErrValidationError)`
+	errExpr := `fmt.Errorf("const mismatch: %w", binarystruct.ErrValidationError)`
 	if isCgBytesConst(goType, binType) {
 		b, err := parseCgConstBytes(cexpr)
 		if err != nil {
@@ -1568,16 +2807,98 @@ func (g *Generator) generateConstValidate(buf *bytes.Buffer, fieldName, goType,
 			got = accessor + "[:]"
 		}
 		fmt.Fprintf(buf, "\tif !bytes.Equal(%s, %s) {\n", got, goByteSliceLiteral(b))
-		buf.WriteString(cgValidationErr(offExpr, fieldName, inner))
+		inner := cgCheckError(strconv.Quote(fmt.Sprintf("%#x", b)), fmt.Sprintf("fmt.Sprintf(\"%%#x\", %s)", got), errExpr)
+		buf.WriteString(cgValidationErr(offExpr, fieldName, tag, inner))
 		buf.WriteString("\t}\n")
 		return nil
 	}
 	fmt.Fprintf(buf, "\tif %s != (%s) {\n", accessor, cexpr)
-	buf.WriteString(cgValidationErr(offExpr, fieldName, inner))
+	inner := cgCheckError(fmt.Sprintf("fmt.Sprintf(\"%%#x\", %s(%s))", goType, cexpr), fmt.Sprintf("fmt.Sprintf(\"%%#x\", %s)", accessor), errExpr)
+	buf.WriteString(cgValidationErr(offExpr, fieldName, tag, inner))
+	buf.WriteString("\t}\n")
+	return nil
+}
+
+// generateCheckValidate emits the test of a check= rule after its field is
+// read. $remaining is refreshed first, since the runtime evaluates the rule
+// after the field too.
+func (g *Generator) generateCheckValidate(buf *bytes.Buffer, fieldName, expr, tag, offExpr string) error {
+	cond, err := cgTranslateCond(expr, g.sizeOf, g.arrayLen, offExpr)
+	if err != nil {
+		return err
+	}
+	if cgExprUsesParam(expr, "remaining") {
+		buf.WriteString("\tremaining, err = cg.RemainingLen(r)\n\tif err != nil {\n\t\treturn n, err\n\t}\n")
+	}
+	fmt.Fprintf(buf, "\tif !%s {\n", cond)
+	inner := cgCheckError(strconv.Quote(expr), `""`, fmt.Sprintf("fmt.Errorf(\"check %%q failed: %%w\", %q, binarystruct.ErrValidationError)", expr))
+	buf.WriteString(cgValidationErr(offExpr, fieldName, tag, inner))
 	buf.WriteString("\t}\n")
 	return nil
 }
 
+// generateEncodeValidate emits, under -validate-encode, the checks of a field
+// about to be written, as Marshaler.ValidateOnEncode runs them: the enum=,
+// range= and match= tests of its value (of each element of an array or slice,
+// of the pointee of a non-nil pointer) and its check= rule, which sees valueof
+// fields at their computed values. A rule that uses $remaining is not tested.
+func (g *Generator) generateEncodeValidate(buf *bytes.Buffer, typeName, fieldName, goType string, parsedTag parsedFieldTag, fields map[string]cgFieldInfo) error {
+	fail := func(inner string) string {
+		return fmt.Sprintf("\t\treturn n, %s\n", inner) // wrapped in the field's EncodeError on return
+	}
+	_, hasEnum := parsedTag.options["enum"]
+	_, hasRange := parsedTag.options["range"]
+	_, hasMatch := parsedTag.options["match"]
+	if (hasEnum || hasRange || hasMatch) && parsedTag.numDims <= 1 {
+		switch {
+		case strings.HasPrefix(goType, "*"):
+			fmt.Fprintf(buf, "\tif s.%s != nil {\n\t\tev := *s.%s\n", fieldName, fieldName)
+		case strings.HasPrefix(goType, "["):
+			fmt.Fprintf(buf, "\tfor _, ev := range s.%s {\n", fieldName)
+		default:
+			fmt.Fprintf(buf, "\t{\n\t\tev := s.%s\n", fieldName)
+		}
+		g.generateValueChecks(buf, "ev", typeName, fieldName, parsedTag, fail)
+		buf.WriteString("\t}\n")
+	}
+
+	c, ok := parsedTag.options["check"]
+	if !ok || c == "" || cgExprUsesParam(c, "remaining") {
+		return nil
+	}
+	cond, err := cgTranslateCond(c, g.sizeOf, g.arrayLen, "n")
+	if err != nil {
+		return err
+	}
+	// A valueof field is written from its expression, not the Go field, so the
+	// rule is given the computed value.
+	var pre bytes.Buffer
+	refs, _ := cgExprFieldRefs(c)
+	seen := make(map[string]bool)
+	for _, r := range refs {
+		fi, ok := fields[r]
+		if !ok || !fi.hasValueof || seen[r] {
+			continue
+		}
+		seen[r] = true
+		if evname, _, isCall := parseCustomValueofCall(fi.valueofExpr); isCall && evname != "bytelen" && evname != "count" && !cgIsExprBuiltin(evname) {
+			return fmt.Errorf("check %q uses %s, whose custom valueof evaluator -validate-encode cannot compute; use the runtime interpreter for this struct", c, r)
+		}
+		p, v, err := g.translateValueof(fi.valueofExpr, fields, map[string]bool{})
+		if err != nil {
+			return err
+		}
+		pre.WriteString(p)
+		cond = strings.ReplaceAll(cond, "int(s."+r+")", "("+v+")")
+	}
+	buf.WriteString("\t{\n")
+	buf.Write(pre.Bytes())
+	fmt.Fprintf(buf, "\tif !%s {\n", cond)
+	buf.WriteString(fail(cgCheckError(strconv.Quote(c), `""`, fmt.Sprintf("fmt.Errorf(\"check %%q failed: %%w\", %q, binarystruct.ErrValidationError)", c))))
+	buf.WriteString("\t}\n\t}\n")
+	return nil
+}
+
 // generateCustomValueofWrite emits the encode-time computation of a custom
 // valueof evaluator: it builds a ValueOfContext from the referenced fields'
 // encoded bytes, calls the evaluator looked up on the Marshaler by name, and
@@ -1606,7 +2927,9 @@ func (g *Generator) generateCustomValueofWrite(buf *bytes.Buffer, fieldName, evn
 // from the stream, erroring (wrapping ErrValidationError) on mismatch. Emitted
 // by default (parity with the runtime); -no-validate strips it, in which case the
 // field is read as a plain scalar with no verification.
-func (g *Generator) generateCustomValueofValidate(buf *bytes.Buffer, fieldName, evname string, args []string, goType string, fields map[string]cgFieldInfo, endianStr string) error {
+func (g *Generator) generateCustomValueofValidate(buf *bytes.Buffer, fieldName, evname string, args []string, goType, tag string, fields map[string]cgFieldInfo, endianStr string) error {
+	// Skipped when the Marshaler's Validation mode does not verify checksums.
+	buf.WriteString("\tif cg.VerifiesChecksums() {\n")
 	fmt.Fprintf(buf, "\tif ms == nil {\n\t\treturn n, errors.New(\"marshaler required for valueof %s\")\n\t}\n", evname)
 	buf.WriteString("\t{\n")
 	g.emitValueofLookup(buf, evname)
@@ -1617,14 +2940,18 @@ func (g *Generator) generateCustomValueofValidate(buf *bytes.Buffer, fieldName,
 	buf.WriteString("\t\tvar voVal uint64\n")
 	fmt.Fprintf(buf, "\t\tvoVal, err = fn(binarystruct.ValueOfContext{Struct: s, Target: %q, Decoding: true, Args: []binarystruct.ValueOfArg{%s}})\n", fieldName, strings.Join(argExprs, ", "))
 	buf.WriteString("\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n")
-	fmt.Fprintf(buf, "\t\tif s.%s != %s(voVal) {\n", fieldName, strings.TrimPrefix(goType, "*"))
+	want := strings.TrimPrefix(goType, "*") + "(voVal)"
+	fmt.Fprintf(buf, "\t\tif s.%s != %s {\n", fieldName, want)
 	// Match the runtime's validateCustomValueofs: a *DecodeError whose Offset is
 	// the end of the struct (n here) and whose Err wraps ErrValidationError, so
 	// errors.As(&DecodeError) and errors.Is(ErrValidationError) both behave the
 	// same whether decoded via the interpreter or generated code.
-	fmt.Fprintf(buf, "\t\t\treturn n, &binarystruct.DecodeError{Offset: n, Field: %q, Err: fmt.Errorf(\"valueof %s() mismatch: %%w\", binarystruct.ErrValidationError)}\n", fieldName, evname)
+	inner := cgCheckError(fmt.Sprintf("fmt.Sprintf(\"%%#x\", %s)", want), fmt.Sprintf("fmt.Sprintf(\"%%#x\", s.%s)", fieldName),
+		fmt.Sprintf("fmt.Errorf(\"valueof %s() mismatch: %%w\", binarystruct.ErrValidationError)", evname))
+	fmt.Fprintf(buf, "\t\t\tif err = cg.ValidationFailed(cg.DecodeError(n, %q, fmt.Sprintf(\"%%T\", s.%s), %q, %s)); err != nil {\n\t\t\t\treturn n, err\n\t\t\t}\n", fieldName, fieldName, tag, inner)
 	buf.WriteString("\t\t}\n")
 	buf.WriteString("\t}\n")
+	buf.WriteString("\t}\n")
 	return nil
 }
 
@@ -1680,6 +3007,9 @@ func (g *Generator) generateFieldWrite(buf *bytes.Buffer, target, goType, binTyp
 		return nil
 	}
 
+	if g.Strict {
+		buf.WriteString(cgStrictWriteCheck(accessor, strings.TrimPrefix(goType, "*"), binType))
+	}
 	switch binType {
 	case "int8", "uint8", "byte":
 		fmt.Fprintf(buf, "\ttmp[0] = byte(%s)\n", accessor)
@@ -1707,13 +3037,38 @@ func (g *Generator) generateFieldWrite(buf *bytes.Buffer, target, goType, binTyp
 		if sizeExpr == "" {
 			sizeExpr = "1"
 		}
-		fmt.Fprintf(buf, "\t{\n\t\tm, err = w.Write(make([]byte, %s))\n\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n\t}\n", sizeExpr)
+		fill, err := cgFill(parsedTag)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(buf, "\t{\n\t\tpadBytes := make([]byte, %s)\n", sizeExpr)
+		if fill != 0 {
+			fmt.Fprintf(buf, "\t\tfor i := range padBytes {\n\t\t\tpadBytes[i] = %#02x\n\t\t}\n", fill)
+		}
+		buf.WriteString("\t\tm, err = w.Write(padBytes)\n\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n\t}\n")
 	case "string", "bstring", "wstring", "dwstring", "zstring", "z16string":
+		if trim := cgTrim(parsedTag); trim != "right" && trim != "none" && trim != "both" {
+			return fmt.Errorf("invalid trim value %q: must be right, none or both", trim)
+		}
 		encodingOpt := parsedTag.options["encoding"]
 		fmt.Fprintf(buf, "\t{\n\t\tstrBytes := []byte(%s)\n", accessor)
 		if encodingOpt != "" {
 			fmt.Fprintf(buf, "\t\tif ms != nil {\n\t\t\tstrBytes, err = ms.EncodeText(strBytes, %q)\n\t\t\tif err != nil {\n\t\t\t\treturn n, err\n\t\t\t}\n\t\t}\n", encodingOpt)
 		}
+		// A string that does not fit its size or length prefix fails, as in the
+		// runtime's writeString.
+		if parsedTag.bufLenExpr != "" {
+			bufSize, err := g.translateEncodeExpr(parsedTag.bufLenExpr, fields, map[string]bool{})
+			if err != nil {
+				return err
+			}
+			if term := map[string]int{"zstring": 1, "z16string": 2}[binType]; term > 0 && g.Strict {
+				fmt.Fprintf(buf, "\t\tif len(strBytes)+%d > %s {\n\t\t\treturn n, fmt.Errorf(\"string too long: len %%d and terminator, buffer size %%d: %%w\", len(strBytes), %s, binarystruct.ErrValueOverflow)\n\t\t}\n", term, bufSize, bufSize)
+			}
+			fmt.Fprintf(buf, "\t\tif len(strBytes) > %s {\n\t\t\treturn n, fmt.Errorf(\"string too long: len %%d, buffer size %%d: %%w\", len(strBytes), %s, binarystruct.ErrValueOverflow)\n\t\t}\n", bufSize, bufSize)
+		} else if max, ok := map[string]string{"bstring": "math.MaxUint8", "wstring": "math.MaxUint16", "dwstring": "math.MaxUint32"}[binType]; ok {
+			fmt.Fprintf(buf, "\t\tif uint64(len(strBytes)) > %s {\n\t\t\treturn n, fmt.Errorf(\"string too long: len %%d, max %%d: %%w\", len(strBytes), uint64(%s), binarystruct.ErrValueOverflow)\n\t\t}\n", max, max)
+		}
 		// Write prefix for prefixed strings
 		switch binType {
 		case "bstring":
@@ -1732,19 +3087,36 @@ func (g *Generator) generateFieldWrite(buf *bytes.Buffer, target, goType, binTyp
 			if err != nil {
 				return err
 			}
-			fmt.Fprintf(buf, "\t\tbufLen := int(%s)\n", bufSize)
-			buf.WriteString("\t\twriteBytes := make([]byte, bufLen)\n")
+			// The terminator of a zstring(N) follows the string inside the
+			// buffer, and the fill follows the terminator, as in writeString.
+			term := map[string]int{"zstring": 1, "z16string": 2}[binType]
+			fmt.Fprintf(buf, "\t\tbufLen := %s\n", bufSize)
+			if term > 0 {
+				fmt.Fprintf(buf, "\t\twriteBytes := make([]byte, max(bufLen, len(strBytes)+%d))\n", term)
+			} else {
+				buf.WriteString("\t\twriteBytes := make([]byte, bufLen)\n")
+			}
 			buf.WriteString("\t\tcopy(writeBytes, strBytes)\n")
+			fill, err := cgFill(parsedTag)
+			if err != nil {
+				return err
+			}
+			if fill != 0 {
+				fmt.Fprintf(buf, "\t\tfor i := len(strBytes) + %d; i < bufLen; i++ {\n\t\t\twriteBytes[i] = %#02x\n\t\t}\n", term, fill)
+			}
 			buf.WriteString("\t\tm, err = w.Write(writeBytes)\n")
 		} else {
 			buf.WriteString("\t\tm, err = w.Write(strBytes)\n")
 		}
 		buf.WriteString("\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n")
-		// Write null termination
-		if binType == "zstring" {
-			buf.WriteString("\t\ttmp[0] = 0\n\t\tm, err = w.Write(tmp[:1])\n\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n")
-		} else if binType == "z16string" {
-			buf.WriteString("\t\torder.PutUint16(tmp[:2], 0)\n\t\tm, err = w.Write(tmp[:2])\n\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n")
+		// Write null termination, unless it went into the buffer above
+		if parsedTag.bufLenExpr == "" {
+			switch binType {
+			case "zstring":
+				buf.WriteString("\t\ttmp[0] = 0\n\t\tm, err = w.Write(tmp[:1])\n\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n")
+			case "z16string":
+				buf.WriteString("\t\torder.PutUint16(tmp[:2], 0)\n\t\tm, err = w.Write(tmp[:2])\n\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n")
+			}
 		}
 		buf.WriteString("\t}\n")
 	default:
@@ -1752,11 +3124,17 @@ func (g *Generator) generateFieldWrite(buf *bytes.Buffer, target, goType, binTyp
 		// directly (passing ms through, so nested codecs/encodings/valueofs work) —
 		// avoiding a per-value Marshaler allocation and the reflection interpreter.
 		// Otherwise fall back to the runtime for that (foreign) type.
-		if g.isGeneratedType(goType) {
+		if g.needsOuter(stripToElemType(goType), map[string]bool{}) {
+			fmt.Fprintf(buf, "\t{\n\t\tcg.PushStruct(s)\n\t\tm, err = (%s).WriteBinaryWithMarshaler(ms, w, order)\n\t\tcg.PopStruct()\n", accessor)
+		} else if g.isGeneratedType(goType) {
 			fmt.Fprintf(buf, "\t{\n\t\tm, err = (%s).WriteBinaryWithMarshaler(ms, w, order)\n", accessor)
 		} else {
 			fmt.Fprintf(buf, "\t{\n\t\tm, err = binarystruct.NewMarshalerOrder(order).Write(w, &%s)\n", accessor)
 		}
+		if strings.HasSuffix(target, "[i]") {
+			// An array element: its index joins the field's EncodeError path.
+			buf.WriteString("\t\tif err != nil {\n\t\t\terr = cg.ElementError(i, n-eoff, err)\n\t\t}\n")
+		}
 		buf.WriteString("\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n\t}\n")
 	}
 
@@ -1766,6 +3144,40 @@ func (g *Generator) generateFieldWrite(buf *bytes.Buffer, target, goType, binTyp
 	return nil
 }
 
+// readPrefixedString emits the read of a length-prefixed string's bytes, once
+// strLen holds its prefix. With a buffer size, as in bstring(N), it reads the
+// larger of the two as the runtime does, and keeps the first strLen bytes
+// unless a text encoding decodes the whole zero-filled buffer.
+func (g *Generator) readPrefixedString(buf *bytes.Buffer, parsedTag parsedFieldTag, encodingOpt string) {
+	readLen := "strLen"
+	if parsedTag.bufLenExpr != "" {
+		readLen = "readLen"
+		fmt.Fprintf(buf, "\t\treadLen := max(strLen, %s)\n", g.translateExpression(parsedTag.bufLenExpr))
+	}
+	fmt.Fprintf(buf, "\t\tif err = cg.LimitString(%s); err != nil {\n\t\t\treturn n, err\n\t\t}\n", readLen)
+	fmt.Fprintf(buf, "\t\tstrBytes, m, err = cg.ReadBytes(r, %s)\n\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n", readLen)
+	fill, _ := cgFill(parsedTag)
+	if parsedTag.bufLenExpr != "" && (encodingOpt == "" || fill != 0 || cgTrim(parsedTag) != "right") {
+		buf.WriteString("\t\tstrBytes = strBytes[:strLen]\n")
+	}
+}
+
+// readZBuffer emits the read of a zstring(N) or z16string(N) as the runtime's
+// readZBuffer: the whole buffer, fill included, of which the string is the
+// part before the terminator, unit zero bytes at a multiple of unit.
+func (g *Generator) readZBuffer(buf *bytes.Buffer, parsedTag parsedFieldTag, unit int) {
+	fmt.Fprintf(buf, "\t\tstrLen := %s\n", g.translateExpression(parsedTag.bufLenExpr))
+	buf.WriteString("\t\tif err = cg.LimitString(strLen); err != nil {\n\t\t\treturn n, err\n\t\t}\n")
+	buf.WriteString("\t\tstrBytes, m, err = cg.ReadBytes(r, strLen)\n\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n")
+	fmt.Fprintf(buf, "\t\tfor i := 0; i+%d <= len(strBytes); i += %d {\n", unit, unit)
+	if unit == 1 {
+		buf.WriteString("\t\t\tif strBytes[i] == 0 {\n")
+	} else {
+		buf.WriteString("\t\t\tif strBytes[i] == 0 && strBytes[i+1] == 0 {\n")
+	}
+	buf.WriteString("\t\t\t\tstrBytes = strBytes[:i]\n\t\t\t\tbreak\n\t\t\t}\n\t\t}\n")
+}
+
 func (g *Generator) generateFieldRead(buf *bytes.Buffer, target, goType, binType string, parsedTag parsedFieldTag, typeName, fieldName, offExpr string) {
 	isPtr := strings.HasPrefix(goType, "*")
 	accessor := target
@@ -1782,32 +3194,60 @@ func (g *Generator) generateFieldRead(buf *bytes.Buffer, target, goType, binType
 		buf.WriteString("\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n")
 		fmt.Fprintf(buf, "\t\t%s = valDec.(%s)\n\t}\n", accessor, strings.TrimPrefix(goType, "*"))
 	} else {
+		// Under -strict, a wire value the Go type cannot hold exactly fails
+		// before it is stored.
+		strictRead := func(wire string) string {
+			if !g.Strict {
+				return ""
+			}
+			return cgStrictReadCheck(wire, strings.TrimPrefix(goType, "*"), binType, func(inner string) string {
+				return cgDecodeErr(offExpr, fieldName, parsedTag.raw, inner)
+			})
+		}
 		switch binType {
 		case "int8", "uint8", "byte":
 			buf.WriteString("\tm, err = io.ReadFull(r, tmp[:1])\n\tn += m\n\tif err != nil {\n\t\treturn n, err\n\t}\n")
+			buf.WriteString(strictRead("uint64(tmp[0])"))
 			fmt.Fprintf(buf, "\t%s = %s(tmp[0])\n", accessor, strings.TrimPrefix(goType, "*"))
 		case "int16", "uint16", "word":
 			buf.WriteString("\tm, err = io.ReadFull(r, tmp[:2])\n\tn += m\n\tif err != nil {\n\t\treturn n, err\n\t}\n")
+			buf.WriteString(strictRead("uint64(order.Uint16(tmp[:2]))"))
 			fmt.Fprintf(buf, "\t%s = %s(order.Uint16(tmp[:2]))\n", accessor, strings.TrimPrefix(goType, "*"))
 		case "int32", "uint32", "dword":
 			buf.WriteString("\tm, err = io.ReadFull(r, tmp[:4])\n\tn += m\n\tif err != nil {\n\t\treturn n, err\n\t}\n")
+			buf.WriteString(strictRead("uint64(order.Uint32(tmp[:4]))"))
 			fmt.Fprintf(buf, "\t%s = %s(order.Uint32(tmp[:4]))\n", accessor, strings.TrimPrefix(goType, "*"))
 		case "int64", "uint64", "qword":
 			buf.WriteString("\tm, err = io.ReadFull(r, tmp[:8])\n\tn += m\n\tif err != nil {\n\t\treturn n, err\n\t}\n")
+			buf.WriteString(strictRead("uint64(order.Uint64(tmp[:8]))"))
 			fmt.Fprintf(buf, "\t%s = %s(order.Uint64(tmp[:8]))\n", accessor, strings.TrimPrefix(goType, "*"))
 		case "float32":
 			buf.WriteString("\tm, err = io.ReadFull(r, tmp[:4])\n\tn += m\n\tif err != nil {\n\t\treturn n, err\n\t}\n")
+			buf.WriteString(strictRead("float64(math.Float32frombits(order.Uint32(tmp[:4])))"))
 			fmt.Fprintf(buf, "\t%s = %s(math.Float32frombits(order.Uint32(tmp[:4])))\n", accessor, strings.TrimPrefix(goType, "*"))
 		case "float64":
 			buf.WriteString("\tm, err = io.ReadFull(r, tmp[:8])\n\tn += m\n\tif err != nil {\n\t\treturn n, err\n\t}\n")
+			buf.WriteString(strictRead("math.Float64frombits(order.Uint64(tmp[:8]))"))
 			fmt.Fprintf(buf, "\t%s = %s(math.Float64frombits(order.Uint64(tmp[:8])))\n", accessor, strings.TrimPrefix(goType, "*"))
 		case "pad":
-			sizeExpr := translateExpression(parsedTag.bufLenExpr)
+			sizeExpr := g.translateExpression(parsedTag.bufLenExpr)
 			if sizeExpr == "" {
 				sizeExpr = "1"
 			}
-			fmt.Fprintf(buf, "\t{\n\t\tpadSize := int(%s)\n", sizeExpr)
-			buf.WriteString("\t\tm, err = io.ReadFull(r, make([]byte, padSize))\n\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n\t}\n")
+			fmt.Fprintf(buf, "\t{\n\t\tpadSize := %s\n", sizeExpr)
+			buf.WriteString("\t\tpadBytes := make([]byte, padSize)\n")
+			buf.WriteString("\t\tm, err = io.ReadFull(r, padBytes)\n\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n")
+			if _, ok := parsedTag.options["reserved"]; ok && !g.NoValidate {
+				// A reserved padding holds its fill byte, checked like a const.
+				fill, _ := cgFill(parsedTag)
+				lit := fmt.Sprintf("%#02x", fill)
+				fmt.Fprintf(buf, "\t\tfor i, b := range padBytes {\n\t\t\tif b != %s {\n", lit)
+				buf.WriteString(cgValidationErr(offExpr, fieldName, parsedTag.raw, cgCheckError(
+					fmt.Sprintf("%q", lit), `fmt.Sprintf("%#02x", b)`,
+					fmt.Sprintf(`fmt.Errorf("reserved byte %%d is %%#02x, want %s: %%w", i, b, binarystruct.ErrValidationError)`, lit))))
+				buf.WriteString("\t\t\t\tbreak\n\t\t\t}\n\t\t}\n")
+			}
+			buf.WriteString("\t}\n")
 		case "string", "bstring", "wstring", "dwstring", "zstring", "z16string":
 			encodingOpt := parsedTag.options["encoding"]
 			buf.WriteString("\t{\n\t\tvar strBytes []byte\n")
@@ -1815,86 +3255,205 @@ func (g *Generator) generateFieldRead(buf *bytes.Buffer, target, goType, binType
 			case "bstring":
 				buf.WriteString("\t\tm, err = io.ReadFull(r, tmp[:1])\n\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n")
 				buf.WriteString("\t\tstrLen := int(tmp[0])\n")
-				buf.WriteString("\t\tstrBytes = make([]byte, strLen)\n")
-				buf.WriteString("\t\tm, err = io.ReadFull(r, strBytes)\n\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n")
+				g.readPrefixedString(buf, parsedTag, encodingOpt)
 			case "wstring":
 				buf.WriteString("\t\tm, err = io.ReadFull(r, tmp[:2])\n\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n")
 				buf.WriteString("\t\tstrLen := int(order.Uint16(tmp[:2]))\n")
-				buf.WriteString("\t\tstrBytes = make([]byte, strLen)\n")
-				buf.WriteString("\t\tm, err = io.ReadFull(r, strBytes)\n\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n")
+				g.readPrefixedString(buf, parsedTag, encodingOpt)
 			case "dwstring":
 				buf.WriteString("\t\tm, err = io.ReadFull(r, tmp[:4])\n\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n")
 				buf.WriteString("\t\tstrLen := int(order.Uint32(tmp[:4]))\n")
-				buf.WriteString("\t\tstrBytes = make([]byte, strLen)\n")
-				buf.WriteString("\t\tm, err = io.ReadFull(r, strBytes)\n\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n")
+				g.readPrefixedString(buf, parsedTag, encodingOpt)
 			case "zstring":
+				if parsedTag.bufLenExpr != "" {
+					g.readZBuffer(buf, parsedTag, 1)
+					break
+				}
 				buf.WriteString("\t\tfor {\n\t\t\tm, err = io.ReadFull(r, tmp[:1])\n\t\t\tn += m\n\t\t\tif err != nil {\n\t\t\t\treturn n, err\n\t\t\t}\n\t\t\tif tmp[0] == 0 {\n\t\t\t\tbreak\n\t\t\t}\n\t\t\tstrBytes = append(strBytes, tmp[0])\n\t\t}\n")
 			case "z16string":
+				if parsedTag.bufLenExpr != "" {
+					g.readZBuffer(buf, parsedTag, 2)
+					break
+				}
 				buf.WriteString("\t\tfor {\n\t\t\tm, err = io.ReadFull(r, tmp[:2])\n\t\t\tn += m\n\t\t\tif err != nil {\n\t\t\t\treturn n, err\n\t\t\t}\n\t\t\tval := order.Uint16(tmp[:2])\n\t\t\tif val == 0 {\n\t\t\t\tbreak\n\t\t\t}\n\t\t\tstrBytes = append(strBytes, byte(val), byte(val>>8)) // UTF-16 bytes\n\t\t}\n")
 			default:
 				if parsedTag.bufLenExpr != "" {
-					fmt.Fprintf(buf, "\t\tstrLen := int(%s)\n", translateExpression(parsedTag.bufLenExpr))
-					buf.WriteString("\t\tstrBytes = make([]byte, strLen)\n")
-					buf.WriteString("\t\tm, err = io.ReadFull(r, strBytes)\n\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n")
+					fmt.Fprintf(buf, "\t\tstrLen := %s\n", g.translateExpression(parsedTag.bufLenExpr))
+					buf.WriteString("\t\tif err = cg.LimitString(strLen); err != nil {\n\t\t\treturn n, err\n\t\t}\n")
+					buf.WriteString("\t\tstrBytes, m, err = cg.ReadBytes(r, strLen)\n\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n")
+					if fill, _ := cgFill(parsedTag); fill != 0 && cgTrim(parsedTag) != "none" {
+						// Drop the fill before decoding the text, as the runtime does.
+						fmt.Fprintf(buf, "\t\tfor len(strBytes) > 0 && strBytes[len(strBytes)-1] == %#02x {\n\t\t\tstrBytes = strBytes[:len(strBytes)-1]\n\t\t}\n", fill)
+					}
 				} else {
 					buf.WriteString("\t\t// Read all remaining\n\t\tstrBytes, err = io.ReadAll(r)\n\t\tn += len(strBytes)\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n")
 				}
 			}
+			if parsedTag.bufLenExpr == "" && (binType == "zstring" || binType == "z16string" || binType == "string") {
+				// Read to its end: checked against the limit once its length is known.
+				buf.WriteString("\t\tif err = cg.LimitString(len(strBytes)); err != nil {\n\t\t\treturn n, err\n\t\t}\n")
+			}
+
+			sized := binType != "zstring" && binType != "z16string"
+			if sized && cgTrim(parsedTag) == "both" {
+				fill, _ := cgFill(parsedTag)
+				fmt.Fprintf(buf, "\t\tfor len(strBytes) > 0 && strBytes[0] == %#02x {\n\t\t\tstrBytes = strBytes[1:]\n\t\t}\n", fill)
+			}
 
 			if encodingOpt != "" {
 				fmt.Fprintf(buf, "\t\tif ms != nil {\n\t\t\tstrBytes, err = ms.DecodeText(strBytes, %q)\n\t\t\tif err != nil {\n\t\t\t\treturn n, err\n\t\t\t}\n\t\t}\n", encodingOpt)
 			}
-			// Remove trailing zeros
+			// Remove trailing zeros, unless trim=none keeps them
 			buf.WriteString("\t\tstrlen := len(strBytes)\n")
-			buf.WriteString("\t\tfor ; strlen > 0 && strBytes[strlen-1] == 0; strlen-- {}\n")
+			if !sized || cgTrim(parsedTag) != "none" {
+				buf.WriteString("\t\tfor ; strlen > 0 && strBytes[strlen-1] == 0; strlen-- {}\n")
+			}
 			fmt.Fprintf(buf, "\t\t%s = string(strBytes[:strlen])\n", accessor)
 			buf.WriteString("\t}\n")
 		default:
 			// Nested struct: direct generated-method call when the nested type is
 			// itself generated (ms passed through); runtime fallback otherwise.
-			if g.isGeneratedType(goType) {
-				fmt.Fprintf(buf, "\t{\n\t\tm, err = (%s).ReadBinaryWithMarshaler(ms, r, order)\n", accessor)
+			// Its failures, returned or collected under ValidateCollect, are
+			// wrapped for the field and the element index, as in the runtime.
+			name := strings.TrimSuffix(fieldName, "[i]")
+			wrapped := "err"
+			buf.WriteString("\t{\n")
+			if name != fieldName {
+				buf.WriteString("\t\testart := n\n")
+				wrapped = fmt.Sprintf("cg.ElementError(i, estart-%s, err)", offExpr)
+			}
+			wrapped = fmt.Sprintf("cg.DecodeError(%s, %q, fmt.Sprintf(\"%%T\", s.%s), %q, %s)", offExpr, name, name, parsedTag.raw, wrapped)
+			if !g.NoValidate {
+				buf.WriteString("\t\tcmark := cg.CollectMark()\n")
+			}
+			if g.needsOuter(stripToElemType(goType), map[string]bool{}) {
+				fmt.Fprintf(buf, "\t\tcg.PushStruct(s)\n\t\tm, err = (%s).ReadBinaryWithMarshaler(ms, r, order)\n\t\tcg.PopStruct()\n", accessor)
+			} else if g.isGeneratedType(goType) {
+				fmt.Fprintf(buf, "\t\tm, err = (%s).ReadBinaryWithMarshaler(ms, r, order)\n", accessor)
 			} else {
-				fmt.Fprintf(buf, "\t{\n\t\tm, err = binarystruct.NewMarshalerOrder(order).Read(r, &%s)\n", accessor)
+				fmt.Fprintf(buf, "\t\tm, err = binarystruct.NewMarshalerOrder(order).Read(r, &%s)\n", accessor)
 			}
-			buf.WriteString("\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n\t}\n")
+			fmt.Fprintf(buf, "\t\tif err != nil {\n\t\t\terr = %s\n\t\t}\n", wrapped)
+			buf.WriteString("\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n")
+			if !g.NoValidate {
+				fmt.Fprintf(buf, "\t\tcg.WrapCollected(cmark, func(err error) error {\n\t\t\treturn %s\n\t\t})\n", wrapped)
+			}
+			buf.WriteString("\t}\n")
+		}
+	}
+
+	// Decode validation, unless -no-validate strips it.
+	if !g.NoValidate {
+		g.generateValueChecks(buf, accessor, typeName, fieldName, parsedTag, func(inner string) string {
+			return cgValidationErr(offExpr, fieldName, parsedTag.raw, inner)
+		})
+	}
+
+	if isPtr {
+		fmt.Fprintf(buf, "\t\t%s = &val\n\t}\n", target)
+	}
+}
+
+// generateValueChecks emits the enum=, range= and match= tests of one value,
+// accessor, shared by the read methods and, under -validate-encode, the write
+// methods. fail formats the statements that report a failure, given the Go
+// expression of an error wrapping ErrValidationError.
+func (g *Generator) generateValueChecks(buf *bytes.Buffer, accessor, typeName, fieldName string, parsedTag parsedFieldTag, fail func(inner string) string) {
+	// enum: an inline set is tested in place; a registered enum is looked up on
+	// the Marshaler, which also names the value in a range error.
+	enumName := ""
+	if e, ok := parsedTag.options["enum"]; ok {
+		name, conds, _ := cgParseEnum(e) // validated by generateMethods
+		enumName = name
+		if name != "" {
+			fmt.Fprintf(buf, "\tif verr := ms.ValidateEnum(%q, uint64(%s)); verr != nil {\n", name, accessor)
+			buf.WriteString(fail("verr"))
+			buf.WriteString("\t}\n")
+		} else {
+			fmt.Fprintf(buf, "\tif v := int64(%s); !(%s) {\n", accessor, strings.Join(conds, " || "))
+			expected := strconv.Quote(strings.TrimSpace(e))
+			buf.WriteString(fail(cgCheckError(expected, "fmt.Sprint(v)", fmt.Sprintf("fmt.Errorf(\"value %%d is not in enum %%s: %%w\", v, %s, binarystruct.ErrValidationError)", expected))))
+			buf.WriteString("\t}\n")
 		}
 	}
 
-	// Apply range check if specified (unless -no-validate strips decode validation)
-	if rangeOpt, ok := parsedTag.options["range"]; ok && !g.NoValidate {
+	// range: the bounds are Go literals, compared in the field's own type.
+	if rangeOpt, ok := parsedTag.options["range"]; ok {
 		bounds := strings.Split(rangeOpt, "..")
 		if len(bounds) == 2 {
 			minStr := strings.TrimSpace(bounds[0])
 			maxStr := strings.TrimSpace(bounds[1])
+			valueFmt, value := "%v", accessor
+			if enumName != "" {
+				valueFmt, value = "%s", fmt.Sprintf("ms.EnumString(%q, uint64(%s))", enumName, accessor)
+			}
+			inner := cgCheckError(strconv.Quote(minStr+".."+maxStr), "fmt.Sprint("+accessor+")",
+				fmt.Sprintf("fmt.Errorf(\"value %s is out of range [%s..%s]: %%w\", %s, binarystruct.ErrValidationError)", valueFmt, minStr, maxStr, value))
 			if minStr != "" {
 				fmt.Fprintf(buf, "\tif %s < %s {\n", accessor, minStr)
-				buf.WriteString(cgValidationErr(offExpr, fieldName, fmt.Sprintf("fmt.Errorf(\"value %%v is out of range [%s..%s]: %%w\", %s, binarystruct.ErrValidationError)", minStr, maxStr, accessor)))
+				buf.WriteString(fail(inner))
 				buf.WriteString("\t}\n")
 			}
 			if maxStr != "" {
 				fmt.Fprintf(buf, "\tif %s > %s {\n", accessor, maxStr)
-				buf.WriteString(cgValidationErr(offExpr, fieldName, fmt.Sprintf("fmt.Errorf(\"value %%v is out of range [%s..%s]: %%w\", %s, binarystruct.ErrValidationError)", minStr, maxStr, accessor)))
+				buf.WriteString(fail(inner))
 				buf.WriteString("\t}\n")
 			}
 		}
 	}
 
-	// Apply regex match check (unless -no-validate strips decode validation)
-	if _, ok := parsedTag.options["match"]; ok && !g.NoValidate {
+	// match: tested against the package-level regexp compiled for the field.
+	if pattern, ok := parsedTag.options["match"]; ok {
 		fmt.Fprintf(buf, "\tif !regex_%s_%s.MatchString(%s) {\n", typeName, fieldName, accessor)
-		buf.WriteString(cgValidationErr(offExpr, fieldName, fmt.Sprintf("fmt.Errorf(\"value %%q does not match pattern: %%w\", %s, binarystruct.ErrValidationError)", accessor)))
+		buf.WriteString(fail(cgCheckError(strconv.Quote(pattern), accessor, fmt.Sprintf("fmt.Errorf(\"value %%q does not match pattern: %%w\", %s, binarystruct.ErrValidationError)", accessor))))
 		buf.WriteString("\t}\n")
 	}
+}
 
-	if isPtr {
-		fmt.Fprintf(buf, "\t\t%s = &val\n\t}\n", target)
+// cgParseEnum parses an enum= option as the runtime's parseFieldEnum does. It
+// returns the registered enum's name, or for an inline set the Go conditions on
+// v (an int64) that accept a value.
+func cgParseEnum(s string) (name string, conds []string, err error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil, fmt.Errorf("empty enum")
 	}
+	if c := s[0]; c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' {
+		for _, c := range s {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+				return "", nil, fmt.Errorf("invalid enum name %q", s)
+			}
+		}
+		return s, nil, nil
+	}
+	for _, item := range strings.Split(s, "|") {
+		lo, hi := item, item
+		if i := strings.Index(item, ".."); i >= 0 {
+			lo, hi = item[:i], item[i+2:]
+		}
+		l, err := strconv.ParseInt(strings.TrimSpace(lo), 0, 64)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid enum value %q", item)
+		}
+		h, err := strconv.ParseInt(strings.TrimSpace(hi), 0, 64)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid enum value %q", item)
+		}
+		switch {
+		case l > h:
+			return "", nil, fmt.Errorf("invalid enum range %q", item)
+		case l == h:
+			conds = append(conds, fmt.Sprintf("v == %d", l))
+		default:
+			conds = append(conds, fmt.Sprintf("v >= %d && v <= %d", l, h))
+		}
+	}
+	return "", conds, nil
 }
 
 // multidimLeafTag derives the per-element tag for a multidimensional array leaf:
 // the array dimensions are stripped, and per-element validation (const/range/
-// match) is dropped (the runtime validates the field as a whole, not per element).
+// match/enum) is dropped (the runtime validates the field as a whole, not per element).
 func multidimLeafTag(parsedTag parsedFieldTag) parsedFieldTag {
 	leafTag := parsedTag
 	leafTag.isArray = false
@@ -1904,7 +3463,7 @@ func multidimLeafTag(parsedTag parsedFieldTag) parsedFieldTag {
 	leafTag.options = map[string]string{}
 	for k, v := range parsedTag.options {
 		switch k {
-		case "const", "range", "match":
+		case "const", "range", "match", "enum":
 			// per-element validation of a multidimensional leaf is out of scope
 		default:
 			leafTag.options[k] = v
@@ -1947,7 +3506,10 @@ func (g *Generator) generateMultidimRead(buf *bytes.Buffer, fieldName, goType, b
 	for k := 0; k < parsedTag.numDims; k++ {
 		idx := fmt.Sprintf("i%d", k)
 		if isSlice[k] {
-			fmt.Fprintf(buf, "\t%s = make(%s, int(%s))\n", accessor, cgPeelArrayLevels(goType, k), translateExpression(parsedTag.arrayDimExprs[k]))
+			lt := cgPeelArrayLevels(goType, k)
+			fmt.Fprintf(buf, "\tdim%d := %s\n", k, g.translateExpression(parsedTag.arrayDimExprs[k]))
+			fmt.Fprintf(buf, "\tif err = cg.LimitSlice(%s(nil), dim%d); err != nil {\n\t\treturn n, err\n\t}\n", lt, k)
+			fmt.Fprintf(buf, "\t%s = make(%s, dim%d)\n", accessor, lt, k)
 		}
 		fmt.Fprintf(buf, "\tfor %s := 0; %s < len(%s); %s++ {\n", idx, idx, accessor, idx)
 		accessor += "[" + idx + "]"
@@ -1963,7 +3525,7 @@ func (g *Generator) generateMultidimRead(buf *bytes.Buffer, fieldName, goType, b
 // scalar-buffer path: a fixed-width scalar element (byte/uint8 excluded — they have
 // their own direct path), with no per-element validation/codec and a non-pointer
 // element type. It returns the element's wire width.
-func cgArrayCanBulk(goType, binType string, parsedTag parsedFieldTag) (width int, ok bool) {
+func (g *Generator) cgArrayCanBulk(goType, binType string, parsedTag parsedFieldTag) (width int, ok bool) {
 	switch binType {
 	case "int8":
 		width = 1
@@ -1980,11 +3542,14 @@ func cgArrayCanBulk(goType, binType string, parsedTag parsedFieldTag) (width int
 	if strings.HasPrefix(elem, "*") {
 		return 0, false
 	}
-	for _, opt := range []string{"range", "match", "const", "codec"} {
+	for _, opt := range []string{"range", "match", "enum", "const", "codec"} {
 		if _, has := parsedTag.options[opt]; has {
 			return 0, false
 		}
 	}
+	if g.cgStrictChecked(elem, binType) {
+		return 0, false
+	}
 	return width, true
 }
 
@@ -2016,7 +3581,7 @@ func (g *Generator) cgArrayCanBulkUnsafe(goType, binType string, parsedTag parse
 	if !g.UnsafeBulk {
 		return 0, false
 	}
-	w, bulkOK := cgArrayCanBulk(goType, binType, parsedTag)
+	w, bulkOK := g.cgArrayCanBulk(goType, binType, parsedTag)
 	if !bulkOK || w == 1 {
 		return 0, false
 	}
@@ -2024,13 +3589,18 @@ func (g *Generator) cgArrayCanBulkUnsafe(goType, binType string, parsedTag parse
 	if gw, known := cgGoScalarWidth(elem); !known || gw != w {
 		return 0, false
 	}
+	// The bytes are copied as they are, so a float must be written as a float
+	// and an integer as an integer.
+	if isFloat := elem == "float32" || elem == "float64"; isFloat != (binType == "float32" || binType == "float64") {
+		return 0, false
+	}
 	return w, true
 }
 
 // generateScalarSliceBulkWrite emits a single buffer fill + one w.Write for a
 // fixed-width scalar array/slice, replacing N per-element order.PutUintN + w.Write.
 func (g *Generator) generateScalarSliceBulkWrite(buf *bytes.Buffer, fieldName, binType, sizeExpr string, width int) {
-	fmt.Fprintf(buf, "\t{\n\t\twriteLen := int(%s)\n", sizeExpr)
+	fmt.Fprintf(buf, "\t{\n\t\twriteLen := %s\n", sizeExpr)
 	fmt.Fprintf(buf, "\t\tsbuf := make([]byte, writeLen*%d)\n", width)
 	buf.WriteString("\t\tfor i := 0; i < writeLen; i++ {\n")
 	switch {
@@ -2057,13 +3627,18 @@ func (g *Generator) generateScalarSliceBulkRead(buf *bytes.Buffer, fieldName, go
 	elem := goType[strings.IndexByte(goType, ']')+1:]
 	buf.WriteString("\t{\n")
 	lenExpr := fmt.Sprintf("len(s.%s)", fieldName)
-	if !isFixed {
-		fmt.Fprintf(buf, "\t\treadLen := int(%s)\n", sizeExpr)
+	if isFixed {
+		fmt.Fprintf(buf, "\t\tsbuf := make([]byte, %s*%d)\n", lenExpr, width)
+		buf.WriteString("\t\tm, err = io.ReadFull(r, sbuf)\n\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n")
+	} else {
+		// The elements are allocated once their bytes have been read.
+		fmt.Fprintf(buf, "\t\treadLen := %s\n", sizeExpr)
+		fmt.Fprintf(buf, "\t\tif err = cg.LimitSlice(%s(nil), readLen); err != nil {\n\t\t\treturn n, err\n\t\t}\n", goType)
+		fmt.Fprintf(buf, "\t\tvar sbuf []byte\n\t\tsbuf, m, err = cg.ReadBytes(r, readLen*%d)\n", width)
+		buf.WriteString("\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n")
 		fmt.Fprintf(buf, "\t\ts.%s = make(%s, readLen)\n", fieldName, goType)
 		lenExpr = "readLen"
 	}
-	fmt.Fprintf(buf, "\t\tsbuf := make([]byte, %s*%d)\n", lenExpr, width)
-	buf.WriteString("\t\tm, err = io.ReadFull(r, sbuf)\n\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n")
 	var get string
 	switch {
 	case width == 1:
@@ -2090,7 +3665,7 @@ func (g *Generator) generateScalarSliceBulkRead(buf *bytes.Buffer, fieldName, go
 // experiment_simd). Replaces the per-element order.PutUintN loop. The caller
 // guarantees the Go element width equals `width` (cgArrayCanBulkUnsafe).
 func (g *Generator) generateScalarSliceBulkWriteUnsafe(buf *bytes.Buffer, fieldName, sizeExpr string, width int) {
-	fmt.Fprintf(buf, "\t{\n\t\twriteLen := int(%s)\n", sizeExpr)
+	fmt.Fprintf(buf, "\t{\n\t\twriteLen := %s\n", sizeExpr)
 	buf.WriteString("\t\tif writeLen > 0 {\n")
 	fmt.Fprintf(buf, "\t\t\tsrc := unsafe.Slice((*byte)(unsafe.Pointer(&s.%s[0])), writeLen*%d)\n", fieldName, width)
 	buf.WriteString("\t\t\tif order == binarystruct.HostEndian() {\n")
@@ -2108,19 +3683,37 @@ func (g *Generator) generateScalarSliceBulkWriteUnsafe(buf *bytes.Buffer, fieldN
 // generateScalarSliceBulkReadUnsafe emits the raw-memory read: ReadFull straight
 // into the element backing store, then one in-place binarystruct.SwapBytes when
 // order != host. Replaces the io.ReadFull + per-element order.UintN decode loop.
+// A slice the input is not known to hold is read instead into a buffer that
+// grows as the input arrives, then copied.
 func (g *Generator) generateScalarSliceBulkReadUnsafe(buf *bytes.Buffer, fieldName, goType string, width int, isFixed bool, sizeExpr string) {
 	buf.WriteString("\t{\n")
 	lenExpr := fmt.Sprintf("len(s.%s)", fieldName)
+	indent := "\t\t"
 	if !isFixed {
-		fmt.Fprintf(buf, "\t\treadLen := int(%s)\n", sizeExpr)
-		fmt.Fprintf(buf, "\t\ts.%s = make(%s, readLen)\n", fieldName, goType)
+		fmt.Fprintf(buf, "\t\treadLen := %s\n", sizeExpr)
+		fmt.Fprintf(buf, "\t\tvar sliceCap int\n\t\tif sliceCap, err = cg.SliceCap(%s(nil), r, readLen, %d); err != nil {\n\t\t\treturn n, err\n\t\t}\n", goType, width)
+		fmt.Fprintf(buf, "\t\ts.%s = make(%s, 0, sliceCap)\n", fieldName, goType)
+		fmt.Fprintf(buf, "\t\tif cap(s.%s) < readLen {\n", fieldName)
+		fmt.Fprintf(buf, "\t\t\tvar sbuf []byte\n\t\t\tsbuf, m, err = cg.ReadBytes(r, readLen*%d)\n", width)
+		buf.WriteString("\t\t\tn += m\n\t\t\tif err != nil {\n\t\t\t\treturn n, err\n\t\t\t}\n")
+		fmt.Fprintf(buf, "\t\t\ts.%s = make(%s, readLen)\n", fieldName, goType)
+		fmt.Fprintf(buf, "\t\t\tdst := unsafe.Slice((*byte)(unsafe.Pointer(&s.%s[0])), readLen*%d)\n", fieldName, width)
+		buf.WriteString("\t\t\tcopy(dst, sbuf)\n")
+		fmt.Fprintf(buf, "\t\t\tif order != binarystruct.HostEndian() {\n\t\t\t\tbinarystruct.SwapBytes(dst, %d)\n\t\t\t}\n", width)
+		buf.WriteString("\t\t} else {\n")
+		fmt.Fprintf(buf, "\t\t\ts.%s = s.%s[:readLen]\n", fieldName, fieldName)
 		lenExpr = "readLen"
+		indent = "\t\t\t"
 	}
-	fmt.Fprintf(buf, "\t\tif %s > 0 {\n", lenExpr)
-	fmt.Fprintf(buf, "\t\t\tdst := unsafe.Slice((*byte)(unsafe.Pointer(&s.%s[0])), %s*%d)\n", fieldName, lenExpr, width)
-	buf.WriteString("\t\t\tm, err = io.ReadFull(r, dst)\n\t\t\tn += m\n\t\t\tif err != nil {\n\t\t\t\treturn n, err\n\t\t\t}\n")
-	fmt.Fprintf(buf, "\t\t\tif order != binarystruct.HostEndian() {\n\t\t\t\tbinarystruct.SwapBytes(dst, %d)\n\t\t\t}\n", width)
-	buf.WriteString("\t\t}\n\t}\n")
+	fmt.Fprintf(buf, "%sif %s > 0 {\n", indent, lenExpr)
+	fmt.Fprintf(buf, "%s\tdst := unsafe.Slice((*byte)(unsafe.Pointer(&s.%s[0])), %s*%d)\n", indent, fieldName, lenExpr, width)
+	fmt.Fprintf(buf, "%s\tm, err = io.ReadFull(r, dst)\n%s\tn += m\n%s\tif err != nil {\n%s\t\treturn n, err\n%s\t}\n", indent, indent, indent, indent, indent)
+	fmt.Fprintf(buf, "%s\tif order != binarystruct.HostEndian() {\n%s\t\tbinarystruct.SwapBytes(dst, %d)\n%s\t}\n", indent, indent, width, indent)
+	fmt.Fprintf(buf, "%s}\n", indent)
+	if !isFixed {
+		buf.WriteString("\t\t}\n")
+	}
+	buf.WriteString("\t}\n")
 }
 
 func (g *Generator) generateArrayWrite(buf *bytes.Buffer, fieldName, goType, binType string, parsedTag parsedFieldTag, fields map[string]cgFieldInfo) error {
@@ -2133,13 +3726,22 @@ func (g *Generator) generateArrayWrite(buf *bytes.Buffer, fieldName, goType, bin
 	if err != nil {
 		return err
 	}
+	if strings.Contains(sizeExpr, cgGuardOpen) {
+		// A length that can fail is evaluated once, as in the runtime.
+		fmt.Fprintf(buf, "\tsize%s := %s\n", fieldName, sizeExpr)
+		sizeExpr = "size" + fieldName
+	}
 	if sizeExpr == "" {
 		sizeExpr = fmt.Sprintf("len(s.%s)", fieldName)
+	} else if sizeExpr != fmt.Sprintf("len(s.%s)", fieldName) {
+		// More elements than the declared length fail, as in the runtime. A
+		// length computed as count() of the field itself always fits.
+		fmt.Fprintf(buf, "\tif l := len(s.%s); l > %s {\n\t\treturn n, fmt.Errorf(\"array too large to fit: len %%d, size %%d: %%w\", %s, l, binarystruct.ErrValueOverflow)\n\t}\n", fieldName, sizeExpr, sizeExpr)
 	}
 
 	if goType == "string" {
 		if binType == "byte" || binType == "uint8" {
-			fmt.Fprintf(buf, "\t{\n\t\twriteLen := int(%s)\n", sizeExpr)
+			fmt.Fprintf(buf, "\t{\n\t\twriteLen := %s\n", sizeExpr)
 			buf.WriteString("\t\tstrBytes := make([]byte, writeLen)\n")
 			fmt.Fprintf(buf, "\t\tcopy(strBytes, s.%s)\n", fieldName)
 			buf.WriteString("\t\tm, err = w.Write(strBytes)\n")
@@ -2150,7 +3752,7 @@ func (g *Generator) generateArrayWrite(buf *bytes.Buffer, fieldName, goType, bin
 
 	// Bulk write optimization for byte slices
 	if binType == "byte" || binType == "uint8" {
-		fmt.Fprintf(buf, "\t{\n\t\twriteLen := int(%s)\n", sizeExpr)
+		fmt.Fprintf(buf, "\t{\n\t\twriteLen := %s\n", sizeExpr)
 		fmt.Fprintf(buf, "\t\tm, err = w.Write(s.%s[:writeLen])\n", fieldName)
 		buf.WriteString("\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n\t}\n")
 		return nil
@@ -2164,12 +3766,12 @@ func (g *Generator) generateArrayWrite(buf *bytes.Buffer, fieldName, goType, bin
 		g.generateScalarSliceBulkWriteUnsafe(buf, fieldName, sizeExpr, width)
 		return nil
 	}
-	if width, ok := cgArrayCanBulk(goType, binType, parsedTag); ok {
+	if width, ok := g.cgArrayCanBulk(goType, binType, parsedTag); ok {
 		g.generateScalarSliceBulkWrite(buf, fieldName, binType, sizeExpr, width)
 		return nil
 	}
 
-	fmt.Fprintf(buf, "\t{\n\t\tlimit := int(%s)\n", sizeExpr)
+	fmt.Fprintf(buf, "\t{\n\t\tlimit := %s\n", sizeExpr)
 	buf.WriteString("\t\tfor i := 0; i < limit; i++ {\n")
 	if err := g.generateFieldWrite(buf, fmt.Sprintf("s.%s[i]", fieldName), strings.TrimPrefix(goType, "[]"), binType, parsedTag, fields); err != nil {
 		return err
@@ -2183,7 +3785,7 @@ func (g *Generator) generateArrayRead(buf *bytes.Buffer, fieldName, goType, binT
 		g.generateMultidimRead(buf, fieldName, goType, binType, parsedTag, typeName, offExpr)
 		return
 	}
-	sizeExpr := translateExpression(parsedTag.arrayLenExpr)
+	sizeExpr := g.translateExpression(parsedTag.arrayLenExpr)
 	if sizeExpr == "" {
 		buf.WriteString("\treturn n, errors.New(\"unknown array size expression\")\n")
 		return
@@ -2191,9 +3793,9 @@ func (g *Generator) generateArrayRead(buf *bytes.Buffer, fieldName, goType, binT
 
 	if goType == "string" {
 		if binType == "byte" || binType == "uint8" {
-			fmt.Fprintf(buf, "\t{\n\t\treadLen := int(%s)\n", sizeExpr)
-			buf.WriteString("\t\tstrBytes := make([]byte, readLen)\n")
-			buf.WriteString("\t\tm, err = io.ReadFull(r, strBytes)\n")
+			fmt.Fprintf(buf, "\t{\n\t\treadLen := %s\n", sizeExpr)
+			buf.WriteString("\t\tif err = cg.LimitString(readLen); err != nil {\n\t\t\treturn n, err\n\t\t}\n")
+			buf.WriteString("\t\tvar strBytes []byte\n\t\tstrBytes, m, err = cg.ReadBytes(r, readLen)\n")
 			buf.WriteString("\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n")
 			buf.WriteString("\t\tstrlen := len(strBytes)\n")
 			buf.WriteString("\t\tfor ; strlen > 0 && strBytes[strlen-1] == 0; strlen-- {}\n")
@@ -2203,9 +3805,19 @@ func (g *Generator) generateArrayRead(buf *bytes.Buffer, fieldName, goType, binT
 		}
 	}
 
+	// Bytes are read in bulk unless their values are validated one by one.
+	byteBulk := binType == "byte" || binType == "uint8"
+	if !g.NoValidate {
+		for _, opt := range []string{"range", "match", "enum"} {
+			if _, has := parsedTag.options[opt]; has {
+				byteBulk = false
+			}
+		}
+	}
+
 	// Fixed [N]T array: read in place; make() is only valid for slices.
 	if isFixedArrayType(goType) {
-		if binType == "byte" || binType == "uint8" {
+		if byteBulk {
 			fmt.Fprintf(buf, "\tm, err = io.ReadFull(r, s.%s[:])\n", fieldName)
 			buf.WriteString("\tn += m\n\tif err != nil {\n\t\treturn n, err\n\t}\n")
 			return
@@ -2214,7 +3826,7 @@ func (g *Generator) generateArrayRead(buf *bytes.Buffer, fieldName, goType, binT
 			g.generateScalarSliceBulkReadUnsafe(buf, fieldName, goType, width, true, sizeExpr)
 			return
 		}
-		if width, ok := cgArrayCanBulk(goType, binType, parsedTag); ok {
+		if width, ok := g.cgArrayCanBulk(goType, binType, parsedTag); ok {
 			g.generateScalarSliceBulkRead(buf, fieldName, goType, binType, width, true, sizeExpr)
 			return
 		}
@@ -2232,23 +3844,29 @@ func (g *Generator) generateArrayRead(buf *bytes.Buffer, fieldName, goType, binT
 		g.generateScalarSliceBulkReadUnsafe(buf, fieldName, goType, width, false, sizeExpr)
 		return
 	}
-	if width, ok := cgArrayCanBulk(goType, binType, parsedTag); ok {
+	if width, ok := g.cgArrayCanBulk(goType, binType, parsedTag); ok {
 		g.generateScalarSliceBulkRead(buf, fieldName, goType, binType, width, false, sizeExpr)
 		return
 	}
 
-	// Slice initialization
-	fmt.Fprintf(buf, "\t{\n\t\treadLen := int(%s)\n", sizeExpr)
-	fmt.Fprintf(buf, "\t\ts.%s = make(%s, readLen)\n", fieldName, goType)
+	fmt.Fprintf(buf, "\t{\n\t\treadLen := %s\n", sizeExpr)
 
 	// Bulk read optimization for byte slices
-	if binType == "byte" || binType == "uint8" {
-		fmt.Fprintf(buf, "\t\tm, err = io.ReadFull(r, s.%s)\n", fieldName)
+	if byteBulk {
+		fmt.Fprintf(buf, "\t\tif err = cg.LimitSlice(%s(nil), readLen); err != nil {\n\t\t\treturn n, err\n\t\t}\n", goType)
+		fmt.Fprintf(buf, "\t\ts.%s, m, err = cg.ReadBytes(r, readLen)\n", fieldName)
 		buf.WriteString("\t\tn += m\n\t\tif err != nil {\n\t\t\treturn n, err\n\t\t}\n\t}\n")
 		return
 	}
 
+	// The elements are appended as they are read, so that a length the input
+	// cannot back is not allocated up front.
+	width, _ := scalarWidth(binType)
+	fmt.Fprintf(buf, "\t\tvar sliceCap int\n\t\tif sliceCap, err = cg.SliceCap(%s(nil), r, readLen, %d); err != nil {\n\t\t\treturn n, err\n\t\t}\n", goType, width)
+	fmt.Fprintf(buf, "\t\ts.%s = make(%s, 0, sliceCap)\n", fieldName, goType)
+	fmt.Fprintf(buf, "\t\tvar zero %s\n", strings.TrimPrefix(goType, "[]"))
 	buf.WriteString("\t\tfor i := 0; i < readLen; i++ {\n")
+	fmt.Fprintf(buf, "\t\t\ts.%s = append(s.%s, zero)\n", fieldName, fieldName)
 	g.generateFieldRead(buf, fmt.Sprintf("s.%s[i]", fieldName), strings.TrimPrefix(goType, "[]"), binType, parsedTag, typeName, fmt.Sprintf("%s[i]", fieldName), offExpr)
 	buf.WriteString("\t\t}\n\t}\n")
 }
@@ -30,6 +30,14 @@
 //	    Strip all decode-time validation from the generated read methods (default off;
 //	    the generated decode otherwise validates const/range/match and custom valueof,
 //	    matching the runtime interpreter).
+//	-validate-encode
+//	    Run the range/match/enum/check validators in the generated write methods too,
+//	    returning a *binarystruct.EncodeError naming the failing field (default off;
+//	    the generated counterpart of Marshaler.ValidateOnEncode).
+//	-strict
+//	    Reject lossy conversions in the generated methods, and trailing input in
+//	    UnmarshalBinary, returning binarystruct.ErrValueOverflow or ErrTrailingData
+//	    (default off; the generated counterpart of Marshaler.Strict).
 //	-unsafe-bulk
 //	    Emit a raw-memory bulk path (via unsafe) for fixed-width scalar arrays/slices
 //	    whose Go element width matches the wire width (default off; byte-identical to
@@ -66,6 +74,8 @@ var (
 	jsonOutput   = flag.Bool("json", false, "generate JSON representation of the struct layout instead of Go source code")
 	endian       = flag.String("endian", "", "fallback byte order `big|little` baked into the no-arg MarshalBinary/UnmarshalBinary/AppendBinary methods; optional when the struct declares its own order via a blank _ struct{} endian= field")
 	noValidate   = flag.Bool("no-validate", false, "strip ALL decode-time validation from the generated read methods (const/range/match checks and custom valueof recompute-and-compare); default off (the generated decode validates everything, matching the runtime interpreter). Set for trusted-input / hot-path decoding")
+	validateEnc  = flag.Bool("validate-encode", false, "run the range/match/enum/check validators in the generated write methods too, returning a *binarystruct.EncodeError naming the failing field, so a producer cannot write what the reader rejects (the generated counterpart of Marshaler.ValidateOnEncode). Default off")
+	strict       = flag.Bool("strict", false, "reject lossy conversions in the generated methods: an integer out of its wire type's range or a float with a fractional part written as an integer (and the reverse on read), and a zstring whose terminator does not fit its size, with binarystruct.ErrValueOverflow; UnmarshalBinary also rejects trailing input with binarystruct.ErrTrailingData (the generated counterpart of Marshaler.Strict). Default off")
 	unsafeBulk   = flag.Bool("unsafe-bulk", false, "emit a raw-memory bulk path (via unsafe) for fixed-width scalar arrays/slices whose Go element width matches the wire width: one Write/ReadFull over the backing store plus one in-place SwapBytes when the order differs from the host (SIMD-accelerated under -tags experiment_simd). Byte-identical to the default per-element path; trades portability (adds an unsafe import) for speed. Default off")
 )
 
@@ -152,11 +162,13 @@ func main() {
 	}
 
 	g := Generator{
-		Dir:          absDir,
-		Types:        types,
-		IncludeTests: *includeTests,
-		NoValidate:   *noValidate,
-		UnsafeBulk:   *unsafeBulk,
+		Dir:            absDir,
+		Types:          types,
+		IncludeTests:   *includeTests,
+		NoValidate:     *noValidate,
+		ValidateEncode: *validateEnc,
+		Strict:         *strict,
+		UnsafeBulk:     *unsafeBulk,
 	}
 
 	if *jsonOutput {
@@ -0,0 +1,174 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// namedEndians interns the names of registered byte orders used in tags, so a
+// tag's `endian=NAME` is an endianOverride like big or little. The name is
+// resolved against the Marshaler's registry (AddByteOrder) when the value is
+// encoded or decoded, since metadata is cached per type. Names are interned
+// under the mutex while metadata is built; names maps each endianOverride back
+// to its name without a lock, for every value encoded or decoded.
+var namedEndians struct {
+	sync.Mutex
+	ids   map[string]endianOverride
+	names sync.Map // endianOverride -> string
+}
+
+// namedEndian returns the endianOverride for a byte-order name.
+func namedEndian(name string) endianOverride {
+	namedEndians.Lock()
+	defer namedEndians.Unlock()
+	if e, ok := namedEndians.ids[name]; ok {
+		return e
+	}
+	if namedEndians.ids == nil {
+		namedEndians.ids = make(map[string]endianOverride)
+	}
+	e := endianNamed + endianOverride(len(namedEndians.ids))
+	namedEndians.names.Store(e, name)
+	namedEndians.ids[name] = e
+	return e
+}
+
+// endianName returns the name of a named endianOverride.
+func endianName(e endianOverride) string {
+	name, _ := namedEndians.names.Load(e)
+	return name.(string)
+}
+
+// isByteOrderName reports whether s can name a registered byte order in a tag:
+// a lower-case letter followed by lower-case letters, digits or '_', and not one
+// of the built-in endian= values. (A name starting with an upper-case letter is
+// a byte-order mark field; see orderMark.)
+func isByteOrderName(s string) bool {
+	switch s {
+	case "", "big", "little", "inverse", "detect":
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z':
+		case i > 0 && (c >= '0' && c <= '9' || c == '_'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// AddByteOrder registers a custom byte order with a Marshaler. The name may then
+// be used in tags, per field or on the struct sentinel, like
+// `binary:"uint32,endian=pdp"`. It must start with a lower-case letter and
+// consist of lower-case letters, digits and '_'; big, little, inverse and detect
+// are reserved.
+func (ms *Marshaler) AddByteOrder(name string, order ByteOrder) {
+	if ms.byteOrders == nil {
+		ms.byteOrders = make(map[string]ByteOrder)
+	}
+	ms.byteOrders[name] = order
+}
+
+// RemoveByteOrder removes a byte order registered by AddByteOrder.
+func (ms *Marshaler) RemoveByteOrder(name string) {
+	if ms.byteOrders != nil {
+		delete(ms.byteOrders, name)
+	}
+}
+
+// resolveOrder is resolveByteOrder that also resolves the byte orders
+// registered on ms by name.
+func (ms *Marshaler) resolveOrder(order ByteOrder, endian endianOverride) (ByteOrder, error) {
+	if endian < endianNamed {
+		return resolveByteOrder(order, endian), nil
+	}
+	name := endianName(endian)
+	if o, ok := ms.byteOrders[name]; ok && o != nil {
+		return o, nil
+	}
+	// Tags are parsed once per type, before any Marshaler is known, so a
+	// misspelt endian= is only caught here; list what it could have been.
+	registered := "none"
+	if names := slices.Sorted(maps.Keys(ms.byteOrders)); len(names) > 0 {
+		registered = strings.Join(names, ", ")
+	}
+	return order, fmt.Errorf("unknown byte order %q: endian= takes big, little, inverse or a name registered with AddByteOrder (registered: %s)", name, registered)
+}
+
+// inverseOrder returns the byte order that reads a value's bytes in reverse
+// order relative to order: little for big and vice versa, and a reversedOrder
+// for any other order.
+func inverseOrder(order ByteOrder) ByteOrder {
+	switch o := order.(type) {
+	case nil:
+		return BigEndian
+	case reversedOrder:
+		return o.base
+	}
+	switch order {
+	case BigEndian:
+		return LittleEndian
+	case LittleEndian:
+		return BigEndian
+	}
+	return reversedOrder{order}
+}
+
+// isStandardOrder reports whether order is big or little endian, the orders the
+// bulk scalar paths can produce by copying or byte-swapping whole elements.
+func isStandardOrder(order ByteOrder) bool {
+	return order == BigEndian || order == LittleEndian
+}
+
+// reversedOrder is the inverse of a custom byte order: a value's bytes are
+// reversed, then decoded as base would.
+type reversedOrder struct {
+	base ByteOrder
+}
+
+func (o reversedOrder) Uint16(b []byte) uint16 {
+	r := [2]byte{b[1], b[0]}
+	return o.base.Uint16(r[:])
+}
+
+func (o reversedOrder) Uint32(b []byte) uint32 {
+	r := [4]byte{b[3], b[2], b[1], b[0]}
+	return o.base.Uint32(r[:])
+}
+
+func (o reversedOrder) Uint64(b []byte) uint64 {
+	r := [8]byte{b[7], b[6], b[5], b[4], b[3], b[2], b[1], b[0]}
+	return o.base.Uint64(r[:])
+}
+
+func (o reversedOrder) PutUint16(b []byte, v uint16) {
+	o.base.PutUint16(b, v)
+	b[0], b[1] = b[1], b[0]
+}
+
+func (o reversedOrder) PutUint32(b []byte, v uint32) {
+	o.base.PutUint32(b, v)
+	reverseBytes(b[:4])
+}
+
+func (o reversedOrder) PutUint64(b []byte, v uint64) {
+	o.base.PutUint64(b, v)
+	reverseBytes(b[:8])
+}
+
+func (o reversedOrder) String() string {
+	return "inverse " + o.base.String()
+}
+
+func reverseBytes(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
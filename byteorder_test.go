@@ -0,0 +1,128 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// pdpEndian is the PDP-11 "middle-endian" order: 16-bit little-endian words,
+// the most significant word first.
+type pdpEndian struct{}
+
+func (pdpEndian) Uint16(b []byte) uint16 { return binary.LittleEndian.Uint16(b) }
+func (pdpEndian) Uint32(b []byte) uint32 {
+	return uint32(binary.LittleEndian.Uint16(b))<<16 | uint32(binary.LittleEndian.Uint16(b[2:]))
+}
+func (p pdpEndian) Uint64(b []byte) uint64     { return uint64(p.Uint32(b))<<32 | uint64(p.Uint32(b[4:])) }
+func (pdpEndian) PutUint16(b []byte, v uint16) { binary.LittleEndian.PutUint16(b, v) }
+func (pdpEndian) PutUint32(b []byte, v uint32) {
+	binary.LittleEndian.PutUint16(b, uint16(v>>16))
+	binary.LittleEndian.PutUint16(b[2:], uint16(v))
+}
+func (p pdpEndian) PutUint64(b []byte, v uint64) {
+	p.PutUint32(b, uint32(v>>32))
+	p.PutUint32(b[4:], uint32(v))
+}
+func (pdpEndian) String() string { return "pdp" }
+
+type pdpInner struct {
+	V uint32
+}
+
+type pdpRecord struct {
+	_     struct{} `binary:"endian=pdp"`
+	A     uint32
+	Words [2]uint32
+	Half  []uint16 `binary:"[2]uint16"`
+	In    pdpInner
+	Be    uint32 `binary:"uint32,endian=big"`
+	Inv   uint32 `binary:"uint32,endian=inverse"`
+}
+
+func TestNamedByteOrder(t *testing.T) {
+	in := pdpRecord{A: 0x0a0b0c0d, Words: [2]uint32{0x01020304, 0x05060708}, Half: []uint16{0x0102, 0x0304},
+		In: pdpInner{V: 0x0a0b0c0d}, Be: 0x01020304, Inv: 0x0a0b0c0d}
+	want := []byte{
+		0x0b, 0x0a, 0x0d, 0x0c, // A
+		0x02, 0x01, 0x04, 0x03, 0x06, 0x05, 0x08, 0x07, // Words
+		0x02, 0x01, 0x04, 0x03, // Half
+		0x0b, 0x0a, 0x0d, 0x0c, // In.V
+		0x01, 0x02, 0x03, 0x04, // Be
+		0x0c, 0x0d, 0x0a, 0x0b, // Inv: the pdp bytes reversed
+	}
+	ms := NewMarshaler()
+	ms.AddByteOrder("pdp", pdpEndian{})
+	blob, err := ms.Marshal(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(blob, want) {
+		t.Fatalf("blob = %x, want %x", blob, want)
+	}
+	var out pdpRecord
+	if _, err := ms.Unmarshal(blob, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("round trip: got %+v, want %+v", out, in)
+	}
+
+	layout, err := ms.Inspect(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := layout.Fields[0].Endian; got != "pdp" {
+		t.Errorf("Inspect: Endian = %q, want pdp", got)
+	}
+
+	ms.RemoveByteOrder("pdp")
+	if _, err := ms.Marshal(&in); err == nil || !strings.Contains(err.Error(), `unknown byte order "pdp"`) || !strings.Contains(err.Error(), "(registered: none)") {
+		t.Fatalf("unregistered order: err = %v", err)
+	}
+
+	// A misspelt name lists the registered ones.
+	type typo struct {
+		F float64 `binary:"float64,endian=pdq"`
+	}
+	ms.AddByteOrder("pdp", pdpEndian{})
+	ms.AddByteOrder("vax", pdpEndian{})
+	if _, err := ms.Marshal(&typo{}); err == nil || !strings.Contains(err.Error(), `unknown byte order "pdq"`) || !strings.Contains(err.Error(), "(registered: pdp, vax)") {
+		t.Fatalf("misspelt order: err = %v", err)
+	}
+}
+
+func TestNamedByteOrder_Field(t *testing.T) {
+	// A registered order on a single field of a big-endian record.
+	type rec struct {
+		N uint16
+		F float64 `binary:"float64,endian=pdp"`
+	}
+	ms := NewMarshalerOrder(BigEndian)
+	ms.AddByteOrder("pdp", pdpEndian{})
+	in := rec{N: 1, F: 1.5}
+	blob, err := ms.Marshal(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1.5 is 0x3ff8000000000000.
+	want := []byte{0, 1, 0xf8, 0x3f, 0, 0, 0, 0, 0, 0}
+	if !bytes.Equal(blob, want) {
+		t.Fatalf("blob = %x, want %x", blob, want)
+	}
+	var out rec
+	if _, err := ms.Unmarshal(blob, &out); err != nil || out != in {
+		t.Fatalf("Unmarshal = %+v, %v", out, err)
+	}
+
+	type bad struct {
+		F float64 `binary:"float64,endian=Pdp"`
+	}
+	if _, err := ms.Marshal(&bad{}); err == nil {
+		t.Fatal("an upper-case order name: expected an error")
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// ErrNonCanonical is wrapped by the error of Unmarshal and UnmarshalAs under
+// Marshaler.Canonical when the input decodes, but is not what encoding the
+// value decoded writes: padding other than its fill, bytes after a string
+// within its buffer, a length prefix counting trailing zeros, a checksum not
+// verified under ValidateNone, and the like.
+var ErrNonCanonical = errors.New("non-canonical encoding")
+
+// errCanonicalStop ends the input of the decode that locates a non-canonical
+// byte.
+var errCanonicalStop = errors.New("non-canonical byte")
+
+// checkCanonical checks, under Canonical, that input is the encoding of
+// govalue, just decoded from it with tag (none if as is false). It re-encodes a
+// copy of the value and, at the first byte that differs, decodes input again
+// up to that byte to find the field the byte is in, returning a *DecodeError
+// for it that wraps ErrNonCanonical.
+func (ms *Marshaler) checkCanonical(input []byte, govalue interface{}, tag string, as bool) error {
+	v := reflect.ValueOf(govalue)
+	clone := func() interface{} {
+		c := reflect.New(v.Type()).Elem()
+		cloneInto(c, v, make(map[clonedPtr]reflect.Value))
+		return c.Interface()
+	}
+
+	var enc []byte
+	var err error
+	if as {
+		enc, err = ms.MarshalAs(clone(), tag)
+	} else {
+		enc, err = ms.Marshal(clone())
+	}
+	if err != nil {
+		return fmt.Errorf("the value decoded does not encode: %w: %w", err, ErrNonCanonical)
+	}
+	at := 0
+	for at < len(input) && at < len(enc) && input[at] == enc[at] {
+		at++
+	}
+	if at == len(input) && at == len(enc) {
+		return nil
+	}
+
+	var got, want string
+	if at < len(input) {
+		got = fmt.Sprintf("%#02x", input[at])
+	}
+	if at < len(enc) {
+		want = fmt.Sprintf("%#02x", enc[at])
+	}
+	cause := fmt.Errorf("byte %d of %d: %w", at, len(enc), ErrNonCanonical)
+
+	// Decode the input up to the byte, with the fields recorded as under
+	// Salvage so that a generated type reads field by field and reports the
+	// field it stops in.
+	salvage, warnings := ms.Salvage, ms.Warnings
+	ms.Salvage = true
+	r := io.MultiReader(bytes.NewReader(input[:at]), &errReader{errCanonicalStop})
+	if as {
+		_, err = ms.ReadAs(r, tag, clone())
+	} else {
+		_, err = ms.Read(r, clone())
+	}
+	ms.Salvage, ms.Warnings = salvage, warnings
+
+	var de *DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, errCanonicalStop) {
+		return &DecodeError{Offset: at, AbsOffset: at, FieldOffset: at, Err: cause, Expected: want, Actual: got}
+	}
+	e := *de
+	e.Err, e.Expected, e.Actual = cause, want, got
+	return &e
+}
+
+// errReader is a reader that fails with err.
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
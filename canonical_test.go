@@ -0,0 +1,81 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"errors"
+	"testing"
+)
+
+type canonHdr struct {
+	Kind uint8
+	Pad  interface{} `binary:"pad(2)"`
+}
+
+type canonRecord struct {
+	_    struct{} `binary:"endian=big"`
+	Hdr  canonHdr
+	On   bool
+	Name string   `binary:"bstring(4)"`
+	Tags []uint16 `binary:"[2]uint16"`
+}
+
+func TestCanonical(t *testing.T) {
+	good := []byte{1, 0, 0, 1, 2, 'a', 'b', 0, 0, 0, 1, 0, 2}
+	at := func(off int, b ...byte) []byte {
+		blob := append([]byte{}, good...)
+		copy(blob[off:], b)
+		return blob
+	}
+	cases := []struct {
+		blob  []byte
+		field string
+		path  string
+		off   int // the offset of the field
+	}{
+		{at(2, 7), "Hdr", "Hdr.Pad", 1}, // non-zero padding
+		{at(8, 'x'), "Name", "Name", 4}, // a byte after the string in its buffer
+		{at(4, 3), "Name", "Name", 4},   // a length counting a trailing zero
+	}
+	ms := NewMarshaler()
+	ms.Canonical = true
+	for _, c := range cases {
+		var v canonRecord
+		if _, err := Unmarshal(c.blob, &v); err != nil {
+			t.Errorf("% x: without Canonical: %v", c.blob, err)
+		}
+		_, err := ms.Unmarshal(c.blob, &v)
+		var de *DecodeError
+		if !errors.As(err, &de) || !errors.Is(err, ErrNonCanonical) || de.Field != c.field || de.Path != c.path || de.AbsOffset != c.off {
+			t.Errorf("% x: err = %v (%+v)", c.blob, err, de)
+		}
+	}
+	var v canonRecord
+	if n, err := ms.Unmarshal(append(good, 9), &v); err != nil || n != len(good) || v.Name != "ab" {
+		t.Errorf("canonical input: n = %d, err = %v", n, err)
+	}
+	ms = NewMarshalerOrder(BigEndian)
+	ms.Canonical = true
+	if _, err := ms.UnmarshalAs([]byte{1, 'a', 'x', 0, 0}, "bstring(4)", new(string)); !errors.Is(err, ErrNonCanonical) {
+		t.Errorf("UnmarshalAs: err = %v", err)
+	}
+}
+
+type canonDetect struct {
+	_     struct{}  `binary:"endian=detect:Order"`
+	Order ByteOrder `binary:"-"`
+	Magic uint16    `binary:"uint16,const=0x1234"`
+	N     uint16
+}
+
+// An endian=detect struct is checked in the order its magic was read in.
+func TestCanonicalDetect(t *testing.T) {
+	ms := NewMarshaler()
+	ms.Canonical = true
+	for _, blob := range [][]byte{{0x12, 0x34, 0, 7, 0x34, 0x12, 7, 0}, {0x34, 0x12, 7, 0, 0x12, 0x34, 0, 7}} {
+		var v [2]canonDetect
+		if n, err := ms.Unmarshal(blob, &v); err != nil || n != len(blob) || v[0].N != 7 || v[1].N != 7 {
+			t.Errorf("% x: %+v, n = %d, err = %v", blob, v, n, err)
+		}
+	}
+}
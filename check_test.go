@@ -0,0 +1,83 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// check= validates a rule over several fields once its field is decoded.
+
+type checkChunk struct {
+	_         struct{} `binary:"endian=big"`
+	TotalSize uint32
+	Offset    uint32
+	Size      uint32 `binary:"uint32,check=Offset+Size<=TotalSize"`
+	Version   uint8
+	Flags     uint8    `binary:"uint8,check=Version >= 2 || Flags == 0"`
+	Items     []uint16 `binary:"[Size]uint16,check=$offset==14 && Items[0] < 100"`
+}
+
+func TestCheck(t *testing.T) {
+	good := []byte{0, 0, 0, 10, 0, 0, 0, 4, 0, 0, 0, 2, 1, 0, 0, 7, 0, 9}
+	var out checkChunk
+	if _, err := Unmarshal(good, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Size != 2 || out.Items[1] != 9 {
+		t.Fatalf("decoded %+v", out)
+	}
+
+	cases := []struct {
+		name   string
+		patch  func(b []byte)
+		field  string
+		offset int
+		rule   string
+	}{
+		{"offset past the end", func(b []byte) { b[7] = 9 }, "Size", 8, "Offset+Size<=TotalSize"},
+		{"flags on version 1", func(b []byte) { b[13] = 1 }, "Flags", 13, "Version >= 2 || Flags == 0"},
+		{"element rule", func(b []byte) { b[14] = 1 }, "Items", 14, "$offset==14 && Items[0] < 100"},
+	}
+	for _, c := range cases {
+		blob := append([]byte{}, good...)
+		c.patch(blob)
+		_, err := Unmarshal(blob, &checkChunk{})
+		var de *DecodeError
+		if !errors.Is(err, ErrValidationError) || !errors.As(err, &de) {
+			t.Fatalf("%s: err = %v, want a validation DecodeError", c.name, err)
+		}
+		if de.Field != c.field || de.Offset != c.offset || !strings.Contains(err.Error(), c.rule) {
+			t.Errorf("%s: err = %v (field %s, offset %d)", c.name, err, de.Field, de.Offset)
+		}
+	}
+
+	// Encoding does not evaluate the rules.
+	if _, err := Marshal(&checkChunk{Offset: 9, Size: 0, Flags: 1}); err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+}
+
+func TestCheck_MetadataErrors(t *testing.T) {
+	type forward struct {
+		A uint8 `binary:"uint8,check=A<B"`
+		B uint8
+	}
+	if _, err := Unmarshal([]byte{1, 2}, &forward{}); err == nil || !strings.Contains(err.Error(), "decoded later") {
+		t.Errorf("forward reference: err = %v", err)
+	}
+	type unknown struct {
+		A uint8 `binary:"uint8,check=A<Nope"`
+	}
+	if _, err := Unmarshal([]byte{1}, &unknown{}); err == nil || !strings.Contains(err.Error(), "unknown field Nope") {
+		t.Errorf("unknown field: err = %v", err)
+	}
+	type fn struct {
+		A []byte `binary:"[2]byte,check=count(A)==2"`
+	}
+	if _, err := Unmarshal([]byte{1, 2}, &fn{}); err == nil {
+		t.Error("count() in a check: expected an error")
+	}
+}
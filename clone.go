@@ -0,0 +1,70 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import "reflect"
+
+// clonedPtr identifies a pointer already copied by cloneInto.
+type clonedPtr struct {
+	p uintptr
+	t reflect.Type
+}
+
+// cloneInto sets dst to a copy of src that a decode can write to without
+// changing src: the pointers, slices and interfaces in it are copied, the maps,
+// channels and functions a decode does not write to are shared, and so are
+// unexported fields, which it skips.
+func cloneInto(dst, src reflect.Value, seen map[clonedPtr]reflect.Value) {
+	switch src.Kind() {
+	case reflect.Ptr:
+		if src.IsNil() {
+			return
+		}
+		k := clonedPtr{src.Pointer(), src.Type()}
+		p, ok := seen[k]
+		if !ok {
+			p = reflect.New(src.Type().Elem())
+			seen[k] = p
+			cloneInto(p.Elem(), src.Elem(), seen)
+		}
+		dst.Set(p)
+	case reflect.Interface:
+		if src.IsNil() {
+			return
+		}
+		e := reflect.New(src.Elem().Type()).Elem()
+		cloneInto(e, src.Elem(), seen)
+		dst.Set(e)
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		s := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		cloneElems(s, src, seen)
+		dst.Set(s)
+	case reflect.Array:
+		cloneElems(dst, src, seen)
+	case reflect.Struct:
+		dst.Set(src)
+		for i := 0; i < src.NumField(); i++ {
+			if dst.Field(i).CanSet() {
+				cloneInto(dst.Field(i), src.Field(i), seen)
+			}
+		}
+	default:
+		dst.Set(src)
+	}
+}
+
+// cloneElems copies the elements of the slice or array src into dst, of the
+// same length.
+func cloneElems(dst, src reflect.Value, seen map[clonedPtr]reflect.Value) {
+	switch src.Type().Elem().Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Array, reflect.Struct:
+		for i := 0; i < src.Len(); i++ {
+			cloneInto(dst.Index(i), src.Index(i), seen)
+		}
+	default:
+		reflect.Copy(dst, src)
+	}
+}
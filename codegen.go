@@ -0,0 +1,296 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Codegen is the run-time support of the methods binarystruct-codegen
+// generates, which call it where the interpreters use the Marshaler's internals:
+// to evaluate a tag expression that can fail, apply the Marshaler's limits,
+// validation mode and other settings, run the hooks and report errors as the
+// interpreters do. It is not meant to be used otherwise; its methods change
+// with the generator.
+//
+// The tag expression methods (Quo, Rem, Shl, Shr, Align, InRange, Param and
+// Outer) do not return an error: one that fails records it and returns a value
+// that keeps the expression safe to finish, and generated code takes the
+// error with Err before it uses the expression's value.
+type Codegen struct {
+	ms  *Marshaler
+	err error
+}
+
+// NewCodegen returns the support of a generated method called with ms, which
+// may be nil.
+func NewCodegen(ms *Marshaler) *Codegen {
+	return &Codegen{ms: ms}
+}
+
+// fail records err as the error of the expression being evaluated, unless an
+// operator evaluated before it already failed.
+func (cg *Codegen) fail(err error) {
+	if cg.err == nil {
+		cg.err = err
+	}
+}
+
+// Err returns the error of the first operator that failed since the last call,
+// or nil, and clears it.
+func (cg *Codegen) Err() error {
+	err := cg.err
+	cg.err = nil
+	return err
+}
+
+// Quo, Rem, Shl and Shr are the /, %, << and >> of a tag expression whose right
+// operand is known only at run time. A zero divisor or a negative shift count
+// fails as in the interpreters, and the result is 0.
+func (cg *Codegen) Quo(a, b int) int {
+	if b == 0 {
+		cg.fail(fmt.Errorf("division by zero"))
+		return 0
+	}
+	return a / b
+}
+
+func (cg *Codegen) Rem(a, b int) int {
+	if b == 0 {
+		cg.fail(fmt.Errorf("division by zero"))
+		return 0
+	}
+	return a % b
+}
+
+func (cg *Codegen) Shl(a, b int) int {
+	if b < 0 {
+		cg.fail(fmt.Errorf("negative shift count %d", b))
+		return 0
+	}
+	return a << b
+}
+
+func (cg *Codegen) Shr(a, b int) int {
+	if b < 0 {
+		cg.fail(fmt.Errorf("negative shift count %d", b))
+		return 0
+	}
+	return a >> b
+}
+
+// Align is align(v, a) of a tag expression whose alignment is known only at run
+// time. An alignment that is not positive fails as in the interpreters.
+func (cg *Codegen) Align(v, a int) int {
+	if a <= 0 {
+		cg.fail(fmt.Errorf("align(): alignment must be positive, got %d", a))
+		return 0
+	}
+	return alignUp(v, a)
+}
+
+// InRange reports whether index i of a field reference such as Dims[1] is in
+// range of the slice or array, of length n, it indexes. One that is not fails as
+// in the interpreters, and generated code reads 0 instead of the element.
+func (cg *Codegen) InRange(n, i int, ref string) bool {
+	if i >= n {
+		cg.fail(fmt.Errorf("%s: index out of range (length %d)", ref, n))
+		return false
+	}
+	return true
+}
+
+// Param is the value of a $name parameter, looked up where the expression reads
+// it: one that ?:, && or || skips need not be set.
+func (cg *Codegen) Param(name string) int {
+	v, err := cg.ms.paramInt(name)
+	if err != nil {
+		cg.fail(err)
+	}
+	return v
+}
+
+// Outer is the value of a parent. or root. field reference of the struct strc
+// points to, resolved against the structs recorded with PushStruct. Without an
+// enclosing struct, root. is strc itself, whose earlier fields a read method
+// has only just decoded.
+func (cg *Codegen) Outer(strc interface{}, ref string) int {
+	v, err := cg.ms.outerInt(strc, ref)
+	if err != nil {
+		cg.fail(err)
+	}
+	return v
+}
+
+// Omitted reports whether the output or input has reached n, the offset at
+// which an omittable= limit omits the field. A limit whose operator failed does
+// not omit the field, as in the interpreters.
+func (cg *Codegen) Omitted(n, limit int) bool {
+	if cg.Err() != nil {
+		return false
+	}
+	return n >= limit
+}
+
+// RemainingLen reports how many bytes of input r still holds, the value of
+// $remaining. r must know its length: a reader with a Len() int method or an
+// *io.LimitedReader.
+func (cg *Codegen) RemainingLen(r io.Reader) (int, error) {
+	return remainingLen(r)
+}
+
+// PushStruct records strc, a pointer to a struct, as the enclosing struct of the
+// values encoded or decoded until the matching PopStruct, so that their parent.
+// and root. references can reach it. The interpreters track nesting
+// themselves. Both are no-ops on a nil Marshaler.
+func (cg *Codegen) PushStruct(strc interface{}) {
+	if cg.ms != nil {
+		cg.ms.enterStruct(reflect.Indirect(reflect.ValueOf(strc)), nil)
+	}
+}
+
+// PopStruct removes the struct recorded by the last PushStruct.
+func (cg *Codegen) PopStruct() {
+	if cg.ms != nil {
+		cg.ms.leaveStruct()
+	}
+}
+
+// BeforeMarshalHook runs the BeforeMarshalBinary hook of the struct strc points
+// to, and AfterUnmarshalHooks its ValidateBinary and AfterUnmarshalBinary hooks,
+// reporting a failure as the interpreters do.
+func (cg *Codegen) BeforeMarshalHook(strc interface{}) error {
+	return beforeMarshalHook(strc)
+}
+
+func (cg *Codegen) AfterUnmarshalHooks(strc interface{}) error {
+	return afterUnmarshalHooks(strc)
+}
+
+// Salvaging reports whether the struct about to be decoded records its fields
+// for Marshaler.Salvage. A generated read method then decodes it with
+// ReadStructFields, through the interpreter, so that a failure keeps the fields
+// decoded before it.
+func (cg *Codegen) Salvaging() bool {
+	return cg.ms.salvaging()
+}
+
+// ReadStructFields decodes the struct strc points to with the interpreter, by
+// its binary tags, without calling its own read methods.
+func (cg *Codegen) ReadStructFields(r io.Reader, order ByteOrder, strc interface{}) (n int, err error) {
+	v := reflect.ValueOf(strc)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return 0, fmt.Errorf("ReadStructFields needs a pointer to a struct, not %T", strc)
+	}
+	cg.ms.beginDecode()
+	defer cg.ms.endDecode(&err)
+	return cg.ms.readStructFields(r, order, v.Elem())
+}
+
+// BeginDecode and EndDecode bracket a read method that validates or allocates,
+// as Read brackets a decode, so that a decode nested in another is reported by
+// the outermost one.
+func (cg *Codegen) BeginDecode() {
+	cg.ms.beginDecode()
+}
+
+func (cg *Codegen) EndDecode(err *error) {
+	cg.ms.endDecode(err)
+}
+
+// EnterNested and LeaveNested bracket a read method, failing when it exceeds
+// MaxDepth.
+func (cg *Codegen) EnterNested() error {
+	return cg.ms.enterNested()
+}
+
+func (cg *Codegen) LeaveNested() {
+	cg.ms.leaveNested()
+}
+
+// LimitString checks size, the length of a string about to be read, against
+// MaxStringLen and charges it to MaxDecodeAlloc.
+func (cg *Codegen) LimitString(size int) error {
+	return cg.ms.limitString(size)
+}
+
+// LimitSlice checks count, the length of a slice of the type of sample (a nil
+// slice) about to be allocated, against MaxSliceLen and charges its memory to
+// MaxDecodeAlloc. A negative count is always an error.
+func (cg *Codegen) LimitSlice(sample interface{}, count int) error {
+	return cg.ms.limitSlice(count, count, int(reflect.TypeOf(sample).Elem().Size()))
+}
+
+// SliceCap checks count as LimitSlice does and returns the capacity to allocate
+// a slice of the type of sample with, for count elements of wireSize bytes of
+// r's input each (0 if it varies): all of them when r is known to hold them,
+// otherwise a part, to which the elements decoded are appended.
+func (cg *Codegen) SliceCap(sample interface{}, r io.Reader, count, wireSize int) (int, error) {
+	size := int(reflect.TypeOf(sample).Elem().Size())
+	if err := cg.ms.limitSlice(count, count, size); err != nil {
+		return 0, err
+	}
+	return allocAhead(r, count, wireSize, size), nil
+}
+
+// ReadBytes reads size bytes of r into a new slice, allocating no more than the
+// input holds ahead of it, as the interpreters do.
+func (cg *Codegen) ReadBytes(r io.Reader, size int) (b []byte, n int, err error) {
+	return readBytes(r, size)
+}
+
+// ValidationFailed applies the Marshaler's Validation mode to err, the failure
+// of a decode-time check, returning the error the read method returns, if any.
+func (cg *Codegen) ValidationFailed(err error) error {
+	return cg.ms.validationFailed(err)
+}
+
+// VerifiesChecksums reports whether a read method recomputes custom valueof
+// evaluators to verify the values decoded.
+func (cg *Codegen) VerifiesChecksums() bool {
+	return cg.ms.verifiesChecksums()
+}
+
+// CollectMark returns the number of failures recorded under ValidateCollect, a
+// mark to pass to WrapCollected.
+func (cg *Codegen) CollectMark() int {
+	if cg.ms == nil {
+		return 0
+	}
+	return len(cg.ms.collected)
+}
+
+// WrapCollected replaces each failure recorded since mark, those of a nested
+// struct, with wrap of it, so that a collected failure carries the same path as
+// one the nested read returns.
+func (cg *Codegen) WrapCollected(mark int, wrap func(error) error) {
+	if cg.ms == nil {
+		return
+	}
+	for j := mark; j < len(cg.ms.collected); j++ {
+		cg.ms.collected[j] = wrap(cg.ms.collected[j])
+	}
+}
+
+// DecodeError, EncodeError and ElementError wrap the failure of a struct field,
+// or of an element of an array field, as the interpreters do (see DecodeError
+// and EncodeError for the fields).
+func (cg *Codegen) DecodeError(offset int, field, goType, tag string, err error) *DecodeError {
+	return newDecodeError(offset, field, goType, tag, err)
+}
+
+func (cg *Codegen) EncodeError(offset int, field string, err error) *EncodeError {
+	return newEncodeError(offset, field, err)
+}
+
+func (cg *Codegen) ElementError(index, offset int, err error) error {
+	return &elementError{index: index, offset: offset, err: err}
+}
+
+// CheckTrailingData fails with ErrTrailingData when a decode consumed n bytes of
+// an input of size bytes, as Unmarshal does under Strict.
+func (cg *Codegen) CheckTrailingData(n, size int) error {
+	return checkTrailingData(n, size)
+}
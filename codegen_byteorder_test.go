@@ -0,0 +1,41 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A named byte order is registered on a Marshaler at run time, so codegen must
+// fail loud on it, per field and on the struct, rather than ignore it.
+func TestCodegen_NamedByteOrder_Errors(t *testing.T) {
+	t.Parallel()
+	for _, src := range []string{
+		"package p\n\ntype Rec struct {\n" +
+			"\t_ struct{} `binary:\"endian=big\"`\n" +
+			"\tV uint32   `binary:\"uint32,endian=pdp\"`\n}\n",
+		"package p\n\ntype Rec struct {\n" +
+			"\t_ struct{} `binary:\"endian=pdp\"`\n" +
+			"\tV uint32\n}\n",
+	} {
+		tmpDir, err := os.MkdirTemp(".", "tmp-bs-namedorder-")
+		if err != nil {
+			t.Fatalf("temp dir: %v", err)
+		}
+		defer os.RemoveAll(tmpDir)
+		if err := os.WriteFile(filepath.Join(tmpDir, "t.go"), []byte(src), 0o644); err != nil {
+			t.Fatalf("write t.go: %v", err)
+		}
+		out, err := exec.Command(sharedCodegenBin, "-type", "Rec", "-endian", "big", tmpDir).CombinedOutput()
+		if err == nil {
+			t.Fatalf("expected a generation error for a named byte order; output:\n%s", out)
+		}
+		if !strings.Contains(string(out), "named byte order") {
+			t.Errorf("error should name the unsupported byte order; got:\n%s", out)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import "testing"
+
+// Canonical reports the field of a non-canonical byte in a type with generated
+// methods as it does for the runtime (see canonical_test.go).
+func TestCodegenCanonical(t *testing.T) {
+	types := `type Hdr struct {
+	Kind uint8
+	Pad  interface{} ` + "`" + `binary:"pad(2)"` + "`" + `
+}
+
+type Record struct {
+	_    struct{} ` + "`" + `binary:"endian=big"` + "`" + `
+	Hdr  Hdr
+	Name string   ` + "`" + `binary:"bstring(4)"` + "`" + `
+	N    uint16
+}
+`
+	test := `import (
+	"errors"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+type rtHdr struct {
+	Kind uint8
+	Pad  interface{} ` + "`" + `binary:"pad(2)"` + "`" + `
+}
+
+type rtRecord struct {
+	_    struct{} ` + "`" + `binary:"endian=big"` + "`" + `
+	Hdr  rtHdr
+	Name string   ` + "`" + `binary:"bstring(4)"` + "`" + `
+	N    uint16
+}
+
+func TestCanonical(t *testing.T) {
+	ms := binarystruct.NewMarshaler()
+	ms.Canonical = true
+	good := []byte{1, 0, 0, 2, 'a', 'b', 0, 0, 0, 7}
+	at := func(off int, b byte) []byte {
+		blob := append([]byte{}, good...)
+		blob[off] = b
+		return blob
+	}
+	for _, blob := range [][]byte{good, at(1, 9), at(7, 'x'), at(3, 3)} {
+		_, gerr := ms.Unmarshal(blob, new(Record))
+		_, rerr := ms.Unmarshal(blob, new(rtRecord))
+		if (gerr == nil) != (blob[1] == 0 && blob[3] == 2 && blob[7] == 0) || (gerr == nil) != (rerr == nil) {
+			t.Fatalf("% x: generated err = %v, runtime err = %v", blob, gerr, rerr)
+		}
+		if gerr == nil {
+			continue
+		}
+		var g, r *binarystruct.DecodeError
+		if !errors.As(gerr, &g) || !errors.As(rerr, &r) || !errors.Is(gerr, binarystruct.ErrNonCanonical) || g.Path != r.Path || g.AbsOffset != r.AbsOffset || g.Error() != r.Error() {
+			t.Errorf("% x: generated %v, runtime %v", blob, gerr, rerr)
+		}
+	}
+}
+`
+	genBytelenCase(t, "tmp_canonical", types, "Record,Hdr", test)
+}
+
+// An endian=detect struct is checked in the order its magic was read in.
+func TestCodegenCanonicalDetect(t *testing.T) {
+	types := `import "github.com/mixcode/binarystruct"
+
+type Detect struct {
+	_     struct{}               ` + "`" + `binary:"endian=detect:Order"` + "`" + `
+	Order binarystruct.ByteOrder ` + "`" + `binary:"-"` + "`" + `
+	Magic uint16                 ` + "`" + `binary:"uint16,const=0x1234"` + "`" + `
+	N     uint16
+}
+
+type Pair struct {
+	A Detect
+	B Detect
+}
+`
+	test := `import (
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+func TestCanonicalDetect(t *testing.T) {
+	ms := binarystruct.NewMarshaler()
+	ms.Canonical = true
+	for _, blob := range [][]byte{{0x12, 0x34, 0, 7, 0x34, 0x12, 7, 0}, {0x34, 0x12, 7, 0, 0x12, 0x34, 0, 7}} {
+		var v Pair
+		if n, err := ms.Unmarshal(blob, &v); err != nil || n != len(blob) || v.A.N != 7 || v.B.N != 7 {
+			t.Errorf("% x: %+v, n = %d, err = %v", blob, v, n, err)
+		}
+	}
+}
+`
+	genBytelenCase(t, "tmp_canonical_detect", types, "Detect,Pair", test)
+}
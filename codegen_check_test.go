@@ -0,0 +1,106 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const checkTypes = `type Chunk struct {
+	TotalSize uint32
+	Offset    uint32
+	Size      uint32   ` + "`" + `binary:"uint32,check=Offset+Size<=TotalSize"` + "`" + `
+	Version   uint8
+	Flags     uint8    ` + "`" + `binary:"uint8,check=Version>=2||Flags==0"` + "`" + `
+	Items     []uint16 ` + "`" + `binary:"[Size]uint16,check=$offset==14 && Items[0] < $limit"` + "`" + `
+}
+`
+
+// Generated decode must evaluate check= rules as the runtime does (see
+// check_test.go), with the same error field and offset.
+func TestCodegenCheck(t *testing.T) {
+	test := `import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+func TestChunk(t *testing.T) {
+	ms := binarystruct.NewMarshalerOrder(binarystruct.BigEndian)
+	ms.SetParam("limit", 100)
+	good := []byte{0, 0, 0, 10, 0, 0, 0, 4, 0, 0, 0, 2, 1, 0, 0, 7, 0, 9}
+	var c Chunk
+	if _, err := c.ReadBinaryWithMarshaler(ms, bytes.NewReader(good), binarystruct.BigEndian); err != nil {
+		t.Fatal(err)
+	}
+	for i, patch := range []func(b []byte){
+		func(b []byte) { b[7] = 9 },
+		func(b []byte) { b[13] = 1 },
+		func(b []byte) { b[14] = 1 },
+	} {
+		blob := append([]byte{}, good...)
+		patch(blob)
+		_, gerr := new(Chunk).ReadBinaryWithMarshaler(ms, bytes.NewReader(blob), binarystruct.BigEndian)
+		_, rerr := ms.Unmarshal(blob, new(Chunk))
+		var gde, rde *binarystruct.DecodeError
+		if !errors.Is(gerr, binarystruct.ErrValidationError) || !errors.As(gerr, &gde) || !errors.As(rerr, &rde) {
+			t.Fatalf("case %d: generated err = %v, runtime err = %v", i, gerr, rerr)
+		}
+		if !reflect.DeepEqual(gde, rde) {
+			t.Errorf("case %d: generated %v, runtime %v", i, gerr, rerr)
+		}
+	}
+}
+`
+	genBytelenCase(t, "tmp_check", checkTypes, "Chunk", test)
+}
+
+// -no-validate strips check= rules with the other decode validation.
+func TestCodegenCheck_NoValidate(t *testing.T) {
+	test := `import (
+	"bytes"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+func TestChunk(t *testing.T) {
+	blob := []byte{0, 0, 0, 1, 0, 0, 0, 4, 0, 0, 0, 2, 1, 1, 0, 7, 0, 9}
+	var c Chunk
+	if _, err := c.ReadBinaryWithMarshaler(nil, bytes.NewReader(blob), binarystruct.BigEndian); err != nil {
+		t.Fatal(err)
+	}
+}
+`
+	genBytelenCase(t, "tmp_checknv", checkTypes, "Chunk", test, "-no-validate")
+}
+
+// A rule on a field may not use a field read after it.
+func TestCodegenCheck_ForwardReference(t *testing.T) {
+	t.Parallel()
+	src := "package p\n\ntype Rec struct {\n" +
+		"\tA uint8 `binary:\"uint8,check=A<B\"`\n" +
+		"\tB uint8\n}\n"
+	tmpDir, err := os.MkdirTemp(".", "tmp-bs-checkfwd-")
+	if err != nil {
+		t.Fatalf("temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	if err := os.WriteFile(filepath.Join(tmpDir, "t.go"), []byte(src), 0o644); err != nil {
+		t.Fatalf("write t.go: %v", err)
+	}
+	out, err := exec.Command(sharedCodegenBin, "-type", "Rec", "-endian", "big", tmpDir).CombinedOutput()
+	if err == nil {
+		t.Fatalf("expected a generation error for a forward reference; output:\n%s", out)
+	}
+	if !strings.Contains(string(out), "decoded later") {
+		t.Errorf("error should explain the forward reference; got:\n%s", out)
+	}
+}
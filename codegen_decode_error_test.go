@@ -0,0 +1,159 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import "testing"
+
+// Generated decode must describe a failed check as the runtime does (see
+// decode_error_test.go): the same path, offsets, type, tag, expected and actual
+// values.
+func TestCodegenDecodeError(t *testing.T) {
+	types := `type Level uint16
+
+type Record struct {
+	Version uint16  ` + "`" + `binary:"uint16,const=2"` + "`" + `
+	Magic   [2]byte ` + "`" + `binary:"[2]byte,const=0xcafe"` + "`" + `
+	Kind    uint8   ` + "`" + `binary:"uint8,enum=1|2|5..9"` + "`" + `
+	Comp    uint8   ` + "`" + `binary:"uint8,enum=Compression"` + "`" + `
+	Lvl     Level   ` + "`" + `binary:"uint16,range=1..100"` + "`" + `
+	Name    string  ` + "`" + `binary:"string(2),match=^[a-z]+$"` + "`" + `
+	Flags   uint8   ` + "`" + `binary:"uint8,check=Kind!=2||Flags==0"` + "`" + `
+	Levels  [2]int8 ` + "`" + `binary:"[2]int8,enum=-1..3"` + "`" + `
+}
+`
+	test := `import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+type rtRecord struct {
+	Version uint16  ` + "`" + `binary:"uint16,const=2"` + "`" + `
+	Magic   [2]byte ` + "`" + `binary:"[2]byte,const=0xcafe"` + "`" + `
+	Kind    uint8   ` + "`" + `binary:"uint8,enum=1|2|5..9"` + "`" + `
+	Comp    uint8   ` + "`" + `binary:"uint8,enum=Compression"` + "`" + `
+	Lvl     Level   ` + "`" + `binary:"uint16,range=1..100"` + "`" + `
+	Name    string  ` + "`" + `binary:"string(2),match=^[a-z]+$"` + "`" + `
+	Flags   uint8   ` + "`" + `binary:"uint8,check=Kind!=2||Flags==0"` + "`" + `
+	Levels  [2]int8 ` + "`" + `binary:"[2]int8,enum=-1..3"` + "`" + `
+}
+
+func TestRecord(t *testing.T) {
+	ms := binarystruct.NewMarshalerOrder(binarystruct.BigEndian)
+	ms.AddEnum("Compression", map[uint64]string{0: "none", 8: "deflate"})
+	good := []byte{0, 2, 0xca, 0xfe, 2, 8, 0, 10, 'o', 'k', 0, 0xff, 3}
+	at := func(off int, b ...byte) []byte {
+		blob := append([]byte{}, good...)
+		copy(blob[off:], b)
+		return blob
+	}
+	for _, blob := range [][]byte{at(0, 0, 3), at(2, 0xbe), at(4, 3), at(5, 9), at(6, 1, 0), at(8, 'O'), at(10, 1), at(12, 4)} {
+		_, gerr := new(Record).ReadBinaryWithMarshaler(ms, bytes.NewReader(blob), binarystruct.BigEndian)
+		_, rerr := ms.Unmarshal(blob, new(rtRecord))
+		var g, r *binarystruct.DecodeError
+		if !errors.As(gerr, &g) || !errors.As(rerr, &r) {
+			t.Fatalf("% x: generated err = %v, runtime err = %v", blob, gerr, rerr)
+		}
+		if g.Path != r.Path || g.Type != r.Type || g.Tag != r.Tag || g.FieldOffset != r.FieldOffset || g.Expected != r.Expected || g.Actual != r.Actual {
+			t.Errorf("% x: generated %+v, runtime %+v", blob, g, r)
+		}
+		if g.Expected == "" && r.Field != "Flags" {
+			t.Errorf("% x: no expected value in %+v", blob, g)
+		}
+	}
+}
+`
+	genBytelenCase(t, "tmp_decerr", types, "Record", test)
+}
+
+// The failure of a nested struct, or of a struct element, carries the path
+// and absolute offset of the runtime's, returned or collected.
+func TestCodegenDecodeErrorNested(t *testing.T) {
+	types := `type Inner struct {
+	A uint8 ` + "`" + `binary:"uint8,range=1..9"` + "`" + `
+}
+
+type Outer struct {
+	K    uint8
+	Hdr  Inner
+	N    uint8
+	Recs []Inner ` + "`" + `binary:"[N]any"` + "`" + `
+}
+`
+	test := `import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+type rtInner struct {
+	A uint8 ` + "`" + `binary:"uint8,range=1..9"` + "`" + `
+}
+
+type rtOuter struct {
+	K    uint8
+	Hdr  rtInner
+	N    uint8
+	Recs []rtInner ` + "`" + `binary:"[N]any"` + "`" + `
+}
+
+func paths(err error) (out []string) {
+	errs := []error{err}
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = j.Unwrap()
+	}
+	for _, e := range errs {
+		var de *binarystruct.DecodeError
+		if errors.As(e, &de) {
+			out = append(out, fmt.Sprintf("%s@%d", de.Path, de.AbsOffset))
+		}
+	}
+	return out
+}
+
+func TestNested(t *testing.T) {
+	for _, tc := range []struct {
+		mode binarystruct.ValidationMode
+		blob []byte
+		want string
+	}{
+		{binarystruct.ValidateAll, []byte{0, 0, 2, 1, 1}, "[Hdr.A@1]"},
+		{binarystruct.ValidateAll, []byte{0, 1, 2, 1, 0}, "[Recs[1].A@4]"},
+		{binarystruct.ValidateCollect, []byte{0, 0, 2, 1, 0}, "[Hdr.A@1 Recs[1].A@4]"},
+	} {
+		ms := binarystruct.NewMarshaler()
+		ms.Validation = tc.mode
+		_, gerr := ms.Unmarshal(tc.blob, new(Outer))
+		_, rerr := ms.Unmarshal(tc.blob, new(rtOuter))
+		g, r := fmt.Sprint(paths(gerr)), fmt.Sprint(paths(rerr))
+		if g != tc.want || r != tc.want {
+			t.Errorf("% x: generated %s (%v), runtime %s (%v), want %s", tc.blob, g, gerr, r, rerr, tc.want)
+		}
+	}
+}
+
+// Warnings carry the same paths, and each decode replaces the last one's.
+func TestNestedWarn(t *testing.T) {
+	ms := binarystruct.NewMarshaler()
+	ms.Validation = binarystruct.ValidateWarn
+	blob := []byte{0, 0, 2, 1, 0}
+	for _, v := range []interface{}{new(Outer), new(rtOuter), new(Outer)} {
+		if _, err := ms.Unmarshal(blob, v); err != nil {
+			t.Fatalf("%T: %v", v, err)
+		}
+		var got []string
+		for _, w := range ms.Warnings {
+			got = append(got, fmt.Sprintf("%s@%d", w.Path, w.AbsOffset))
+		}
+		if fmt.Sprint(got) != "[Hdr.A@1 Recs[1].A@4]" {
+			t.Errorf("%T: warnings %v", v, got)
+		}
+	}
+}
+`
+	genBytelenCase(t, "tmp_decerr_nested", types, "Outer,Inner", test)
+}
@@ -0,0 +1,78 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import "testing"
+
+// Generated code must apply default= on decode and omitdefault on encode as
+// the runtime does (see default_test.go).
+func TestCodegenDefault(t *testing.T) {
+	types := `type Level uint16
+
+type Record struct {
+	Version uint8
+	Scale   float32 ` + "`" + `binary:"float32,omittable,default=1.5"` + "`" + `
+	Lvl     Level   ` + "`" + `binary:"uint16,omittable,default=0x10"` + "`" + `
+	TTL     uint8   ` + "`" + `binary:"uint8,omittable,default=64,omitdefault"` + "`" + `
+	Name    string  ` + "`" + `binary:"bstring,omittable,default=anon,omitdefault"` + "`" + `
+	Key     []byte  ` + "`" + `binary:"[2]byte,omittable,default=0xcafe,omitdefault"` + "`" + `
+	ID      [2]byte ` + "`" + `binary:"[2]byte,omittable,default=0x0102,omitdefault"` + "`" + `
+}
+`
+	test := `import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+type rtRecord struct {
+	Version uint8
+	Scale   float32 ` + "`" + `binary:"float32,omittable,default=1.5"` + "`" + `
+	Lvl     Level   ` + "`" + `binary:"uint16,omittable,default=0x10"` + "`" + `
+	TTL     uint8   ` + "`" + `binary:"uint8,omittable,default=64,omitdefault"` + "`" + `
+	Name    string  ` + "`" + `binary:"bstring,omittable,default=anon,omitdefault"` + "`" + `
+	Key     []byte  ` + "`" + `binary:"[2]byte,omittable,default=0xcafe,omitdefault"` + "`" + `
+	ID      [2]byte ` + "`" + `binary:"[2]byte,omittable,default=0x0102,omitdefault"` + "`" + `
+}
+
+func TestRecord(t *testing.T) {
+	ms := binarystruct.NewMarshalerOrder(binarystruct.BigEndian)
+	for _, blob := range [][]byte{{1}, {1, 0x3f, 0x80, 0, 0}, {1, 0x3f, 0x80, 0, 0, 0, 2, 5}} {
+		var g Record
+		if _, err := g.ReadBinary(bytes.NewReader(blob), binarystruct.BigEndian); err != nil {
+			t.Fatal(err)
+		}
+		var r rtRecord
+		if _, err := ms.Unmarshal(blob, &r); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(g, Record(r)) {
+			t.Errorf("% x: generated %+v, runtime %+v", blob, g, r)
+		}
+	}
+	key, id := []byte{0xca, 0xfe}, [2]byte{1, 2}
+	for _, in := range []Record{
+		{Version: 1, Scale: 1.5, Lvl: 16, TTL: 64, Name: "anon", Key: key, ID: id},
+		{Version: 1, Scale: 1.5, Lvl: 16, TTL: 5, Name: "anon", Key: key, ID: id},
+		{Version: 1, Scale: 1.5, Lvl: 16, TTL: 64, Name: "anon", Key: []byte{1, 2}, ID: id},
+		{Version: 1, Scale: 1.5, Lvl: 16, TTL: 64, Name: "anon", Key: key, ID: [2]byte{}},
+	} {
+		var gb bytes.Buffer
+		if _, err := in.WriteBinary(&gb, binarystruct.BigEndian); err != nil {
+			t.Fatal(err)
+		}
+		rt := rtRecord(in)
+		rb, err := ms.Marshal(&rt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(gb.Bytes(), rb) {
+			t.Errorf("%+v: generated % x, runtime % x", in, gb.Bytes(), rb)
+		}
+	}
+}
+`
+	genBytelenCase(t, "tmp_default", types, "Record", test)
+}
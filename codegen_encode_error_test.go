@@ -0,0 +1,62 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import "testing"
+
+// Generated encode must fail as the runtime does (see encode_error_test.go): an
+// *EncodeError with the same field, offsets and path, wrapping ErrValueOverflow
+// for a value that does not fit.
+func TestCodegenEncodeError(t *testing.T) {
+	types := `type Entry struct {
+	Tag  string ` + "`" + `binary:"string(2)"` + "`" + `
+	Name string ` + "`" + `binary:"bstring"` + "`" + `
+}
+
+type Section struct {
+	Count   uint8
+	Entries []Entry ` + "`" + `binary:"[Count]any"` + "`" + `
+	Ids     []uint16 ` + "`" + `binary:"[2]uint16"` + "`" + `
+}
+`
+	test := `import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+type rtEntry Entry
+
+type rtSection struct {
+	Count   uint8
+	Entries []rtEntry ` + "`" + `binary:"[Count]any"` + "`" + `
+	Ids     []uint16  ` + "`" + `binary:"[2]uint16"` + "`" + `
+}
+
+func TestSection(t *testing.T) {
+	for _, in := range []Section{
+		{Count: 2, Entries: []Entry{{"ab", "a"}, {"cd", strings.Repeat("x", 300)}}, Ids: []uint16{1, 2}},
+		{Count: 2, Entries: []Entry{{"abc", "a"}, {"cd", "b"}}, Ids: []uint16{1, 2}},
+		{Count: 1, Entries: []Entry{{"ab", "a"}}, Ids: []uint16{1, 2, 3}},
+	} {
+		_, gerr := in.WriteBinary(new(bytes.Buffer), binarystruct.BigEndian)
+		rt := rtSection{Count: in.Count, Ids: in.Ids}
+		for _, e := range in.Entries {
+			rt.Entries = append(rt.Entries, rtEntry(e))
+		}
+		_, rerr := binarystruct.NewMarshalerOrder(binarystruct.BigEndian).Marshal(&rt)
+		var g, r *binarystruct.EncodeError
+		if !errors.As(gerr, &g) || !errors.As(rerr, &r) || !errors.Is(gerr, binarystruct.ErrValueOverflow) || !errors.Is(rerr, binarystruct.ErrValueOverflow) {
+			t.Fatalf("%+v: generated err = %v, runtime err = %v", in, gerr, rerr)
+		}
+		if g.Field != r.Field || g.Offset != r.Offset || g.Path != r.Path || g.AbsOffset != r.AbsOffset {
+			t.Errorf("generated %+v, runtime %+v", g, r)
+		}
+	}
+}
+`
+	genBytelenCase(t, "tmp_encerr", types, "Entry,Section", test)
+}
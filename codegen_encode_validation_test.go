@@ -0,0 +1,82 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import "testing"
+
+// Under -validate-encode the generated write methods must reject what the
+// runtime rejects with Marshaler.ValidateOnEncode (see
+// encode_validation_test.go), naming the same field at the same offset.
+func TestCodegenEncodeValidation(t *testing.T) {
+	types := `type Record struct {
+	Magic uint16 ` + "`" + `binary:"uint16,const=0xcafe"` + "`" + `
+	Count uint16 ` + "`" + `binary:"uint16,range=1..100"` + "`" + `
+	Name  string ` + "`" + `binary:"bstring,match=^[a-z]+$"` + "`" + `
+	Kinds []uint8 ` + "`" + `binary:"[2]uint8,enum=1|2|5..7"` + "`" + `
+	Len   uint8 ` + "`" + `binary:"uint8,valueof=bytelen(Data)"` + "`" + `
+	Data  []byte ` + "`" + `binary:"[Len]byte,check=Len<=4"` + "`" + `
+	Level *int8 ` + "`" + `binary:"int8,omittable,range=-5..5"` + "`" + `
+}
+`
+	test := `import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+// rtRecord has no generated methods, so it encodes through the runtime
+// interpreter.
+type rtRecord struct {
+	Magic uint16 ` + "`" + `binary:"uint16,const=0xcafe"` + "`" + `
+	Count uint16 ` + "`" + `binary:"uint16,range=1..100"` + "`" + `
+	Name  string ` + "`" + `binary:"bstring,match=^[a-z]+$"` + "`" + `
+	Kinds []uint8 ` + "`" + `binary:"[2]uint8,enum=1|2|5..7"` + "`" + `
+	Len   uint8 ` + "`" + `binary:"uint8,valueof=bytelen(Data)"` + "`" + `
+	Data  []byte ` + "`" + `binary:"[Len]byte,check=Len<=4"` + "`" + `
+	Level *int8 ` + "`" + `binary:"int8,omittable,range=-5..5"` + "`" + `
+}
+
+func TestEncode(t *testing.T) {
+	lvl, bad := int8(-5), int8(9)
+	for i, edit := range []func(*Record){
+		func(r *Record) {},
+		func(r *Record) { r.Magic = 1; r.Level = &lvl },
+		func(r *Record) { r.Count = 0 },
+		func(r *Record) { r.Count = 101 },
+		func(r *Record) { r.Name = "Bad" },
+		func(r *Record) { r.Kinds[1] = 3 },
+		func(r *Record) { r.Data = make([]byte, 5) },
+		func(r *Record) { r.Len = 9 },
+		func(r *Record) { r.Level = &bad },
+	} {
+		in := Record{Count: 10, Name: "ok", Kinds: []uint8{1, 6}, Data: []byte{1, 2}}
+		edit(&in)
+		var gb bytes.Buffer
+		_, gerr := in.WriteBinary(&gb, binarystruct.BigEndian)
+		ms := binarystruct.NewMarshalerOrder(binarystruct.BigEndian)
+		ms.ValidateOnEncode = true
+		rt := rtRecord(in)
+		rb, rerr := ms.Marshal(&rt)
+		if (gerr == nil) != (rerr == nil) {
+			t.Fatalf("case %d: generated err = %v, runtime err = %v", i, gerr, rerr)
+		}
+		if rerr == nil {
+			if !bytes.Equal(gb.Bytes(), rb) {
+				t.Errorf("case %d: generated % x, runtime % x", i, gb.Bytes(), rb)
+			}
+			continue
+		}
+		var ge, re *binarystruct.EncodeError
+		if !errors.As(gerr, &ge) || !errors.As(rerr, &re) || !errors.Is(gerr, binarystruct.ErrValidationError) {
+			t.Fatalf("case %d: generated err = %v, runtime err = %v", i, gerr, rerr)
+		}
+		if ge.Field != re.Field || ge.Offset != re.Offset {
+			t.Errorf("case %d: generated %v, runtime %v", i, ge, re)
+		}
+	}
+}
+`
+	genBytelenCase(t, "tmp_encval", types, "Record", test, "-validate-encode")
+}
@@ -0,0 +1,92 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// endian=detect must choose the order from the magic in generated code as the
+// runtime does (see endian_detect_test.go).
+func TestCodegenEndianDetect(t *testing.T) {
+	types := `import "github.com/mixcode/binarystruct"
+
+type Pcap struct {
+	_       struct{}               ` + "`" + `binary:"endian=detect:Order"` + "`" + `
+	Order   binarystruct.ByteOrder ` + "`" + `binary:"-"` + "`" + `
+	Magic   uint32                 ` + "`" + `binary:"uint32,const=0xa1b2c3d4"` + "`" + `
+	Major   uint16
+	Minor   uint16
+	SnapLen uint32
+}
+`
+	test := `import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+func TestPcap(t *testing.T) {
+	be := []byte{0xa1, 0xb2, 0xc3, 0xd4, 0, 2, 0, 4, 0, 0, 0xff, 0xff}
+	le := []byte{0xd4, 0xc3, 0xb2, 0xa1, 2, 0, 4, 0, 0xff, 0xff, 0, 0}
+	for _, c := range []struct {
+		blob  []byte
+		order binarystruct.ByteOrder
+	}{{be, binarystruct.BigEndian}, {le, binarystruct.LittleEndian}} {
+		var h Pcap
+		if err := h.UnmarshalBinary(c.blob); err != nil {
+			t.Fatal(err)
+		}
+		want := Pcap{Order: c.order, Magic: 0xa1b2c3d4, Major: 2, Minor: 4, SnapLen: 0xffff}
+		if !reflect.DeepEqual(h, want) {
+			t.Fatalf("%v: got %+v, want %+v", c.order, h, want)
+		}
+		blob, err := h.MarshalBinary()
+		if err != nil || !bytes.Equal(blob, c.blob) {
+			t.Fatalf("%v: MarshalBinary = %x, %v; want %x", c.order, blob, err, c.blob)
+		}
+		// The runtime interpreter agrees.
+		var rt Pcap
+		if _, err := binarystruct.NewMarshaler().Unmarshal(c.blob, &rt); err != nil || !reflect.DeepEqual(rt, want) {
+			t.Fatalf("runtime Unmarshal = %+v, %v", rt, err)
+		}
+	}
+	var h Pcap
+	if err := h.UnmarshalBinary([]byte{1, 2, 3, 4, 0, 2, 0, 4, 0, 0, 0xff, 0xff}); !errors.Is(err, binarystruct.ErrValidationError) {
+		t.Fatalf("bad magic: err = %v", err)
+	}
+}
+`
+	genBytelenCase(t, "tmp_endiandetect", types, "Pcap", test)
+}
+
+// Without a record field the order read would be lost, so codegen rejects
+// the declaration as the runtime does.
+func TestCodegenEndianDetect_NoRecord_Errors(t *testing.T) {
+	t.Parallel()
+	src := "package p\n\ntype Rec struct {\n" +
+		"\t_     struct{} `binary:\"endian=detect\"`\n" +
+		"\tMagic uint16   `binary:\"uint16,const=0x1234\"`\n}\n"
+	tmpDir, err := os.MkdirTemp(".", "tmp-bs-detect-")
+	if err != nil {
+		t.Fatalf("temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	if err := os.WriteFile(filepath.Join(tmpDir, "t.go"), []byte(src), 0o644); err != nil {
+		t.Fatalf("write t.go: %v", err)
+	}
+	out, err := exec.Command(sharedCodegenBin, "-type", "Rec", "-endian", "big", tmpDir).CombinedOutput()
+	if err == nil {
+		t.Fatalf("expected a generation error for endian=detect without a record field; output:\n%s", out)
+	}
+	if !strings.Contains(string(out), "endian=detect:Field") {
+		t.Errorf("error should ask for a record field; got:\n%s", out)
+	}
+}
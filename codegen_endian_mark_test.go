@@ -0,0 +1,89 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import "testing"
+
+// A byte-order mark field must switch the order of the generated code's later
+// fields and nested structs as the runtime does (see endian_mark_test.go).
+func TestCodegenEndianMark(t *testing.T) {
+	types := `type Sub struct {
+	V uint16
+}
+
+type TIFF struct {
+	_     struct{} ` + "`" + `binary:"endian=Order:0x4949=little,0x4d4d=big"` + "`" + `
+	Order [2]byte
+	Magic uint16
+	IFD   uint32
+	Sub   Sub
+}
+
+type BOM struct {
+	_    struct{} ` + "`" + `binary:"endian=Mark"` + "`" + `
+	Mark uint16
+	A    uint16
+	B    uint16
+}
+`
+	test := `import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+func TestTIFF(t *testing.T) {
+	cases := []struct {
+		in   TIFF
+		want []byte
+	}{
+		{TIFF{Order: [2]byte{'I', 'I'}, Magic: 42, IFD: 8, Sub: Sub{V: 0x102}},
+			[]byte{'I', 'I', 42, 0, 8, 0, 0, 0, 2, 1}},
+		{TIFF{Order: [2]byte{'M', 'M'}, Magic: 42, IFD: 8, Sub: Sub{V: 0x102}},
+			[]byte{'M', 'M', 0, 42, 0, 0, 0, 8, 1, 2}},
+	}
+	for _, c := range cases {
+		blob, err := c.in.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(blob, c.want) {
+			t.Fatalf("%s: blob = %x, want %x", c.in.Order[:], blob, c.want)
+		}
+		var out TIFF
+		if err := out.UnmarshalBinary(blob); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(c.in, out) {
+			t.Fatalf("%s: round trip: got %+v, want %+v", c.in.Order[:], out, c.in)
+		}
+		// The runtime interpreter agrees.
+		var rt TIFF
+		if _, err := binarystruct.NewMarshaler().Unmarshal(blob, &rt); err != nil || !reflect.DeepEqual(rt, c.in) {
+			t.Fatalf("runtime Unmarshal = %+v, %v", rt, err)
+		}
+	}
+	var out TIFF
+	if err := out.UnmarshalBinary([]byte{'X', 'X', 0, 42, 0, 0, 0, 8, 1, 2}); err == nil || !strings.Contains(err.Error(), "matches no byte order") {
+		t.Fatalf("unknown mark: err = %v", err)
+	}
+}
+
+func TestBOM(t *testing.T) {
+	in := BOM{Mark: 0xfffe, A: 1, B: 2}
+	want := []byte{0xff, 0xfe, 1, 0, 2, 0}
+	blob, err := in.MarshalBinary()
+	if err != nil || !bytes.Equal(blob, want) {
+		t.Fatalf("MarshalBinary = %x, %v; want %x", blob, err, want)
+	}
+	var out BOM
+	if err := out.UnmarshalBinary(blob); err != nil || out != in {
+		t.Fatalf("UnmarshalBinary = %+v, %v", out, err)
+	}
+}
+`
+	genBytelenCase(t, "tmp_endianmark", types, "TIFF,Sub,BOM", test)
+}
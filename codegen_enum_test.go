@@ -0,0 +1,57 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import "testing"
+
+// Generated decode must validate enum= fields as the runtime does (see
+// enum_test.go), with the same error field and offset.
+func TestCodegenEnum(t *testing.T) {
+	types := `type Header struct {
+	Kind        uint8   ` + "`" + `binary:"uint8,enum=1|2|5..9"` + "`" + `
+	Compression uint16  ` + "`" + `binary:"uint16,enum=Compression,range=..8"` + "`" + `
+	Levels      [2]int8 ` + "`" + `binary:"[2]int8,enum=-1..3"` + "`" + `
+}
+`
+	test := `import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+func TestHeader(t *testing.T) {
+	ms := binarystruct.NewMarshalerOrder(binarystruct.BigEndian)
+	ms.AddEnum("Compression", map[uint64]string{0: "none", 8: "deflate", 12: "bzip2"})
+	var h Header
+	if _, err := h.ReadBinaryWithMarshaler(ms, bytes.NewReader([]byte{7, 0, 8, 0xff, 3}), binarystruct.BigEndian); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		blob []byte
+		msg  string
+	}{
+		{[]byte{3, 0, 8, 0, 0}, "value 3 is not in enum 1|2|5..9"},
+		{[]byte{1, 0, 9, 0, 0}, "value 9 is not in enum Compression"},
+		{[]byte{1, 0, 12, 0, 0}, "value bzip2(12) is out of range"},
+		{[]byte{1, 0, 0, 0, 0xfe}, "value -2 is not in enum -1..3"},
+	} {
+		_, gerr := new(Header).ReadBinaryWithMarshaler(ms, bytes.NewReader(c.blob), binarystruct.BigEndian)
+		_, rerr := ms.Unmarshal(c.blob, new(Header))
+		var gde, rde *binarystruct.DecodeError
+		if !errors.Is(gerr, binarystruct.ErrValidationError) || !errors.As(gerr, &gde) || !errors.As(rerr, &rde) {
+			t.Fatalf("% x: generated err = %v, runtime err = %v", c.blob, gerr, rerr)
+		}
+		if gde.Field != rde.Field || gde.Offset != rde.Offset || !strings.Contains(gerr.Error(), c.msg) {
+			t.Errorf("% x: generated %v, runtime %v", c.blob, gerr, rerr)
+		}
+	}
+	if err := h.UnmarshalBinary([]byte{1, 0, 8, 0, 0}); err == nil || !strings.Contains(err.Error(), "unknown enum") {
+		t.Fatalf("no Marshaler: err = %v", err)
+	}
+}
+`
+	genBytelenCase(t, "tmp_enum", types, "Header", test)
+}
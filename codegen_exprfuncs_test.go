@@ -0,0 +1,114 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// align, min, max, abs and sizeof in size, valueof and omittable expressions
+// must translate to Go that produces the same bytes as the runtime interpreter
+// (see expr_functions_test.go).
+func TestCodegenExprFunctions(t *testing.T) {
+	types := `type Entry struct {
+	ID   uint16
+	Kind uint8
+	Rsv  uint8
+}
+
+type Rec struct {
+	NameLen uint8   ` + "`" + `binary:"uint8,valueof=bytelen(Name)"` + "`" + `
+	Name    []byte  ` + "`" + `binary:"[NameLen]byte"` + "`" + `
+	Pad     []byte  ` + "`" + `binary:"[align(NameLen + 1, 4) - NameLen - 1]byte"` + "`" + `
+	Size    uint16  ` + "`" + `binary:"uint16,valueof=max(sizeof(Entry), 2)"` + "`" + `
+	Entries []Entry ` + "`" + `binary:"[min(Size / sizeof(Entry), 4)]"` + "`" + `
+	Label   string  ` + "`" + `binary:"string(align(NameLen, 4))"` + "`" + `
+	Opt     uint16  ` + "`" + `binary:"uint16,omittable=abs(NameLen - 3) * 16"` + "`" + `
+}
+`
+	test := `import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestRec(t *testing.T) {
+	in := Rec{Name: []byte("abcde"), Pad: []byte{0, 0}, Entries: []Entry{{ID: 9, Kind: 1}}, Label: "lbl", Opt: 0x1234}
+	blob, err := in.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{5, 'a', 'b', 'c', 'd', 'e', 0, 0, 0, 4, 0, 9, 1, 0, 'l', 'b', 'l', 0, 0, 0, 0, 0, 0x12, 0x34}
+	if !bytes.Equal(blob, want) {
+		t.Fatalf("blob = %x, want %x", blob, want)
+	}
+	var out Rec
+	if err := out.UnmarshalBinary(blob); err != nil {
+		t.Fatal(err)
+	}
+	in.NameLen, in.Size = 5, 4
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("round trip: got %+v, want %+v", out, in)
+	}
+}
+`
+	genBytelenCase(t, "tmp_exprfuncs", types, "Rec", test)
+}
+
+// align() with an alignment that is not positive fails as in the runtime.
+func TestCodegenExprFunctions_AlignZero(t *testing.T) {
+	fields := `	N    uint8
+	A    uint8
+	Data []byte ` + "`" + `binary:"[align(N, A)]byte"` + "`" + `
+}
+`
+	types := "type Aligned struct {\n" + fields
+	test := `import (
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+type rtAligned struct {
+` + fields + `
+func TestAligned(t *testing.T) {
+	_, gerr := binarystruct.Unmarshal([]byte{3, 0}, new(Aligned))
+	_, rerr := binarystruct.Unmarshal([]byte{3, 0}, new(rtAligned))
+	if gerr == nil || rerr == nil || gerr.Error() != rerr.Error() {
+		t.Errorf("generated err = %v, runtime err = %v", gerr, rerr)
+	}
+	_, gerr = binarystruct.Marshal(&Aligned{N: 3, Data: []byte{1, 2, 3}})
+	_, rerr = binarystruct.Marshal(&rtAligned{N: 3, Data: []byte{1, 2, 3}})
+	if gerr == nil || rerr == nil || gerr.Error() != rerr.Error() {
+		t.Errorf("generated err = %v, runtime err = %v", gerr, rerr)
+	}
+}
+`
+	genBytelenCase(t, "tmp_exprfuncs_align", types, "Aligned", test)
+}
+
+// sizeof() names a struct type the generated one uses, as in the runtime.
+func TestCodegenExprFunctions_SizeofUnused(t *testing.T) {
+	t.Parallel()
+	src := "package p\n\ntype Entry struct {\n\tID uint16\n}\n\ntype Rec struct {\n" +
+		"\tSize uint16 `binary:\"uint16,valueof=sizeof(Entry)\"`\n}\n"
+	tmpDir, err := os.MkdirTemp(".", "tmp-bs-sizeof-")
+	if err != nil {
+		t.Fatalf("temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	if err := os.WriteFile(filepath.Join(tmpDir, "t.go"), []byte(src), 0o644); err != nil {
+		t.Fatalf("write t.go: %v", err)
+	}
+	out, err := exec.Command(sharedCodegenBin, "-type", "Rec", "-endian", "big", tmpDir).CombinedOutput()
+	if err == nil {
+		t.Fatalf("expected a generation error for sizeof of an unused type; output:\n%s", out)
+	}
+	if !strings.Contains(string(out), "no struct type named Entry is used by Rec") {
+		t.Errorf("error should name the unused type; got:\n%s", out)
+	}
+}
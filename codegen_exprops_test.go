@@ -0,0 +1,126 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import "testing"
+
+// Bitwise, shift, modulo, comparison, logical and conditional operators in size,
+// valueof and omittable expressions must translate to Go that encodes and
+// decodes the same bytes as the runtime interpreter (see expr_operators_test.go).
+func TestCodegenExprOperators(t *testing.T) {
+	divFields := `	_ struct{} ` + "`" + `binary:"endian=big"` + "`" + `
+	N uint8
+	D uint8
+	S int8
+	Q []byte ` + "`" + `binary:"[N / D]byte"` + "`" + `
+	R []byte ` + "`" + `binary:"[N % D]byte"` + "`" + `
+	L []byte ` + "`" + `binary:"[N << S]byte"` + "`" + `
+}
+`
+	types := `type Rec struct {
+	Flags    uint8
+	Log2Size uint8
+	Count    uint8    ` + "`" + `binary:"uint8,valueof=Flags & 0x0F"` + "`" + `
+	Data     []byte   ` + "`" + `binary:"[1 << Log2Size]byte"` + "`" + `
+	Name     string   ` + "`" + `binary:"string(Flags >> 4)"` + "`" + `
+	Extra    []uint16 ` + "`" + `binary:"[Count % 3]uint16"` + "`" + `
+	Opt      uint32   ` + "`" + `binary:"uint32,omittable=Flags & 0x80 != 0 || Log2Size > 6 ? 0 : 1 << 16"` + "`" + `
+}
+
+type Div struct {
+` + divFields
+	test := `import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+type rtDiv struct {
+` + divFields + `
+// A zero divisor or a negative shift count fails as in the runtime, rather
+// than panicking.
+func TestDiv(t *testing.T) {
+	for _, blob := range [][]byte{{4, 0, 0}, {4, 3, -1 & 0xff, 1, 2, 3, 4, 5, 6}} {
+		_, gerr := binarystruct.Unmarshal(blob, new(Div))
+		_, rerr := binarystruct.Unmarshal(blob, new(rtDiv))
+		if gerr == nil || rerr == nil || gerr.Error() != rerr.Error() {
+			t.Errorf("% x: generated err = %v, runtime err = %v", blob, gerr, rerr)
+		}
+	}
+	for _, v := range []rtDiv{{N: 4}, {N: 4, D: 3, S: -1, Q: []byte{1}, R: []byte{1}}} {
+		_, gerr := binarystruct.Marshal(&Div{N: v.N, D: v.D, S: v.S, Q: v.Q, R: v.R})
+		_, rerr := binarystruct.Marshal(&v)
+		if gerr == nil || rerr == nil || gerr.Error() != rerr.Error() {
+			t.Errorf("%+v: generated err = %v, runtime err = %v", v, gerr, rerr)
+		}
+	}
+}
+
+func TestRec(t *testing.T) {
+	in := Rec{Flags: 0x25, Log2Size: 2, Data: []byte{1, 2, 3, 4}, Name: "hi", Extra: []uint16{7, 8}, Opt: 0xdeadbeef}
+	blob, err := in.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0x25, 0x02, 0x05, 1, 2, 3, 4, 'h', 'i', 0, 7, 0, 8, 0xde, 0xad, 0xbe, 0xef}
+	if !bytes.Equal(blob, want) {
+		t.Fatalf("blob = %x, want %x", blob, want)
+	}
+	var out Rec
+	if err := out.UnmarshalBinary(blob); err != nil {
+		t.Fatal(err)
+	}
+	in.Count = 5
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("round trip: got %+v, want %+v", out, in)
+	}
+
+	in.Flags = 0x85
+	in.Name = "12345678"
+	blob, err = in.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blob) != 3+4+8+4 {
+		t.Fatalf("blob = %x: Opt should have been omitted", blob)
+	}
+}
+`
+	genBytelenCase(t, "tmp_exprops", types, "Rec,Div", test)
+}
+
+// A failed operator in omittable= leaves the field in, as in the runtime.
+func TestCodegenExprOperatorsOmittable(t *testing.T) {
+	fields := `	_ struct{} ` + "`" + `binary:"endian=big"` + "`" + `
+	D uint8
+	V uint8 ` + "`" + `binary:"uint8,omittable=8 / D"` + "`" + `
+}
+`
+	types := "type Opt struct {\n" + fields
+	test := `import (
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+type rtOpt struct {
+` + fields + `
+func TestOpt(t *testing.T) {
+	var g Opt
+	var r rtOpt
+	gn, gerr := binarystruct.Unmarshal([]byte{0, 1}, &g)
+	rn, rerr := binarystruct.Unmarshal([]byte{0, 1}, &r)
+	if gerr != nil || rerr != nil || gn != rn || g.V != r.V || g.V != 1 {
+		t.Errorf("generated %+v (%d, %v), runtime %+v (%d, %v)", g, gn, gerr, r, rn, rerr)
+	}
+	gb, gerr := binarystruct.Marshal(&g)
+	rb, rerr := binarystruct.Marshal(&r)
+	if gerr != nil || rerr != nil || string(gb) != string(rb) {
+		t.Errorf("generated % x (%v), runtime % x (%v)", gb, gerr, rb, rerr)
+	}
+}
+`
+	genBytelenCase(t, "tmp_exprops_omit", types, "Opt", test)
+}
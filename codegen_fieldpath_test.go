@@ -0,0 +1,90 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import "testing"
+
+// Dotted and indexed field references in size, valueof and omittable expressions
+// must translate to Go selectors that produce the same bytes as the runtime
+// interpreter (see expr_fieldpath_test.go).
+func TestCodegenFieldPath(t *testing.T) {
+	types := `type Header struct {
+	Magic      uint16
+	PayloadLen uint16
+}
+
+type Rec struct {
+	Hdr     Header
+	Dims    [2]uint8
+	Data    []byte    ` + "`" + `binary:"[Hdr.PayloadLen]byte"` + "`" + `
+	Grid    []float32 ` + "`" + `binary:"[Dims[0] * Dims[1]]float32"` + "`" + `
+	Name    string    ` + "`" + `binary:"string(Dims[1])"` + "`" + `
+	Tail    uint8     ` + "`" + `binary:"uint8,omittable=Hdr.Magic"` + "`" + `
+	DataLen uint16    ` + "`" + `binary:"uint16,valueof=Hdr.PayloadLen + Dims[0]"` + "`" + `
+}
+`
+	test := `import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestRec(t *testing.T) {
+	in := Rec{Hdr: Header{Magic: 100, PayloadLen: 3}, Dims: [2]uint8{1, 2}, Data: []byte{7, 8, 9}, Grid: []float32{1.5, -2}, Name: "ab", Tail: 0xee}
+	blob, err := in.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0, 100, 0, 3, 1, 2, 7, 8, 9, 0x3f, 0xc0, 0, 0, 0xc0, 0, 0, 0, 'a', 'b', 0xee, 0, 4}
+	if !bytes.Equal(blob, want) {
+		t.Fatalf("blob = %x, want %x", blob, want)
+	}
+	var out Rec
+	if err := out.UnmarshalBinary(blob); err != nil {
+		t.Fatal(err)
+	}
+	in.DataLen = 4
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("round trip: got %+v, want %+v", out, in)
+	}
+}
+`
+	genBytelenCase(t, "tmp_fieldpath", types, "Rec", test)
+}
+
+// An index past the end of a slice fails as in the runtime rather than
+// panicking.
+func TestCodegenFieldPathIndexRange(t *testing.T) {
+	fields := `	N    uint8
+	Dims []uint8 ` + "`" + `binary:"[N]uint8"` + "`" + `
+	Data []byte  ` + "`" + `binary:"[Dims[1]]byte"` + "`" + `
+}
+`
+	types := "type Rec struct {\n" + fields
+	test := `import (
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+type rtRec struct {
+` + fields + `
+func TestRec(t *testing.T) {
+	_, gerr := binarystruct.Unmarshal([]byte{1, 5}, new(Rec))
+	_, rerr := binarystruct.Unmarshal([]byte{1, 5}, new(rtRec))
+	if gerr == nil || rerr == nil || gerr.Error() != rerr.Error() {
+		t.Errorf("decode: generated err = %v, runtime err = %v", gerr, rerr)
+	}
+	_, gerr = binarystruct.Marshal(&Rec{N: 1, Dims: []uint8{5}})
+	_, rerr = binarystruct.Marshal(&rtRec{N: 1, Dims: []uint8{5}})
+	if gerr == nil || rerr == nil || gerr.Error() != rerr.Error() {
+		t.Errorf("encode: generated err = %v, runtime err = %v", gerr, rerr)
+	}
+	var out Rec
+	if _, err := binarystruct.Unmarshal([]byte{2, 5, 1, 9}, &out); err != nil || len(out.Data) != 1 || out.Data[0] != 9 {
+		t.Errorf("in range: %+v, %v", out, err)
+	}
+}
+`
+	genBytelenCase(t, "tmp_fieldpath_range", types, "Rec", test)
+}
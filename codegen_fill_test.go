@@ -0,0 +1,88 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import "testing"
+
+// fill= and reserved encode and check a type with generated methods as they
+// do the runtime (see fill_test.go).
+func TestCodegenFill(t *testing.T) {
+	fields := `	_    struct{}    ` + "`" + `binary:"endian=big"` + "`" + `
+	A    uint8
+	Rsv  interface{} ` + "`" + `binary:"pad(3),fill=0xff,reserved"` + "`" + `
+	Name string      ` + "`" + `binary:"string(6),fill=0x20"` + "`" + `
+	Tag  string      ` + "`" + `binary:"bstring(4),fill=0xff"` + "`" + `
+	B    uint8
+}
+`
+	zfields := `	_   struct{} ` + "`" + `binary:"endian=big"` + "`" + `
+	N   uint8    ` + "`" + `binary:"uint8,valueof=bytelen(Z)"` + "`" + `
+	Z   string   ` + "`" + `binary:"zstring(6),fill=0x41"` + "`" + `
+	Z16 string   ` + "`" + `binary:"z16string(6),fill=0x41"` + "`" + `
+	X   uint8
+}
+`
+	types := "type Record struct {\n" + fields + "\ntype ZRecord struct {\n" + zfields
+	test := `import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+type rtRecord struct {
+` + fields + `
+type rtZRecord struct {
+` + zfields + `
+func TestFill(t *testing.T) {
+	ms := binarystruct.NewMarshaler()
+	gb, gerr := ms.Marshal(&Record{A: 1, Name: "FLASH", Tag: "ok", B: 2})
+	rb, rerr := ms.Marshal(&rtRecord{A: 1, Name: "FLASH", Tag: "ok", B: 2})
+	if gerr != nil || rerr != nil || !bytes.Equal(gb, rb) {
+		t.Fatalf("generated % x %v, runtime % x %v", gb, gerr, rb, rerr)
+	}
+	var g Record
+	if _, err := ms.Unmarshal(gb, &g); err != nil || g.Name != "FLASH" || g.Tag != "ok" || g.B != 2 {
+		t.Fatalf("generated decode: %+v, %v", g, err)
+	}
+	for off := 1; off < 4; off++ {
+		bad := append([]byte{}, gb...)
+		bad[off] = 0
+		for _, mode := range []binarystruct.ValidationMode{binarystruct.ValidateAll, binarystruct.ValidateNone, binarystruct.ValidateWarn} {
+			ms.Validation, ms.Warnings = mode, nil
+			_, gerr := ms.Unmarshal(bad, new(Record))
+			gw := len(ms.Warnings)
+			ms.Warnings = nil
+			_, rerr := ms.Unmarshal(bad, new(rtRecord))
+			if (gerr == nil) != (rerr == nil) || gw != len(ms.Warnings) {
+				t.Fatalf("byte %d, mode %v: generated %v (%d warnings), runtime %v (%v)", off, mode, gerr, gw, rerr, ms.Warnings)
+			}
+			if gerr == nil {
+				continue
+			}
+			var gd, rd *binarystruct.DecodeError
+			if !errors.As(gerr, &gd) || !errors.As(rerr, &rd) || gd.Field != rd.Field || gd.AbsOffset != rd.AbsOffset || !errors.Is(gerr, binarystruct.ErrValidationError) {
+				t.Errorf("byte %d: generated %v, runtime %v", off, gerr, rerr)
+			}
+		}
+	}
+	// The fill of a zstring(N) follows its terminator, and decoding consumes
+	// the whole buffer.
+	gb, gerr = binarystruct.Marshal(&ZRecord{Z: "e", Z16: "ef", X: 7})
+	rb, rerr = binarystruct.Marshal(&rtZRecord{Z: "e", Z16: "ef", X: 7})
+	want := []byte{6, 'e', 0, 'A', 'A', 'A', 'A', 'e', 'f', 0, 0, 'A', 'A', 7}
+	if gerr != nil || rerr != nil || !bytes.Equal(gb, want) || !bytes.Equal(rb, want) {
+		t.Fatalf("zstring: generated % x %v, runtime % x %v", gb, gerr, rb, rerr)
+	}
+	var gz ZRecord
+	var rz rtZRecord
+	gn, gerr := binarystruct.Unmarshal(want, &gz)
+	rn, rerr := binarystruct.Unmarshal(want, &rz)
+	if gerr != nil || rerr != nil || gn != len(want) || rn != len(want) || gz.Z != "e" || gz.Z16 != "ef" || gz.X != 7 || rz != rtZRecord(gz) {
+		t.Errorf("zstring: generated %d %+v %v, runtime %d %+v %v", gn, gz, gerr, rn, rz, rerr)
+	}
+}
+`
+	genBytelenCase(t, "tmp_fill", types, "Record,ZRecord", test)
+}
@@ -0,0 +1,85 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import "testing"
+
+// Generated methods must call the lifecycle hooks as the runtime does (see
+// hooks_test.go).
+func TestCodegenHooks(t *testing.T) {
+	types := `import "errors"
+
+type Span struct {
+	Start uint16
+	End   uint16
+	Len   int ` + "`" + `binary:"-"` + "`" + `
+}
+
+var errBadSpan = errors.New("end before start")
+
+func (s *Span) BeforeMarshalBinary() error {
+	if s.Start > s.End {
+		s.Start, s.End = s.End, s.Start
+	}
+	return nil
+}
+
+func (s *Span) ValidateBinary() error {
+	if s.End < s.Start {
+		return errBadSpan
+	}
+	return nil
+}
+
+func (s *Span) AfterUnmarshalBinary() error {
+	s.Len = int(s.End - s.Start)
+	return nil
+}
+
+type Opt struct {
+	A    uint8
+	Tail uint16 ` + "`" + `binary:"uint16,omittable"` + "`" + `
+	Seen bool   ` + "`" + `binary:"-"` + "`" + `
+}
+
+func (o *Opt) AfterUnmarshalBinary() error {
+	o.Seen = true
+	return nil
+}
+`
+	test := `import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+func TestSpan(t *testing.T) {
+	in := Span{Start: 9, End: 5}
+	blob, err := in.MarshalBinary()
+	if err != nil || !bytes.Equal(blob, []byte{0, 5, 0, 9}) {
+		t.Fatalf("MarshalBinary = %x, %v", blob, err)
+	}
+	var out Span
+	if err := out.UnmarshalBinary(blob); err != nil || out.Len != 4 {
+		t.Fatalf("UnmarshalBinary = %+v, %v", out, err)
+	}
+	// Without an enclosing field the hook's error is returned as is.
+	err = out.UnmarshalBinary([]byte{0, 9, 0, 5})
+	var de *binarystruct.DecodeError
+	if !errors.Is(err, errBadSpan) || errors.As(err, &de) || err.Error() != "ValidateBinary: end before start" {
+		t.Fatalf("invalid span: err = %v", err)
+	}
+}
+
+func TestOmitted(t *testing.T) {
+	// The hook runs when an omittable field ends the input too.
+	var out Opt
+	if err := out.UnmarshalBinary([]byte{1}); err != nil || !out.Seen {
+		t.Fatalf("UnmarshalBinary = %+v, %v", out, err)
+	}
+}
+`
+	genBytelenCase(t, "tmp_hooks", types, "Span,Opt", test)
+}
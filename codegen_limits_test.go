@@ -0,0 +1,123 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import "testing"
+
+// The generated read methods apply the Marshaler's limits as the runtime does
+// (see limits_test.go), and do not allocate ahead of a stream for a corrupt
+// length.
+func TestCodegenLimits(t *testing.T) {
+	types := `type Node struct {
+	N    uint8
+	Kids []Node ` + "`" + `binary:"[N]any"` + "`" + `
+}
+
+type Record struct {
+	Count uint32
+	Items []uint16 ` + "`" + `binary:"[Count]uint16"` + "`" + `
+	Small []int32  ` + "`" + `binary:"[Count]int8"` + "`" + `
+	Raw   []byte   ` + "`" + `binary:"[Count]byte"` + "`" + `
+	Names []string ` + "`" + `binary:"[Count]bstring"` + "`" + `
+	Name  string   ` + "`" + `binary:"dwstring"` + "`" + `
+	Tree  Node
+}
+`
+	test := `import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+// rtRecord and rtNode have no generated methods, so they go through the
+// runtime interpreter.
+type rtNode struct {
+	_    struct{} ` + "`" + `binary:"endian=big"` + "`" + `
+	N    uint8
+	Kids []rtNode ` + "`" + `binary:"[N]any"` + "`" + `
+}
+
+type rtRecord struct {
+	_     struct{} ` + "`" + `binary:"endian=big"` + "`" + `
+	Count uint32
+	Items []uint16 ` + "`" + `binary:"[Count]uint16"` + "`" + `
+	Small []int32  ` + "`" + `binary:"[Count]int8"` + "`" + `
+	Raw   []byte   ` + "`" + `binary:"[Count]byte"` + "`" + `
+	Names []string ` + "`" + `binary:"[Count]bstring"` + "`" + `
+	Name  string   ` + "`" + `binary:"dwstring"` + "`" + `
+	Tree  rtNode
+}
+
+type streamOnly struct{ io.Reader }
+
+var blob = []byte{
+	0, 0, 0, 2, // Count
+	0, 1, 0, 2, // Items
+	0xff, 3, // Small
+	'a', 'b', // Raw
+	1, 'x', 2, 'y', 'z', // Names
+	0, 0, 0, 4, 'n', 'a', 'm', 'e', // Name
+	1, 1, 0, // Tree, three levels
+}
+
+func TestLimits(t *testing.T) {
+	for i, set := range []func(*binarystruct.Marshaler){
+		func(ms *binarystruct.Marshaler) {},
+		func(ms *binarystruct.Marshaler) { ms.MaxSliceLen = 1 },
+		func(ms *binarystruct.Marshaler) { ms.MaxStringLen = 3 },
+		func(ms *binarystruct.Marshaler) { ms.MaxDecodeAlloc = 20 },
+		func(ms *binarystruct.Marshaler) { ms.MaxDepth = 3 },
+	} {
+		for _, stream := range []bool{false, true} {
+			var gr, rr io.Reader = bytes.NewReader(blob), bytes.NewReader(blob)
+			if stream {
+				gr, rr = streamOnly{gr}, streamOnly{rr}
+			}
+			gms, rms := binarystruct.NewMarshaler(), binarystruct.NewMarshaler()
+			set(gms)
+			set(rms)
+			var g Record
+			_, gerr := g.ReadBinaryWithMarshaler(gms, gr, binarystruct.BigEndian)
+			var r rtRecord
+			_, rerr := rms.Read(rr, &r)
+			if rerr == nil {
+				if gerr != nil || !reflect.DeepEqual(g.Small, r.Small) || !reflect.DeepEqual(g.Names, r.Names) || g.Name != r.Name || len(g.Tree.Kids[0].Kids) != 1 {
+					t.Errorf("case %d: generated %+v, %v; runtime %+v", i, g, gerr, r)
+				}
+				continue
+			}
+			if !errors.Is(gerr, binarystruct.ErrLimitExceeded) || !errors.Is(rerr, binarystruct.ErrLimitExceeded) {
+				t.Errorf("case %d: generated err = %v, runtime err = %v", i, gerr, rerr)
+			}
+		}
+	}
+}
+
+// A corrupt length fails at the end of the input without allocating what it
+// claims.
+func TestHostileLength(t *testing.T) {
+	for _, in := range [][]byte{
+		{0xff, 0xff, 0xff, 0xff, 0, 1},
+		{0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 'a', 'b'},
+	} {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		var g Record
+		_, err := g.ReadBinary(streamOnly{bytes.NewReader(in)}, binarystruct.BigEndian)
+		runtime.ReadMemStats(&after)
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("% x: err = %v", in, err)
+		}
+		if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 1<<20 {
+			t.Errorf("% x: allocated %d bytes", in, alloc)
+		}
+	}
+}
+`
+	genBytelenCase(t, "tmp_limits", types, "Record,Node", test)
+}
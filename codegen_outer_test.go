@@ -0,0 +1,122 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import "testing"
+
+// parent. and root. references in nested generated structs must resolve against
+// the enclosing structs exactly as the runtime interpreter resolves them (see
+// expr_outer_test.go).
+func TestCodegenOuterRefs(t *testing.T) {
+	types := `type Header struct {
+	Count uint8
+}
+
+type Entry struct {
+	Kind uint8
+	Data []byte ` + "`" + `binary:"[parent.EntrySize - 1]byte"` + "`" + `
+}
+
+type Sub struct {
+	Tag  uint8
+	Vals []uint16 ` + "`" + `binary:"[root.Hdr.Count]uint16"` + "`" + `
+	Note string   ` + "`" + `binary:"string(parent.parent.NoteLen)"` + "`" + `
+}
+
+type Mid struct {
+	Sub Sub
+}
+
+type Table struct {
+	Hdr       Header
+	EntrySize uint8
+	NoteLen   uint8
+	Entries   []Entry ` + "`" + `binary:"[2]"` + "`" + `
+	Mid       *Mid
+}
+`
+	test := `import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+func TestTable(t *testing.T) {
+	in := Table{
+		Hdr:       Header{Count: 2},
+		EntrySize: 3,
+		NoteLen:   4,
+		Entries:   []Entry{{Kind: 1, Data: []byte{0xa, 0xb}}, {Kind: 2, Data: []byte{0xc, 0xd}}},
+		Mid:       &Mid{Sub: Sub{Tag: 9, Vals: []uint16{0x102, 0x304}, Note: "hey"}},
+	}
+	want := []byte{2, 3, 4, 1, 0xa, 0xb, 2, 0xc, 0xd, 9, 1, 2, 3, 4, 'h', 'e', 'y', 0}
+	blob, err := in.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(blob, want) {
+		t.Fatalf("blob = %x, want %x", blob, want)
+	}
+	var out Table
+	if err := out.UnmarshalBinary(blob); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("round trip: got %+v, want %+v", out, in)
+	}
+
+	// The runtime interpreter dispatches to the generated methods and supplies
+	// the same context.
+	ms := binarystruct.NewMarshalerOrder(binarystruct.BigEndian)
+	blob, err = ms.Marshal(&in)
+	if err != nil || !bytes.Equal(blob, want) {
+		t.Fatalf("runtime Marshal = %x, %v; want %x", blob, err, want)
+	}
+
+	// Decoded on its own, an Entry has no parent.
+	var e Entry
+	if err := e.UnmarshalBinary([]byte{1, 2, 3}); err == nil {
+		t.Fatal("Entry without a parent: want error")
+	}
+}
+`
+	genBytelenCase(t, "tmp_outer", types, "Table,Entry,Sub,Mid,Header", test)
+}
+
+// Without an enclosing struct, root. is the struct itself: a read method must
+// see the fields it has decoded so far, as the runtime does, not their values
+// on entry.
+func TestCodegenOuterRefsSelfRoot(t *testing.T) {
+	fields := `	N    uint8
+	Vals []uint8 ` + "`" + `binary:"[root.N]uint8"` + "`" + `
+}
+`
+	types := "type Rec struct {\n" + fields
+	test := `import (
+	"reflect"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+type rtRec struct {
+` + fields + `
+func TestRec(t *testing.T) {
+	in := []byte{2, 1, 2}
+	var out Rec
+	var rt rtRec
+	if _, err := binarystruct.Unmarshal(in, &rt); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := binarystruct.Unmarshal(in, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out.Vals, rt.Vals) || len(out.Vals) != 2 {
+		t.Errorf("generated %+v, runtime %+v", out, rt)
+	}
+}
+`
+	genBytelenCase(t, "tmp_outer_self", types, "Rec", test)
+}
@@ -0,0 +1,82 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// padchar= and trim= encode and decode a type with generated methods as they
+// do the runtime (see fill_test.go).
+func TestCodegenPadchar(t *testing.T) {
+	fields := `	_    struct{} ` + "`" + `binary:"endian=big"` + "`" + `
+	Vol  string   ` + "`" + `binary:"string(8),padchar=0x20"` + "`" + `
+	Raw  string   ` + "`" + `binary:"string(4),padchar=0x20,trim=none"` + "`" + `
+	Num  string   ` + "`" + `binary:"string(6),padchar=0x20,trim=both"` + "`" + `
+	Wide string   ` + "`" + `binary:"string(8),padchar=0x20,encoding=utf16"` + "`" + `
+	Name string   ` + "`" + `binary:"bstring(5),padchar=0x2e,trim=both"` + "`" + `
+	Zero string   ` + "`" + `binary:"wstring(4),trim=none"` + "`" + `
+}
+`
+	types := "type Record struct {\n" + fields
+	test := `import (
+	"bytes"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+	"golang.org/x/text/encoding/unicode"
+)
+
+type rtRecord struct {
+` + fields + `
+func TestPadchar(t *testing.T) {
+	ms := binarystruct.NewMarshaler()
+	ms.AddTextEncoding("utf16", unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM))
+	gb, gerr := ms.Marshal(&Record{Vol: "CD_01", Raw: "ab", Num: "42", Wide: "ab", Name: "x", Zero: "a"})
+	rb, rerr := ms.Marshal(&rtRecord{Vol: "CD_01", Raw: "ab", Num: "42", Wide: "ab", Name: "x", Zero: "a"})
+	if gerr != nil || rerr != nil || !bytes.Equal(gb, rb) {
+		t.Fatalf("generated %q %v, runtime %q %v", gb, gerr, rb, rerr)
+	}
+	for _, blob := range [][]byte{
+		gb,
+		[]byte("CD 01   " + "ab\x00 " + " 42   " + "a\x00b\x00 \x00  " + "\x03..y.." + "\x00\x02a\x00\x00\x00"),
+		[]byte("        " + "\x00\x00\x00\x00" + "      " + "a\x00\x00\x00    " + "\x00....." + "\x00\x00\x00\x00\x00\x00"),
+	} {
+		var g Record
+		var r rtRecord
+		_, gerr := ms.Unmarshal(blob, &g)
+		_, rerr := ms.Unmarshal(blob, &r)
+		if gerr != nil || rerr != nil || g != Record(r) {
+			t.Errorf("%q: generated %q %v, runtime %q %v", blob, g, gerr, r, rerr)
+		}
+	}
+}
+`
+	genBytelenCase(t, "tmp_padchar", types, "Record", test)
+}
+
+// fill= and its alias padchar= together are a generation error, as they are a
+// tag error in the runtime.
+func TestCodegenPadcharWithFill(t *testing.T) {
+	t.Parallel()
+	src := "package p\n\ntype Rec struct {\n\tName string `binary:\"string(4),fill=0xff,padchar=0x20\"`\n}\n"
+	tmpDir, err := os.MkdirTemp(".", "tmp-bs-padchar-")
+	if err != nil {
+		t.Fatalf("temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	if err := os.WriteFile(filepath.Join(tmpDir, "t.go"), []byte(src), 0o644); err != nil {
+		t.Fatalf("write t.go: %v", err)
+	}
+	out, err := exec.Command(sharedCodegenBin, "-type", "Rec", "-endian", "big", tmpDir).CombinedOutput()
+	if err == nil {
+		t.Fatalf("expected a generation error for fill= with padchar=; output:\n%s", out)
+	}
+	if !strings.Contains(string(out), "fill= and padchar= set the same byte") {
+		t.Errorf("unexpected error:\n%s", out)
+	}
+}
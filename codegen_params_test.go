@@ -0,0 +1,111 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import "testing"
+
+// $name parameters, $offset and $remaining must resolve in generated code as
+// they do in the runtime interpreter (see expr_params_test.go).
+func TestCodegenParams(t *testing.T) {
+	types := `type Rec struct {
+	Kind  uint8
+	Name  string ` + "`" + `binary:"string($NameSize)"` + "`" + `
+	Gap   []byte ` + "`" + `binary:"[align($offset, 4) - $offset]byte"` + "`" + `
+	Value uint32
+	Rest  []byte ` + "`" + `binary:"[$remaining]byte"` + "`" + `
+}
+`
+	test := `import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+func TestRec(t *testing.T) {
+	in := Rec{Kind: 1, Name: "ab", Gap: []byte{0}, Value: 0x01020304, Rest: []byte("xyz")}
+	want := []byte{1, 'a', 'b', 0, 1, 2, 3, 4, 'x', 'y', 'z'}
+
+	ms := binarystruct.NewMarshalerOrder(binarystruct.BigEndian)
+	ms.SetParam("NameSize", 2)
+	var b bytes.Buffer
+	if _, err := in.WriteBinaryWithMarshaler(ms, &b, binarystruct.BigEndian); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.Bytes(), want) {
+		t.Fatalf("blob = %x, want %x", b.Bytes(), want)
+	}
+	var out Rec
+	n, err := out.ReadBinaryWithMarshaler(ms, bytes.NewReader(want), binarystruct.BigEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(want) || !reflect.DeepEqual(in, out) {
+		t.Fatalf("round trip: got %+v (%d bytes), want %+v", out, n, in)
+	}
+
+	// The runtime interpreter dispatches to the generated methods.
+	blob, err := ms.Marshal(&in)
+	if err != nil || !bytes.Equal(blob, want) {
+		t.Fatalf("runtime Marshal = %x, %v; want %x", blob, err, want)
+	}
+
+	// Without a Marshaler there are no parameters.
+	if _, err := in.MarshalBinary(); err == nil || !strings.Contains(err.Error(), "$NameSize") {
+		t.Fatalf("unset parameter: err = %v", err)
+	}
+}
+`
+	genBytelenCase(t, "tmp_params", types, "Rec", test)
+}
+
+// A $name parameter is looked up only where it is read: one in a branch of ?:,
+// && or || that is not evaluated need not be set, as in the runtime.
+func TestCodegenParamsShortCircuit(t *testing.T) {
+	fields := `	Kind  uint8
+	Extra []byte ` + "`" + `binary:"[Kind == 2 ? $ExtraLen : 0]byte"` + "`" + `
+	Opt   []byte ` + "`" + `binary:"[Kind == 2 && $HasOpt]byte"` + "`" + `
+	Tail  []byte ` + "`" + `binary:"[(Kind == 1 || $Tail) * 2]byte"` + "`" + `
+}
+`
+	types := "type Rec struct {\n" + fields
+	test := `import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+type rtRec struct {
+` + fields + `
+func TestRec(t *testing.T) {
+	in := []byte{1, 7, 8}
+	var out Rec
+	var rt rtRec
+	if _, err := binarystruct.Unmarshal(in, &rt); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := binarystruct.Unmarshal(in, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual([]byte(out.Tail), []byte(rt.Tail)) || len(out.Tail) != 2 {
+		t.Errorf("generated %+v, runtime %+v", out, rt)
+	}
+	blob, err := binarystruct.Marshal(&out)
+	if err != nil || !bytes.Equal(blob, in) {
+		t.Errorf("encode = %x, %v; want %x", blob, err, in)
+	}
+
+	// Where the parameter is read, it must be set.
+	_, gerr := binarystruct.Unmarshal([]byte{2}, new(Rec))
+	_, rerr := binarystruct.Unmarshal([]byte{2}, new(rtRec))
+	if gerr == nil || rerr == nil || gerr.Error() != rerr.Error() {
+		t.Errorf("unset: generated err = %v, runtime err = %v", gerr, rerr)
+	}
+}
+`
+	genBytelenCase(t, "tmp_params_short", types, "Rec", test)
+}
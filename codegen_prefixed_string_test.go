@@ -0,0 +1,45 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import "testing"
+
+// Generated decode of a length-prefixed string with a buffer size reads the
+// whole buffer, so the fields after it land where the runtime puts them.
+func TestCodegenPrefixedStringBuffer(t *testing.T) {
+	fields := `
+	_ struct{} ` + "`" + `binary:"endian=big"` + "`" + `
+	B string   ` + "`" + `binary:"bstring(4)"` + "`" + `
+	W string   ` + "`" + `binary:"wstring(4)"` + "`" + `
+	D string   ` + "`" + `binary:"dwstring(6)"` + "`" + `
+	N uint16
+`
+	types := "type Rec struct {" + fields + "}\n"
+	test := `import (
+	"bytes"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+type rtRec struct {` + fields + `}
+
+func TestPrefixedStringBuffer(t *testing.T) {
+	in := Rec{B: "ab", W: "c", D: "", N: 0x1234}
+	blob, err := binarystruct.Marshal(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rblob, err := binarystruct.Marshal(&rtRec{B: in.B, W: in.W, D: in.D, N: in.N})
+	if err != nil || !bytes.Equal(blob, rblob) {
+		t.Fatalf("generated % x, runtime % x, err %v", blob, rblob, err)
+	}
+	var out Rec
+	n, err := binarystruct.Unmarshal(blob, &out)
+	if err != nil || n != len(blob) || out != in {
+		t.Fatalf("decoded %+v (%d of %d bytes), err %v", out, n, len(blob), err)
+	}
+}
+`
+	genBytelenCase(t, "tmp_prefixed_string", types, "Rec", test)
+}
@@ -0,0 +1,74 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import "testing"
+
+// A Decoder resyncs on a type with generated methods as it does on the runtime
+// (see decoder_test.go): the generated const check names its field.
+func TestCodegenResync(t *testing.T) {
+	types := `type Record struct {
+	_     struct{} ` + "`" + `binary:"endian=big"` + "`" + `
+	Magic uint16   ` + "`" + `binary:"uint16,const=0xa55a"` + "`" + `
+	Len   uint8    ` + "`" + `binary:"uint8,valueof=bytelen(Data)"` + "`" + `
+	Data  []byte   ` + "`" + `binary:"[Len]byte"` + "`" + `
+	CRC   uint32   ` + "`" + `binary:"uint32,valueof=CRC32(Data)"` + "`" + `
+}
+`
+	test := `import (
+	"bytes"
+	"hash/crc32"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+type rtRecord struct {
+	_     struct{} ` + "`" + `binary:"endian=big"` + "`" + `
+	Magic uint16   ` + "`" + `binary:"uint16,const=0xa55a"` + "`" + `
+	Len   uint8    ` + "`" + `binary:"uint8,valueof=bytelen(Data)"` + "`" + `
+	Data  []byte   ` + "`" + `binary:"[Len]byte"` + "`" + `
+	CRC   uint32   ` + "`" + `binary:"uint32,valueof=CRC32(Data)"` + "`" + `
+}
+
+func TestResync(t *testing.T) {
+	ms := binarystruct.NewMarshaler()
+	ms.AddValueOf("CRC32", func(c binarystruct.ValueOfContext) (uint64, error) {
+		return uint64(crc32.ChecksumIEEE(c.Args[0].Bytes)), nil
+	})
+	rec := func(data string) []byte {
+		b, err := ms.Marshal(&rtRecord{Data: []byte(data)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	bad := rec("x")
+	bad[len(bad)-1]++
+	var stream []byte
+	for _, b := range [][]byte{rec("one"), {'g', 0xa5}, bad, rec("two"), {0xa5, 0x5a, 9}} {
+		stream = append(stream, b...)
+	}
+	for _, verify := range []bool{false, true} {
+		gd, rd := ms.NewDecoder(bytes.NewReader(stream)), ms.NewDecoder(bytes.NewReader(stream))
+		gd.VerifyResync, rd.VerifyResync = verify, verify
+		for i := 0; i < 8; i++ {
+			var g Record
+			var r rtRecord
+			gerr, rerr := gd.Decode(&g), rd.Decode(&r)
+			if (gerr == nil) != (rerr == nil) || gerr == nil && string(g.Data) != string(r.Data) {
+				t.Fatalf("verify %v, step %d: generated %v, runtime %v", verify, i, gerr, rerr)
+			}
+			if gerr != nil {
+				gs, gerr := gd.Resync(&g)
+				rs, rerr := rd.Resync(&r)
+				if gs != rs || gerr != rerr {
+					t.Errorf("verify %v, step %d: generated %v %v, runtime %v %v", verify, i, gs, gerr, rs, rerr)
+				}
+			}
+		}
+	}
+}
+`
+	genBytelenCase(t, "tmp_resync", types, "Record", test)
+}
@@ -0,0 +1,85 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import "testing"
+
+// Under Salvage a type with generated methods keeps the fields decoded before a
+// failure, and reports them, as the runtime does (see salvage_test.go).
+func TestCodegenSalvage(t *testing.T) {
+	types := `type Hdr struct {
+	Magic uint32
+	Len   uint16 ` + "`" + `binary:"uint16,range=0..100"` + "`" + `
+}
+
+type Record struct {
+	_     struct{} ` + "`" + `binary:"endian=big"` + "`" + `
+	Hdr   Hdr
+	Count uint8
+	Items []uint16 ` + "`" + `binary:"[Count]uint16"` + "`" + `
+	Name  string   ` + "`" + `binary:"zstring"` + "`" + `
+}
+`
+	test := `import (
+	"bytes"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+type rtHdr struct {
+	Magic uint32
+	Len   uint16 ` + "`" + `binary:"uint16,range=0..100"` + "`" + `
+}
+
+type rtRecord struct {
+	_     struct{} ` + "`" + `binary:"endian=big"` + "`" + `
+	Hdr   rtHdr
+	Count uint8
+	Items []uint16 ` + "`" + `binary:"[Count]uint16"` + "`" + `
+	Name  string   ` + "`" + `binary:"zstring"` + "`" + `
+}
+
+func TestSalvage(t *testing.T) {
+	blob := []byte{0xca, 0xfe, 0xba, 0xbe, 0, 7, 2, 0, 1, 0, 2, 'o', 'k', 0}
+	bad := append(blob[:4:4], 1, 0)
+	for _, in := range [][]byte{blob[:2], blob[:5], bad, blob[:9], blob[:12], blob} {
+		ms := binarystruct.NewMarshaler()
+		ms.Salvage = true
+		g := Record{Hdr: Hdr{9, 9}, Count: 9, Items: []uint16{9}, Name: "old"}
+		r := rtRecord{Hdr: rtHdr{9, 9}, Count: 9, Items: []uint16{9}, Name: "old"}
+		_, gerr := ms.Unmarshal(in, &g)
+		_, rerr := ms.Unmarshal(in, &r)
+		if (gerr == nil) != (rerr == nil) {
+			t.Fatalf("% x: generated err = %v, runtime err = %v", in, gerr, rerr)
+		}
+		if gerr != nil {
+			var gs, rs *binarystruct.SalvageError
+			if !errors.As(gerr, &gs) || !errors.As(rerr, &rs) || !slices.Equal(gs.Decoded, rs.Decoded) {
+				t.Errorf("% x: generated err = %v, runtime err = %v", in, gerr, rerr)
+			}
+		}
+		if g.Hdr != Hdr(r.Hdr) || g.Count != r.Count || !reflect.DeepEqual(g.Items, r.Items) || g.Name != r.Name {
+			t.Errorf("% x: generated %+v, runtime %+v", in, g, r)
+		}
+
+		// The generated read method called directly salvages the same.
+		d := Record{Hdr: Hdr{9, 9}, Count: 9, Items: []uint16{9}, Name: "old"}
+		_, derr := d.ReadBinaryWithMarshaler(ms, bytes.NewReader(in), nil)
+		if (derr == nil) != (gerr == nil) || !reflect.DeepEqual(d, g) {
+			t.Errorf("% x: direct %+v (%v), Unmarshal %+v (%v)", in, d, derr, g, gerr)
+		}
+		if derr != nil {
+			var ds, gs *binarystruct.SalvageError
+			if !errors.As(derr, &ds) || !errors.As(gerr, &gs) || !slices.Equal(ds.Decoded, gs.Decoded) {
+				t.Errorf("% x: direct err = %v, Unmarshal err = %v", in, derr, gerr)
+			}
+		}
+	}
+}
+`
+	genBytelenCase(t, "tmp_salvage", types, "Record,Hdr", test)
+}
@@ -0,0 +1,139 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import "testing"
+
+// Under -strict the generated methods must reject what the runtime rejects
+// with Marshaler.Strict (see strict_test.go), and decode what it decodes.
+func TestCodegenStrict(t *testing.T) {
+	types := `type Record struct {
+	Level int      ` + "`" + `binary:"uint8"` + "`" + `
+	Flags int      ` + "`" + `binary:"byte"` + "`" + `
+	Scale float64  ` + "`" + `binary:"int16"` + "`" + `
+	Count int8     ` + "`" + `binary:"float32"` + "`" + `
+	Items []uint16 ` + "`" + `binary:"[2]float64"` + "`" + `
+	Delta int      ` + "`" + `binary:"int8"` + "`" + `
+	Name  string   ` + "`" + `binary:"zstring(4)"` + "`" + `
+	Label string   ` + "`" + `binary:"string(4)"` + "`" + `
+}
+`
+	test := `import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+// rtRecord has no generated methods, so it goes through the runtime
+// interpreter.
+type rtRecord struct {
+	Level int      ` + "`" + `binary:"uint8"` + "`" + `
+	Flags int      ` + "`" + `binary:"byte"` + "`" + `
+	Scale float64  ` + "`" + `binary:"int16"` + "`" + `
+	Count int8     ` + "`" + `binary:"float32"` + "`" + `
+	Items []uint16 ` + "`" + `binary:"[2]float64"` + "`" + `
+	Delta int      ` + "`" + `binary:"int8"` + "`" + `
+	Name  string   ` + "`" + `binary:"zstring(4)"` + "`" + `
+	Label string   ` + "`" + `binary:"string(4)"` + "`" + `
+}
+
+func strictMarshaler() *binarystruct.Marshaler {
+	ms := binarystruct.NewMarshalerOrder(binarystruct.BigEndian)
+	ms.Strict = true
+	return ms
+}
+
+func good() Record {
+	return Record{Level: 200, Flags: -1, Scale: -3, Count: 5, Items: []uint16{1, 2}, Delta: -2, Name: "abc", Label: "ab"}
+}
+
+func TestEncode(t *testing.T) {
+	for i, edit := range []func(*Record){
+		func(r *Record) {},
+		func(r *Record) { r.Level = 300 },
+		func(r *Record) { r.Level = -1 },
+		func(r *Record) { r.Flags = 256 },
+		func(r *Record) { r.Flags = -129 },
+		func(r *Record) { r.Scale = 1.5 },
+		func(r *Record) { r.Scale = 40000 },
+		func(r *Record) { r.Delta = 128 },
+		func(r *Record) { r.Name = "abcd" },
+		func(r *Record) { r.Name = "abcde" },
+		func(r *Record) { r.Label = "abcde" },
+	} {
+		in := good()
+		edit(&in)
+		var gb bytes.Buffer
+		_, gerr := in.WriteBinary(&gb, binarystruct.BigEndian)
+		rt := rtRecord(in)
+		rb, rerr := strictMarshaler().Marshal(&rt)
+		if rerr == nil {
+			if gerr != nil || !bytes.Equal(gb.Bytes(), rb) {
+				t.Errorf("case %d: generated % x, %v; runtime % x", i, gb.Bytes(), gerr, rb)
+			}
+			continue
+		}
+		var ge, re *binarystruct.EncodeError
+		if !errors.As(gerr, &ge) || !errors.As(rerr, &re) || !errors.Is(gerr, binarystruct.ErrValueOverflow) {
+			t.Fatalf("case %d: generated err = %v, runtime err = %v", i, gerr, rerr)
+		}
+		if ge.Field != re.Field || ge.Offset != re.Offset || ge.Err.Error() != re.Err.Error() {
+			t.Errorf("case %d: generated %v, runtime %v", i, ge, re)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	in := good()
+	blob, err := in.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(off int, b ...byte) []byte {
+		out := append([]byte{}, blob...)
+		copy(out[off:], b)
+		return out
+	}
+	// Count is at 4, Items at 8 and 16, Delta at 24.
+	for i, c := range [][]byte{
+		blob,
+		at(4, 0x3f, 0xc0, 0, 0),   // 1.5
+		at(4, 0x43, 0x80, 0, 0),   // 256
+		at(16, 0xbf, 0xf0),        // -1
+		at(16, 0x40, 0xf0),        // 65536
+		at(24, 0x80),              // -128
+	} {
+		var g Record
+		_, gerr := g.ReadBinary(bytes.NewReader(c), binarystruct.BigEndian)
+		var r rtRecord
+		_, rerr := strictMarshaler().Unmarshal(c, &r)
+		if rerr == nil {
+			if gerr != nil || g.Count != r.Count || g.Delta != r.Delta || g.Items[1] != r.Items[1] {
+				t.Errorf("case %d: generated %+v, %v; runtime %+v", i, g, gerr, r)
+			}
+			continue
+		}
+		var ge, re *binarystruct.DecodeError
+		if !errors.As(gerr, &ge) || !errors.As(rerr, &re) || !errors.Is(gerr, binarystruct.ErrValueOverflow) {
+			t.Fatalf("case %d: generated err = %v, runtime err = %v", i, gerr, rerr)
+		}
+		if ge.Field != re.Field || ge.Offset != re.Offset {
+			t.Errorf("case %d: generated %v, runtime %v", i, ge, re)
+		}
+	}
+
+	var g Record
+	if err := g.UnmarshalBinary(append(blob, 0)); !errors.Is(err, binarystruct.ErrTrailingData) {
+		t.Errorf("trailing data: err = %v", err)
+	}
+	// Strict only adds checks: a negative int8 decodes into an int as the
+	// runtime decodes it, without sign extension.
+	if err := g.UnmarshalBinary(blob); err != nil || g.Delta != 254 {
+		t.Errorf("exact input: %+v, err = %v", g, err)
+	}
+}
+`
+	genBytelenCase(t, "tmp_strict", types, "Record", test, "-strict")
+}
@@ -0,0 +1,61 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import "testing"
+
+// TryUnmarshal reports a prefix of a type with generated methods as it does for
+// the runtime (see needmore_test.go), and leaves the value alone.
+func TestCodegenTryUnmarshal(t *testing.T) {
+	types := `type Frame struct {
+	_    struct{} ` + "`" + `binary:"endian=big"` + "`" + `
+	Len  uint16
+	Body []byte  ` + "`" + `binary:"[Len]byte"` + "`" + `
+	Tags []int16 ` + "`" + `binary:"[2]int8"` + "`" + `
+	Name string  ` + "`" + `binary:"zstring"` + "`" + `
+}
+`
+	test := `import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+
+type rtFrame struct {
+	_    struct{} ` + "`" + `binary:"endian=big"` + "`" + `
+	Len  uint16
+	Body []byte  ` + "`" + `binary:"[Len]byte"` + "`" + `
+	Tags []int16 ` + "`" + `binary:"[2]int8"` + "`" + `
+	Name string  ` + "`" + `binary:"zstring"` + "`" + `
+}
+
+func TestTryUnmarshal(t *testing.T) {
+	frame := []byte{0, 3, 'a', 'b', 'c', 0xff, 2, 'x', 0}
+	orig := Frame{Len: 1, Body: []byte{7}, Name: "old"}
+	for k := 0; k < len(frame); k++ {
+		v := orig
+		v.Body = append([]byte{}, orig.Body...)
+		_, err := binarystruct.TryUnmarshal(frame[:k], &v)
+		var rt rtFrame
+		_, rerr := binarystruct.TryUnmarshal(frame[:k], &rt)
+		var nm, rnm *binarystruct.ErrNeedMore
+		if !errors.As(err, &nm) || !errors.As(rerr, &rnm) || nm.Min != rnm.Min || k+nm.Min > len(frame) || !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("prefix %d: generated err = %v, runtime err = %v", k, err, rerr)
+		}
+		if !reflect.DeepEqual(v, orig) {
+			t.Errorf("prefix %d: value changed to %+v", k, v)
+		}
+	}
+	var v Frame
+	n, err := binarystruct.TryUnmarshal(append(frame, 1, 2), &v)
+	want := Frame{Len: 3, Body: []byte("abc"), Tags: []int16{255, 2}, Name: "x"}
+	if n != len(frame) || err != nil || !reflect.DeepEqual(v, want) {
+		t.Errorf("n = %d, err = %v, %+v", n, err, v)
+	}
+}
+`
+	genBytelenCase(t, "tmp_tryunmarshal", types, "Frame", test)
+}
@@ -0,0 +1,104 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct_test
+
+import "testing"
+
+// Generated decode must honor Marshaler.Validation as the runtime does (see
+// validation_mode_test.go): the same checks skipped, the same warnings, the
+// same failures collected.
+func TestCodegenValidationMode(t *testing.T) {
+	types := `type Record struct {
+	Magic uint16 ` + "`" + `binary:"uint16,const=0xcafe"` + "`" + `
+	Kind  uint8  ` + "`" + `binary:"uint8,range=1..3"` + "`" + `
+	Name  string ` + "`" + `binary:"string(2),match=^[a-z]+$"` + "`" + `
+	Flags uint8  ` + "`" + `binary:"uint8,check=Kind!=2||Flags==0"` + "`" + `
+}
+
+type Span struct {
+	Total  uint8
+	Offset uint8
+	Size   uint8 ` + "`" + `binary:"uint8,range=0..8,check=Offset+Size<=Total"` + "`" + `
+}
+
+` + cvChunkSrc
+	test := `import (
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"io"
+	"testing"
+
+	"github.com/mixcode/binarystruct"
+)
+` + cvHelperSrc + `
+// rtRecord and rtChunk have no generated methods, so they decode through the
+// runtime interpreter.
+type rtRecord struct {
+	Magic uint16 ` + "`" + `binary:"uint16,const=0xcafe"` + "`" + `
+	Kind  uint8  ` + "`" + `binary:"uint8,range=1..3"` + "`" + `
+	Name  string ` + "`" + `binary:"string(2),match=^[a-z]+$"` + "`" + `
+	Flags uint8  ` + "`" + `binary:"uint8,check=Kind!=2||Flags==0"` + "`" + `
+}
+
+type rtChunk Chunk
+
+type rtSpan Span
+
+type decoded interface {
+	ReadBinaryWithMarshaler(*binarystruct.Marshaler, io.Reader, binarystruct.ByteOrder) (int, error)
+}
+
+// failures counts the errors joined under ValidateCollect.
+func failures(err error) int {
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		return len(j.Unwrap())
+	}
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+func TestModes(t *testing.T) {
+	chunk, err := crcMarshaler().Marshal(&Chunk{Type: "IHDR", Data: []byte{1, 2, 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk[len(chunk)-1] ^= 0xff
+	bad := []byte{0xbe, 0xef, 2, 'O', 'K', 1}
+	for _, mode := range []binarystruct.ValidationMode{binarystruct.ValidateAll, binarystruct.ValidateNone, binarystruct.ValidateSkipChecksums, binarystruct.ValidateWarn, binarystruct.ValidateCollect} {
+		for _, c := range []struct {
+			blob []byte
+			gen  decoded
+			rt   interface{}
+		}{
+			{bad, new(Record), new(rtRecord)},
+			{chunk, new(Chunk), new(rtChunk)},
+			{[]byte{10, 5, 9}, new(Span), new(rtSpan)},
+		} {
+			gms, rms := crcMarshaler(), crcMarshaler()
+			gms.Validation, rms.Validation = mode, mode
+			rms.Order = binarystruct.BigEndian
+			_, gerr := c.gen.ReadBinaryWithMarshaler(gms, bytes.NewReader(c.blob), binarystruct.BigEndian)
+			_, rerr := rms.Read(bytes.NewReader(c.blob), c.rt)
+			if (gerr == nil) != (rerr == nil) || errors.Is(gerr, binarystruct.ErrValidationError) != errors.Is(rerr, binarystruct.ErrValidationError) {
+				t.Fatalf("mode %d, % x: generated err = %v, runtime err = %v", mode, c.blob, gerr, rerr)
+			}
+			if failures(gerr) != failures(rerr) {
+				t.Fatalf("mode %d, % x: generated err = %v, runtime err = %v", mode, c.blob, gerr, rerr)
+			}
+			if len(gms.Warnings) != len(rms.Warnings) {
+				t.Fatalf("mode %d, % x: generated warnings %v, runtime %v", mode, c.blob, gms.Warnings, rms.Warnings)
+			}
+			for i, w := range gms.Warnings {
+				if w.Field != rms.Warnings[i].Field || w.Offset != rms.Warnings[i].Offset {
+					t.Errorf("mode %d: generated warning %v, runtime %v", mode, w, rms.Warnings[i])
+				}
+			}
+		}
+	}
+}
+`
+	genBytelenCase(t, "tmp_valmode", types, "Record,Chunk,Span", test)
+}
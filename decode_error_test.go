@@ -0,0 +1,104 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"errors"
+	"testing"
+)
+
+// A DecodeError describes the innermost field that failed: its path, offsets,
+// type and tag, and for a failed check the expected and actual values.
+
+type deEntry struct {
+	Kind uint8  `binary:"uint8,enum=1|2|5..9"`
+	Size uint16 `binary:"uint16,range=1..100"`
+	Name string `binary:"string(2),match=^[a-z]+$"`
+}
+
+type deSection struct {
+	Magic   [2]byte `binary:"[2]byte,const=0xcafe"`
+	Count   uint8
+	Entries []deEntry `binary:"[Count]any"`
+}
+
+type deFile struct {
+	_        struct{} `binary:"endian=big"`
+	Version  uint16   `binary:"uint16,const=2"`
+	Sections [2]deSection
+}
+
+func TestDecodeError_Context(t *testing.T) {
+	entry := []byte{1, 0, 10, 'a', 'b'}
+	section := append([]byte{0xca, 0xfe, 2}, append(append([]byte{}, entry...), entry...)...)
+	good := append(append([]byte{0, 2}, section...), section...)
+	if _, err := Unmarshal(good, &deFile{}); err != nil {
+		t.Fatal(err)
+	}
+	at := func(off int, b ...byte) []byte {
+		blob := append([]byte{}, good...)
+		copy(blob[off:], b)
+		return blob
+	}
+	// The second section starts at 2+13=15, its second entry at 15+3+5=23.
+	cases := []struct {
+		blob                          []byte
+		path                          string
+		abs, field                    int
+		goType, tag, expected, actual string
+	}{
+		{at(0, 0, 3), "Version", 0, 0, "uint16", "uint16,const=2", "0x2", "0x3"},
+		{at(15, 0xbe, 0xef), "Sections[1].Magic", 15, 0, "[2]uint8", "[2]byte,const=0xcafe", "0xcafe", "0xbeef"},
+		{at(23, 3), "Sections[1].Entries[1].Kind", 23, 0, "uint8", "uint8,enum=1|2|5..9", "1|2|5..9", "3"},
+		{at(24, 1, 0), "Sections[1].Entries[1].Size", 24, 1, "uint16", "uint16,range=1..100", "1..100", "256"},
+		{at(26, 'A'), "Sections[1].Entries[1].Name", 26, 3, "string", "string(2),match=^[a-z]+$", "^[a-z]+$", "Ab"},
+	}
+	for _, c := range cases {
+		_, err := Unmarshal(c.blob, &deFile{})
+		var de *DecodeError
+		if !errors.As(err, &de) || !errors.Is(err, ErrValidationError) {
+			t.Fatalf("%s: err = %v", c.path, err)
+		}
+		if de.Path != c.path || de.AbsOffset != c.abs || de.FieldOffset != c.field || de.Type != c.goType || de.Tag != c.tag {
+			t.Errorf("%s: got path %s, offsets %d/%d, type %s, tag %q", c.path, de.Path, de.AbsOffset, de.FieldOffset, de.Type, de.Tag)
+		}
+		if de.Expected != c.expected || de.Actual != c.actual {
+			t.Errorf("%s: expected %q, actual %q", c.path, de.Expected, de.Actual)
+		}
+	}
+
+	// An error other than a failed check has no Expected or Actual.
+	_, err := Unmarshal(good[:20], &deFile{})
+	var de *DecodeError
+	if !errors.As(err, &de) || de.Path != "Sections[1].Entries[0].Size" || de.AbsOffset != 19 || de.Expected != "" {
+		t.Errorf("truncated: err = %v, %+v", err, de)
+	}
+}
+
+// Custom valueof mismatches and check= rules fill Expected and Actual too.
+func TestDecodeError_ChecksumAndRule(t *testing.T) {
+	blob, err := newCRCMarshaler().Marshal(&crcChunk{Type: "IHDR", Data: []byte{1, 2, 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want crcChunk
+	if _, err := newCRCMarshaler().Unmarshal(blob, &want); err != nil {
+		t.Fatal(err)
+	}
+	blob[len(blob)-1] ^= 0xff
+	var got crcChunk
+	_, err = newCRCMarshaler().Unmarshal(blob, &got)
+	var de *DecodeError
+	if !errors.As(err, &de) || de.Path != "CRC" || de.Expected == "" || de.Expected == de.Actual {
+		t.Fatalf("checksum: err = %v, %+v", err, de)
+	}
+
+	type rule struct {
+		Kind  uint8
+		Flags uint8 `binary:"uint8,check=Kind!=2||Flags==0"`
+	}
+	_, err = Unmarshal([]byte{2, 1}, &rule{})
+	if !errors.As(err, &de) || de.Path != "Flags" || de.Expected != "Kind!=2||Flags==0" || de.Actual != "" {
+		t.Errorf("check: err = %v, %+v", err, de)
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+)
+
+// decoderChunk is the least a Decoder reads from its stream at a time.
+const decoderChunk = 4096
+
+// A Decoder reads a sequence of values from a stream, such as the records of a
+// log file or a serial capture, and can skip a corrupt one with Resync.
+type Decoder struct {
+	// VerifyResync makes Resync stop only where a whole value decodes, its
+	// checks and custom valueof checksums verified, instead of where its
+	// leading const fields match.
+	VerifyResync bool
+
+	ms  *Marshaler
+	r   io.Reader
+	buf []byte // input read from r and not consumed yet
+	off int    // the offset of buf in the stream
+	err error  // the error r returned, once it has
+}
+
+// SkippedRange is a range of a Decoder's stream that Resync passed over: the
+// bytes from Start up to End, offsets from the start of the stream.
+type SkippedRange struct {
+	Start, End int
+}
+
+// NewDecoder returns a Decoder reading r; see Marshaler.NewDecoder.
+func NewDecoder(r io.Reader) *Decoder {
+	return NewMarshaler().NewDecoder(r)
+}
+
+// Marshaler.NewDecoder() returns a Decoder that reads values from r with the
+// Marshaler's settings. The Decoder reads ahead of the values it decodes.
+func (ms *Marshaler) NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{ms: ms, r: r}
+}
+
+// Offset returns the offset in the stream of the next value to be decoded.
+func (d *Decoder) Offset() int {
+	return d.off
+}
+
+// Decode decodes the next value of the stream into govalue, as Marshaler.Read
+// does. It returns io.EOF at the end of the stream. When the decode fails the
+// Decoder stays at the start of the value, so that Resync can skip it.
+func (d *Decoder) Decode(govalue interface{}) error {
+	r := &decoderReader{d: d}
+	_, err := d.ms.Read(r, govalue)
+	if err != nil {
+		return err
+	}
+	d.consume(r.pos)
+	return nil
+}
+
+// Resync skips a corrupt value: it scans the stream from the byte after the
+// Decoder's offset for the next offset where a value of govalue's type, a
+// pointer to a struct that begins with const fields, starts, and moves the
+// Decoder there. A candidate offset is one where those leading const fields
+// decode, or, under VerifyResync, where the whole value does; govalue is not
+// modified. It returns the range skipped, which runs to the end of the stream
+// with io.EOF when no value is found, or with the stream's error when reading
+// it fails.
+//
+// Only an offset that begins with the leading const fields as the Marshaler
+// encodes them is trial-decoded, so most of a corrupt stretch is skipped by a
+// byte comparison. Resync reads as much of the stream as its trial decodes do;
+// set the Marshaler's decode limits when a corrupt length could claim a lot of
+// it.
+func (d *Decoder) Resync(govalue interface{}) (SkippedRange, error) {
+	v := reflect.ValueOf(govalue)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return SkippedRange{}, fmt.Errorf("Resync needs a pointer to a struct, not %T", govalue)
+	}
+	leading, magicLen, err := leadingConsts(v.Elem().Type())
+	if err != nil {
+		return SkippedRange{}, err
+	}
+
+	magics := d.leadingBytes(v.Elem(), magicLen)
+
+	ms := d.ms
+	validation, salvage, warnings := ms.Validation, ms.Salvage, ms.Warnings
+	ms.Validation, ms.Salvage = ValidateAll, false
+	defer func() {
+		ms.Validation, ms.Salvage, ms.Warnings = validation, salvage, warnings
+	}()
+
+	// Each offset rejected is consumed, so that the buffer holds only the
+	// candidate and the bytes read ahead of it.
+	start := d.off
+	for {
+		if len(d.buf) == 0 && d.fill(1) != nil {
+			return SkippedRange{start, d.off}, d.err
+		}
+		d.consume(1)
+		if len(d.buf) == 0 && d.fill(1) != nil {
+			return SkippedRange{start, d.off}, d.err
+		}
+		if magics != nil {
+			// Only an offset that begins with the leading consts is worth a
+			// trial decode. A stream too short for them matches none, and
+			// the scan runs on to its end.
+			if len(d.buf) < magicLen {
+				d.fill(magicLen - len(d.buf))
+			}
+			if !slices.ContainsFunc(magics, func(m []byte) bool { return bytes.HasPrefix(d.buf, m) }) {
+				continue
+			}
+		}
+		scratch := reflect.New(v.Elem().Type())
+		cloneInto(scratch.Elem(), v.Elem(), make(map[clonedPtr]reflect.Value))
+		r := &decoderReader{d: d}
+		_, err := ms.Read(r, scratch.Interface())
+		if err == nil || !d.VerifyResync && r.pos >= magicLen && !failedIn(err, leading) {
+			return SkippedRange{start, d.off}, nil
+		}
+	}
+}
+
+// leadingConsts returns the names of the const fields a struct of type t
+// begins with, and their encoded size.
+func leadingConsts(t reflect.Type) (names []string, size int, err error) {
+	meta, err := getStructMetadata(t)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, f := range meta.fields {
+		if f.ignore || f.unexported {
+			continue
+		}
+		if !f.hasConst {
+			break
+		}
+		sz, err := staticFieldSize(t.Field(f.index).Type, f)
+		if err != nil {
+			return nil, 0, err
+		}
+		names = append(names, f.name)
+		size += sz
+	}
+	if len(names) == 0 {
+		return nil, 0, fmt.Errorf("%s does not begin with a const field to resync on", t)
+	}
+	return names, size, nil
+}
+
+// leadingBytes returns the leading const fields of v, a struct, as encoded by
+// the Decoder's Marshaler: the first size bytes of any value of its type, in
+// each byte order an endian=detect struct may be read in. It returns nil when
+// they cannot be encoded, and Resync then trial-decodes at every offset.
+func (d *Decoder) leadingBytes(v reflect.Value, size int) [][]byte {
+	meta, err := getStructMetadata(v.Type())
+	if err != nil {
+		return nil
+	}
+	orders := []ByteOrder{nil}
+	if meta.orderDetect != nil {
+		orders = []ByteOrder{BigEndian, LittleEndian}
+	}
+	var magics [][]byte
+	for _, order := range orders {
+		scratch := reflect.New(v.Type())
+		cloneInto(scratch.Elem(), v, make(map[clonedPtr]reflect.Value))
+		if order != nil {
+			// Encode in the order as if it had been detected.
+			scratch.Elem().Field(meta.orderDetect.recordIndex).Set(reflect.ValueOf(&order).Elem())
+		}
+		w := &prefixWriter{size: size}
+		d.ms.Write(w, scratch.Interface())
+		if len(w.b) < size {
+			return nil
+		}
+		magics = append(magics, w.b)
+	}
+	return magics
+}
+
+// prefixWriter keeps the first size bytes written to it, and fails the writes
+// after them.
+type prefixWriter struct {
+	b    []byte
+	size int
+}
+
+var errPrefixFull = errors.New("prefix written")
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	n := min(len(p), w.size-len(w.b))
+	w.b = append(w.b, p[:n]...)
+	if n < len(p) {
+		return n, errPrefixFull
+	}
+	return n, nil
+}
+
+// failedIn reports whether err is the failure of one of the fields named.
+func failedIn(err error, fields []string) bool {
+	var de *DecodeError
+	return errors.As(err, &de) && slices.Contains(fields, de.Field)
+}
+
+// fill reads at least want more bytes of the stream into d.buf, unless the
+// stream ends or fails first, returning its error if it read none.
+func (d *Decoder) fill(want int) error {
+	for added := 0; added < want; {
+		if d.err != nil {
+			if added > 0 {
+				return nil
+			}
+			return d.err
+		}
+		l := len(d.buf)
+		d.buf = slices.Grow(d.buf, max(want-added, decoderChunk))
+		var n int
+		n, d.err = io.ReadAtLeast(d.r, d.buf[l:cap(d.buf)], 1)
+		d.buf = d.buf[:l+n]
+		added += n
+	}
+	return nil
+}
+
+// consume drops the first n bytes of d.buf.
+func (d *Decoder) consume(n int) {
+	d.buf = d.buf[n:]
+	d.off += n
+}
+
+// decoderReader reads a Decoder's stream from pos in its buffer, filling the
+// buffer from the stream as it goes, so that the Decoder can return to
+// where a read started.
+type decoderReader struct {
+	d   *Decoder
+	pos int
+}
+
+func (r *decoderReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if r.pos >= len(r.d.buf) {
+		if err := r.d.fill(len(p)); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.d.buf[r.pos:])
+	r.pos += n
+	return n, nil
+}
+
+func (r *decoderReader) ReadByte() (byte, error) {
+	if r.pos >= len(r.d.buf) {
+		if err := r.d.fill(1); err != nil {
+			return 0, err
+		}
+	}
+	r.pos++
+	return r.d.buf[r.pos-1], nil
+}
@@ -0,0 +1,162 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"testing/iotest"
+)
+
+type syncRecord struct {
+	_     struct{} `binary:"endian=big"`
+	Magic uint16   `binary:"uint16,const=0xa55a"`
+	Len   uint8    `binary:"uint8,valueof=bytelen(Data)"`
+	Data  []byte   `binary:"[Len]byte"`
+	CRC   uint32   `binary:"uint32,valueof=CRC32(Data)"`
+}
+
+func TestDecoder_Resync(t *testing.T) {
+	ms := newCRCMarshaler()
+	rec := func(data string) []byte {
+		b, err := ms.Marshal(&syncRecord{Data: []byte(data)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	bad := rec("x")
+	bad[len(bad)-1]++ // a magic followed by a record failing its checksum
+	var stream []byte
+	stream = append(stream, rec("one")...)  // 0..10
+	stream = append(stream, 'g', 'a', 0xa5) // 10..13
+	stream = append(stream, bad...)         // 13..21
+	stream = append(stream, rec("two")...)  // 21..31
+	stream = append(stream, 0xa5, 0x5a, 9)  // 31..34, truncated
+
+	type step struct {
+		data    string // the record Decode returns, or "" if it fails
+		skipped SkippedRange
+		err     error // Resync's error
+	}
+	for _, c := range []struct {
+		verify bool
+		steps  []step
+	}{
+		{false, []step{{"one", SkippedRange{}, nil}, {"", SkippedRange{10, 13}, nil}, {"", SkippedRange{13, 21}, nil}, {"two", SkippedRange{}, nil}, {"", SkippedRange{31, 34}, io.EOF}}},
+		{true, []step{{"one", SkippedRange{}, nil}, {"", SkippedRange{10, 21}, nil}, {"two", SkippedRange{}, nil}, {"", SkippedRange{31, 34}, io.EOF}}},
+	} {
+		d := ms.NewDecoder(iotest.OneByteReader(bytes.NewReader(stream)))
+		d.VerifyResync = c.verify
+		for i, s := range c.steps {
+			var v syncRecord
+			err := d.Decode(&v)
+			if s.data != "" {
+				if err != nil || string(v.Data) != s.data {
+					t.Errorf("verify %v, step %d: %+v, err = %v", c.verify, i, v, err)
+				}
+				continue
+			}
+			if err == nil {
+				t.Errorf("verify %v, step %d: decoded %+v", c.verify, i, v)
+				continue
+			}
+			start, before := d.Offset(), v
+			skipped, err := d.Resync(&v)
+			if skipped != s.skipped || err != s.err || skipped.Start != start || d.Offset() != skipped.End || !reflect.DeepEqual(v, before) {
+				t.Errorf("verify %v, step %d: skipped %v to %d, err = %v", c.verify, i, skipped, d.Offset(), err)
+			}
+		}
+		if err := d.Decode(&syncRecord{}); err != io.EOF {
+			t.Errorf("verify %v: at the end, err = %v", c.verify, err)
+		}
+	}
+
+	type noMagic struct {
+		_ struct{} `binary:"endian=big"`
+		A uint8
+	}
+	if _, err := NewDecoder(bytes.NewReader(stream)).Resync(&noMagic{}); err == nil {
+		t.Error("resync on a struct without a const field")
+	}
+
+	// The stream's own error ends a Resync.
+	failing := errors.New("disk failure")
+	d := ms.NewDecoder(io.MultiReader(bytes.NewReader([]byte{1, 2, 3}), iotest.ErrReader(failing)))
+	if skipped, err := d.Resync(&syncRecord{}); err != failing || skipped != (SkippedRange{0, 3}) {
+		t.Errorf("skipped %v, err = %v", skipped, err)
+	}
+}
+
+// Resync trial-decodes only where the leading consts, as the Marshaler encodes
+// them, begin; an endian=detect magic matches in either order.
+func TestDecoder_ResyncLeadingBytes(t *testing.T) {
+	ms := newCRCMarshaler()
+	d := ms.NewDecoder(bytes.NewReader(nil))
+	if got := d.leadingBytes(reflect.ValueOf(syncRecord{}), 2); !reflect.DeepEqual(got, [][]byte{{0xa5, 0x5a}}) {
+		t.Errorf("syncRecord: %x", got)
+	}
+
+	type detected struct {
+		_     struct{}  `binary:"endian=detect:Order"`
+		Order ByteOrder `binary:"-"`
+		Magic uint32    `binary:"uint32,const=0xa1b2c3d4"`
+		Ver   uint16    `binary:"uint16,const=2"`
+		N     uint16
+	}
+	want := [][]byte{{0xa1, 0xb2, 0xc3, 0xd4, 0, 2}, {0xd4, 0xc3, 0xb2, 0xa1, 2, 0}}
+	if got := d.leadingBytes(reflect.ValueOf(detected{}), 6); !reflect.DeepEqual(got, want) {
+		t.Errorf("detected: %x, want %x", got, want)
+	}
+
+	stream := []byte{0xd4, 0xc3, 0xb2, 0xa1, 2, 0, 0xa1, 0xb2, 0xc3, 0xd4, 0, 2, 0, 7}
+	d = NewDecoder(bytes.NewReader(stream))
+	var v detected
+	if skipped, err := d.Resync(&v); err != nil || skipped != (SkippedRange{0, 6}) {
+		t.Fatalf("skipped %v, err = %v", skipped, err)
+	}
+	if err := d.Decode(&v); err != nil || v.N != 7 {
+		t.Errorf("decoded %+v, err = %v", v, err)
+	}
+}
+
+// bufWatcher reads r, noting the most input d has buffered at any read.
+type bufWatcher struct {
+	r   io.Reader
+	d   *Decoder
+	max int
+}
+
+func (w *bufWatcher) Read(p []byte) (int, error) {
+	w.max = max(w.max, len(w.d.buf))
+	return w.r.Read(p)
+}
+
+// Resync consumes the offsets it rejects, so skipping a long corrupt stretch
+// buffers little more than a read's worth of it.
+func TestDecoder_ResyncBuffer(t *testing.T) {
+	ms := newCRCMarshaler()
+	rec, err := ms.Marshal(&syncRecord{Data: []byte("one")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := append(make([]byte, 1<<20), rec...)
+	for _, verify := range []bool{false, true} {
+		w := &bufWatcher{r: bytes.NewReader(stream)}
+		d := ms.NewDecoder(w)
+		w.d, d.VerifyResync = d, verify
+		var v syncRecord
+		if skipped, err := d.Resync(&v); err != nil || skipped != (SkippedRange{0, 1 << 20}) {
+			t.Fatalf("verify %v: skipped %v, err = %v", verify, skipped, err)
+		}
+		if err := d.Decode(&v); err != nil || string(v.Data) != "one" {
+			t.Errorf("verify %v: decoded %+v, err = %v", verify, v, err)
+		}
+		if w.max > 2*decoderChunk {
+			t.Errorf("verify %v: buffered %d bytes", verify, w.max)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// parseDefault parses the value of a `default=` option into a value of the
+// field's Go type t: an integer literal (decimal/hex/octal/binary), a
+// floating-point number, true or false, a string's text as written, or a hex
+// blob such as 0x0a0b for a []byte or [N]byte field.
+func parseDefault(s string, t reflect.Type) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 0, 64)
+		if err != nil || v.OverflowInt(i) {
+			return v, fmt.Errorf("invalid default %q for %s", s, t)
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(s, 0, 64)
+		if err != nil || v.OverflowUint(u) {
+			return v, fmt.Errorf("invalid default %q for %s", s, t)
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) || v.OverflowFloat(f) {
+			return v, fmt.Errorf("invalid default %q for %s; it must be a finite number", s, t)
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return v, fmt.Errorf("invalid default %q for %s", s, t)
+		}
+		v.SetBool(b)
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() != reflect.Uint8 {
+			return v, fmt.Errorf("default is not supported on type %s", t)
+		}
+		b, err := parseConstHexBytes(s)
+		if err != nil {
+			return v, err
+		}
+		if t.Kind() == reflect.Array {
+			if len(b) != t.Len() {
+				return v, fmt.Errorf("default %q is %d bytes; %s needs %d", s, len(b), t, t.Len())
+			}
+			reflect.Copy(v, reflect.ValueOf(b))
+		} else {
+			v = reflect.ValueOf(b).Convert(t)
+		}
+	default:
+		return v, fmt.Errorf("default is not supported on type %s", t)
+	}
+	return v, nil
+}
+
+// applyDefaults sets each field with a default= to its default before a
+// decode, so a field the input omits keeps it while a decoded field replaces it.
+func (m *structMetadata) applyDefaults(strc reflect.Value) {
+	for _, f := range m.fields {
+		if !f.defaultValue.IsValid() {
+			continue
+		}
+		v := f.defaultValue
+		if v.Kind() == reflect.Slice {
+			// a fresh copy, so the decoded value does not alias the metadata's
+			v = reflect.ValueOf(append([]byte{}, v.Bytes()...)).Convert(v.Type())
+		}
+		strc.Field(f.index).Set(v)
+	}
+}
+
+// omitDefaultsFrom returns the index in m.fields from which an encode stops:
+// the start of the trailing run of omitdefault fields equal to their defaults,
+// or len(m.fields) when the last field is written.
+func (m *structMetadata) omitDefaultsFrom(strc reflect.Value) int {
+	from := len(m.fields)
+	for i := len(m.fields) - 1; i >= 0; i-- {
+		f := &m.fields[i]
+		if f.ignore || f.unexported {
+			continue
+		}
+		if !f.omitDefault || !isDefault(strc.Field(f.index), f.defaultValue) {
+			break
+		}
+		from = i
+	}
+	return from
+}
+
+// isDefault reports whether a field's value equals its default.
+func isDefault(v, def reflect.Value) bool {
+	if v.Kind() == reflect.Slice {
+		return bytes.Equal(v.Bytes(), def.Bytes())
+	}
+	return v.Interface() == def.Interface()
+}
@@ -0,0 +1,133 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// default= gives a field the input omits a value; omitdefault drops trailing
+// fields equal to it on encode.
+
+type defaultRecord struct {
+	_       struct{} `binary:"endian=big"`
+	Version uint8
+	Scale   float32 `binary:"float32,omittable,default=1.5"`
+	TTL     uint8   `binary:"uint8,omittable,default=64,omitdefault"`
+	Name    string  `binary:"bstring,omittable,default=anon,omitdefault"`
+	Key     []byte  `binary:"[2]byte,omittable,default=0xcafe,omitdefault"`
+}
+
+func TestDefault_Decode(t *testing.T) {
+	var out defaultRecord
+	if _, err := Unmarshal([]byte{1}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Scale != 1.5 || out.TTL != 64 || out.Name != "anon" || !bytes.Equal(out.Key, []byte{0xca, 0xfe}) {
+		t.Fatalf("decoded %+v", out)
+	}
+	// The decoded value must not share the default's bytes.
+	out.Key[0] = 0
+	var again defaultRecord
+	if _, err := Unmarshal([]byte{1, 0x3f, 0x80, 0, 0, 5}, &again); err != nil {
+		t.Fatal(err)
+	}
+	if again.Scale != 1 || again.TTL != 5 || again.Name != "anon" || again.Key[0] != 0xca {
+		t.Fatalf("decoded %+v", again)
+	}
+}
+
+func TestDefault_OmitDefault(t *testing.T) {
+	scale := []byte{0x3f, 0xc0, 0, 0} // 1.5
+	cases := []struct {
+		in   defaultRecord
+		want []byte
+	}{
+		{defaultRecord{Version: 1, Scale: 1.5, TTL: 64, Name: "anon", Key: []byte{0xca, 0xfe}}, append([]byte{1}, scale...)},
+		{defaultRecord{Version: 1, Scale: 1.5, TTL: 5, Name: "anon", Key: []byte{0xca, 0xfe}}, append(append([]byte{1}, scale...), 5)},
+		{defaultRecord{Version: 1, Scale: 1.5, TTL: 64, Name: "x", Key: []byte{0xca, 0xfe}}, append(append([]byte{1}, scale...), 64, 1, 'x')},
+		{defaultRecord{Version: 1, Scale: 1.5, TTL: 64, Name: "anon", Key: []byte{1, 2}}, append(append([]byte{1}, scale...), 64, 4, 'a', 'n', 'o', 'n', 1, 2)},
+	}
+	for i, c := range cases {
+		got, err := Marshal(&c.in)
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		if !bytes.Equal(got, c.want) {
+			t.Errorf("case %d: got % x, want % x", i, got, c.want)
+		}
+		var back defaultRecord
+		if _, err := Unmarshal(got, &back); err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		if back.TTL != c.in.TTL || back.Name != c.in.Name || !bytes.Equal(back.Key, c.in.Key) {
+			t.Errorf("case %d: round trip %+v", i, back)
+		}
+	}
+}
+
+func TestDefault_Inspect(t *testing.T) {
+	layout, err := Inspect(&defaultRecord{Version: 1, Scale: 1.5, TTL: 5, Name: "anon", Key: []byte{0xca, 0xfe}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"Name": "omitted (equal to its default)",
+		"Key":  "omitted (subsequent to an omitted field)",
+	}
+	for _, f := range layout.Fields {
+		if d, ok := want[f.Name]; ok && (f.Details != d || f.Size != 0) {
+			t.Errorf("%s: Details = %q, Size = %d", f.Name, f.Details, f.Size)
+		}
+	}
+	if layout.TotalSize != 6 {
+		t.Errorf("TotalSize = %d, want 6", layout.TotalSize)
+	}
+}
+
+// A default= value is kept verbatim after the first '='.
+func TestDefault_Verbatim(t *testing.T) {
+	type eq struct {
+		V uint8
+		S string `binary:"bstring,omittable,default=a = b"`
+	}
+	var out eq
+	if _, err := Unmarshal([]byte{1}, &out); err != nil || out.S != "a = b" {
+		t.Fatalf("decoded %+v, err = %v", out, err)
+	}
+}
+
+func TestDefault_MetadataErrors(t *testing.T) {
+	type noDefault struct {
+		V uint8 `binary:"uint8,omittable,omitdefault"`
+	}
+	type notOmittable struct {
+		V uint8 `binary:"uint8,default=1,omitdefault"`
+	}
+	type pointer struct {
+		V *uint8 `binary:"uint8,omittable,default=1"`
+	}
+	type overflow struct {
+		V uint8 `binary:"uint8,omittable,default=300"`
+	}
+	type short struct {
+		V [4]byte `binary:"[4]byte,omittable,default=0xcafe"`
+	}
+	cases := []struct {
+		v   interface{}
+		msg string
+	}{
+		{&noDefault{}, "omitdefault requires a default"},
+		{&notOmittable{}, "requires a plain omittable field"},
+		{&pointer{}, "pointer or interface"},
+		{&overflow{}, `invalid default "300"`},
+		{&short{}, "is 2 bytes"},
+	}
+	for _, c := range cases {
+		if _, err := Unmarshal([]byte{1}, c.v); err == nil || !strings.Contains(err.Error(), c.msg) {
+			t.Errorf("%T: err = %v, want %q", c.v, err, c.msg)
+		}
+	}
+}
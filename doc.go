@@ -60,6 +60,8 @@ Example:
   - codec=NAME: Applies a registered Codec for custom encoding.
   - omittable: Suppresses EOF errors at this field's start.
   - omittable=Expr: Skips the field if byte size limits are reached.
+  - default=Value: The value an omitted field takes on decode instead of its zero value.
+  - omitdefault: (encode) Drops trailing omittable fields equal to their defaults.
   - range=min..max: Performs range validation check on integers and floats.
   - match=pattern: Performs regex match validation check on string fields.
   - valueof=Expr: (encode-only) Auto-computes an integer field's serialized value from other fields via bytelen()/count() and arithmetic. Emit-only: the Go field is not modified. See "Computed Field Values" below.
@@ -177,6 +179,15 @@ If "omittable=Expression" is specified, the field is omitted if the current byte
 		Extra2    *uint32 `binary:"uint32,omittable=TotalSize"`
 	}
 
+## Default Values
+
+An omitted field is left at its zero value unless it declares "default=Value": an integer, a floating-point number, true or false, a string's text, or a hex blob for a []byte or [N]byte field. With "omitdefault" on a plain omittable field, Marshal does not write a trailing run of such fields that equal their defaults.
+
+	type Options struct {
+		Version uint8
+		TTL     uint8 `binary:"uint8,omittable,default=64,omitdefault"`
+	}
+
 # JSON Layout Export
 
 The compiled struct layout metadata can be exported as a formatted JSON document by calling ToJSON on the StructLayout:
@@ -0,0 +1,114 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// An encode failure is an *EncodeError naming the field, with the path and
+// output offset of the innermost field that failed.
+
+type eeEntry struct {
+	Tag  string `binary:"string(2)"`
+	Name string `binary:"bstring"`
+}
+
+type eeSection struct {
+	Count   uint8
+	Entries []eeEntry `binary:"[Count]any"`
+}
+
+type eeFile struct {
+	_        struct{} `binary:"endian=big"`
+	Version  uint16
+	Sections [2]eeSection
+}
+
+func TestEncodeError_Path(t *testing.T) {
+	entries := func() []eeEntry { return []eeEntry{{"ab", "a"}, {"cd", "b"}} }
+	in := eeFile{Version: 1, Sections: [2]eeSection{{2, entries()}, {2, entries()}}}
+	if _, err := Marshal(&in); err != nil {
+		t.Fatal(err)
+	}
+	// Each entry is 4 bytes and each section 9: the second section starts at 11,
+	// its second entry at 16 and that entry's Name at 18.
+	in.Sections[1].Entries[1].Name = strings.Repeat("x", 300)
+	_, err := Marshal(&in)
+	var ee *EncodeError
+	if !errors.As(err, &ee) || !errors.Is(err, ErrValueOverflow) {
+		t.Fatalf("err = %v", err)
+	}
+	if ee.Field != "Sections" || ee.Offset != 2 || ee.Path != "Sections[1].Entries[1].Name" || ee.AbsOffset != 18 {
+		t.Errorf("got %+v", ee)
+	}
+	if !strings.Contains(err.Error(), "array index [1]") || !strings.Contains(err.Error(), "string too long: len 300, max 255") {
+		t.Errorf("message %q", err)
+	}
+
+	in.Sections[1].Entries[1].Name = "b"
+	in.Sections[0].Entries[0].Tag = "abc"
+	_, err = Marshal(&in)
+	if !errors.As(err, &ee) || !errors.Is(err, ErrValueOverflow) || ee.Path != "Sections[0].Entries[0].Tag" || ee.AbsOffset != 3 {
+		t.Errorf("err = %v, %+v", err, ee)
+	}
+}
+
+type eeRejecting struct {
+	V uint8
+}
+
+func (r *eeRejecting) BeforeMarshalBinary() error {
+	return errBadSpan
+}
+
+func TestEncodeError_Causes(t *testing.T) {
+	type ids struct {
+		_   struct{} `binary:"endian=big"`
+		N   uint8
+		Ids []uint16 `binary:"[2]uint16"`
+	}
+	type codec struct {
+		_ struct{} `binary:"endian=big"`
+		V uint8    `binary:"uint8,codec=Missing"`
+	}
+	type noOrder struct {
+		V uint16
+	}
+	type hooked struct {
+		N uint8
+		R eeRejecting
+	}
+	type pdp struct {
+		_ struct{} `binary:"endian=pdp"`
+		V uint16
+	}
+	type namedOrder struct {
+		N uint8
+		P pdp
+	}
+	cases := []struct {
+		v     interface{}
+		field string
+		off   int
+		msg   string
+	}{
+		{&ids{Ids: []uint16{1, 2, 3}}, "Ids", 1, "array too large to fit: len 2, size 3"},
+		{&codec{}, "V", 0, "unknown codec: Missing"},
+		{&noOrder{}, "V", 0, "no byte order"},
+		{&hooked{}, "R", 1, "encode error at offset 1 (field R): BeforeMarshalBinary: end before start"},
+		{&namedOrder{}, "P", 1, "encode error at offset 1 (field P): unknown byte order \"pdp\""},
+	}
+	for _, c := range cases {
+		_, err := Marshal(c.v)
+		var ee *EncodeError
+		if !errors.As(err, &ee) || ee.Field != c.field || ee.Offset != c.off || !strings.Contains(err.Error(), c.msg) {
+			t.Errorf("%T: err = %v", c.v, err)
+		}
+	}
+	if _, err := Marshal(&ids{Ids: []uint16{1, 2, 3}}); !errors.Is(err, ErrValueOverflow) {
+		t.Errorf("array overflow: err = %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Marshaler.ValidateOnEncode runs the decode-time validators before each field
+// is written.
+
+type encRecord struct {
+	_     struct{} `binary:"endian=big"`
+	Magic uint16   `binary:"uint16,const=0xcafe"`
+	Count uint16   `binary:"uint16,range=1..100"`
+	Name  string   `binary:"bstring,match=^[a-z]+$"`
+	Kinds []uint8  `binary:"[2]uint8,enum=1|2|5..7"`
+	Len   uint8    `binary:"uint8,valueof=bytelen(Data)"`
+	Data  []byte   `binary:"[Len]byte,check=Len<=4"`
+	Level *int8    `binary:"int8,omittable,range=-5..5"`
+}
+
+func TestEncodeValidation(t *testing.T) {
+	good := func() encRecord {
+		return encRecord{Count: 10, Name: "ok", Kinds: []uint8{1, 6}, Data: []byte{1, 2}}
+	}
+	lvl := int8(9)
+	cases := []struct {
+		edit  func(*encRecord)
+		field string
+		off   int
+	}{
+		{func(r *encRecord) { r.Count = 0 }, "Count", 2},
+		{func(r *encRecord) { r.Name = "Bad" }, "Name", 4},
+		{func(r *encRecord) { r.Kinds[1] = 3 }, "Kinds", 7},
+		{func(r *encRecord) { r.Data = make([]byte, 5) }, "Data", 10},
+		{func(r *encRecord) { r.Level = &lvl }, "Level", 12},
+	}
+	for _, c := range cases {
+		in := good()
+		c.edit(&in)
+		// Off by default: the bad value is written as given.
+		if _, err := Marshal(&in); err != nil {
+			t.Fatalf("%s: without ValidateOnEncode: %v", c.field, err)
+		}
+		ms := NewMarshaler()
+		ms.ValidateOnEncode = true
+		_, err := ms.Marshal(&in)
+		var ee *EncodeError
+		if !errors.As(err, &ee) || !errors.Is(err, ErrValidationError) {
+			t.Fatalf("%s: err = %v, want an *EncodeError wrapping ErrValidationError", c.field, err)
+		}
+		if ee.Field != c.field || ee.Offset != c.off {
+			t.Errorf("%s: EncodeError{Field: %q, Offset: %d}, want offset %d", c.field, ee.Field, ee.Offset, c.off)
+		}
+		if !strings.Contains(err.Error(), c.field) {
+			t.Errorf("%s: message %q does not name the field", c.field, err)
+		}
+	}
+
+	// A valid struct encodes, and the const field is not checked: it always
+	// writes its constant.
+	ms := NewMarshaler()
+	ms.ValidateOnEncode = true
+	in := good()
+	in.Magic = 1
+	b, err := ms.Marshal(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Unmarshal(b, &encRecord{}); err != nil {
+		t.Errorf("decoding the validated output: %v", err)
+	}
+}
+
+// A check= rule that uses $remaining has no value while encoding and is not run.
+func TestEncodeValidation_Remaining(t *testing.T) {
+	type rec struct {
+		N uint8 `binary:"uint8,check=$remaining>=N"`
+	}
+	ms := NewMarshaler()
+	ms.ValidateOnEncode = true
+	if _, err := ms.Marshal(&rec{N: 9}); err != nil {
+		t.Fatal(err)
+	}
+}
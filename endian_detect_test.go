@@ -0,0 +1,94 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type detectPcap struct {
+	_       struct{}  `binary:"endian=detect:Order"`
+	Order   ByteOrder `binary:"-"`
+	Magic   uint32    `binary:"uint32,const=0xa1b2c3d4"`
+	Major   uint16
+	Minor   uint16
+	SnapLen uint32
+	Link    detectLink
+}
+
+type detectLink struct {
+	Type uint16
+}
+
+func TestEndianDetect(t *testing.T) {
+	be := []byte{0xa1, 0xb2, 0xc3, 0xd4, 0, 2, 0, 4, 0, 0, 0xff, 0xff, 0, 1}
+	le := []byte{0xd4, 0xc3, 0xb2, 0xa1, 2, 0, 4, 0, 0xff, 0xff, 0, 0, 1, 0}
+	for _, c := range []struct {
+		blob  []byte
+		order ByteOrder
+	}{{be, BigEndian}, {le, LittleEndian}} {
+		// No fallback order: the magic supplies it.
+		ms := NewMarshaler()
+		var h detectPcap
+		if _, err := ms.Unmarshal(c.blob, &h); err != nil {
+			t.Fatal(err)
+		}
+		want := detectPcap{Order: c.order, Magic: 0xa1b2c3d4, Major: 2, Minor: 4, SnapLen: 0xffff, Link: detectLink{Type: 1}}
+		if !reflect.DeepEqual(h, want) {
+			t.Fatalf("%v: got %+v, want %+v", c.order, h, want)
+		}
+		// Re-encoding preserves the detected order.
+		blob, err := ms.Marshal(&h)
+		if err != nil || !bytes.Equal(blob, c.blob) {
+			t.Fatalf("%v: Marshal = %x, %v; want %x", c.order, blob, err, c.blob)
+		}
+	}
+
+	// Without a recorded order, encoding uses the Marshaler's.
+	blob, err := NewMarshalerOrder(LittleEndian).Marshal(&detectPcap{Major: 2, Minor: 4, SnapLen: 0xffff, Link: detectLink{Type: 1}})
+	if err != nil || !bytes.Equal(blob, le) {
+		t.Fatalf("fallback Marshal = %x, %v; want %x", blob, err, le)
+	}
+
+	var h detectPcap
+	if _, err := NewMarshaler().Unmarshal([]byte{1, 2, 3, 4, 0, 2, 0, 4, 0, 0, 0xff, 0xff, 0, 1}, &h); !errors.Is(err, ErrValidationError) {
+		t.Fatalf("bad magic: err = %v", err)
+	}
+}
+
+func TestEndianDetect_BadDeclaration(t *testing.T) {
+	// Without a record field the order read would be lost and the value could
+	// not be encoded again.
+	type noRecord struct {
+		_ struct{} `binary:"endian=detect"`
+		A uint16   `binary:"uint16,const=0x1234"`
+	}
+	type noMagic struct {
+		_     struct{}  `binary:"endian=detect:Order"`
+		Order ByteOrder `binary:"-"`
+		A     uint16
+	}
+	type byteMagic struct {
+		_     struct{}  `binary:"endian=detect:Order"`
+		Order ByteOrder `binary:"-"`
+		A     uint8     `binary:"uint8,const=7"`
+	}
+	type symmetric struct {
+		_     struct{}  `binary:"endian=detect:Order"`
+		Order ByteOrder `binary:"-"`
+		A     uint16    `binary:"uint16,const=0x4949"`
+	}
+	type badRecord struct {
+		_     struct{} `binary:"endian=detect:Order"`
+		Order int      `binary:"-"`
+		A     uint16   `binary:"uint16,const=0x1234"`
+	}
+	for _, v := range []interface{}{&noRecord{}, &noMagic{}, &byteMagic{}, &symmetric{}, &badRecord{}} {
+		if _, err := NewMarshalerOrder(BigEndian).Marshal(v); err == nil {
+			t.Errorf("%T: expected a declaration error", v)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type markSub struct {
+	V uint16
+}
+
+type markTIFF struct {
+	_     struct{} `binary:"endian=Order:0x4949=little,0x4d4d=big"`
+	Order [2]byte
+	Magic uint16
+	IFD   uint32
+	Sub   markSub
+	Be    uint16 `binary:"uint16,endian=big"`
+}
+
+type markBOM struct {
+	_    struct{} `binary:"endian=BOM"`
+	BOM  uint16
+	Text []uint16 `binary:"[2]uint16"`
+}
+
+func TestEndianMark_TIFF(t *testing.T) {
+	cases := []struct {
+		in   markTIFF
+		want []byte
+	}{
+		{markTIFF{Order: [2]byte{'I', 'I'}, Magic: 42, IFD: 8, Sub: markSub{V: 0x102}, Be: 0x304},
+			[]byte{'I', 'I', 42, 0, 8, 0, 0, 0, 2, 1, 3, 4}},
+		{markTIFF{Order: [2]byte{'M', 'M'}, Magic: 42, IFD: 8, Sub: markSub{V: 0x102}, Be: 0x304},
+			[]byte{'M', 'M', 0, 42, 0, 0, 0, 8, 1, 2, 3, 4}},
+	}
+	// No fallback order: the mark supplies it.
+	ms := NewMarshaler()
+	for _, c := range cases {
+		blob, err := ms.Marshal(&c.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(blob, c.want) {
+			t.Fatalf("%s: blob = %x, want %x", c.in.Order[:], blob, c.want)
+		}
+		var out markTIFF
+		if _, err := ms.Unmarshal(blob, &out); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(c.in, out) {
+			t.Fatalf("%s: round trip: got %+v, want %+v", c.in.Order[:], out, c.in)
+		}
+	}
+
+	var out markTIFF
+	if _, err := ms.Unmarshal([]byte{'X', 'X', 0, 42, 0, 0, 0, 8, 1, 2, 3, 4}, &out); err == nil || !strings.Contains(err.Error(), "matches no byte order") {
+		t.Fatalf("unknown mark: err = %v", err)
+	}
+	if _, err := ms.Marshal(&markTIFF{Order: [2]byte{'X', 'X'}}); err == nil {
+		t.Fatal("unknown mark on encode: expected an error")
+	}
+}
+
+func TestEndianMark_DefaultMapping(t *testing.T) {
+	ms := NewMarshalerOrder(BigEndian)
+	var out markBOM
+	if _, err := ms.Unmarshal([]byte{0xff, 0xfe, 'h', 0, 'i', 0}, &out); err != nil {
+		t.Fatal(err)
+	}
+	want := markBOM{BOM: 0xfffe, Text: []uint16{'h', 'i'}}
+	if !reflect.DeepEqual(out, want) {
+		t.Fatalf("got %+v, want %+v", out, want)
+	}
+	blob, err := ms.Marshal(&want)
+	if err != nil || !bytes.Equal(blob, []byte{0xff, 0xfe, 'h', 0, 'i', 0}) {
+		t.Fatalf("Marshal = %x, %v", blob, err)
+	}
+}
+
+func TestEndianMark_BadDeclaration(t *testing.T) {
+	type noField struct {
+		_ struct{} `binary:"endian=Missing"`
+		A uint8
+	}
+	type badType struct {
+		_ struct{} `binary:"endian=A"`
+		A float32
+	}
+	type badValue struct {
+		_ struct{} `binary:"endian=A:0x4949=big-endian"`
+		A uint16
+	}
+	for _, v := range []interface{}{&noField{}, &badType{}, &badValue{}} {
+		if _, err := NewMarshalerOrder(BigEndian).Marshal(v); err == nil {
+			t.Errorf("%T: expected a declaration error", v)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// fieldEnum is a field's `enum=` option: an inline set of values and ranges
+// (`enum=1|2|5..9`), or the name of an enum registered with AddEnum
+// (`enum=Compression`), which also names the values.
+type fieldEnum struct {
+	expr   string      // the option's text, for messages
+	name   string      // registered enum name; empty for an inline set
+	ranges []enumRange // inline set
+}
+
+type enumRange struct {
+	lo, hi int64
+}
+
+// parseFieldEnum parses the value of an `enum=` option. A value starting with a
+// letter names a registered enum; anything else is a '|'-separated list of
+// integers and lo..hi ranges.
+func parseFieldEnum(s string) (*fieldEnum, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("empty enum")
+	}
+	if c := s[0]; c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' {
+		for _, c := range s {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+				return nil, fmt.Errorf("invalid enum name %q", s)
+			}
+		}
+		return &fieldEnum{expr: s, name: s}, nil
+	}
+	e := &fieldEnum{expr: s}
+	for _, item := range strings.Split(s, "|") {
+		lo, hi := item, item
+		if i := strings.Index(item, ".."); i >= 0 {
+			lo, hi = item[:i], item[i+2:]
+		}
+		l, err := strconv.ParseInt(strings.TrimSpace(lo), 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid enum value %q", item)
+		}
+		h, err := strconv.ParseInt(strings.TrimSpace(hi), 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid enum value %q", item)
+		}
+		if l > h {
+			return nil, fmt.Errorf("invalid enum range %q", item)
+		}
+		e.ranges = append(e.ranges, enumRange{l, h})
+	}
+	return e, nil
+}
+
+// AddEnum registers the names of an enumeration's values with a Marshaler. A
+// field tagged `enum=NAME` then accepts only these values on decode, and Inspect
+// and error messages show a value by name, as in `deflate(8)`. A signed field's
+// value v is looked up as uint64(v).
+func (ms *Marshaler) AddEnum(name string, values map[uint64]string) {
+	if ms.enums == nil {
+		ms.enums = make(map[string]map[uint64]string)
+	}
+	m := make(map[uint64]string, len(values))
+	for k, v := range values {
+		m[k] = v
+	}
+	ms.enums[name] = m
+}
+
+// RemoveEnum removes an enum registered by AddEnum.
+func (ms *Marshaler) RemoveEnum(name string) {
+	if ms.enums != nil {
+		delete(ms.enums, name)
+	}
+}
+
+// ValidateEnum returns an error wrapping ErrValidationError if v is not a value
+// of the enum registered as name, or an error if no such enum is registered
+// (including on a nil Marshaler). Generated code checks `enum=NAME` fields
+// through it.
+func (ms *Marshaler) ValidateEnum(name string, v uint64) error {
+	var values map[uint64]string
+	if ms != nil {
+		values = ms.enums[name]
+	}
+	if values == nil {
+		return fmt.Errorf("unknown enum %q (register it with AddEnum)", name)
+	}
+	if _, ok := values[v]; !ok {
+		return &CheckError{Expected: name, Actual: strconv.FormatUint(v, 10),
+			Err: fmt.Errorf("value %d is not in enum %s: %w", v, name, ErrValidationError)}
+	}
+	return nil
+}
+
+// EnumString formats v as `name(v)` when the enum registered as name has a name
+// for it, and as plain v otherwise.
+func (ms *Marshaler) EnumString(enum string, v uint64) string {
+	if ms != nil {
+		if label, ok := ms.enums[enum][v]; ok {
+			return fmt.Sprintf("%s(%d)", label, v)
+		}
+	}
+	return strconv.FormatUint(v, 10)
+}
+
+// enumInt returns an integer value as an int64 for an inline set (an unsigned
+// value is converted, as generated code does) and as a uint64 key for a
+// registered enum. ok is false for a value that is not an integer.
+func enumInt(v reflect.Value) (i int64, u uint64, ok bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), uint64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int64(v.Uint()), v.Uint(), true
+	}
+	return 0, 0, false
+}
+
+// validateEnum checks a decoded value against the field's enum.
+func (ms *Marshaler) validateEnum(v reflect.Value, e *fieldEnum) error {
+	i, u, ok := enumInt(v)
+	if !ok {
+		return fmt.Errorf("enum validation not supported on type %s", v.Type().String())
+	}
+	if e.name != "" {
+		values := ms.enums[e.name]
+		if values == nil {
+			return ms.ValidateEnum(e.name, u) // unknown enum
+		}
+		if _, ok := values[u]; ok {
+			return nil
+		}
+		return &CheckError{Expected: e.name, Actual: strconv.FormatUint(u, 10),
+			Err: fmt.Errorf("value %v is not in enum %s: %w", v.Interface(), e.name, ErrValidationError)}
+	}
+	for _, r := range e.ranges {
+		if i >= r.lo && i <= r.hi {
+			return nil
+		}
+	}
+	return &CheckError{Expected: e.expr, Actual: strconv.FormatInt(i, 10),
+		Err: fmt.Errorf("value %d is not in enum %s: %w", i, e.expr, ErrValidationError)}
+}
+
+// enumValueString formats a field's value for a message: `deflate(8)` when the
+// field's registered enum names it, else the value as %v would.
+func (ms *Marshaler) enumValueString(v reflect.Value, fMeta *structFieldMetadata) string {
+	if fMeta.enum != nil && fMeta.enum.name != "" {
+		if _, u, ok := enumInt(v); ok {
+			if label, ok := ms.enums[fMeta.enum.name][u]; ok {
+				return fmt.Sprintf("%s(%v)", label, v.Interface())
+			}
+		}
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
+
+// enumDetails describes a field's value in Inspect's Details: its name from a
+// registered enum, as in `deflate(8)`, or for an inline set the values that are
+// not in it. It is empty when there is nothing to note.
+func (ms *Marshaler) enumDetails(v reflect.Value, fMeta *structFieldMetadata) string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if fMeta.enum.name != "" && ms.enums[fMeta.enum.name] == nil {
+		return fmt.Sprintf("unknown enum %q", fMeta.enum.name)
+	}
+	elems := []reflect.Value{v}
+	if k := v.Kind(); k == reflect.Slice || k == reflect.Array {
+		elems = make([]reflect.Value, v.Len())
+		for i := range elems {
+			elems[i] = v.Index(i)
+		}
+	}
+	var named, invalid []string
+	for _, e := range elems {
+		if err := ms.validateEnum(e, fMeta.enum); err != nil {
+			invalid = append(invalid, fmt.Sprintf("%v", e.Interface()))
+		}
+		named = append(named, ms.enumValueString(e, fMeta))
+	}
+	details := ""
+	if fMeta.enum.name != "" {
+		details = strings.Join(named, " ")
+		if len(elems) != 1 {
+			details = "[" + details + "]"
+		}
+	}
+	if len(invalid) > 0 {
+		if details != "" {
+			details += "; "
+		}
+		details += fmt.Sprintf("not in enum %s: %s", fMeta.enum.expr, strings.Join(invalid, " "))
+	}
+	return details
+}
@@ -0,0 +1,89 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type enumHeader struct {
+	_           struct{} `binary:"endian=big"`
+	Kind        uint8    `binary:"uint8,enum=1|2|5..9"`
+	Compression uint16   `binary:"uint16,enum=Compression,range=..8"`
+	Levels      []int8   `binary:"[2]int8,enum=-1..3"`
+}
+
+func enumMarshaler() *Marshaler {
+	ms := NewMarshaler()
+	ms.AddEnum("Compression", map[uint64]string{0: "none", 8: "deflate", 12: "bzip2"})
+	return ms
+}
+
+func TestEnum(t *testing.T) {
+	ms := enumMarshaler()
+	var out enumHeader
+	if _, err := ms.Unmarshal([]byte{7, 0, 8, 0xff, 3}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Kind != 7 || out.Compression != 8 || out.Levels[0] != -1 {
+		t.Fatalf("decoded %+v", out)
+	}
+
+	cases := []struct {
+		blob  []byte
+		field string
+		msg   string
+	}{
+		{[]byte{3, 0, 8, 0, 0}, "Kind", "value 3 is not in enum 1|2|5..9"},
+		{[]byte{1, 0, 9, 0, 0}, "Compression", "value 9 is not in enum Compression"},
+		{[]byte{1, 0, 12, 0, 0}, "Compression", "value bzip2(12) is out of range"},
+		{[]byte{1, 0, 0, 0, 0xfe}, "Levels", "value -2 is not in enum -1..3"},
+	}
+	for _, c := range cases {
+		_, err := ms.Unmarshal(c.blob, &enumHeader{})
+		var de *DecodeError
+		if !errors.Is(err, ErrValidationError) || !errors.As(err, &de) || de.Field != c.field || !strings.Contains(err.Error(), c.msg) {
+			t.Errorf("% x: err = %v, want %s in %s", c.blob, err, c.msg, c.field)
+		}
+	}
+
+	// The enum must be registered on the Marshaler that decodes.
+	_, err := NewMarshaler().Unmarshal([]byte{1, 0, 8, 0, 0}, &enumHeader{})
+	if err == nil || !strings.Contains(err.Error(), `unknown enum "Compression"`) {
+		t.Fatalf("unregistered enum: err = %v", err)
+	}
+}
+
+func TestEnum_Inspect(t *testing.T) {
+	ms := enumMarshaler()
+	layout, err := ms.Inspect(&enumHeader{Kind: 4, Compression: 8, Levels: []int8{0, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"Kind":        "not in enum 1|2|5..9: 4",
+		"Compression": "deflate(8)",
+		"Levels":      "expr: 2",
+	}
+	for _, f := range layout.Fields {
+		if f.Details != want[f.Name] {
+			t.Errorf("%s: Details = %q, want %q", f.Name, f.Details, want[f.Name])
+		}
+	}
+}
+
+func TestEnum_MetadataErrors(t *testing.T) {
+	type str struct {
+		S string `binary:"string(2),enum=1|2"`
+	}
+	type bad struct {
+		V uint8 `binary:"uint8,enum=3..1"`
+	}
+	for _, v := range []interface{}{&str{}, &bad{}} {
+		if _, err := Unmarshal([]byte{1, 2}, v); err == nil {
+			t.Errorf("%T: expected an error", v)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestExprFieldPath_Evaluate(t *testing.T) {
+	type Inner struct {
+		Len  uint16
+		Dims [3]uint8
+	}
+	type Hdr struct {
+		PayloadLen uint16
+		In         Inner
+		Ptr        *Inner
+		Nil        *Inner
+		Sizes      []int32
+	}
+	type S struct {
+		Hdr  Hdr
+		Dims [2]uint8
+	}
+	strc := reflect.ValueOf(S{
+		Hdr: Hdr{
+			PayloadLen: 12,
+			In:         Inner{Len: 5, Dims: [3]uint8{1, 2, 3}},
+			Ptr:        &Inner{Len: 7},
+			Sizes:      []int32{-4, 40},
+		},
+		Dims: [2]uint8{3, 4},
+	})
+	cases := []struct {
+		expr string
+		want int
+	}{
+		{"Hdr.PayloadLen", 12},
+		{"Hdr.PayloadLen - 2", 10},
+		{"Dims[0] * Dims[1]", 12},
+		{"Dims[0x1]", 4},
+		{"Hdr.In.Len + Hdr.In.Dims[2]", 8},
+		{"Hdr.Ptr.Len", 7},
+		{"Hdr.Sizes[1] + Hdr.Sizes[0]", 36},
+		{"Hdr . In . Dims [ 1 ]", 2},
+		{"Dims[0] > 2 ? Hdr.PayloadLen : 0", 12},
+	}
+	for _, c := range cases {
+		got, err := evaluateTagValue(strc, c.expr)
+		if err != nil {
+			t.Errorf("%q: %v", c.expr, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%q = %d, want %d", c.expr, got, c.want)
+		}
+	}
+
+	for _, bad := range []string{
+		"Hdr.NoSuch", "Hdr.In", "Dims[2]", "Hdr.Sizes[2]", "Dims[Hdr.PayloadLen]",
+		"Dims[-1]", "Hdr.Nil.Len", "Hdr.PayloadLen.X", "Hdr.PayloadLen[0]", "Hdr.", "Dims[0",
+	} {
+		if _, err := evaluateTagValue(strc, bad); err == nil {
+			t.Errorf("%q: want error", bad)
+		}
+	}
+
+	refs, _, err := exprReferences("Hdr.In.Dims[0] + Dims[1]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Hdr.In.Dims[0]", "Dims[1]"}; !reflect.DeepEqual(refs, want) {
+		t.Errorf("refs = %q, want %q", refs, want)
+	}
+}
+
+func TestExprFieldPath_Tags(t *testing.T) {
+	type Header struct {
+		Magic      uint16
+		PayloadLen uint16
+	}
+	type Rec struct {
+		Hdr     Header
+		Dims    [2]uint8
+		Data    []byte    `binary:"[Hdr.PayloadLen]byte"`
+		Grid    []float32 `binary:"[Dims[0] * Dims[1]]float32"`
+		Name    string    `binary:"string(Dims[1])"`
+		Tail    uint8     `binary:"uint8,omittable=Hdr.Magic"`
+		DataLen uint16    `binary:"uint16,valueof=Hdr.PayloadLen + Dims[0]"`
+	}
+	in := Rec{
+		Hdr:  Header{Magic: 100, PayloadLen: 3},
+		Dims: [2]uint8{1, 2},
+		Data: []byte{7, 8, 9},
+		Grid: []float32{1.5, -2},
+		Name: "ab",
+		Tail: 0xee,
+	}
+	ms := NewMarshalerOrder(BigEndian)
+	blob, err := ms.Marshal(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0, 100, 0, 3, 1, 2, 7, 8, 9, 0x3f, 0xc0, 0, 0, 0xc0, 0, 0, 0, 'a', 'b', 0xee, 0, 4}
+	if !bytes.Equal(blob, want) {
+		t.Fatalf("blob = %x, want %x", blob, want)
+	}
+	var out Rec
+	if _, err := ms.Unmarshal(blob, &out); err != nil {
+		t.Fatal(err)
+	}
+	in.DataLen = 4
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("round trip: got %+v, want %+v", out, in)
+	}
+
+	type Bad struct {
+		N uint16 `binary:"uint16,valueof=Missing.Len"`
+	}
+	if _, err := ms.Marshal(&Bad{}); err == nil {
+		t.Error("valueof with an unknown root field: want error")
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExprFunctions_Evaluate(t *testing.T) {
+	type Hdr struct {
+		Magic [4]byte
+		Ver   uint16
+		Flags uint8 `binary:"uint8"`
+		Rsv   [2]byte
+		Name  string `binary:"bstring(7)"`
+		Pad   [3]uint16
+	}
+	type S struct {
+		Len  int16
+		Cnt  uint8
+		Neg  int32
+		Head Hdr
+	}
+	strc := reflect.ValueOf(S{Len: 10, Cnt: 3, Neg: -7})
+	cases := []struct {
+		expr string
+		want int
+	}{
+		{"align(Len, 4)", 12},
+		{"align(12, 4)", 12},
+		{"align(0, 8)", 0},
+		{"align(Neg, 4)", -4},
+		{"min(Len, Cnt)", 3},
+		{"min(Len, Cnt, Neg)", -7},
+		{"max(Len, Cnt, 2)", 10},
+		{"abs(Neg)", 7},
+		{"abs(Len)", 10},
+		{"sizeof(Hdr)", 4 + 2 + 1 + 2 + 8 + 6},
+		{"max(Len - sizeof(Hdr), 0)", 0},
+		{"align(Len + 1, min(4, Cnt + 1))", 12},
+		// a function not taken by a short circuit is never evaluated.
+		{"Cnt == 0 ? align(Len, Cnt - 3) : 1", 1},
+	}
+	for _, c := range cases {
+		got, err := evaluateTagValue(strc, c.expr)
+		if err != nil {
+			t.Errorf("%q: %v", c.expr, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%q = %d, want %d", c.expr, got, c.want)
+		}
+	}
+
+	for _, bad := range []string{"align(Len, 0)", "align(Len)", "min(Len)", "abs(Len, Cnt)", "sizeof(NoSuchType)", "sizeof(1)"} {
+		if _, err := evaluateTagValue(strc, bad); err == nil {
+			t.Errorf("%q: want error", bad)
+		}
+	}
+}
+
+func TestExprFunctions_SizeofVariable(t *testing.T) {
+	type Var struct {
+		N    uint8
+		Data []byte `binary:"[N]byte"`
+	}
+	type S struct {
+		V Var
+	}
+	_, err := evaluateTagValue(reflect.ValueOf(S{}), "sizeof(Var)")
+	if err == nil || !strings.Contains(err.Error(), "field Data") {
+		t.Fatalf("err = %v, want an error naming field Data", err)
+	}
+}
+
+func TestExprFunctions_Tags(t *testing.T) {
+	type Entry struct {
+		ID   uint16
+		Kind uint8
+		Rsv  uint8
+	}
+	type Rec struct {
+		NameLen uint8   `binary:"uint8,valueof=bytelen(Name)"`
+		Name    string  `binary:"string(NameLen)"`
+		Pad     []byte  `binary:"[align(NameLen + 1, 4) - NameLen - 1]byte"`
+		Size    uint16  `binary:"uint16,valueof=max(sizeof(Entry), 2)"`
+		Entries []Entry `binary:"[min(Size / sizeof(Entry), 4)]"`
+		Label   string  `binary:"string(align(NameLen, 4))"`
+		Opt     uint16  `binary:"uint16,omittable=abs(NameLen - 3) * 16"`
+	}
+	in := Rec{
+		Name:    "abcde",
+		Pad:     []byte{0, 0},
+		Entries: []Entry{{ID: 9, Kind: 1}},
+		Label:   "lbl",
+		Opt:     0x1234,
+	}
+	ms := NewMarshalerOrder(BigEndian)
+	blob, err := ms.Marshal(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{5, 'a', 'b', 'c', 'd', 'e', 0, 0, 0, 4, 0, 9, 1, 0, 'l', 'b', 'l', 0, 0, 0, 0, 0, 0x12, 0x34}
+	if !bytes.Equal(blob, want) {
+		t.Fatalf("blob = %x, want %x", blob, want)
+	}
+	var out Rec
+	if _, err := ms.Unmarshal(blob, &out); err != nil {
+		t.Fatal(err)
+	}
+	in.NameLen, in.Size = 5, 4
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("round trip: got %+v, want %+v", out, in)
+	}
+}
+
+func TestParseTypeTag(t *testing.T) {
+	cases := []struct {
+		tag, dims, typ, buf string
+	}{
+		{"int16", "", "int16", ""},
+		{" [4][N]int16", "[4][N]", "int16", ""},
+		{"string(16)", "", "string", "16"},
+		{"string(align(Len, 4))", "", "string", "align(Len, 4)"},
+		{"[min(A, (B + 1))]byte", "[min(A, (B + 1))]", "byte", ""},
+		{"[]wstring((N + 1) * 2)", "[]", "wstring", "(N + 1) * 2"},
+	}
+	for _, c := range cases {
+		dims, typ, buf := parseTypeTag(c.tag)
+		if dims != c.dims || typ != c.typ || buf != c.buf {
+			t.Errorf("parseTypeTag(%q) = %q, %q, %q; want %q, %q, %q", c.tag, dims, typ, buf, c.dims, c.typ, c.buf)
+		}
+	}
+	if got := parseArrayDims("[min(A, B)][4]"); !reflect.DeepEqual(got, []string{"min(A, B)", "4"}) {
+		t.Errorf("parseArrayDims = %q", got)
+	}
+}
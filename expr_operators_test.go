@@ -0,0 +1,154 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestExprOperators_Evaluate(t *testing.T) {
+	type S struct {
+		Flags    uint8
+		Log2Size uint8
+		Len      int16
+		Ver      uint8
+	}
+	strc := reflect.ValueOf(S{Flags: 0xA7, Log2Size: 4, Len: 10, Ver: 2})
+	cases := []struct {
+		expr string
+		want int
+	}{
+		{"Flags & 0x0F", 7},
+		{"Flags | 0x08", 0xAF},
+		{"Flags ^ 0xFF", 0x58},
+		{"1 << Log2Size", 16},
+		{"Flags >> 4", 0xA},
+		{"Len % 4", 2},
+		{"~0", -1},
+		{"~Len & 0xFF", 0xF5},
+		{"!Ver", 0},
+		{"!(Ver - 2)", 1},
+		{"Len == 10", 1},
+		{"Len != 10", 0},
+		{"Len < 10", 0},
+		{"Len <= 10", 1},
+		{"Len > 9", 1},
+		{"Len >= 11", 0},
+		{"Ver >= 2 && Len > 0", 1},
+		{"Ver > 2 || Len > 100", 0},
+		{"Ver >= 2 ? 8 : 4", 8},
+		{"Ver > 2 ? 8 : Ver == 2 ? 6 : 4", 6},
+		{"(Len + 3) & ~3", 12},
+		// Go precedence: & binds tighter than ==, + is level with |.
+		{"Flags & 0x0F == 7", 1},
+		{"1 + 2 | 4", 7},
+		{"2 * 3 << 1", 12},
+		// short circuit: the operand not taken is never evaluated.
+		{"Ver == 0 && Len / 0", 0},
+		{"Ver != 0 || Len % 0", 1},
+		{"Ver != 0 ? 1 : Len / 0", 1},
+		{"Ver == 0 ? NoSuchField : 3", 3},
+	}
+	for _, c := range cases {
+		got, err := evaluateTagValue(strc, c.expr)
+		if err != nil {
+			t.Errorf("%q: %v", c.expr, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%q = %d, want %d", c.expr, got, c.want)
+		}
+	}
+
+	for _, bad := range []string{"Len % 0", "1 << (Ver - 3)", "Ver ? 1", "Len = 1", "Ver == 2 ? NoSuchField : 3"} {
+		if _, err := evaluateTagValue(strc, bad); err == nil {
+			t.Errorf("%q: want error", bad)
+		}
+	}
+}
+
+func TestExprOperators_References(t *testing.T) {
+	// exprReferences must see every operand, including those a short circuit
+	// would skip at evaluation time.
+	refs, _, err := exprReferences("A != 0 && B > 1 ? C : D / E")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"A", "B", "C", "D", "E"}
+	if !reflect.DeepEqual(refs, want) {
+		t.Fatalf("refs = %v, want %v", refs, want)
+	}
+}
+
+func TestExprOperators_Tags(t *testing.T) {
+	type Rec struct {
+		Flags    uint8
+		Log2Size uint8
+		Count    uint8    `binary:"uint8,valueof=Flags & 0x0F"`
+		Data     []byte   `binary:"[1 << Log2Size]byte"`
+		Name     string   `binary:"string(Flags >> 4)"`
+		Extra    []uint16 `binary:"[Count % 3]uint16"`
+		Opt      uint32   `binary:"uint32,omittable=Flags & 0x80 ? 0 : 1 << 16"`
+	}
+	in := Rec{
+		Flags:    0x25,
+		Log2Size: 2,
+		Data:     []byte{1, 2, 3, 4},
+		Name:     "hi",
+		Extra:    []uint16{7, 8},
+		Opt:      0xdeadbeef,
+	}
+	ms := NewMarshalerOrder(BigEndian)
+	blob, err := ms.Marshal(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0x25, 0x02, 0x05, 1, 2, 3, 4, 'h', 'i', 0, 7, 0, 8, 0xde, 0xad, 0xbe, 0xef}
+	if !bytes.Equal(blob, want) {
+		t.Fatalf("blob = %x, want %x", blob, want)
+	}
+	var out Rec
+	if _, err := ms.Unmarshal(blob, &out); err != nil {
+		t.Fatal(err)
+	}
+	in.Count = 5
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("round trip: got %+v, want %+v", out, in)
+	}
+
+	// With the high flag bit set, omittable= evaluates to 0 and Opt is dropped.
+	in.Flags = 0x85
+	in.Name = "12345678"
+	blob, err = ms.Marshal(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blob) != 3+4+8+4 {
+		t.Fatalf("blob = %x: Opt should have been omitted", blob)
+	}
+}
+
+func TestExprOperators_OmittableComparison(t *testing.T) {
+	// '=' inside an omittable= expression must survive tag option splitting.
+	type S struct {
+		Ver uint8
+		Ext uint16 `binary:"uint16,omittable=Ver == 1 ? 0 : 100"`
+	}
+	ms := NewMarshalerOrder(BigEndian)
+	blob, err := ms.Marshal(&S{Ver: 1, Ext: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(blob, []byte{1}) {
+		t.Fatalf("Ver=1: blob = %x, want 01", blob)
+	}
+	blob, err = ms.Marshal(&S{Ver: 2, Ext: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(blob, []byte{2, 0, 9}) {
+		t.Fatalf("Ver=2: blob = %x, want 020009", blob)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+type outerEntry struct {
+	Kind uint8
+	Data []byte `binary:"[parent.EntrySize - 1]byte"`
+}
+
+type outerSub struct {
+	Tag  uint8
+	Vals []uint16 `binary:"[root.Hdr.Count]uint16"`
+	Note string   `binary:"string(parent.parent.NoteLen)"`
+}
+
+type outerMid struct {
+	Sub outerSub
+}
+
+type outerTable struct {
+	Hdr struct {
+		Count uint8
+	}
+	EntrySize uint8
+	NoteLen   uint8
+	Entries   []outerEntry `binary:"[2]"`
+	Mid       *outerMid
+}
+
+func TestExprOuter_RoundTrip(t *testing.T) {
+	in := outerTable{
+		EntrySize: 3,
+		NoteLen:   4,
+		Entries: []outerEntry{
+			{Kind: 1, Data: []byte{0xa, 0xb}},
+			{Kind: 2, Data: []byte{0xc, 0xd}},
+		},
+		Mid: &outerMid{Sub: outerSub{Tag: 9, Vals: []uint16{0x102, 0x304}, Note: "hey"}},
+	}
+	in.Hdr.Count = 2
+	want := []byte{2, 3, 4, 1, 0xa, 0xb, 2, 0xc, 0xd, 9, 1, 2, 3, 4, 'h', 'e', 'y', 0}
+
+	ms := NewMarshalerOrder(BigEndian)
+	blob, err := ms.Marshal(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(blob, want) {
+		t.Fatalf("blob = %x, want %x", blob, want)
+	}
+	var out outerTable
+	if _, err := ms.Unmarshal(blob, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("round trip: got %+v, want %+v", out, in)
+	}
+	if len(ms.structStack) != 0 {
+		t.Errorf("struct stack not unwound: %d entries", len(ms.structStack))
+	}
+
+	sl, err := ms.Inspect(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noteSize := -1
+	for _, f := range sl.Fields {
+		if f.Name == "Mid.Sub.Note" {
+			noteSize = f.Size
+		}
+	}
+	if noteSize != 4 {
+		t.Errorf("Inspect: Mid.Sub.Note size = %d, want 4", noteSize)
+	}
+}
+
+func TestExprOuter_Errors(t *testing.T) {
+	// A struct decoded on its own has no parent; root. is the struct itself.
+	ms := NewMarshalerOrder(BigEndian)
+	var e outerEntry
+	if _, err := ms.Unmarshal([]byte{1, 2, 3}, &e); err == nil {
+		t.Error("parent. reference at top level: want error")
+	}
+
+	type Self struct {
+		N    uint8
+		Data []byte `binary:"[root.N]byte"`
+	}
+	var s Self
+	if _, err := ms.Unmarshal([]byte{2, 7, 8}, &s); err != nil || !bytes.Equal(s.Data, []byte{7, 8}) {
+		t.Errorf("root. at top level: got %+v, %v", s, err)
+	}
+
+	cg := NewCodegen(ms)
+	if v, err := cg.Outer(&s, "root.N"), cg.Err(); err != nil || v != 2 {
+		t.Errorf("Outer(root.N) = %d, %v; want 2", v, err)
+	}
+	cg.PushStruct(&outerTable{EntrySize: 5})
+	v, err := cg.Outer(&e, "parent.EntrySize"), cg.Err()
+	cg.PopStruct()
+	if err != nil || v != 5 {
+		t.Errorf("Outer(parent.EntrySize) = %d, %v; want 5", v, err)
+	}
+	cg = NewCodegen(nil)
+	if cg.Outer(&e, "parent.EntrySize"); cg.Err() == nil {
+		t.Error("nil Marshaler parent.: want error")
+	}
+
+	type BadValueof struct {
+		N uint8 `binary:"uint8,valueof=parent.Len + 1"`
+	}
+	if _, err := getStructMetadata(reflect.TypeOf(BadValueof{})); err != nil {
+		t.Errorf("valueof with a parent. reference must be accepted: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type paramRecord struct {
+	Kind  uint8
+	Name  string `binary:"string($NameSize)"`
+	Gap   []byte `binary:"[align($offset, 4) - $offset]byte"`
+	Value uint32
+	Rest  []byte `binary:"[$remaining]byte"`
+}
+
+// onlyReader hides the Len method of the reader it wraps.
+type onlyReader struct{ r io.Reader }
+
+func (o onlyReader) Read(p []byte) (int, error) { return o.r.Read(p) }
+
+func TestExprParams_RoundTrip(t *testing.T) {
+	in := paramRecord{Kind: 1, Name: "ab", Gap: []byte{0}, Value: 0x01020304, Rest: []byte("xyz")}
+	want := []byte{1, 'a', 'b', 0, 1, 2, 3, 4, 'x', 'y', 'z'}
+
+	ms := NewMarshalerOrder(BigEndian)
+	ms.SetParam("NameSize", 2)
+	blob, err := ms.Marshal(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(blob, want) {
+		t.Fatalf("blob = %x, want %x", blob, want)
+	}
+	var out paramRecord
+	n, err := ms.Unmarshal(blob, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(want) || !reflect.DeepEqual(in, out) {
+		t.Fatalf("round trip: got %+v (%d bytes), want %+v", out, n, in)
+	}
+
+	// An *io.LimitedReader knows how much input is left too.
+	out = paramRecord{}
+	if _, err := ms.Read(&io.LimitedReader{R: onlyReader{bytes.NewReader(want)}, N: int64(len(want))}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("LimitedReader: got %+v, want %+v", out, in)
+	}
+}
+
+func TestExprParams_Errors(t *testing.T) {
+	blob := []byte{1, 'a', 'b', 0, 1, 2, 3, 4, 'x'}
+
+	ms := NewMarshalerOrder(BigEndian)
+	var out paramRecord
+	if _, err := ms.Unmarshal(blob, &out); err == nil || !strings.Contains(err.Error(), "$NameSize") {
+		t.Fatalf("unset parameter: err = %v", err)
+	}
+
+	ms.SetParam("NameSize", 2)
+	ms.RemoveParam("NameSize")
+	if _, err := ms.Marshal(&paramRecord{}); err == nil || !strings.Contains(err.Error(), "$NameSize") {
+		t.Fatalf("removed parameter: err = %v", err)
+	}
+
+	ms.SetParam("NameSize", 2)
+	if _, err := ms.Read(onlyReader{bytes.NewReader(blob)}, &out); err == nil || !strings.Contains(err.Error(), "$remaining") {
+		t.Fatalf("reader without Len: err = %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/unicode"
+)
+
+type fillRecord struct {
+	_    struct{} `binary:"endian=big"`
+	A    uint8
+	Rsv  interface{} `binary:"pad(3),fill=0xff,reserved"`
+	Name string      `binary:"string(6),fill=0x20"`
+	Tag  string      `binary:"bstring(4),fill=0xff"`
+	Gap  interface{} `binary:"[2]pad(2),fill=0xaa,reserved"`
+	B    uint8
+}
+
+func TestFill(t *testing.T) {
+	want := []byte{
+		1,
+		0xff, 0xff, 0xff,
+		'F', 'L', 'A', 'S', 'H', ' ',
+		2, 'o', 'k', 0xff, 0xff,
+		0xaa, 0xaa, 0xaa, 0xaa,
+		2,
+	}
+	v := fillRecord{A: 1, Name: "FLASH", Tag: "ok", B: 2}
+	for _, ms := range []*Marshaler{NewMarshaler(), nil} {
+		var blob []byte
+		var err error
+		if ms == nil {
+			blob, err = Marshal(&v)
+		} else {
+			blob, err = ms.Marshal(&v)
+		}
+		if err != nil || !bytes.Equal(blob, want) {
+			t.Fatalf("Marshal: % x, %v", blob, err)
+		}
+	}
+
+	var got fillRecord
+	if n, err := Unmarshal(want, &got); err != nil || n != len(want) || got.Name != "FLASH" || got.Tag != "ok" || got.B != 2 {
+		t.Fatalf("Unmarshal: %d, %+v, %v", n, got, err)
+	}
+
+	// A reserved byte other than its fill.
+	for _, c := range []struct {
+		off   int
+		field string
+	}{{2, "Rsv"}, {17, "Gap"}} {
+		bad := append([]byte{}, want...)
+		bad[c.off] = 0
+		_, err := Unmarshal(bad, &fillRecord{})
+		var de *DecodeError
+		var ce *CheckError
+		if !errors.As(err, &de) || de.Field != c.field || !errors.Is(err, ErrValidationError) || !errors.As(err, &ce) || ce.Expected == ce.Actual {
+			t.Errorf("byte %d: err = %v", c.off, err)
+		}
+
+		ms := NewMarshaler()
+		ms.Validation = ValidateNone
+		if _, err := ms.Unmarshal(bad, &fillRecord{}); err != nil {
+			t.Errorf("byte %d: ValidateNone: %v", c.off, err)
+		}
+		ms.Validation = ValidateWarn
+		got = fillRecord{}
+		if _, err := ms.Unmarshal(bad, &got); err != nil || len(ms.Warnings) != 1 || ms.Warnings[0].Field != c.field || got.B != 2 {
+			t.Errorf("byte %d: ValidateWarn: %v, %v", c.off, err, ms.Warnings)
+		}
+		ms.Validation = ValidateCollect
+		if _, err := ms.Unmarshal(bad, &fillRecord{}); !errors.As(err, &de) || de.Field != c.field {
+			t.Errorf("byte %d: ValidateCollect: %v", c.off, err)
+		}
+	}
+
+	// The fill of a zstring(N) or z16string(N) follows its terminator, and
+	// decoding consumes the whole buffer.
+	type zRecord struct {
+		_ struct{} `binary:"endian=big"`
+		C string   `binary:"zstring(6),fill=0xff"`
+		W string   `binary:"z16string(6),padchar=0x20"`
+		X uint8
+	}
+	zwant := []byte{'a', 'b', 0, 0xff, 0xff, 0xff, 'c', 'd', 0, 0, ' ', ' ', 7}
+	if blob, err := Marshal(&zRecord{C: "ab", W: "cd", X: 7}); err != nil || !bytes.Equal(blob, zwant) {
+		t.Fatalf("zstring: % x, %v", blob, err)
+	}
+	var zgot zRecord
+	if n, err := Unmarshal(zwant, &zgot); err != nil || n != len(zwant) || zgot.C != "ab" || zgot.W != "cd" || zgot.X != 7 {
+		t.Errorf("zstring: %d, %+v, %v", n, zgot, err)
+	}
+
+	// A single value.
+	ms := NewMarshalerOrder(BigEndian)
+	blob, err := ms.MarshalAs("ab", "wstring(4),fill=0x20")
+	if err != nil || !bytes.Equal(blob, []byte{0, 2, 'a', 'b', ' ', ' '}) {
+		t.Errorf("MarshalAs: % x, %v", blob, err)
+	}
+	var s string
+	if _, err := ms.UnmarshalAs([]byte{'a', 'b', ' ', ' '}, "string(4),fill=0x20", &s); err != nil || s != "ab" {
+		t.Errorf("UnmarshalAs: %q, %v", s, err)
+	}
+
+	for _, tag := range []string{`binary:"uint8,reserved"`, `binary:"uint8,fill=1"`, `binary:"pad(2),fill=0x100"`} {
+		typ := reflect.StructOf([]reflect.StructField{{Name: "F", Type: reflect.TypeOf(uint8(0)), Tag: reflect.StructTag(tag)}})
+		if _, err := getStructMetadata(typ); err == nil {
+			t.Errorf("%s: no error", tag)
+		}
+	}
+}
+
+type padcharRecord struct {
+	Vol  string `binary:"string(8),padchar=0x20"`
+	Raw  string `binary:"string(4),padchar=0x20,trim=none"`
+	Num  string `binary:"string(6),padchar=0x20,trim=both"`
+	Wide string `binary:"string(8),padchar=0x20,encoding=utf16"`
+	Name string `binary:"bstring(5),padchar=0x2e"`
+}
+
+func TestPadcharTrim(t *testing.T) {
+	ms := NewMarshaler()
+	ms.AddTextEncoding("utf16", unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM))
+	v := padcharRecord{Vol: "CD_01", Raw: "ab", Num: "42", Wide: "ab", Name: "x "}
+	want := []byte("CD_01   ab  42    a\x00b\x00    \x02x ...")
+	blob, err := ms.Marshal(&v)
+	if err != nil || !bytes.Equal(blob, want) {
+		t.Fatalf("Marshal: %q, %v", blob, err)
+	}
+
+	var got padcharRecord
+	if _, err := ms.Unmarshal(want, &got); err != nil || got != (padcharRecord{Vol: "CD_01", Raw: "ab  ", Num: "42", Wide: "ab", Name: "x "}) {
+		t.Errorf("Unmarshal: %+v, %v", got, err)
+	}
+	right := []byte("CD_01   ab      42a\x00b\x00    \x02x ...")
+	if _, err := ms.Unmarshal(right, &got); err != nil || got.Num != "42" {
+		t.Errorf("trim=both: %q, %v", got.Num, err)
+	}
+
+	var s string
+	if _, err := ms.UnmarshalAs([]byte("  ab\x00 "), "string(6),padchar=0x20,trim=both", &s); err != nil || s != "ab" {
+		t.Errorf("UnmarshalAs: %q, %v", s, err)
+	}
+	if _, err := ms.UnmarshalAs([]byte("ab\x00\x00"), "string(4),trim=none", &s); err != nil || s != "ab\x00\x00" {
+		t.Errorf("UnmarshalAs: %q, %v", s, err)
+	}
+
+	for _, tag := range []string{`binary:"uint8,padchar=0x20"`, `binary:"uint8,trim=both"`, `binary:"string(2),trim=left"`} {
+		typ := reflect.StructOf([]reflect.StructField{{Name: "F", Type: reflect.TypeOf(uint8(0)), Tag: reflect.StructTag(tag)}})
+		if _, err := getStructMetadata(typ); err == nil {
+			t.Errorf("%s: no error", tag)
+		}
+	}
+
+	// padchar= is an alias of fill=: giving the byte twice is an error, not
+	// the last one winning.
+	for _, tag := range []string{`binary:"string(4),fill=0xff,padchar=0x20"`, `binary:"string(4),padchar=0x20,padchar=0x2e"`} {
+		typ := reflect.StructOf([]reflect.StructField{{Name: "F", Type: reflect.TypeOf(""), Tag: reflect.StructTag(tag)}})
+		if _, err := getStructMetadata(typ); err == nil || !strings.Contains(err.Error(), "fill= and padchar= set the same byte") {
+			t.Errorf("%s: err = %v", tag, err)
+		}
+	}
+	if _, err := ms.MarshalAs("ab", "string(4),fill=0xff,padchar=0x20"); err == nil {
+		t.Error("MarshalAs with fill= and padchar=: no error")
+	}
+}
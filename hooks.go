@@ -0,0 +1,93 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// BinaryMarshalHook is implemented by structs that prepare themselves for
+// encoding, e.g. normalizing values or filling derived fields. The interpreters
+// and generated code call BeforeMarshalBinary before encoding the struct's
+// fields; an error aborts encoding.
+type BinaryMarshalHook interface {
+	BeforeMarshalBinary() error
+}
+
+// BinaryUnmarshalHook is implemented by structs that finish themselves after
+// decoding, e.g. building caches derived from the decoded fields.
+// AfterUnmarshalBinary is called once all of the struct's fields are decoded and
+// validated.
+type BinaryUnmarshalHook interface {
+	AfterUnmarshalBinary() error
+}
+
+// BinaryValidator is implemented by structs that check invariants spanning
+// several fields, such as End >= Start. ValidateBinary is called after the
+// struct's fields are decoded, before AfterUnmarshalBinary.
+type BinaryValidator interface {
+	ValidateBinary() error
+}
+
+// beforeMarshalHook calls v's BeforeMarshalBinary if v implements
+// BinaryMarshalHook. An error is returned prefixed with the hook's name; the
+// EncodeError of the field holding the struct, if any, locates it.
+func beforeMarshalHook(v interface{}) error {
+	if h, ok := v.(BinaryMarshalHook); ok {
+		if err := h.BeforeMarshalBinary(); err != nil {
+			return fmt.Errorf("BeforeMarshalBinary: %w", err)
+		}
+	}
+	return nil
+}
+
+// afterUnmarshalHooks calls v's ValidateBinary and then its
+// AfterUnmarshalBinary, for those of BinaryValidator and BinaryUnmarshalHook
+// that v implements. An error is returned prefixed with the hook's name; the
+// DecodeError of the field holding the struct, if any, locates it.
+func afterUnmarshalHooks(v interface{}) error {
+	if h, ok := v.(BinaryValidator); ok {
+		if err := h.ValidateBinary(); err != nil {
+			return fmt.Errorf("ValidateBinary: %w", err)
+		}
+	}
+	if h, ok := v.(BinaryUnmarshalHook); ok {
+		if err := h.AfterUnmarshalBinary(); err != nil {
+			return fmt.Errorf("AfterUnmarshalBinary: %w", err)
+		}
+	}
+	return nil
+}
+
+// hookTarget returns the interface value whose method set holds strc's hooks:
+// its address when addressable, so pointer-receiver hooks are found.
+func hookTarget(strc reflect.Value) interface{} {
+	if strc.CanAddr() && strc.Addr().CanInterface() {
+		return strc.Addr().Interface()
+	}
+	if strc.CanInterface() {
+		return strc.Interface()
+	}
+	return nil
+}
+
+// beforeEncode runs strc's BeforeMarshalBinary and returns the struct value to
+// encode. A struct that is not addressable but whose pointer has the hook is
+// copied, so the hook's changes reach the output without touching the caller's
+// value.
+func (ms *Marshaler) beforeEncode(strc reflect.Value) (reflect.Value, error) {
+	if !strc.CanAddr() && strc.CanInterface() {
+		if _, ok := reflect.New(strc.Type()).Interface().(BinaryMarshalHook); ok {
+			c := reflect.New(strc.Type()).Elem()
+			c.Set(strc)
+			strc = c
+		}
+	}
+	return strc, beforeMarshalHook(hookTarget(strc))
+}
+
+// afterDecode runs strc's ValidateBinary and AfterUnmarshalBinary hooks.
+func (ms *Marshaler) afterDecode(strc reflect.Value) error {
+	return afterUnmarshalHooks(hookTarget(strc))
+}
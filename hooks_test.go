@@ -0,0 +1,89 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type hookSpan struct {
+	Start uint16
+	End   uint16
+	Len   int `binary:"-"` // derived after decoding
+}
+
+var errBadSpan = errors.New("end before start")
+
+func (s *hookSpan) BeforeMarshalBinary() error {
+	if s.Start > s.End {
+		s.Start, s.End = s.End, s.Start
+	}
+	return nil
+}
+
+func (s *hookSpan) ValidateBinary() error {
+	if s.End < s.Start {
+		return errBadSpan
+	}
+	return nil
+}
+
+func (s *hookSpan) AfterUnmarshalBinary() error {
+	s.Len = int(s.End - s.Start)
+	return nil
+}
+
+type hookFile struct {
+	_     struct{} `binary:"endian=big"`
+	Count uint8
+	Spans []hookSpan `binary:"[Count]"`
+}
+
+func TestHooks(t *testing.T) {
+	in := hookFile{Count: 2, Spans: []hookSpan{{Start: 1, End: 3}, {Start: 9, End: 5}}}
+	blob, err := Marshal(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{2, 0, 1, 0, 3, 0, 5, 0, 9}
+	if !bytes.Equal(blob, want) {
+		t.Fatalf("blob = %x, want %x", blob, want)
+	}
+
+	var out hookFile
+	if _, err := Unmarshal(blob, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Spans[0].Len != 2 || out.Spans[1].Len != 4 {
+		t.Fatalf("AfterUnmarshalBinary not run: %+v", out.Spans)
+	}
+
+	// A value that is not addressable is normalized in the output only.
+	span := hookSpan{Start: 7, End: 2}
+	blob, err = NewMarshalerOrder(BigEndian).Marshal(span)
+	if err != nil || !bytes.Equal(blob, []byte{0, 2, 0, 7}) {
+		t.Fatalf("Marshal by value = %x, %v", blob, err)
+	}
+	if span.Start != 7 {
+		t.Fatalf("the caller's value changed: %+v", span)
+	}
+}
+
+func TestHooks_ValidateError(t *testing.T) {
+	var out hookFile
+	_, err := Unmarshal([]byte{2, 0, 1, 0, 3, 0, 9, 0, 5}, &out)
+	if !errors.Is(err, errBadSpan) {
+		t.Fatalf("err = %v, want errBadSpan", err)
+	}
+	// The field holding the struct locates the failure; the hook is named.
+	var de *DecodeError
+	if !errors.As(err, &de) || de.Field != "Spans" || de.Offset != 1 || de.Path != "Spans[1]" || de.AbsOffset != 5 {
+		t.Fatalf("err = %v, want a DecodeError for Spans[1]", err)
+	}
+	if strings.Contains(err.Error(), "hookSpan") || !strings.Contains(err.Error(), "ValidateBinary: ") {
+		t.Fatalf("err = %v, want the hook named and no type name", err)
+	}
+}
@@ -185,6 +185,9 @@ func (ms *Marshaler) Inspect(govalue interface{}) (*StructLayout, error) {
 }
 
 func (ms *Marshaler) inspectStruct(strc reflect.Value, order ByteOrder, prefix string, fields *[]FieldLayout, offset *int) error {
+	ms.enterStruct(strc, nil)
+	defer ms.leaveStruct()
+	start := *offset
 	typ := strc.Type()
 	meta, err := getStructMetadata(typ)
 	if err != nil {
@@ -192,16 +195,31 @@ func (ms *Marshaler) inspectStruct(strc reflect.Value, order ByteOrder, prefix s
 	}
 	// Match the encode/decode paths: a struct-level byte order overrides the
 	// inherited order for this struct's fields.
-	order = resolveByteOrder(order, meta.endian)
+	if order, err = ms.resolveOrder(order, meta.endian); err != nil {
+		return err
+	}
 
 	omittedRemaining := false
-	for _, fMeta := range meta.fields {
+	dropFrom := meta.omitDefaultsFrom(strc)
+	marked := false // whether meta.orderMark has been applied
+	for fi, fMeta := range meta.fields {
 		if fMeta.ignore {
 			continue
 		}
 		if fMeta.unexported {
 			continue
 		}
+		// The layout is that of the encoded value.
+		fMeta = fMeta.encodeMeta()
+		ms.setStructOffset(*offset - start)
+		if order, err = meta.orderMark.switchOrder(ms, order, strc, fMeta.index, &marked); err != nil {
+			return err
+		}
+		order = meta.orderDetect.writeOrder(order, strc, fMeta.index)
+		fieldOrder, err := ms.resolveOrder(order, fMeta.endian)
+		if err != nil {
+			return err
+		}
 
 		fieldName := fMeta.name
 		if prefix != "" {
@@ -219,7 +237,7 @@ func (ms *Marshaler) inspectStruct(strc reflect.Value, order ByteOrder, prefix s
 				Offset:     *offset,
 				Size:       0,
 				Tag:        tagStr,
-				Endian:     endianString(resolveByteOrder(order, fMeta.endian)),
+				Endian:     endianString(fieldOrder),
 				RawValue:   nil,
 				Details:    "omitted (subsequent to an omitted field)",
 			})
@@ -228,7 +246,7 @@ func (ms *Marshaler) inspectStruct(strc reflect.Value, order ByteOrder, prefix s
 
 		// Check if omittable expression is met
 		if fMeta.omittable && fMeta.omittableExpr != "" {
-			limit, errEval := evaluateTagValue(strc, fMeta.omittableExpr)
+			limit, errEval := ms.evalTagValue(strc, fMeta.omittableExpr)
 			if errEval == nil && *offset >= limit {
 				*fields = append(*fields, FieldLayout{
 					Index:      fMeta.index,
@@ -238,7 +256,7 @@ func (ms *Marshaler) inspectStruct(strc reflect.Value, order ByteOrder, prefix s
 					Offset:     *offset,
 					Size:       0,
 					Tag:        tagStr,
-					Endian:     endianString(resolveByteOrder(order, fMeta.endian)),
+					Endian:     endianString(fieldOrder),
 					RawValue:   nil,
 					Details:    fmt.Sprintf("omitted (reached limit %d)", limit),
 				})
@@ -247,6 +265,24 @@ func (ms *Marshaler) inspectStruct(strc reflect.Value, order ByteOrder, prefix s
 			}
 		}
 
+		// A trailing omitdefault field left at its default is not written.
+		if fi >= dropFrom {
+			*fields = append(*fields, FieldLayout{
+				Index:      fMeta.index,
+				Name:       fieldName,
+				GoType:     typ.Field(fMeta.index).Type.String(),
+				BinaryType: fMeta.encodeType.String(),
+				Offset:     *offset,
+				Size:       0,
+				Tag:        tagStr,
+				Endian:     endianString(fieldOrder),
+				RawValue:   nil,
+				Details:    "omitted (equal to its default)",
+			})
+			omittedRemaining = true
+			continue
+		}
+
 		fieldVal := strc.Field(fMeta.index)
 		fKind := typ.Field(fMeta.index).Type.Kind()
 
@@ -260,7 +296,7 @@ func (ms *Marshaler) inspectStruct(strc reflect.Value, order ByteOrder, prefix s
 				Offset:     *offset,
 				Size:       0,
 				Tag:        tagStr,
-				Endian:     endianString(resolveByteOrder(order, fMeta.endian)),
+				Endian:     endianString(fieldOrder),
 				RawValue:   nil,
 				Details:    "omitted (pointer is nil)",
 			})
@@ -281,11 +317,11 @@ func (ms *Marshaler) inspectStruct(strc reflect.Value, order ByteOrder, prefix s
 			if fMeta.isArray {
 				option.isArray = true
 				if fMeta.arrayLenExpr != "" {
-					option.arrayLen, _ = evaluateTagValue(strc, fMeta.arrayLenExpr)
+					option.arrayLen, _ = ms.evalTagValue(strc, fMeta.arrayLenExpr)
 				}
 			}
 			if fMeta.bufLenExpr != "" {
-				option.bufLen, _ = evaluateTagValue(strc, fMeta.bufLenExpr)
+				option.bufLen, _ = ms.evalTagValue(strc, fMeta.bufLenExpr)
 			}
 			if fMeta.encoding != "" {
 				option.encoding = fMeta.encoding
@@ -298,8 +334,6 @@ func (ms *Marshaler) inspectStruct(strc reflect.Value, order ByteOrder, prefix s
 			}
 		}
 
-		fieldOrder := resolveByteOrder(order, option.endian)
-
 		// Dereference pointer/interface for size/nested analysis
 		v := fieldVal
 		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
@@ -342,6 +376,14 @@ func (ms *Marshaler) inspectStruct(strc reflect.Value, order ByteOrder, prefix s
 				}
 			}
 		}
+		if fMeta.enum != nil {
+			if e := ms.enumDetails(fieldVal, &fMeta); e != "" {
+				if details != "" {
+					details += "; "
+				}
+				details += e
+			}
+		}
 
 		*fields = append(*fields, FieldLayout{
 			Index:      fMeta.index,
@@ -0,0 +1,145 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+)
+
+// ErrLimitExceeded is wrapped by the decode errors of a value that exceeds one
+// of the Marshaler's limits: MaxSliceLen, MaxStringLen, MaxDecodeAlloc or
+// MaxDepth.
+var ErrLimitExceeded = errors.New("decode limit exceeded")
+
+// allocChunk is the most memory, in bytes, a decode allocates ahead of the
+// input it has read for a slice or string whose input is of unknown size.
+const allocChunk = 64 << 10
+
+// allocAhead returns how many of count elements, each wireSize bytes of input
+// (0 if it varies) and size bytes of memory, a decode may allocate before
+// reading them: all of them when r is known to hold them, otherwise no more
+// than allocChunk bytes' worth, so that a corrupt count runs into the end of
+// the input instead of allocating memory the input can never fill.
+func allocAhead(r io.Reader, count, wireSize, size int) int {
+	if p, ok := r.(*prefixReader); ok {
+		p.expect(count, wireSize)
+	}
+	if rem, err := remainingLen(r); err == nil && wireSize > 0 && count <= rem/wireSize {
+		return count
+	}
+	if size < 1 {
+		size = 1
+	}
+	if c := max(allocChunk/size, 1); count > c {
+		return c
+	}
+	return count
+}
+
+// limitString checks size, the length in bytes of a string about to be
+// decoded, against ms.MaxStringLen and charges it to ms.MaxDecodeAlloc.
+func (ms *Marshaler) limitString(size int) error {
+	if size < 0 {
+		return errNegativeSize
+	}
+	if ms == nil {
+		return nil
+	}
+	if ms.MaxStringLen > 0 && size > ms.MaxStringLen {
+		return fmt.Errorf("string length %d exceeds MaxStringLen %d: %w", size, ms.MaxStringLen, ErrLimitExceeded)
+	}
+	return ms.chargeAlloc(size)
+}
+
+// limitSlice checks count, the length of a slice being decoded, against
+// MaxSliceLen, and charges alloc of its elements, those the decode allocates,
+// of size bytes each to MaxDecodeAlloc.
+func (ms *Marshaler) limitSlice(count, alloc, size int) error {
+	if count < 0 {
+		return errNegativeSize
+	}
+	if ms == nil {
+		return nil
+	}
+	if ms.MaxSliceLen > 0 && count > ms.MaxSliceLen {
+		return fmt.Errorf("slice length %d exceeds MaxSliceLen %d: %w", count, ms.MaxSliceLen, ErrLimitExceeded)
+	}
+	if alloc <= 0 {
+		return nil
+	}
+	if size > 0 && alloc > math.MaxInt/size {
+		return fmt.Errorf("slice length %d of %d-byte elements exceeds the address space: %w", count, size, ErrLimitExceeded)
+	}
+	return ms.chargeAlloc(alloc * size)
+}
+
+// chargeAlloc adds size bytes to the memory allocated by the current decode,
+// failing when it would exceed MaxDecodeAlloc.
+func (ms *Marshaler) chargeAlloc(size int) error {
+	if ms.MaxDecodeAlloc <= 0 {
+		return nil
+	}
+	if size > ms.MaxDecodeAlloc-ms.allocated {
+		return fmt.Errorf("allocating %d bytes after %d exceeds MaxDecodeAlloc %d: %w", size, ms.allocated, ms.MaxDecodeAlloc, ErrLimitExceeded)
+	}
+	ms.allocated += size
+	return nil
+}
+
+// enterNested records that a decode enters a nested struct, failing when that
+// exceeds ms.MaxDepth; leaveNested records that it left it. Both are no-ops on
+// a nil Marshaler.
+func (ms *Marshaler) enterNested() error {
+	if ms == nil {
+		return nil
+	}
+	if ms.MaxDepth > 0 && ms.depth >= ms.MaxDepth {
+		return fmt.Errorf("struct nesting exceeds MaxDepth %d: %w", ms.MaxDepth, ErrLimitExceeded)
+	}
+	ms.depth++
+	return nil
+}
+
+// leaveNested ends a nesting begun by enterNested; see there.
+func (ms *Marshaler) leaveNested() {
+	if ms != nil && ms.depth > 0 {
+		ms.depth--
+	}
+}
+
+// readBytes reads size bytes of r into a new slice. When r does not tell how
+// much input it holds (see remainingLen), or holds less than size, the slice
+// grows as the input arrives, so that a corrupt length fails at the end of the
+// input instead of first allocating size bytes. Like io.ReadFull it returns the
+// bytes read with io.EOF if there were none, or io.ErrUnexpectedEOF if there
+// were fewer than size.
+func readBytes(r io.Reader, size int) (b []byte, n int, err error) {
+	if size < 0 {
+		return nil, 0, errNegativeSize
+	}
+	if allocAhead(r, size, 1, 1) == size {
+		b = make([]byte, size)
+		n, err = io.ReadFull(r, b)
+		return b[:n], n, err
+	}
+	b = make([]byte, 0, allocChunk)
+	for len(b) < size {
+		if len(b) == cap(b) {
+			b = slices.Grow(b, min(size-len(b), len(b)))
+		}
+		var m int
+		m, err = io.ReadFull(r, b[len(b):min(cap(b), size)])
+		b = b[:len(b)+m]
+		if err != nil {
+			if err == io.EOF && len(b) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			break
+		}
+	}
+	return b, len(b), err
+}
@@ -0,0 +1,153 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// The Marshaler's limits bound what a decode allocates for the lengths it
+// reads, and a length the input cannot back is not allocated ahead of it.
+
+type limitRecord struct {
+	_     struct{} `binary:"endian=big"`
+	Count uint32
+	Items []uint16 `binary:"[Count]uint16"`
+	Name  string   `binary:"dwstring"`
+}
+
+type limitNode struct {
+	_    struct{} `binary:"endian=big"`
+	N    uint8
+	Kids []limitNode `binary:"[N]any"`
+}
+
+// streamOnly hides the length of its input.
+type streamOnly struct{ io.Reader }
+
+func TestLimits(t *testing.T) {
+	blob := []byte{0, 0, 0, 3, 0, 1, 0, 2, 0, 3, 0, 0, 0, 4, 'n', 'a', 'm', 'e'}
+	cases := []struct {
+		set   func(*Marshaler)
+		field string
+		msg   string
+	}{
+		{func(ms *Marshaler) {}, "", ""},
+		{func(ms *Marshaler) { ms.MaxSliceLen = 3; ms.MaxStringLen = 4; ms.MaxDecodeAlloc = 10 }, "", ""},
+		{func(ms *Marshaler) { ms.MaxSliceLen = 2 }, "Items", "slice length 3 exceeds MaxSliceLen 2"},
+		{func(ms *Marshaler) { ms.MaxStringLen = 3 }, "Name", "string length 4 exceeds MaxStringLen 3"},
+		{func(ms *Marshaler) { ms.MaxDecodeAlloc = 9 }, "Name", "allocating 4 bytes after 6 exceeds MaxDecodeAlloc 9"},
+	}
+	for _, c := range cases {
+		ms := NewMarshaler()
+		c.set(ms)
+		for i := 0; i < 2; i++ { // the allocation is counted per decode
+			var out limitRecord
+			_, err := ms.Unmarshal(blob, &out)
+			if c.field == "" {
+				if err != nil || len(out.Items) != 3 || out.Name != "name" {
+					t.Errorf("%+v, err = %v", out, err)
+				}
+				continue
+			}
+			var de *DecodeError
+			if !errors.As(err, &de) || de.Field != c.field || !errors.Is(err, ErrLimitExceeded) || !strings.Contains(err.Error(), c.msg) {
+				t.Errorf("%s: err = %v", c.msg, err)
+			}
+		}
+	}
+}
+
+func TestLimits_Depth(t *testing.T) {
+	blob := []byte{1, 1, 0} // three levels
+	ms := NewMarshaler()
+	ms.MaxDepth = 3
+	if _, err := ms.Unmarshal(blob, &limitNode{}); err != nil {
+		t.Fatal(err)
+	}
+	ms.MaxDepth = 2
+	_, err := ms.Unmarshal(blob, &limitNode{})
+	var de *DecodeError
+	if !errors.As(err, &de) || de.Path != "Kids[0].Kids[0]" || !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("err = %v", err)
+	}
+	if _, err := ms.Unmarshal([]byte{1, 0}, &limitNode{}); err != nil {
+		t.Errorf("after a failed decode: %v", err)
+	}
+}
+
+// A corrupt length fails at the end of the input without allocating what it
+// claims, whether or not the reader tells its length.
+func TestLimits_HostileLength(t *testing.T) {
+	for _, blob := range [][]byte{
+		{0xff, 0xff, 0xff, 0xff, 0, 1},                 // Count
+		{0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 'a', 'b'}, // Name's length
+	} {
+		for _, stream := range []bool{false, true} {
+			var r io.Reader = bytes.NewReader(blob)
+			if stream {
+				r = streamOnly{r}
+			}
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			_, err := Read(r, &limitRecord{})
+			runtime.ReadMemStats(&after)
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("stream %v: err = %v", stream, err)
+			}
+			if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 1<<20 {
+				t.Errorf("stream %v: allocated %d bytes", stream, alloc)
+			}
+		}
+	}
+}
+
+// A long slice or string read from a stream is read a chunk at a time.
+func TestLimits_Stream(t *testing.T) {
+	in := limitRecord{Items: make([]uint16, 100000), Name: strings.Repeat("x", 200000)}
+	for i := range in.Items {
+		in.Items[i] = uint16(i)
+	}
+	in.Count = uint32(len(in.Items))
+	blob, err := Marshal(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out limitRecord
+	if _, err := Read(streamOnly{bytes.NewReader(blob)}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Items) != len(in.Items) || out.Items[len(out.Items)-1] != in.Items[len(in.Items)-1] || out.Name != in.Name {
+		t.Errorf("got %d items, %d-byte name", len(out.Items), len(out.Name))
+	}
+
+	type node struct {
+		_     struct{} `binary:"endian=big"`
+		Count uint32
+		Kids  []limitNode `binary:"[Count]any"`
+	}
+	blob = append([]byte{0, 1, 0, 0}, make([]byte, 65536)...)
+	var nodes node
+	if _, err := Read(streamOnly{bytes.NewReader(blob)}, &nodes); err != nil || len(nodes.Kids) != 65536 {
+		t.Errorf("%d nodes, err = %v", len(nodes.Kids), err)
+	}
+}
+
+// Decoding into a slice shorter than the length read keeps its elements and
+// appends the rest.
+func TestLimits_ShortSlice(t *testing.T) {
+	type rec struct {
+		N int     `binary:"uint8"`
+		A []int16 `binary:"[N]int8"`
+		B []int8  `binary:"[N]int8"`
+	}
+	out := rec{A: []int16{9}, B: []int8{9}}
+	if _, err := Unmarshal([]byte{3, 1, 2, 3, 4, 5, 6}, &out); err != nil || len(out.A) != 3 || out.A[2] != 3 || len(out.B) != 3 || out.B[2] != 6 {
+		t.Errorf("%+v, err = %v", out, err)
+	}
+}
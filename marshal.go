@@ -53,8 +53,21 @@ type Marshaler struct {
 	Order               ByteOrder                    // fallback byte order for values that declare none; see NewMarshalerOrder
 	TextEncoding        map[string]encoding.Encoding // map[encodingName]Encoding
 	DefaultTextEncoding string                       // default text encoding name
+	Validation          ValidationMode               // decode-time validation; see ValidationMode
+	Warnings            []*DecodeError               // checks failed under ValidateWarn in the last decode
+	ValidateOnEncode    bool                         // run the range/match/enum/check validators on encode too, failing with an *EncodeError
+	Strict              bool                         // reject lossy conversions and, in Unmarshal, trailing input; see SPECIFICATION.md
+	MaxSliceLen         int                          // decode: most elements in a slice or array read from the input; 0 for no limit
+	MaxStringLen        int                          // decode: most bytes in a string; 0 for no limit
+	MaxDecodeAlloc      int                          // decode: most bytes of slices and strings allocated by one decode; 0 for no limit
+	MaxDepth            int                          // decode: most levels of nested structs, the value decoded being the first; 0 for no limit
+	Salvage             bool                         // decode: on failure keep the fields decoded and return a *SalvageError listing them
+	Canonical           bool                         // decode: Unmarshal rejects input other than the encoding of the value decoded; see ErrNonCanonical
 	codecs              map[string]Codec             // registered custom codecs
 	valueofs            map[string]ValueOfFunc       // registered custom valueof evaluators
+	params              map[string]int               // tag-expression parameters ($name)
+	byteOrders          map[string]ByteOrder         // registered named byte orders
+	enums               map[string]map[uint64]string // registered enum value names
 
 	encoderCache map[string]*encoding.Encoder // cache of encoding.NewEncoder()
 	decoderCache map[string]*encoding.Decoder // cache of encoding.NewDecoder()
@@ -67,6 +80,36 @@ type Marshaler struct {
 	// concurrency note on the package functions — the same rule already applies to
 	// the lazily-populated encoder cache).
 	scratch [8]byte
+
+	// structStack holds the structs whose fields are being processed, outermost
+	// first, so that parent. and root. references in tag expressions can reach
+	// the enclosing structs, and $offset and $remaining the current position.
+	// Like scratch, it is per-operation state.
+	structStack []structFrame
+
+	// collected holds the failed checks of a decode under ValidateCollect, and
+	// decodeDepth the nesting of beginDecode calls; see beginDecode.
+	collected   []error
+	decodeDepth int
+
+	// allocated is the memory charged to MaxDecodeAlloc by the current decode,
+	// and depth the nesting of structs counted against MaxDepth; see limits.go.
+	allocated int
+	depth     int
+
+	// salvaged holds the paths of the fields decoded under Salvage, and
+	// salvageArmed whether the next struct to be decoded records its fields,
+	// with salvageNext the path prefix of those fields; see salvage.go.
+	salvaged     []string
+	salvageArmed bool
+	salvageNext  string
+}
+
+// structFrame is a struct being encoded or decoded.
+type structFrame struct {
+	strc   reflect.Value
+	offset int       // bytes of strc encoded or decoded so far ($offset)
+	r      io.Reader // the input while decoding ($remaining), nil while encoding
 }
 
 // NewMarshaler returns a Marshaler with no fallback byte order: values must
@@ -134,9 +177,14 @@ func (ms *Marshaler) GetCodec(name string) Codec {
 
 // AddValueOf registers a custom valueof evaluator with a Marshaler. The name may
 // then be used in struct field tags, like `binary:"uint32,valueof=name(Field, ...)"`,
-// to compute the field's serialized value (e.g. a CRC over other fields). The
-// name must not collide with the built-ins bytelen or count.
+// to compute the field's serialized value (e.g. a CRC over other fields).
+// AddValueOf panics if name is bytelen, count or an expression built-in (align,
+// min, max, abs, sizeof): a tag always calls the built-in, so such an evaluator
+// could never run.
 func (ms *Marshaler) AddValueOf(name string, fn ValueOfFunc) {
+	if name == "bytelen" || name == "count" || isExprBuiltin(name) {
+		panic("binarystruct: AddValueOf: " + name + " is reserved by the tag expression language")
+	}
 	if ms.valueofs == nil {
 		ms.valueofs = make(map[string]ValueOfFunc)
 	}
@@ -161,6 +209,114 @@ func (ms *Marshaler) GetValueOf(name string) ValueOfFunc {
 	return nil
 }
 
+// SetParam sets a parameter that tag expressions refer to as $name, e.g.
+// `binary:"[$RecordSize]byte"` after ms.SetParam("RecordSize", 64). The
+// built-in variables $offset and $remaining take precedence over parameters of
+// the same name.
+func (ms *Marshaler) SetParam(name string, value int) {
+	if ms.params == nil {
+		ms.params = make(map[string]int)
+	}
+	ms.params[name] = value
+}
+
+// RemoveParam removes a parameter set by SetParam.
+func (ms *Marshaler) RemoveParam(name string) {
+	if ms.params != nil {
+		delete(ms.params, name)
+	}
+}
+
+// paramInt returns the parameter set by SetParam, or an error if it is not set
+// (including on a nil Marshaler).
+func (ms *Marshaler) paramInt(name string) (int, error) {
+	if ms != nil {
+		if v, ok := ms.params[name]; ok {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("parameter $%s is not set", name)
+}
+
+// remainingLen reports how many bytes of input r still holds, the value of
+// $remaining in tag expressions. r must know its length: a reader with a Len()
+// int method (such as *bytes.Reader, *bytes.Buffer or *strings.Reader, which
+// Unmarshal uses) or an *io.LimitedReader.
+func remainingLen(r io.Reader) (int, error) {
+	switch rd := r.(type) {
+	case interface{ Len() int }:
+		return rd.Len(), nil
+	case *io.LimitedReader:
+		return int(rd.N), nil
+	}
+	return 0, errRemainingUnknown
+}
+
+// outerInt evaluates a parent. or root. field reference, such as
+// "parent.EntrySize" or "root.Hdr.Count", for strc, a pointer to the struct
+// being encoded or decoded, against the enclosing structs on structStack. With
+// no enclosing struct, root. refers to strc itself and parent. is an error.
+func (ms *Marshaler) outerInt(strc interface{}, ref string) (int, error) {
+	var outer []structFrame
+	if ms != nil {
+		outer = ms.structStack
+	}
+	return fieldValueResolver(reflect.Indirect(reflect.ValueOf(strc)), outer)(ref)
+}
+
+// enterStruct pushes strc onto structStack; r is the input when decoding.
+func (ms *Marshaler) enterStruct(strc reflect.Value, r io.Reader) {
+	ms.structStack = append(ms.structStack, structFrame{strc: strc, r: r})
+}
+
+func (ms *Marshaler) leaveStruct() {
+	ms.structStack[len(ms.structStack)-1] = structFrame{}
+	ms.structStack = ms.structStack[:len(ms.structStack)-1]
+}
+
+// setStructOffset records n, the bytes of the current struct processed so far,
+// as the value of $offset for the field about to be processed.
+func (ms *Marshaler) setStructOffset(n int) {
+	ms.structStack[len(ms.structStack)-1].offset = n
+}
+
+// outerStructs returns the structs enclosing the one being processed (the top
+// of structStack), outermost first.
+func (ms *Marshaler) outerStructs() []structFrame {
+	if len(ms.structStack) == 0 {
+		return nil
+	}
+	return ms.structStack[:len(ms.structStack)-1]
+}
+
+// resolveParam resolves $name in a tag expression: the built-in variables
+// $offset (the offset of the current field within its struct) and $remaining
+// (the input left to decode), then the parameters set by SetParam.
+func (ms *Marshaler) resolveParam(name string) (int, error) {
+	switch name {
+	case "offset", "remaining":
+		if len(ms.structStack) == 0 {
+			return 0, fmt.Errorf("$%s is only available within a struct", name)
+		}
+		top := ms.structStack[len(ms.structStack)-1]
+		if name == "offset" {
+			return top.offset, nil
+		}
+		if top.r == nil {
+			return 0, fmt.Errorf("$remaining is only available when decoding")
+		}
+		return remainingLen(top.r)
+	}
+	return ms.paramInt(name)
+}
+
+// evalTagValue evaluates a tag expression of strc, the struct being processed,
+// resolving parent. and root. references against the enclosing structs and
+// $name against the Marshaler.
+func (ms *Marshaler) evalTagValue(strc reflect.Value, expr string) (int, error) {
+	return evaluateTagValueIn(strc, ms.outerStructs(), ms.resolveParam, expr)
+}
+
 // Marshaler.Marshal() encodes a go value into binary data using the Marshaler's byte order.
 func (ms *Marshaler) Marshal(govalue interface{}) (encoded []byte, err error) {
 	var b bytes.Buffer
@@ -239,7 +395,9 @@ func (ms *Marshaler) writeValue(w io.Writer, order ByteOrder, v reflect.Value) (
 // write a value as given type
 func (ms *Marshaler) writeMain(w io.Writer, order ByteOrder, v reflect.Value, encodeType eType, option typeOption, parentStruct reflect.Value, fieldIndex int) (n int, err error) {
 
-	order = resolveByteOrder(order, option.endian)
+	if order, err = ms.resolveOrder(order, option.endian); err != nil {
+		return 0, err
+	}
 
 	if option.codec != "" {
 		codec, ok := ms.codecs[option.codec]
@@ -290,7 +448,7 @@ func (ms *Marshaler) writeMain(w io.Writer, order ByteOrder, v reflect.Value, en
 		if l == 0 {
 			l = 1
 		}
-		return zeroFill(w, l)
+		return padFill(w, l, option.fill)
 
 	case Ignore: // ignoring value: `binary:"ignore"`
 		return 0, nil
@@ -310,7 +468,7 @@ func (ms *Marshaler) writeMain(w io.Writer, order ByteOrder, v reflect.Value, en
 		return ms.writeStruct(w, order, v)
 
 	case stringKind:
-		return ms.writeString(w, order, v, encodeType, option.bufLen, option.encoding)
+		return ms.writeString(w, order, v, encodeType, option.bufLen, option.encoding, option.fill)
 	}
 
 	err = fmt.Errorf("unknown type %s", encodeType)
@@ -333,7 +491,7 @@ func (ms *Marshaler) writeArray(w io.Writer, order ByteOrder, array reflect.Valu
 			desiredLen = actualLen
 		}
 		if actualLen > desiredLen {
-			return 0, fmt.Errorf("array too large to fit: len %d, size %d", desiredLen, actualLen)
+			return 0, fmt.Errorf("array too large to fit: len %d, size %d: %w", desiredLen, actualLen, ErrValueOverflow)
 		}
 		child := option
 		child.dims = option.dims[1:]
@@ -349,7 +507,7 @@ func (ms *Marshaler) writeArray(w io.Writer, order ByteOrder, array reflect.Valu
 			m, err = ms.writeMain(w, order, e, elementType, child, reflect.Value{}, -1)
 			n += m
 			if err != nil {
-				return n, fmt.Errorf("array index [%d]: %w", i, err)
+				return n, &elementError{index: i, offset: n - m, err: err}
 			}
 		}
 		return n, nil
@@ -380,7 +538,7 @@ func (ms *Marshaler) writeArray(w io.Writer, order ByteOrder, array reflect.Valu
 		desiredLen = arrayLen
 	}
 	if desiredLen < arrayLen {
-		err = fmt.Errorf("array too large to fit: len %d, size %d", desiredLen, arrayLen)
+		err = fmt.Errorf("array too large to fit: len %d, size %d: %w", desiredLen, arrayLen, ErrValueOverflow)
 		return
 		// arrayLen = desiredLen
 	}
@@ -396,7 +554,7 @@ func (ms *Marshaler) writeArray(w io.Writer, order ByteOrder, array reflect.Valu
 	}
 
 	wErr := func(i int, e error) error {
-		return fmt.Errorf("array index [%d]: %w", i, e)
+		return &elementError{index: i, offset: n, err: e}
 	}
 	var m int
 	for i := 0; i < arrayLen; i++ {
@@ -416,6 +574,7 @@ func (ms *Marshaler) writeArray(w io.Writer, order ByteOrder, array reflect.Valu
 			var o typeOption
 			o.bufLen = option.bufLen     // option may contain inheritable values
 			o.encoding = option.encoding // option may contain inheritable values
+			o.fill, o.trim = option.fill, option.trim
 			m, err = ms.writeMain(w, order, e, elementType, o, reflect.Value{}, -1)
 			if err != nil {
 				err = wErr(i, err)
@@ -444,8 +603,12 @@ func (ms *Marshaler) writeArray(w io.Writer, order ByteOrder, array reflect.Valu
 		// total size = element size * element count
 		sz = sz * (desiredLen - arrayLen)
 
-		// write blank bytes
-		m, err = zeroFill(w, sz)
+		// write blank bytes, or a padding's fill
+		fill := byte(0)
+		if elementType == Pad {
+			fill = option.fill
+		}
+		m, err = padFill(w, sz, fill)
 		n += m
 		if err != nil {
 			return
@@ -478,9 +641,16 @@ func (ms *Marshaler) writeScalarSliceBulk(w io.Writer, order ByteOrder, array re
 	}
 	buf := make([]byte, desiredLen*sz)
 	for i := 0; i < arrayLen; i++ {
-		u64, _, e := enc(array.Index(i))
+		var u64 uint64
+		var e error
+		if ms.Strict {
+			e = strictEncode(array.Index(i), elementType)
+		}
+		if e == nil {
+			u64, _, e = enc(array.Index(i))
+		}
 		if e != nil {
-			return 0, true, fmt.Errorf("array index [%d]: %w", i, e)
+			return 0, true, &elementError{index: i, offset: i * sz, err: e}
 		}
 		switch sz {
 		case 1:
@@ -540,6 +710,11 @@ func (ms *Marshaler) writeStruct(w io.Writer, order ByteOrder, strc reflect.Valu
 	if !safeMode {
 		return ms.unsafeWriteStruct(w, order, strc)
 	}
+	if strc, err = ms.beforeEncode(strc); err != nil {
+		return 0, err
+	}
+	ms.enterStruct(strc, nil)
+	defer ms.leaveStruct()
 	typ := strc.Type()
 	meta, err := getStructMetadata(typ)
 	if err != nil {
@@ -548,13 +723,19 @@ func (ms *Marshaler) writeStruct(w io.Writer, order ByteOrder, strc reflect.Valu
 	// A struct-level byte order (declared via a `_` sentinel or inherited from an
 	// embedded struct) overrides the inherited order for this struct's fields;
 	// per-field endian= still overrides it in turn.
-	order = resolveByteOrder(order, meta.endian)
+	if order, err = ms.resolveOrder(order, meta.endian); err != nil {
+		return 0, err
+	}
 	wErr := func(i int, e error) error {
-		f := typ.Field(i)
-		return fmt.Errorf("field <%s>: %w", f.Name, e)
+		return newEncodeError(n, typ.Field(i).Name, e)
 	}
 	writeEval := ms.encodeExprEval(order, strc, meta)
-	for _, fMeta := range meta.fields {
+	dropFrom := meta.omitDefaultsFrom(strc) // trailing omitdefault fields left at their defaults
+	marked := false                         // whether meta.orderMark has been applied
+	for fi, fMeta := range meta.fields {
+		if fi >= dropFrom {
+			break
+		}
 		if fMeta.ignore {
 			continue
 		}
@@ -565,12 +746,18 @@ func (ms *Marshaler) writeStruct(w io.Writer, order ByteOrder, strc reflect.Valu
 			err = wErr(fMeta.index, fMeta.fieldErr)
 			return
 		}
+		fMeta = fMeta.encodeMeta()
+		ms.setStructOffset(n)
+		if order, err = meta.orderMark.switchOrder(ms, order, strc, fMeta.index, &marked); err != nil {
+			return n, wErr(meta.orderMark.index, err)
+		}
+		order = meta.orderDetect.writeOrder(order, strc, fMeta.index)
 
 		fieldVal := strc.Field(fMeta.index)
 
 		if fMeta.omittable {
 			if fMeta.omittableExpr != "" {
-				limit, errEval := evaluateTagValue(strc, fMeta.omittableExpr)
+				limit, errEval := ms.evalTagValue(strc, fMeta.omittableExpr)
 				if errEval == nil && n >= limit {
 					break
 				}
@@ -580,6 +767,11 @@ func (ms *Marshaler) writeStruct(w io.Writer, order ByteOrder, strc reflect.Valu
 				break
 			}
 		}
+		if ms.ValidateOnEncode {
+			if err = ms.validateEncode(fieldVal, &fMeta, writeEval); err != nil {
+				return n, wErr(fMeta.index, err)
+			}
+		}
 
 		naturalType, option, errF := ms.resolveFieldEncoding(fieldVal, fMeta, writeEval)
 		if errF != nil {
@@ -701,6 +893,7 @@ func (ms *Marshaler) resolveFieldEncoding(fieldVal reflect.Value, fMeta structFi
 	if fMeta.codec != "" {
 		option.codec = fMeta.codec
 	}
+	option.fill, option.reserved, option.trim = fMeta.option.fill, fMeta.option.reserved, fMeta.option.trim
 	return
 }
 
@@ -715,14 +908,14 @@ func (ms *Marshaler) encodeExprEval(order ByteOrder, strc reflect.Value, meta *s
 }
 
 // evalEncodeExpr evaluates a size expression at ENCODE time, resolving any
-// referenced valueof field to its computed value. Functions are not permitted
-// in size expressions.
+// referenced valueof field to its computed value. Only the expression built-ins
+// (align, min, max, abs, sizeof) are permitted in size expressions.
 func (ms *Marshaler) evalEncodeExpr(order ByteOrder, strc reflect.Value, meta *structMetadata, expr string) (int, error) {
 	tokens, err := tokenize(expr)
 	if err != nil {
 		return 0, err
 	}
-	base := fieldValueResolver(strc)
+	base := fieldValueResolver(strc, ms.outerStructs())
 	p := &tagParser{
 		tokens: tokens,
 		strc:   strc,
@@ -732,6 +925,8 @@ func (ms *Marshaler) evalEncodeExpr(order ByteOrder, strc reflect.Value, meta *s
 			}
 			return base(name)
 		},
+		sizeOf:       typeSizeResolver(strc.Type()),
+		resolveParam: ms.resolveParam,
 	}
 	v, err := p.parseExpr()
 	if err != nil {
@@ -753,10 +948,12 @@ func (ms *Marshaler) evalValueof(order ByteOrder, strc reflect.Value, meta *stru
 	p := &tagParser{
 		tokens:       tokens,
 		strc:         strc,
-		resolveIdent: fieldValueResolver(strc),
+		resolveIdent: fieldValueResolver(strc, ms.outerStructs()),
 		callFunc: func(fn string, args []string) (int, error) {
 			return ms.evalValueofFunc(order, strc, meta, fn, args)
 		},
+		sizeOf:       typeSizeResolver(strc.Type()),
+		resolveParam: ms.resolveParam,
 	}
 	v, err := p.parseExpr()
 	if err != nil {
@@ -1065,7 +1262,7 @@ func (ms *Marshaler) DecodeText(encoded []byte, textEncoding string) (utf8 []byt
 }
 
 // write string types
-func (ms *Marshaler) writeString(w io.Writer, order ByteOrder, v reflect.Value, encodeType eType, bufLen int, textEncoding string) (n int, err error) {
+func (ms *Marshaler) writeString(w io.Writer, order ByteOrder, v reflect.Value, encodeType eType, bufLen int, textEncoding string, fill byte) (n int, err error) {
 	s := v.String()
 	stringBytes := []byte(s)
 
@@ -1085,9 +1282,12 @@ func (ms *Marshaler) writeString(w io.Writer, order ByteOrder, v reflect.Value,
 	strlen := len(stringBytes)
 	if bufLen <= 0 {
 		bufLen = strlen
+	} else if term := terminatorSize(encodeType); ms.Strict && term > 0 && strlen+term > bufLen {
+		err = fmt.Errorf("string too long: len %d and terminator, buffer size %d: %w", strlen, bufLen, ErrValueOverflow)
+		return
 	}
 	if bufLen < strlen {
-		err = fmt.Errorf("string too long: len %d, buffer size %d", strlen, bufLen)
+		err = fmt.Errorf("string too long: len %d, buffer size %d: %w", strlen, bufLen, ErrValueOverflow)
 		return
 	}
 
@@ -1102,7 +1302,7 @@ func (ms *Marshaler) writeString(w io.Writer, order ByteOrder, v reflect.Value,
 		maxlen, headersz = math.MaxUint32, 4
 	}
 	if uint64(bufLen) > maxlen {
-		err = fmt.Errorf("string too long: len %d, max %d", strlen, maxlen)
+		err = fmt.Errorf("string too long: len %d, max %d: %w", strlen, maxlen, ErrValueOverflow)
 		return
 	}
 
@@ -1138,7 +1338,7 @@ func (ms *Marshaler) writeString(w io.Writer, order ByteOrder, v reflect.Value,
 
 	if m < bufLen {
 		// fill the leftovers
-		m, err = zeroFill(w, bufLen-m)
+		m, err = padFill(w, bufLen-m, fill)
 		n += m
 		if err != nil {
 			return
@@ -1156,6 +1356,11 @@ func (ms *Marshaler) writeScalar(w io.Writer, order ByteOrder, v reflect.Value,
 		err = ErrInvalidType
 		return
 	}
+	if ms.Strict {
+		if err = strictEncode(v, k); err != nil {
+			return
+		}
+	}
 	u64, sz, err := enc(v)
 	if err != nil {
 		return
@@ -1188,12 +1393,22 @@ func (ms *Marshaler) writeU64(w io.Writer, order ByteOrder, u64 uint64, bytesize
 
 // write blank padding bytes
 func zeroFill(w io.Writer, sz int) (n int, err error) {
+	return padFill(w, sz, 0)
+}
+
+// write sz padding bytes of the value fill
+func padFill(w io.Writer, sz int, fill byte) (n int, err error) {
 	maxBufSize := 16384
 	bsz := sz
 	if bsz > maxBufSize {
 		bsz = maxBufSize
 	}
 	buf := make([]byte, bsz)
+	if fill != 0 {
+		for i := range buf {
+			buf[i] = fill
+		}
+	}
 	var m int
 	for sz > 0 {
 		if sz > maxBufSize {
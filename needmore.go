@@ -0,0 +1,125 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+)
+
+// ErrNeedMore is returned by TryUnmarshal when the input ends before the value
+// does: the input is a valid prefix of the value, which needs at least Min more
+// bytes. It wraps io.ErrUnexpectedEOF.
+type ErrNeedMore struct {
+	Min int // a lower bound of the bytes still needed
+}
+
+func (e *ErrNeedMore) Error() string {
+	return fmt.Sprintf("need at least %d more bytes", e.Min)
+}
+
+func (e *ErrNeedMore) Unwrap() error {
+	return io.ErrUnexpectedEOF
+}
+
+// TryUnmarshal decodes a value from the start of buf, a buffer that may hold
+// only part of it; see Marshaler.TryUnmarshal.
+func TryUnmarshal(buf []byte, govalue interface{}) (n int, err error) {
+	return NewMarshaler().TryUnmarshal(buf, govalue)
+}
+
+// Marshaler.TryUnmarshal() decodes a value from the start of buf, for a caller
+// that accumulates input in buf until a whole value has arrived. It returns the
+// bytes the value took, leaving any input after it for the next call even
+// under Strict, or an *ErrNeedMore when buf ends before the value does.
+//
+// The value is decoded into a new value of *govalue's type, which replaces
+// *govalue when the decode succeeds; *govalue is not modified otherwise, except
+// under Salvage by a failure other than for want of input, when buf is decoded
+// into *govalue as Unmarshal would. A field decoded as the
+// value it holds, such as an interface, is therefore left nil. A layout whose
+// end is only known from the end of the input ($remaining, or a trailing
+// omittable field) decodes from whatever prefix it is given.
+//
+// ErrNeedMore.Min counts the rest of the field being read when the input ran
+// out, if its length has been decoded, and otherwise the bytes that read asked
+// for.
+func (ms *Marshaler) TryUnmarshal(buf []byte, govalue interface{}) (n int, err error) {
+	v := reflect.ValueOf(govalue)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return 0, fmt.Errorf("TryUnmarshal needs a non-nil pointer, not %T", govalue)
+	}
+	scratch := reflect.New(v.Type().Elem())
+
+	r := &prefixReader{buf: buf}
+	n, err = ms.Read(r, scratch.Interface())
+	if err != nil {
+		if r.short > 0 && (errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)) {
+			ms.Warnings = nil // reported again by the call that completes it
+			return n, &ErrNeedMore{Min: max(r.short, r.end-len(r.buf))}
+		}
+		if ms.Salvage {
+			// Salvage keeps the fields of *govalue itself that the decode
+			// did not reach, so the prefix is decoded into it again.
+			return ms.Read(&prefixReader{buf: buf}, govalue)
+		}
+		return n, err
+	}
+	v.Elem().Set(scratch.Elem())
+	return n, nil
+}
+
+// prefixReader reads buf, recording in short how many bytes past its end the
+// last read that ran out of it asked for, and in end where the field being
+// read ends when its length is known.
+type prefixReader struct {
+	buf   []byte
+	off   int
+	short int
+	end   int
+}
+
+// expect records that the decode is about to read count elements of wireSize
+// bytes each (0 if it varies), with the length of a field just evaluated.
+func (r *prefixReader) expect(count, wireSize int) {
+	if wireSize <= 0 || count <= 0 {
+		return
+	}
+	if count > (math.MaxInt-r.off)/wireSize {
+		r.end = math.MaxInt
+		return
+	}
+	r.end = max(r.end, r.off+count*wireSize)
+}
+
+func (r *prefixReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	n := copy(p, r.buf[r.off:])
+	r.off += n
+	if n < len(p) {
+		r.short = len(p) - n
+		if n == 0 {
+			return 0, io.EOF
+		}
+	}
+	return n, nil
+}
+
+func (r *prefixReader) ReadByte() (byte, error) {
+	if r.off == len(r.buf) {
+		r.short = 1
+		return 0, io.EOF
+	}
+	r.off++
+	return r.buf[r.off-1], nil
+}
+
+// Len reports the bytes left, for $remaining and to allocate as Unmarshal does.
+func (r *prefixReader) Len() int {
+	return len(r.buf) - r.off
+}
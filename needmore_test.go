@@ -0,0 +1,98 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+type nmFrame struct {
+	_    struct{} `binary:"endian=big"`
+	Kind uint8    `binary:"uint8,range=1..9"`
+	Len  uint16
+	Body []byte  `binary:"[Len]byte"`
+	Tags []int16 `binary:"[2]int8"`
+	Name string  `binary:"zstring"`
+}
+
+func TestTryUnmarshal(t *testing.T) {
+	frame := []byte{1, 0, 3, 'a', 'b', 'c', 0xff, 2, 'x', 0}
+	next := []byte{2, 0, 0}
+	// Min for the prefix of each length: the rest of a field whose length is
+	// known (Body, Tags), the rest of the scalar or byte being read otherwise
+	min := []int{1, 2, 1, 3, 2, 1, 2, 1, 1, 1}
+
+	orig := nmFrame{Kind: 9, Body: []byte{7, 7, 7}, Tags: []int16{5}, Name: "old"}
+	for k := 0; k < len(frame); k++ {
+		v := orig
+		v.Body = append([]byte{}, orig.Body...)
+		n, err := TryUnmarshal(frame[:k], &v)
+		var nm *ErrNeedMore
+		if !errors.As(err, &nm) || nm.Min != min[k] || !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("prefix %d: n = %d, err = %v", k, n, err)
+		}
+		if !reflect.DeepEqual(v, orig) {
+			t.Errorf("prefix %d: value changed to %+v", k, v)
+		}
+	}
+
+	var v nmFrame
+	n, err := TryUnmarshal(append(append([]byte{}, frame...), next...), &v)
+	want := nmFrame{Kind: 1, Len: 3, Body: []byte("abc"), Tags: []int16{255, 2}, Name: "x"}
+	if n != len(frame) || err != nil || !reflect.DeepEqual(v, want) {
+		t.Errorf("whole frame: n = %d, err = %v, %+v", n, err, v)
+	}
+
+	// With N decoded, the rest of Vals is needed.
+	type counted struct {
+		_    struct{} `binary:"endian=little"`
+		N    uint8
+		Vals []uint16 `binary:"[N]uint16"`
+	}
+	var c counted
+	var nm *ErrNeedMore
+	if _, err := TryUnmarshal([]byte{3, 0}, &c); !errors.As(err, &nm) || nm.Min != 5 {
+		t.Errorf("counted: err = %v", err)
+	}
+
+	// An invalid prefix fails as it would in Unmarshal.
+	_, err = TryUnmarshal([]byte{0, 0}, &v)
+	var de *DecodeError
+	if !errors.As(err, &de) || !errors.Is(err, ErrValidationError) {
+		t.Errorf("invalid prefix: err = %v", err)
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("invalid prefix: value changed to %+v", v)
+	}
+}
+
+// The value is decoded into a new one: a value reached through a pointer is
+// not written to, and an interface field is left nil.
+func TestTryUnmarshal_Fresh(t *testing.T) {
+	type inner struct {
+		A, B uint8
+	}
+	type outer struct {
+		P *inner
+		C uint8
+	}
+	p := &inner{1, 1}
+	v := outer{P: p, C: 9}
+	if _, err := TryUnmarshal([]byte{3, 3}, &v); err == nil || v.P != p || v.C != 9 {
+		t.Errorf("err = %v, %+v", err, v)
+	}
+	if _, err := TryUnmarshal([]byte{3, 3, 4}, &v); err != nil || v.P == p || *v.P != (inner{3, 3}) || v.C != 4 || *p != (inner{1, 1}) {
+		t.Errorf("err = %v, %+v, %+v", err, v, p)
+	}
+
+	type iface struct {
+		I interface{}
+	}
+	w := iface{I: &inner{2, 2}}
+	if _, err := TryUnmarshal([]byte{3, 3}, &w); err == nil || *w.I.(*inner) != (inner{2, 2}) {
+		t.Errorf("interface: err = %v, %+v", err, w)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// SalvageError is returned by a decode under Marshaler.Salvage that fails. The
+// value decoded keeps the fields listed in Decoded, as they were decoded; every
+// other field holds the value it had before the decode.
+type SalvageError struct {
+	// Decoded are the paths of the fields decoded before the failure, in the
+	// order they were decoded, such as "Hdr.Magic". A struct field is listed
+	// after its own fields; a field that failed inside a nested struct lists
+	// the fields of that struct decoded before it, but not the field itself.
+	Decoded []string
+	Err     error
+}
+
+func (e *SalvageError) Error() string {
+	return fmt.Sprintf("salvaged %d fields: %v", len(e.Decoded), e.Err)
+}
+
+func (e *SalvageError) Unwrap() error {
+	return e.Err
+}
+
+// salvaging reports whether the struct about to be decoded records its fields
+// for Marshaler.Salvage: the value decoded, or a struct field of a struct that
+// does. A generated read method then decodes through the interpreter. It is
+// true under Salvage outside a decode, where the method's own struct is the
+// value decoded, and false on a nil Marshaler.
+func (ms *Marshaler) salvaging() bool {
+	return ms != nil && (ms.salvageArmed || ms.Salvage && ms.decodeDepth == 0)
+}
+
+// armSalvage lets the struct v is, or points to, record its fields under
+// Salvage, when v is the value a decode begins with. beginDecode arms the
+// value of a decode begun elsewhere, a generated read method called directly.
+func (ms *Marshaler) armSalvage(v reflect.Value) {
+	if !ms.Salvage || ms.decodeDepth != 1 {
+		return
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	ms.salvageArmed = v.Kind() == reflect.Struct
+	ms.salvageNext = ""
+}
+
+// salvager records, under Salvage, the fields of a struct as they are decoded,
+// and restores those it did not when the decode fails.
+type salvager struct {
+	ms      *Marshaler
+	strc    reflect.Value
+	saved   reflect.Value // a copy of strc before the decode
+	prefix  string        // the path of strc's fields
+	done    []bool        // the fields decoded, by index
+	nested  int           // the struct field that records its own fields, or -1
+	current int           // the field being decoded, or -1
+}
+
+// startSalvage returns the salvager of strc, the struct whose fields are about
+// to be decoded, or nil when they are not recorded: Salvage is off, or strc is
+// not the value decoded or a struct field of a struct whose fields are.
+func (ms *Marshaler) startSalvage(strc reflect.Value) *salvager {
+	if ms == nil || !ms.salvageArmed {
+		return nil
+	}
+	ms.salvageArmed = false
+	s := &salvager{ms: ms, strc: strc, prefix: ms.salvageNext, done: make([]bool, strc.NumField()), nested: -1, current: -1}
+	if strc.CanSet() {
+		s.saved = reflect.New(strc.Type()).Elem()
+		cloneInto(s.saved, strc, make(map[clonedPtr]reflect.Value))
+	}
+	return s
+}
+
+// begin records that field i is about to be decoded. A struct field records
+// its own fields, unless it is decoded by a method of its own.
+func (s *salvager) begin(i int) {
+	if s == nil {
+		return
+	}
+	s.current = i
+	if s.strc.Field(i).Kind() == reflect.Struct {
+		s.ms.salvageArmed = true
+		s.ms.salvageNext = s.prefix + s.strc.Type().Field(i).Name + "."
+	}
+}
+
+// end records that the field begun was decoded.
+func (s *salvager) end() {
+	if s == nil || s.current < 0 {
+		return
+	}
+	s.ms.salvageArmed = false
+	s.done[s.current] = true
+	s.ms.salvaged = append(s.ms.salvaged, s.prefix+s.strc.Type().Field(s.current).Name)
+	s.current = -1
+}
+
+// finish ends the decode of the struct. When it failed, the fields not decoded
+// get back their values from before the decode, except a struct field that
+// restored its own.
+func (s *salvager) finish(err error) {
+	if s == nil {
+		return
+	}
+	if s.current >= 0 && s.strc.Field(s.current).Kind() == reflect.Struct && !s.ms.salvageArmed {
+		s.nested = s.current
+	}
+	s.ms.salvageArmed = false
+	if err == nil || !s.saved.IsValid() {
+		return
+	}
+	for i, done := range s.done {
+		if !done && i != s.nested && s.strc.Field(i).CanSet() {
+			s.strc.Field(i).Set(s.saved.Field(i))
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+type salvageHdr struct {
+	Magic uint32
+	Len   uint16 `binary:"uint16,range=0..100"`
+}
+
+type salvagePoint struct {
+	X, Y int8
+}
+
+type salvageRecord struct {
+	_      struct{} `binary:"endian=big"`
+	Hdr    salvageHdr
+	Count  uint8
+	Items  []uint16       `binary:"[Count]uint16"`
+	Points []salvagePoint `binary:"[Count]any"`
+	Name   string         `binary:"zstring"`
+}
+
+func TestSalvage(t *testing.T) {
+	blob := []byte{
+		0xca, 0xfe, 0xba, 0xbe, 0, 7, // Hdr
+		2,          // Count
+		0, 1, 0, 2, // Items
+		1, 2, 3, 4, // Points
+		'o', 'k', 0, // Name
+	}
+	old := salvageRecord{Hdr: salvageHdr{9, 9}, Count: 9, Items: []uint16{9}, Points: []salvagePoint{{9, 9}}, Name: "old"}
+	decoded := salvageRecord{Hdr: salvageHdr{0xcafebabe, 7}, Count: 2, Items: []uint16{1, 2}, Points: []salvagePoint{{1, 2}, {3, 4}}, Name: "ok"}
+	fields := []string{"Hdr.Magic", "Hdr.Len", "Hdr", "Count", "Items", "Points", "Name"}
+
+	cases := []struct {
+		blob    []byte
+		decoded int // len(Decoded)
+		hdr     salvageHdr
+		cause   error
+	}{
+		{blob[:2], 0, salvageHdr{9, 9}, io.ErrUnexpectedEOF},
+		{blob[:5], 1, salvageHdr{0xcafebabe, 9}, io.ErrUnexpectedEOF},
+		{append(blob[:4:4], 1, 0), 1, salvageHdr{0xcafebabe, 9}, ErrValidationError},
+		{blob[:9], 4, decoded.Hdr, io.ErrUnexpectedEOF},
+		{blob[:13], 5, decoded.Hdr, io.EOF},
+		{blob[:16], 6, decoded.Hdr, io.EOF},
+	}
+	for i, c := range cases {
+		ms := NewMarshaler()
+		ms.Salvage = true
+		v := old
+		v.Items = append([]uint16{}, old.Items...)
+		v.Points = append([]salvagePoint{}, old.Points...)
+		_, err := ms.Unmarshal(c.blob, &v)
+		var se *SalvageError
+		if !errors.As(err, &se) || !slices.Equal(se.Decoded, fields[:len(se.Decoded)]) || !errors.Is(err, c.cause) {
+			t.Errorf("case %d: err = %v", i, err)
+			continue
+		}
+		want := old
+		want.Hdr = c.hdr
+		for _, f := range se.Decoded {
+			switch f {
+			case "Count":
+				want.Count = decoded.Count
+			case "Items":
+				want.Items = decoded.Items
+			case "Points":
+				want.Points = decoded.Points
+			}
+		}
+		if len(se.Decoded) != c.decoded || !reflect.DeepEqual(v, want) {
+			t.Errorf("case %d: decoded %q into %+v", i, se.Decoded, v)
+		}
+	}
+
+	// TryUnmarshal keeps the fields of the target a failed decode did not
+	// reach, as Unmarshal does.
+	ms := NewMarshaler()
+	ms.Salvage = true
+	v := old
+	if _, err := ms.TryUnmarshal(append(blob[:4:4], 1, 0), &v); !errors.Is(err, ErrValidationError) || v.Hdr.Magic != 0xcafebabe || v.Name != "old" || v.Count != 9 {
+		t.Errorf("TryUnmarshal: %+v, err = %v", v, err)
+	}
+
+	v = salvageRecord{}
+	if _, err := ms.Unmarshal(blob, &v); err != nil || !reflect.DeepEqual(v, decoded) {
+		t.Errorf("whole input: %+v, err = %v", v, err)
+	}
+	var se *SalvageError
+	if _, err := Unmarshal(blob[:9], &v); err == nil || errors.As(err, &se) {
+		t.Errorf("without Salvage: err = %v", err)
+	}
+}
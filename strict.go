@@ -0,0 +1,118 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// ErrTrailingData is returned by Unmarshal and UnmarshalAs under
+// Marshaler.Strict when the value decoded does not consume the whole input.
+var ErrTrailingData = errors.New("trailing data")
+
+// checkTrailingData returns an error wrapping ErrTrailingData when a decode
+// consumed n bytes of an input of size bytes, and nil when it consumed them all.
+func checkTrailingData(n, size int) error {
+	if n < size {
+		return fmt.Errorf("%d bytes of trailing data after %d: %w", size-n, n, ErrTrailingData)
+	}
+	return nil
+}
+
+// strictEncode checks, under Marshaler.Strict, a scalar v about to be encoded
+// as k for the losses the plain conversion allows: a float with a fractional
+// part, or out of range, encoded as an integer, and an integer wider than a
+// byte/word/dword/qword, which is otherwise cut to its low bytes.
+func strictEncode(v reflect.Value, k eType) error {
+	p, ok := properties[k]
+	if !ok {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		if p.kind != intKind && p.kind != uintKind && p.kind != bitmapKind {
+			return nil
+		}
+		f := v.Float()
+		if f != math.Trunc(f) {
+			return fmt.Errorf("value %v loses precision in %s: %w", f, k, ErrValueOverflow)
+		}
+		lo, hi := floatRange(p.kind, p.bytesize)
+		if !(f >= lo && f < hi) { // also rejects NaN
+			return fmt.Errorf("value %v not fit in %s: %w", f, k, ErrValueOverflow)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if p.kind == bitmapKind && p.bytesize < 8 {
+			if i, bits := v.Int(), uint(p.bytesize*8); i < -1<<(bits-1) || i >= 1<<bits {
+				return fmt.Errorf("value %v not fit in %s: %w", i, k, ErrValueOverflow)
+			}
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if p.kind == bitmapKind && p.bytesize < 8 {
+			if u := v.Uint(); u >= 1<<uint(p.bytesize*8) {
+				return fmt.Errorf("value %v not fit in %s: %w", u, k, ErrValueOverflow)
+			}
+		}
+	}
+	return nil
+}
+
+// strictDecode checks, under Marshaler.Strict, a float decoded as k, with bits
+// u, into the integer v: it must be a whole number within v's range.
+func strictDecode(v reflect.Value, k eType, u uint64) error {
+	var f float64
+	switch k {
+	case Float32:
+		f = float64(math.Float32frombits(uint32(u)))
+	case Float64:
+		f = math.Float64frombits(u)
+	default:
+		return nil
+	}
+	var lo, hi float64
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		bits := v.Type().Bits()
+		lo, hi = -math.Ldexp(1, bits-1), math.Ldexp(1, bits-1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		lo, hi = 0, math.Ldexp(1, v.Type().Bits())
+	default:
+		return nil
+	}
+	if f != math.Trunc(f) {
+		return fmt.Errorf("value %v loses precision in type %v: %w", f, v.Type(), ErrValueOverflow)
+	}
+	if !(f >= lo && f < hi) {
+		return fmt.Errorf("value %v not fit in type %v: %w", f, v.Type(), ErrValueOverflow)
+	}
+	return nil
+}
+
+// floatRange returns the half-open range [lo, hi) of the whole numbers an
+// integer wire type of the kind and size holds; a bitmap type holds both its
+// signed and unsigned values.
+func floatRange(kind iKind, size int) (lo, hi float64) {
+	bits := size * 8
+	switch kind {
+	case intKind:
+		return -math.Ldexp(1, bits-1), math.Ldexp(1, bits-1)
+	case uintKind:
+		return 0, math.Ldexp(1, bits)
+	}
+	return -math.Ldexp(1, bits-1), math.Ldexp(1, bits)
+}
+
+// terminatorSize is the size of the terminator a string type writes after its
+// bytes.
+func terminatorSize(t eType) int {
+	switch t {
+	case Zstring:
+		return 1
+	case Z16string:
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,155 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Marshaler.Strict rejects the conversions that lose data, and input left over
+// after the value.
+
+type strictRecord struct {
+	_     struct{} `binary:"endian=big"`
+	Level int      `binary:"uint8"`
+	Flags int      `binary:"byte"`
+	Scale float64  `binary:"int16"`
+	Name  string   `binary:"zstring(4)"`
+	Label string   `binary:"string(4)"`
+}
+
+func TestStrict_Encode(t *testing.T) {
+	good := strictRecord{Level: 200, Flags: -1, Scale: -3, Name: "abc", Label: "ab"}
+	cases := []struct {
+		edit   func(*strictRecord)
+		strict bool // whether only Strict rejects it
+		field  string
+		msg    string
+	}{
+		{func(r *strictRecord) { r.Level = 300 }, false, "Level", "value 300 not fit in Uint8"},
+		{func(r *strictRecord) { r.Level = -1 }, false, "Level", "value -1 not fit in Uint8"},
+		{func(r *strictRecord) { r.Flags = 256 }, true, "Flags", "value 256 not fit in Byte"},
+		{func(r *strictRecord) { r.Flags = -129 }, true, "Flags", "value -129 not fit in Byte"},
+		{func(r *strictRecord) { r.Scale = 1.5 }, true, "Scale", "value 1.5 loses precision in Int16"},
+		{func(r *strictRecord) { r.Scale = 40000 }, false, "Scale", "value 40000 not fit in Int16"},
+		{func(r *strictRecord) { r.Name = "abcd" }, true, "Name", "string too long: len 4 and terminator, buffer size 4"},
+		{func(r *strictRecord) { r.Label = "abcde" }, false, "Label", "string too long: len 5, buffer size 4"},
+	}
+	for _, strict := range []bool{false, true} {
+		ms := NewMarshaler()
+		ms.Strict = strict
+		if _, err := ms.Marshal(&good); err != nil {
+			t.Fatalf("strict %v: good value: %v", strict, err)
+		}
+		for _, c := range cases {
+			in := good
+			c.edit(&in)
+			_, err := ms.Marshal(&in)
+			if !strict && c.strict {
+				if err != nil {
+					t.Errorf("not strict: %s: err = %v", c.msg, err)
+				}
+				continue
+			}
+			var ee *EncodeError
+			if !errors.As(err, &ee) || ee.Field != c.field || !errors.Is(err, ErrValueOverflow) || !strings.Contains(err.Error(), c.msg) {
+				t.Errorf("strict %v: %s: err = %v", strict, c.msg, err)
+			}
+		}
+	}
+}
+
+func TestStrict_Decode(t *testing.T) {
+	type rec struct {
+		_ struct{} `binary:"endian=big"`
+		A int8     `binary:"float32"`
+		B []uint16 `binary:"[2]float64"`
+	}
+	blob := func(a uint32, b uint64) []byte {
+		out := []byte{byte(a >> 24), byte(a >> 16), byte(a >> 8), byte(a)}
+		for i := 0; i < 2; i++ {
+			for s := 56; s >= 0; s -= 8 {
+				out = append(out, byte(b>>s))
+			}
+		}
+		return out
+	}
+	const f32One, f32Half, f32Big = 0x3f800000, 0x3fc00000, 0x43800000 // 1, 1.5, 256
+	const f64Two, f64Neg = 0x4000000000000000, 0xbff0000000000000      // 2, -1
+	cases := []struct {
+		in    []byte
+		field string
+		msg   string
+	}{
+		{blob(f32One, f64Two), "", ""},
+		{blob(f32Half, f64Two), "A", "value 1.5 loses precision in type int8"},
+		{blob(f32Big, f64Two), "A", "value 256 not fit in type int8"},
+		{blob(f32One, f64Neg), "B", "value -1 not fit in type uint16"},
+	}
+	ms := NewMarshaler()
+	ms.Strict = true
+	for _, c := range cases {
+		var out rec
+		_, err := ms.Unmarshal(c.in, &out)
+		if c.field == "" {
+			if err != nil || out.A != 1 || out.B[1] != 2 {
+				t.Errorf("good input: %+v, err = %v", out, err)
+			}
+			continue
+		}
+		var de *DecodeError
+		if !errors.As(err, &de) || de.Field != c.field || !errors.Is(err, ErrValueOverflow) || !strings.Contains(err.Error(), c.msg) {
+			t.Errorf("%s: err = %v", c.msg, err)
+		}
+	}
+
+	// Without Strict a float is truncated.
+	var out rec
+	if _, err := Unmarshal(blob(f32Half, f64Two), &out); err != nil || out.A != 1 {
+		t.Errorf("not strict: %+v, err = %v", out, err)
+	}
+}
+
+func TestStrict_TrailingData(t *testing.T) {
+	type rec struct {
+		V uint8
+	}
+	ms := NewMarshaler()
+	if n, err := ms.Unmarshal([]byte{1, 2, 3}, &rec{}); n != 1 || err != nil {
+		t.Fatalf("not strict: n = %d, err = %v", n, err)
+	}
+	ms.Strict = true
+	n, err := ms.Unmarshal([]byte{1, 2, 3}, &rec{})
+	if n != 1 || !errors.Is(err, ErrTrailingData) || !strings.Contains(err.Error(), "2 bytes of trailing data") {
+		t.Errorf("n = %d, err = %v", n, err)
+	}
+	var v uint8
+	if _, err := ms.UnmarshalAs([]byte{1, 2}, "uint8", &v); !errors.Is(err, ErrTrailingData) {
+		t.Errorf("UnmarshalAs: err = %v", err)
+	}
+	if _, err := ms.Unmarshal([]byte{1}, &rec{}); err != nil {
+		t.Errorf("exact input: %v", err)
+	}
+}
+
+// Both interpreters convert a field whose Go type differs from its wire type
+// the same way: the unsafe one used to copy the field's memory as the wire
+// type. A signed wire value is not sign-extended into a wider Go integer.
+func TestStrict_MismatchedScalars(t *testing.T) {
+	type rec struct {
+		_ struct{} `binary:"endian=big"`
+		A int      `binary:"int8"`
+		B float64  `binary:"int16"`
+		C int      `binary:"float32"`
+	}
+	blob, err := Marshal(&rec{A: -2, B: 7.9, C: 3})
+	if err != nil || string(blob) != "\xfe\x00\x07\x40\x40\x00\x00" {
+		t.Fatalf("% x, err = %v", blob, err)
+	}
+	out := rec{A: 1 << 40}
+	if _, err := Unmarshal(blob, &out); err != nil || out.A != 254 || out.B != 7 || out.C != 3 {
+		t.Errorf("%+v, err = %v", out, err)
+	}
+}
@@ -3,14 +3,18 @@
 package binarystruct
 
 import (
+	"bytes"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"unicode"
 )
 
 const (
@@ -33,38 +37,48 @@ type structFieldMetadata struct {
 	// re-tokenizing and re-evaluating it per operation.
 	arrayLenConst bool
 	bufLenConst   bool
-	valueofExpr   string
+	// sizedByInput is true when an array dimension or buf_len refers to
+	// $remaining, which only has a value while decoding; see encodeMeta.
+	sizedByInput bool
+	valueofExpr  string
 	// valueofCustom* hold a custom valueof evaluator parsed from a
 	// `valueof=NAME(field, ...)` tag whose NAME is not a built-in (bytelen,
 	// count). Empty name means the valueof (if any) is a built-in/arithmetic
 	// expression handled by evalValueof. The evaluator is looked up by name on
 	// the Marshaler at run time (not validated at parse time, since metadata is
 	// cached per type while evaluators are registered per Marshaler).
-	valueofCustomName string
-	valueofCustomArgs []string
-	encoding          string
-	endian            endianOverride
-	codec             string
-	ignore            bool
-	unexported        bool
-	fieldErr          error
-	omittable         bool
-	omittableExpr     string
-	naturalType       eType
-	option            typeOption
-	hasRange          bool
-	rangeMin          float64
-	rangeMax          float64
-	hasRangeMin       bool
-	hasRangeMax       bool
-	hasMatch          bool
-	matchPattern      string
-	matchRegexp       *regexp.Regexp
-	hasConst          bool
-	constExpr         string // raw const= text, kept for codegen and error messages
-	constIsBytes      bool   // target is a byte sequence (vs an integer/bitmap)
-	constInt          int64  // integer target: the constant value to emit/validate
-	constBytes        []byte // byte-sequence target: the constant bytes to emit/validate
+	valueofCustomName  string
+	valueofCustomArgs  []string
+	encoding           string
+	endian             endianOverride
+	codec              string
+	ignore             bool
+	unexported         bool
+	fieldErr           error
+	omittable          bool
+	omittableExpr      string
+	naturalType        eType
+	option             typeOption
+	hasRange           bool
+	rangeMin           float64
+	rangeMax           float64
+	hasRangeMin        bool
+	hasRangeMax        bool
+	hasMatch           bool
+	rangeExpr          string // range= text, as min..max
+	matchPattern       string
+	matchRegexp        *regexp.Regexp
+	checkExpr          string // check= rule, evaluated once the field is decoded
+	checkUsesRemaining bool   // the check= rule uses $remaining, so it is not run on encode
+	enum               *fieldEnum
+	defaultExpr        string        // default= text, kept for codegen and error messages
+	defaultValue       reflect.Value // the field's value when the input omits it; invalid if none
+	omitDefault        bool          // omitdefault: a trailing field equal to its default is not written
+	hasConst           bool
+	constExpr          string // raw const= text, kept for codegen and error messages
+	constIsBytes       bool   // target is a byte sequence (vs an integer/bitmap)
+	constInt           int64  // integer target: the constant value to emit/validate
+	constBytes         []byte // byte-sequence target: the constant bytes to emit/validate
 }
 
 type structMetadata struct {
@@ -78,6 +92,14 @@ type structMetadata struct {
 	// string field's metadata that does not set its own encoding=, so it sits
 	// between a per-field encoding= and the Marshaler's DefaultTextEncoding.
 	defaultEncoding string
+	// orderMark, from a sentinel's `binary:"endian=Field:…"`, chooses the byte
+	// order of the fields after Field from Field's value. nil when absent.
+	orderMark *orderMark
+	// orderDetect, from a sentinel's `binary:"endian=detect:Field"`, chooses the
+	// byte order from the encoding of the struct's magic number. nil when absent.
+	orderDetect *orderDetect
+	// hasDefaults is true when a field declares default=; see applyDefaults.
+	hasDefaults bool
 }
 
 // fieldByName returns the metadata for the field with the given Go name.
@@ -91,14 +113,8 @@ func (m *structMetadata) fieldByName(name string) (structFieldMetadata, bool) {
 }
 
 var (
-	errNegativeSize = errors.New("the size must not be negative")
-
-	// regexp to match a tag. Group 1 is the (possibly multi-dimensional) array
-	// bracket run "[4][2]"; group 2 is the binary type; group 4 is the (buflen).
-	mTag = regexp.MustCompile(`^\s*((?:\[[^\]]*\])*)([^\s\(\)\[\]]*)(\(([^\)]+)\))?`)
-
-	// splits an array bracket run "[4][2]" into its per-dimension expressions.
-	mTagDim = regexp.MustCompile(`\[([^\]]*)\]`)
+	errNegativeSize     = errors.New("the size must not be negative")
+	errRemainingUnknown = errors.New("$remaining: the length of the input is unknown (the reader has no Len method)")
 
 	// single entry of tag-value evaluation
 	mExpression = regexp.MustCompile(`\s*([\+\-])?\s*([^\s\+\-]+)`)
@@ -119,6 +135,28 @@ const (
 	tokLParen
 	tokRParen
 	tokComma
+	tokMod      // %
+	tokAnd      // &
+	tokOr       // |
+	tokXor      // ^
+	tokShl      // <<
+	tokShr      // >>
+	tokTilde    // ~ (bitwise complement)
+	tokNot      // ! (logical not)
+	tokEq       // ==
+	tokNe       // !=
+	tokLt       // <
+	tokLe       // <=
+	tokGt       // >
+	tokGe       // >=
+	tokLAnd     // &&
+	tokLOr      // ||
+	tokQuestion // ?
+	tokColon    // :
+	tokDot      // . (nested field selector)
+	tokLBrack   // [ (array element selector)
+	tokRBrack   // ]
+	tokParam    // $name (Marshaler parameter or built-in variable)
 )
 
 type token struct {
@@ -126,50 +164,34 @@ type token struct {
 	val string
 }
 
+// operator tokens, longest first so that "<<" wins over "<" and "&&" over "&".
+var exprOperators = []token{
+	{tokShl, "<<"}, {tokShr, ">>"}, {tokLe, "<="}, {tokGe, ">="},
+	{tokEq, "=="}, {tokNe, "!="}, {tokLAnd, "&&"}, {tokLOr, "||"},
+	{tokPlus, "+"}, {tokMinus, "-"}, {tokMul, "*"}, {tokDiv, "/"}, {tokMod, "%"},
+	{tokAnd, "&"}, {tokOr, "|"}, {tokXor, "^"}, {tokTilde, "~"}, {tokNot, "!"},
+	{tokLt, "<"}, {tokGt, ">"}, {tokQuestion, "?"}, {tokColon, ":"},
+	{tokLParen, "("}, {tokRParen, ")"}, {tokComma, ","},
+	{tokDot, "."}, {tokLBrack, "["}, {tokRBrack, "]"},
+}
+
 func tokenize(expr string) ([]token, error) {
 	var tokens []token
 	i := 0
 	n := len(expr)
+next:
 	for i < n {
 		c := expr[i]
 		if c == ' ' || c == '\t' || c == '\r' || c == '\n' {
 			i++
 			continue
 		}
-		if c == '+' {
-			tokens = append(tokens, token{tokPlus, "+"})
-			i++
-			continue
-		}
-		if c == '-' {
-			tokens = append(tokens, token{tokMinus, "-"})
-			i++
-			continue
-		}
-		if c == '*' {
-			tokens = append(tokens, token{tokMul, "*"})
-			i++
-			continue
-		}
-		if c == '/' {
-			tokens = append(tokens, token{tokDiv, "/"})
-			i++
-			continue
-		}
-		if c == '(' {
-			tokens = append(tokens, token{tokLParen, "("})
-			i++
-			continue
-		}
-		if c == ')' {
-			tokens = append(tokens, token{tokRParen, ")"})
-			i++
-			continue
-		}
-		if c == ',' {
-			tokens = append(tokens, token{tokComma, ","})
-			i++
-			continue
+		for _, op := range exprOperators {
+			if strings.HasPrefix(expr[i:], op.val) {
+				tokens = append(tokens, op)
+				i += len(op.val)
+				continue next
+			}
 		}
 		if c >= '0' && c <= '9' {
 			start := i
@@ -191,25 +213,71 @@ func tokenize(expr string) ([]token, error) {
 			tokens = append(tokens, token{tokIdent, expr[start:i]})
 			continue
 		}
+		if c == '$' {
+			start := i + 1
+			i++
+			for i < n && ((expr[i] >= 'a' && expr[i] <= 'z') || (expr[i] >= 'A' && expr[i] <= 'Z') || (expr[i] >= '0' && expr[i] <= '9') || expr[i] == '_') {
+				i++
+			}
+			if i == start {
+				return nil, fmt.Errorf("parameter name expected after $")
+			}
+			tokens = append(tokens, token{tokParam, expr[start:i]})
+			continue
+		}
 		return nil, fmt.Errorf("unexpected character: %c", c)
 	}
 	tokens = append(tokens, token{tokEOF, ""})
 	return tokens, nil
 }
 
+// tagParser evaluates a tag expression by recursive descent. The grammar, from
+// lowest to highest precedence (the levels follow Go's operator precedence, with
+// a C-style conditional on top):
+//
+//	cond ? a : b                  (right-associative)
+//	||
+//	&&
+//	==  !=  <  <=  >  >=
+//	+  -  |  ^
+//	*  /  %  <<  >>  &
+//	unary + - ! ~, parentheses, literals, field references, $parameters,
+//	function calls
+//
+// All values are ints; comparisons and the logical operators yield 1 or 0, and
+// any non-zero value is true. &&, || and ?: short-circuit: the operand that is
+// not taken is parsed (so syntax errors still surface) but not evaluated, so it
+// neither resolves field references nor fails on e.g. a division by zero.
 type tagParser struct {
 	tokens []token
 	pos    int
 	strc   reflect.Value
 
-	// resolveIdent resolves a bare field reference (e.g. "PayloadSize").
-	// When nil, bare field references are rejected.
+	// resolveIdent resolves a field reference: a field name, optionally
+	// followed by .Field and [N] selectors (e.g. "PayloadSize", "Hdr.Len",
+	// "Dims[0]"). When nil, field references are rejected.
 	resolveIdent func(name string) (int, error)
 	// callFunc resolves a function call such as bytelen(Name) or count(Items),
 	// or a custom multi-argument evaluator such as CRC32(Type, Data). When nil,
 	// function calls are rejected (the case for decode-side size expressions,
 	// where functions are not permitted).
 	callFunc func(funcName string, args []string) (int, error)
+	// sizeOf resolves sizeof(TypeName) to the fixed encoded size of a struct
+	// type. When nil, sizeof() is rejected.
+	sizeOf func(typeName string) (int, error)
+	// resolveParam resolves $name: a Marshaler parameter (SetParam) or one of
+	// the built-in variables $offset and $remaining. The name is passed without
+	// the $. When nil, parameters are rejected.
+	resolveParam func(name string) (int, error)
+
+	// skip is the nesting depth of short-circuited operands currently being
+	// parsed; while positive, references and calls are not resolved and
+	// arithmetic faults are not reported.
+	skip int
+	// visitAll disables short-circuiting and arithmetic faults, so every operand
+	// reaches resolveIdent/callFunc. Used by exprReferences, whose resolvers
+	// return placeholder zeros.
+	visitAll bool
 }
 
 func (p *tagParser) peek() token {
@@ -227,32 +295,150 @@ func (p *tagParser) consume() token {
 	return t
 }
 
+// evaluating reports whether the current operand's value is actually used, i.e.
+// whether lookups and arithmetic faults apply.
+func (p *tagParser) evaluating() bool {
+	return p.skip == 0 && !p.visitAll
+}
+
+// parseOperand parses one operand with parse, skipping its evaluation unless
+// taken is true (or visitAll is set).
+func (p *tagParser) parseOperand(taken bool, parse func() (int, error)) (int, error) {
+	if !taken && !p.visitAll {
+		p.skip++
+		defer func() { p.skip-- }()
+	}
+	return parse()
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// parseExpr parses a full expression; the conditional operator binds loosest.
 func (p *tagParser) parseExpr() (int, error) {
+	cond, err := p.parseLogicalOr()
+	if err != nil {
+		return 0, err
+	}
+	if p.peek().typ != tokQuestion {
+		return cond, nil
+	}
+	p.consume()
+	a, err := p.parseOperand(cond != 0, p.parseExpr)
+	if err != nil {
+		return 0, err
+	}
+	if p.consume().typ != tokColon {
+		return 0, fmt.Errorf("missing ':' in conditional expression")
+	}
+	b, err := p.parseOperand(cond == 0, p.parseExpr)
+	if err != nil {
+		return 0, err
+	}
+	if cond != 0 {
+		return a, nil
+	}
+	return b, nil
+}
+
+func (p *tagParser) parseLogicalOr() (int, error) {
+	val, err := p.parseLogicalAnd()
+	if err != nil {
+		return 0, err
+	}
+	for p.peek().typ == tokLOr {
+		p.consume()
+		r, err := p.parseOperand(val == 0, p.parseLogicalAnd)
+		if err != nil {
+			return 0, err
+		}
+		val = boolInt(val != 0 || r != 0)
+	}
+	return val, nil
+}
+
+func (p *tagParser) parseLogicalAnd() (int, error) {
+	val, err := p.parseComparison()
+	if err != nil {
+		return 0, err
+	}
+	for p.peek().typ == tokLAnd {
+		p.consume()
+		r, err := p.parseOperand(val != 0, p.parseComparison)
+		if err != nil {
+			return 0, err
+		}
+		val = boolInt(val != 0 && r != 0)
+	}
+	return val, nil
+}
+
+func (p *tagParser) parseComparison() (int, error) {
+	val, err := p.parseAdditive()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := p.peek().typ
+		switch op {
+		case tokEq, tokNe, tokLt, tokLe, tokGt, tokGe:
+		default:
+			return val, nil
+		}
+		p.consume()
+		r, err := p.parseAdditive()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case tokEq:
+			val = boolInt(val == r)
+		case tokNe:
+			val = boolInt(val != r)
+		case tokLt:
+			val = boolInt(val < r)
+		case tokLe:
+			val = boolInt(val <= r)
+		case tokGt:
+			val = boolInt(val > r)
+		case tokGe:
+			val = boolInt(val >= r)
+		}
+	}
+}
+
+func (p *tagParser) parseAdditive() (int, error) {
 	val, err := p.parseTerm()
 	if err != nil {
 		return 0, err
 	}
 	for {
-		t := p.peek()
-		if t.typ == tokPlus {
-			p.consume()
-			r, err := p.parseTerm()
-			if err != nil {
-				return 0, err
-			}
+		op := p.peek().typ
+		switch op {
+		case tokPlus, tokMinus, tokOr, tokXor:
+		default:
+			return val, nil
+		}
+		p.consume()
+		r, err := p.parseTerm()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case tokPlus:
 			val = val + r
-		} else if t.typ == tokMinus {
-			p.consume()
-			r, err := p.parseTerm()
-			if err != nil {
-				return 0, err
-			}
+		case tokMinus:
 			val = val - r
-		} else {
-			break
+		case tokOr:
+			val = val | r
+		case tokXor:
+			val = val ^ r
 		}
 	}
-	return val, nil
 }
 
 func (p *tagParser) parseTerm() (int, error) {
@@ -261,44 +447,68 @@ func (p *tagParser) parseTerm() (int, error) {
 		return 0, err
 	}
 	for {
-		t := p.peek()
-		if t.typ == tokMul {
-			p.consume()
-			r, err := p.parseFactor()
-			if err != nil {
-				return 0, err
-			}
+		op := p.peek().typ
+		switch op {
+		case tokMul, tokDiv, tokMod, tokShl, tokShr, tokAnd:
+		default:
+			return val, nil
+		}
+		p.consume()
+		r, err := p.parseFactor()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case tokMul:
 			val = val * r
-		} else if t.typ == tokDiv {
-			p.consume()
-			r, err := p.parseFactor()
-			if err != nil {
-				return 0, err
-			}
+		case tokDiv, tokMod:
 			if r == 0 {
-				return 0, fmt.Errorf("division by zero")
+				if p.evaluating() {
+					return 0, fmt.Errorf("division by zero")
+				}
+				val = 0
+			} else if op == tokDiv {
+				val = val / r
+			} else {
+				val = val % r
 			}
-			val = val / r
-		} else {
-			break
+		case tokShl, tokShr:
+			if r < 0 {
+				if p.evaluating() {
+					return 0, fmt.Errorf("negative shift count %d", r)
+				}
+				val = 0
+			} else if op == tokShl {
+				val = val << r
+			} else {
+				val = val >> r
+			}
+		case tokAnd:
+			val = val & r
 		}
 	}
-	return val, nil
 }
 
 func (p *tagParser) parseFactor() (int, error) {
 	t := p.peek()
-	if t.typ == tokPlus {
+	switch t.typ {
+	case tokPlus:
 		p.consume()
 		return p.parseFactor()
-	}
-	if t.typ == tokMinus {
+	case tokMinus, tokTilde, tokNot:
 		p.consume()
 		val, err := p.parseFactor()
 		if err != nil {
 			return 0, err
 		}
-		return -val, nil
+		switch t.typ {
+		case tokMinus:
+			return -val, nil
+		case tokTilde:
+			return ^val, nil
+		default:
+			return boolInt(val == 0), nil
+		}
 	}
 	if t.typ == tokLParen {
 		p.consume()
@@ -311,6 +521,16 @@ func (p *tagParser) parseFactor() (int, error) {
 		}
 		return val, nil
 	}
+	if t.typ == tokParam {
+		p.consume()
+		if p.resolveParam == nil {
+			return 0, fmt.Errorf("parameter $%s is not allowed here", t.val)
+		}
+		if p.skip > 0 {
+			return 0, nil
+		}
+		return p.resolveParam(t.val)
+	}
 	if t.typ == tokNum {
 		p.consume()
 		i64, err := strconv.ParseInt(t.val, 0, 64)
@@ -321,6 +541,9 @@ func (p *tagParser) parseFactor() (int, error) {
 	}
 	if t.typ == tokIdent {
 		p.consume()
+		if p.peek().typ == tokLParen && isExprBuiltin(t.val) {
+			return p.parseBuiltin(t.val)
+		}
 		// function call: IDENT '(' IDENT (',' IDENT)* ')'
 		if p.peek().typ == tokLParen {
 			p.consume() // '('
@@ -344,18 +567,216 @@ func (p *tagParser) parseFactor() (int, error) {
 			if p.callFunc == nil {
 				return 0, fmt.Errorf("function %s() is not allowed here (functions are valid only in valueof)", t.val)
 			}
+			if p.skip > 0 {
+				return 0, nil
+			}
 			return p.callFunc(t.val, args)
 		}
+		path, err := p.parseFieldPath(t.val)
+		if err != nil {
+			return 0, err
+		}
 		if p.resolveIdent == nil {
-			return 0, fmt.Errorf("field reference %s is not allowed here", t.val)
+			return 0, fmt.Errorf("field reference %s is not allowed here", path)
 		}
-		return p.resolveIdent(t.val)
+		if p.skip > 0 {
+			return 0, nil
+		}
+		return p.resolveIdent(path)
 	}
 	return 0, fmt.Errorf("unexpected token %s", t.val)
 }
 
+// parseFieldPath parses the selectors that may follow a field name — .Field
+// into a nested struct and [N] into an array or slice, N a non-negative integer
+// literal — and returns the whole reference in canonical form, e.g.
+// "Hdr.Dims[2]". The name itself has been consumed.
+func (p *tagParser) parseFieldPath(name string) (string, error) {
+	path := name
+	for {
+		switch p.peek().typ {
+		case tokDot:
+			p.consume()
+			f := p.consume()
+			if f.typ != tokIdent {
+				return "", fmt.Errorf("field name expected after %s.", path)
+			}
+			path += "." + f.val
+		case tokLBrack:
+			p.consume()
+			n := p.consume()
+			if n.typ != tokNum {
+				return "", fmt.Errorf("index of %s must be an integer literal", path)
+			}
+			idx, err := strconv.ParseInt(n.val, 0, 64)
+			if err != nil {
+				return "", err
+			}
+			if p.consume().typ != tokRBrack {
+				return "", fmt.Errorf("missing closing bracket in %s[...]", path)
+			}
+			path += "[" + strconv.FormatInt(idx, 10) + "]"
+		default:
+			return path, nil
+		}
+	}
+}
+
+// isOuterRef reports whether a field reference names a field of an enclosing
+// struct (parent.X or root.X) rather than one of the struct itself.
+func isOuterRef(path string) bool {
+	return strings.HasPrefix(path, "parent.") || strings.HasPrefix(path, "root.")
+}
+
+// exprUsesParam reports whether expr refers to $name. Malformed expressions
+// report false; they fail when evaluated.
+func exprUsesParam(expr, name string) bool {
+	refs, _, err := exprReferences(expr)
+	if err != nil {
+		return false
+	}
+	for _, r := range refs {
+		if r == "$"+name {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeMeta returns the metadata the write paths use for the field. Sizes
+// that refer to $remaining mean "the rest of the input" and have no value
+// while encoding, so such an array dimension or buf_len is dropped and the
+// value's own length is written instead, as for an untagged size.
+func (f structFieldMetadata) encodeMeta() structFieldMetadata {
+	if !f.sizedByInput {
+		return f
+	}
+	if f.bufLenExpr != "" && f.encodeType != Pad && exprUsesParam(f.bufLenExpr, "remaining") {
+		f.bufLenExpr = ""
+	}
+	if len(f.arrayDimExprs) > 0 {
+		dims := append([]string(nil), f.arrayDimExprs...)
+		for i, d := range dims {
+			if d != "" && exprUsesParam(d, "remaining") {
+				dims[i] = ""
+			}
+		}
+		f.arrayDimExprs = dims
+		f.arrayLenExpr = dims[0]
+	}
+	return f
+}
+
+// fieldPathRoot returns the top-level field name of a field reference such as
+// "Hdr.Len" or "Dims[0]".
+func fieldPathRoot(path string) string {
+	if i := strings.IndexAny(path, ".["); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
+
+// isExprBuiltin reports whether name is a function of the expression language
+// itself, available in every expression context. Other names in call position
+// are valueof functions (bytelen, count, or a custom evaluator).
+func isExprBuiltin(name string) bool {
+	switch name {
+	case "align", "min", "max", "abs", "sizeof":
+		return true
+	}
+	return false
+}
+
+// parseBuiltin parses and evaluates a call to a built-in function; the name has
+// been consumed and the next token is '('. sizeof takes a type name; the others
+// take expressions.
+func (p *tagParser) parseBuiltin(name string) (int, error) {
+	p.consume() // '('
+	if name == "sizeof" {
+		arg := p.consume()
+		if arg.typ != tokIdent || p.consume().typ != tokRParen {
+			return 0, fmt.Errorf("sizeof() expects a single type name")
+		}
+		if p.sizeOf == nil {
+			return 0, fmt.Errorf("sizeof(%s) is not allowed here", arg.val)
+		}
+		if p.skip > 0 {
+			return 0, nil
+		}
+		return p.sizeOf(arg.val)
+	}
+
+	var args []int
+	for {
+		v, err := p.parseExpr()
+		if err != nil {
+			return 0, err
+		}
+		args = append(args, v)
+		if p.peek().typ != tokComma {
+			break
+		}
+		p.consume()
+	}
+	if p.consume().typ != tokRParen {
+		return 0, fmt.Errorf("missing closing parenthesis in %s(...)", name)
+	}
+
+	switch name {
+	case "abs":
+		if len(args) != 1 {
+			return 0, fmt.Errorf("abs() takes exactly one argument")
+		}
+		if args[0] < 0 {
+			return -args[0], nil
+		}
+		return args[0], nil
+	case "align":
+		if len(args) != 2 {
+			return 0, fmt.Errorf("align() takes exactly two arguments")
+		}
+		v, a := args[0], args[1]
+		if a <= 0 {
+			if p.evaluating() {
+				return 0, fmt.Errorf("align(): alignment must be positive, got %d", a)
+			}
+			return 0, nil
+		}
+		return alignUp(v, a), nil
+	default: // min, max
+		if len(args) < 2 {
+			return 0, fmt.Errorf("%s() takes two or more arguments", name)
+		}
+		v := args[0]
+		for _, a := range args[1:] {
+			if (name == "min" && a < v) || (name == "max" && a > v) {
+				v = a
+			}
+		}
+		return v, nil
+	}
+}
+
+// alignUp rounds v up to the next multiple of a (a > 0).
+func alignUp(v, a int) int {
+	if r := v % a; r != 0 {
+		if r < 0 {
+			r += a
+		}
+		v += a - r
+	}
+	return v
+}
+
 // evaluateTagValue evaluates arithmetic expressions for struct field tagging.
 func evaluateTagValue(strc reflect.Value, stmt string) (value int, err error) {
+	return evaluateTagValueIn(strc, nil, nil, stmt)
+}
+
+// evaluateTagValueIn is evaluateTagValue for a struct nested in the outer
+// structs (outermost first), which parent. and root. references reach, with
+// $name references resolved by resolveParam (rejected when nil).
+func evaluateTagValueIn(strc reflect.Value, outer []structFrame, resolveParam func(string) (int, error), stmt string) (value int, err error) {
 	tokens, err := tokenize(stmt)
 	if err != nil {
 		return 0, err
@@ -363,10 +784,14 @@ func evaluateTagValue(strc reflect.Value, stmt string) (value int, err error) {
 	p := &tagParser{
 		tokens:       tokens,
 		strc:         strc,
-		resolveIdent: fieldValueResolver(strc),
+		resolveIdent: fieldValueResolver(strc, outer),
+		resolveParam: resolveParam,
 		// callFunc stays nil: bytelen()/count() are not permitted in
 		// arithmetic decode-side expressions ([arrayLen] and buf_len).
 	}
+	if strc.Kind() == reflect.Struct {
+		p.sizeOf = typeSizeResolver(strc.Type())
+	}
 	value, err = p.parseExpr()
 	if err != nil {
 		return 0, err
@@ -378,7 +803,7 @@ func evaluateTagValue(strc reflect.Value, stmt string) (value int, err error) {
 }
 
 // evalConstIntExpr evaluates a constant integer expression (literals in
-// decimal/hex/octal/binary, the operators + - * /, and parentheses). Field
+// decimal/hex/octal/binary, the tag-expression operators, and parentheses). Field
 // references and functions are rejected, so the result depends only on the
 // expression text. Used by range bounds so they accept the same numeric syntax
 // as size expressions (e.g. range=0x04034b50..0x04034b50).
@@ -415,6 +840,22 @@ func parseRangeBound(s string) (float64, error) {
 	return f, nil
 }
 
+// errFillPadchar rejects a tag giving both fill= and its alias padchar=, or
+// either twice, rather than let the last one win.
+var errFillPadchar = errors.New("fill= and padchar= set the same byte; give one of them once")
+
+// parseFillByte parses the value of a fill= option, a byte such as 0xff.
+func parseFillByte(s string) (byte, error) {
+	v, err := evalConstIntExpr(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid fill value %q: %w", s, err)
+	}
+	if v < 0 || v > math.MaxUint8 {
+		return 0, fmt.Errorf("fill value %s is not a byte", s)
+	}
+	return byte(v), nil
+}
+
 // parseConstHexBytes decodes a byte-sequence const value, which must be a hex
 // blob such as 0x504b0304 (the bytes in natural order, endianness-independent).
 // Underscores are allowed as digit separators.
@@ -503,18 +944,34 @@ func resolveConst(meta *structFieldMetadata, goType reflect.Type) error {
 }
 
 // fieldValueResolver returns a resolver that reads a sibling field's integer
-// value from strc, for use by arithmetic and decode-side size expressions.
-func fieldValueResolver(strc reflect.Value) func(string) (int, error) {
+// value from strc, for use by arithmetic and decode-side size expressions. The
+// name may be a path into nested structs and arrays (see fieldByPath). A name
+// starting with parent. (repeatable: parent.parent.) or root. is read from the
+// enclosing structs in outer, outermost first; root. is strc itself when there
+// are none.
+func fieldValueResolver(strc reflect.Value, outer []structFrame) func(string) (int, error) {
 	return func(name string) (int, error) {
-		if strc.Kind() != reflect.Struct {
-			return 0, fmt.Errorf("cannot reference field %s of non-struct", name)
+		target, path := strc, name
+		if strings.HasPrefix(path, "root.") {
+			if len(outer) > 0 {
+				target = outer[0].strc
+			}
+			path = path[len("root."):]
+		} else if strings.HasPrefix(path, "parent.") {
+			level := 0
+			for strings.HasPrefix(path, "parent.") {
+				level++
+				path = path[len("parent."):]
+			}
+			if level > len(outer) {
+				return 0, fmt.Errorf("%s: no enclosing struct at that level", name)
+			}
+			target = outer[len(outer)-level].strc
 		}
-		typ := strc.Type()
-		f, ok := typ.FieldByName(name)
-		if !ok {
-			return 0, fmt.Errorf("no field named %s", name)
+		v, err := fieldByPath(target, path)
+		if err != nil {
+			return 0, err
 		}
-		v := strc.FieldByIndex(f.Index)
 		if !v.Type().ConvertibleTo(i64type) {
 			return 0, fmt.Errorf("field %s is not convertible to integer", name)
 		}
@@ -522,6 +979,211 @@ func fieldValueResolver(strc reflect.Value) func(string) (int, error) {
 	}
 }
 
+// fieldByPath returns the value a field reference such as "Len",
+// "Hdr.PayloadLen" or "Dims[1]" denotes within strc, following pointers along
+// the way.
+func fieldByPath(strc reflect.Value, path string) (reflect.Value, error) {
+	v := strc
+	for i := 0; i < len(path); {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, fmt.Errorf("%s: nil pointer", path[:i])
+			}
+			v = v.Elem()
+		}
+		if path[i] == '[' {
+			end := strings.IndexByte(path[i:], ']') + i
+			idx, err := strconv.Atoi(path[i+1 : end])
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			if v.Kind() != reflect.Array && v.Kind() != reflect.Slice {
+				return reflect.Value{}, fmt.Errorf("%s is not an array or slice", path[:i])
+			}
+			if idx >= v.Len() {
+				return reflect.Value{}, fmt.Errorf("%s: index out of range (length %d)", path[:end+1], v.Len())
+			}
+			v = v.Index(idx)
+			i = end + 1
+			continue
+		}
+		if path[i] == '.' {
+			i++
+		}
+		end := len(path)
+		if j := strings.IndexAny(path[i:], ".["); j >= 0 {
+			end = i + j
+		}
+		name := path[i:end]
+		if v.Kind() != reflect.Struct {
+			if i == 0 {
+				return reflect.Value{}, fmt.Errorf("cannot reference field %s of non-struct", name)
+			}
+			return reflect.Value{}, fmt.Errorf("%s is not a struct", path[:i-1])
+		}
+		f, ok := v.Type().FieldByName(name)
+		if !ok {
+			if i == 0 {
+				return reflect.Value{}, fmt.Errorf("no field named %s", name)
+			}
+			return reflect.Value{}, fmt.Errorf("%s has no field named %s", path[:i-1], name)
+		}
+		v = v.FieldByIndex(f.Index)
+		i = end
+	}
+	return v, nil
+}
+
+// staticSizeCache memoizes staticStructSize per struct type.
+var staticSizeCache sync.Map // map[reflect.Type]int
+
+// staticStructSize returns the encoded size of struct type t when it is the same
+// for every value: each encoded field is a fixed-width scalar, an array with
+// constant dimensions of fixed-size elements, a constant-size string or pad
+// buffer, or a nested fixed-size struct. Otherwise it returns an error naming
+// the first field whose size varies.
+func staticStructSize(t reflect.Type) (int, error) {
+	if v, ok := staticSizeCache.Load(t); ok {
+		return v.(int), nil
+	}
+	meta, err := getStructMetadata(t)
+	if err != nil {
+		return 0, err
+	}
+	size := 0
+	for _, f := range meta.fields {
+		if f.ignore || f.unexported {
+			continue
+		}
+		sz, err := staticFieldSize(t.Field(f.index).Type, f)
+		if err != nil {
+			return 0, fmt.Errorf("%s has no fixed size: field %s: %w", t.Name(), f.name, err)
+		}
+		size += sz
+	}
+	staticSizeCache.Store(t, size)
+	return size, nil
+}
+
+// staticFieldSize returns the fixed encoded size of one struct field of Go type
+// ft (see staticStructSize).
+func staticFieldSize(ft reflect.Type, f structFieldMetadata) (int, error) {
+	switch {
+	case f.fieldErr != nil:
+		return 0, f.fieldErr
+	case f.omittable:
+		return 0, fmt.Errorf("omittable")
+	case f.codec != "":
+		return 0, fmt.Errorf("custom codec %s", f.codec)
+	case ft.Kind() == reflect.Ptr || ft.Kind() == reflect.Interface:
+		return 0, fmt.Errorf("%s may be nil", ft)
+	}
+	et := f.naturalType
+
+	count := 1
+	if f.option.isArray {
+		dims := f.arrayDimExprs
+		if !f.isArray {
+			dims = []string{""} // untagged Go array: its own length
+		}
+		for _, d := range dims {
+			n := -1
+			if d != "" {
+				if v, err := evalConstIntExpr(d); err == nil {
+					n = v
+				}
+			} else if ft.Kind() == reflect.Array {
+				n = ft.Len()
+			}
+			if n < 0 {
+				return 0, fmt.Errorf("array length is not constant")
+			}
+			if ft.Kind() != reflect.Array && ft.Kind() != reflect.Slice {
+				return 0, fmt.Errorf("%s has fewer array levels than the tag", ft)
+			}
+			count *= n
+			ft = ft.Elem()
+		}
+	}
+
+	var elem int
+	switch {
+	case et.ByteSize() > 0:
+		elem = et.ByteSize()
+	case et == iStruct || et.iKind() == structKind:
+		if ft.Kind() != reflect.Struct {
+			return 0, fmt.Errorf("%s may be nil", ft)
+		}
+		sz, err := staticStructSize(ft)
+		if err != nil {
+			return 0, err
+		}
+		elem = sz
+	case et == Pad:
+		elem = 1
+		if f.bufLenExpr != "" {
+			if !f.bufLenConst {
+				return 0, fmt.Errorf("pad size is not constant")
+			}
+			elem = f.option.bufLen
+		}
+	case et == String || et == Bstring || et == Wstring || et == Dwstring:
+		if !f.bufLenConst {
+			return 0, fmt.Errorf("%s without a constant buffer size", et)
+		}
+		elem = f.option.bufLen
+		switch et {
+		case Bstring:
+			elem++
+		case Wstring:
+			elem += 2
+		case Dwstring:
+			elem += 4
+		}
+	default:
+		return 0, fmt.Errorf("%s has no fixed size", et)
+	}
+	return count * elem, nil
+}
+
+// typeSizeResolver returns a sizeof() resolver for expressions evaluated within
+// struct type root. A type name is looked up among root itself and the struct
+// types reachable from its fields (through pointers, arrays and slices).
+func typeSizeResolver(root reflect.Type) func(string) (int, error) {
+	return func(name string) (int, error) {
+		t := findStructType(root, name, map[reflect.Type]bool{})
+		if t == nil {
+			return 0, fmt.Errorf("sizeof(%s): no struct type named %s is used by %s", name, name, root.Name())
+		}
+		sz, err := staticStructSize(t)
+		if err != nil {
+			return 0, fmt.Errorf("sizeof(%s): %w", name, err)
+		}
+		return sz, nil
+	}
+}
+
+// findStructType searches t and the types reachable from its fields for a struct
+// type with the given name.
+func findStructType(t reflect.Type, name string, seen map[reflect.Type]bool) reflect.Type {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return nil
+	}
+	seen[t] = true
+	if t.Name() == name {
+		return t
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if found := findStructType(t.Field(i).Type, name, seen); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
 type exprFuncCall struct {
 	name string   // "bytelen", "count", or a custom evaluator name
 	args []string // referenced field names
@@ -588,7 +1250,8 @@ func exprReferences(expr string) (refs []string, funcs []exprFuncCall, err error
 		return nil, nil, err
 	}
 	p := &tagParser{
-		tokens: tokens,
+		tokens:   tokens,
+		visitAll: true,
 		resolveIdent: func(name string) (int, error) {
 			refs = append(refs, name)
 			return 0, nil
@@ -598,6 +1261,11 @@ func exprReferences(expr string) (refs []string, funcs []exprFuncCall, err error
 			refs = append(refs, args...)
 			return 0, nil
 		},
+		sizeOf: func(string) (int, error) { return 0, nil },
+		resolveParam: func(name string) (int, error) {
+			refs = append(refs, "$"+name)
+			return 0, nil
+		},
 	}
 	if _, err = p.parseExpr(); err != nil {
 		return nil, nil, err
@@ -639,17 +1307,71 @@ func splitTagOptions(s string) []string {
 // per-dimension expression strings (["4","2"]); "[]" yields one empty entry, and
 // an empty run yields nil (not an array).
 func parseArrayDims(bracketRun string) []string {
-	if bracketRun == "" {
-		return nil
-	}
-	ms := mTagDim.FindAllStringSubmatch(bracketRun, -1)
-	dims := make([]string, 0, len(ms))
-	for _, m := range ms {
-		dims = append(dims, strings.TrimSpace(m[1]))
+	var dims []string
+	for i := 0; i < len(bracketRun); i++ {
+		if bracketRun[i] != '[' {
+			continue
+		}
+		end := closingBracket(bracketRun, i)
+		if end < 0 {
+			break
+		}
+		dims = append(dims, strings.TrimSpace(bracketRun[i+1:end]))
+		i = end
 	}
 	return dims
 }
 
+// parseTypeTag splits the leading type part of a binary tag, such as
+// "[4][N]int16" or "string(align(Len, 4))", into the array bracket run
+// ("[4][N]", empty if not an array), the binary type name, and the buffer
+// length expression inside the trailing parentheses (empty if none). Brackets
+// and parentheses nest, so size expressions may contain calls and parentheses.
+func parseTypeTag(s string) (dimRun, typeName, bufLen string) {
+	i := 0
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\r' || s[i] == '\n') {
+		i++
+	}
+	start := i
+	for i < len(s) && s[i] == '[' {
+		end := closingBracket(s, i)
+		if end < 0 {
+			break
+		}
+		i = end + 1
+	}
+	dimRun = s[start:i]
+	start = i
+	for i < len(s) && !strings.ContainsRune(" \t\r\n()[]", rune(s[i])) {
+		i++
+	}
+	typeName = s[start:i]
+	if i < len(s) && s[i] == '(' {
+		if end := closingBracket(s, i); end > i+1 {
+			bufLen = s[i+1 : end]
+		}
+	}
+	return
+}
+
+// closingBracket returns the index of the bracket that closes the one at
+// s[open], counting nested () and [] pairs, or -1 if there is none.
+func closingBracket(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
 // parse tag string directly
 func parseTagString(tagStr string, strc reflect.Value, naturalType eType, naturalOption typeOption, fieldErr error) (encodeType eType, option typeOption, err error) {
 	encodeType = naturalType
@@ -665,8 +1387,7 @@ func parseTagString(tagStr string, strc reflect.Value, naturalType eType, natura
 		return
 	}
 
-	m := mTag.FindStringSubmatch(tags[0])
-	typeTag := m[2]
+	dimRun, typeTag, bufLenExpr := parseTypeTag(tags[0])
 	parsedType := Any
 	if typeTag != "" {
 		parsedType = typeByName(typeTag)
@@ -684,7 +1405,7 @@ func parseTagString(tagStr string, strc reflect.Value, naturalType eType, natura
 	encodeType = parsedType
 
 	// check for array type and its size(s); a run like [4][2] is multidimensional.
-	dims := parseArrayDims(m[1])
+	dims := parseArrayDims(dimRun)
 	option.isArray = len(dims) > 0
 	if option.isArray {
 		option.dims = make([]int, len(dims))
@@ -706,14 +1427,15 @@ func parseTagString(tagStr string, strc reflect.Value, naturalType eType, natura
 		option.arrayLen = option.dims[0]
 	}
 
-	if m[4] != "" {
-		option.bufLen, err = evaluateTagValue(strc, m[4])
+	if bufLenExpr != "" {
+		option.bufLen, err = evaluateTagValue(strc, bufLenExpr)
 		if option.bufLen < 0 {
 			err = errNegativeSize
 			return
 		}
 	}
 
+	hasFill := false
 	for i := 1; i < len(tags); i++ {
 		t := strings.Split(tags[i], "=")
 		for j := 0; j < len(t); j++ {
@@ -729,15 +1451,7 @@ func parseTagString(tagStr string, strc reflect.Value, naturalType eType, natura
 			}
 		case "endian":
 			if len(t) > 1 {
-				switch strings.ToLower(t[1]) {
-				case "big":
-					option.endian = endianBig
-				case "little":
-					option.endian = endianLittle
-				case "inverse":
-					option.endian = endianInverse
-				default:
-					err = fmt.Errorf("unknown endian value: %s", t[1])
+				if option.endian, err = parseEndianValue(t[1]); err != nil {
 					return
 				}
 			} else {
@@ -751,6 +1465,31 @@ func parseTagString(tagStr string, strc reflect.Value, naturalType eType, natura
 				err = fmt.Errorf("missing value for codec tag")
 				return
 			}
+		case "fill", "padchar": // padchar= is fill= for a string
+			if len(t) > 1 {
+				if hasFill {
+					err = errFillPadchar
+					return
+				}
+				if option.fill, err = parseFillByte(t[1]); err != nil {
+					return
+				}
+				hasFill = true
+			} else {
+				err = fmt.Errorf("missing value for %s tag", t[0])
+				return
+			}
+		case "reserved":
+			option.reserved = true
+		case "trim":
+			if len(t) > 1 {
+				if option.trim, err = parseTrimMode(t[1]); err != nil {
+					return
+				}
+			} else {
+				err = fmt.Errorf("missing value for trim tag")
+				return
+			}
 		case "valueof":
 			err = fmt.Errorf("valueof is only supported on struct fields, not single values")
 			return
@@ -764,7 +1503,8 @@ func parseTagString(tagStr string, strc reflect.Value, naturalType eType, natura
 	return
 }
 
-// parseEndianValue maps a tag's endian= value to an endianOverride.
+// parseEndianValue maps a tag's endian= value to an endianOverride: big, little,
+// inverse, or the name of a byte order registered with AddByteOrder.
 func parseEndianValue(s string) (endianOverride, error) {
 	switch strings.ToLower(strings.TrimSpace(s)) {
 	case "big":
@@ -773,17 +1513,20 @@ func parseEndianValue(s string) (endianOverride, error) {
 		return endianLittle, nil
 	case "inverse":
 		return endianInverse, nil
-	default:
-		return endianNone, fmt.Errorf("unknown endian value: %s", s)
 	}
+	if name := strings.TrimSpace(s); isByteOrderName(name) {
+		return namedEndian(name), nil
+	}
+	return endianNone, fmt.Errorf("unknown endian value: %s", s)
 }
 
 // parseStructSentinel parses the struct-scope options carried by a blank
-// `_ struct{}` sentinel field's binary tag: endian= (the struct's byte order) and
+// `_ struct{}` sentinel field's binary tag: endian= (the struct's byte order, or
+// a byte-order mark field, see orderMark, or detect, see orderDetect) and
 // encoding= (its default text encoding).
-func parseStructSentinel(tagStr string) (eo endianOverride, encoding string, err error) {
+func parseStructSentinel(tagStr string) (eo endianOverride, mark *orderMark, detect *orderDetect, encoding string, err error) {
 	eo = endianNone
-	for _, seg := range splitTagOptions(tagStr) {
+	for _, seg := range joinOrderMarkValues(splitTagOptions(tagStr)) {
 		seg = strings.TrimSpace(seg)
 		if seg == "" {
 			continue
@@ -793,23 +1536,292 @@ func parseStructSentinel(tagStr string) (eo endianOverride, encoding string, err
 		switch key {
 		case "endian":
 			if len(kv) < 2 {
-				return endianNone, "", fmt.Errorf("missing value for endian in struct-level `_` sentinel tag")
+				return endianNone, nil, nil, "", fmt.Errorf("missing value for endian in struct-level `_` sentinel tag")
+			}
+			if v := strings.TrimSpace(kv[1]); v == "detect" || strings.HasPrefix(v, "detect:") {
+				record := strings.TrimSpace(strings.TrimPrefix(v[len("detect"):], ":"))
+				if record == "" {
+					// Without a field to record it in, the order read would be
+					// lost and the value could not be encoded again.
+					return endianNone, nil, nil, "", fmt.Errorf("endian=detect needs a field to record the byte order in: declare endian=detect:Field")
+				}
+				detect = &orderDetect{record: record}
+				eo, mark = endianNone, nil
+				continue
+			}
+			if isOrderMarkSpec(kv[1]) {
+				m, perr := parseOrderMark(kv[1])
+				if perr != nil {
+					return endianNone, nil, nil, "", perr
+				}
+				eo, mark, detect = endianNone, m, nil
+				continue
 			}
 			e, perr := parseEndianValue(kv[1])
 			if perr != nil {
-				return endianNone, "", perr
+				return endianNone, nil, nil, "", perr
 			}
-			eo = e
+			eo, mark, detect = e, nil, nil
 		case "encoding":
 			if len(kv) < 2 || strings.TrimSpace(kv[1]) == "" {
-				return endianNone, "", fmt.Errorf("missing value for encoding in struct-level `_` sentinel tag")
+				return endianNone, nil, nil, "", fmt.Errorf("missing value for encoding in struct-level `_` sentinel tag")
 			}
 			encoding = strings.TrimSpace(kv[1])
 		default:
-			return endianNone, "", fmt.Errorf("unknown struct-level option %q in `_` sentinel tag (only endian= and encoding= are supported)", key)
+			return endianNone, nil, nil, "", fmt.Errorf("unknown struct-level option %q in `_` sentinel tag (only endian= and encoding= are supported)", key)
+		}
+	}
+	return eo, mark, detect, encoding, nil
+}
+
+// orderMark is a struct-level `endian=Field` or `endian=Field:V=ORDER,…`
+// declaration: the fields after Field are encoded and decoded in the byte order
+// that Field's value maps to (TIFF's "II"/"MM", a Unicode BOM). When decoding,
+// the value is the one just read; when encoding, the Go value.
+type orderMark struct {
+	field  string
+	index  int // struct field index of field, set by getStructMetadata
+	values []orderMarkValue
+}
+
+// orderMarkValue maps one mark value to a byte order. An integer field matches
+// num; a byte array, byte slice or string field matches raw, the bytes of a hex
+// key (nil for a decimal key).
+type orderMarkValue struct {
+	num    uint64
+	raw    []byte
+	endian endianOverride
+}
+
+// defaultOrderMarks is the mapping of a bare endian=Field: TIFF's "II"/"MM"
+// and the Unicode byte-order mark.
+const defaultOrderMarks = "0x4949=little,0x4d4d=big,0xfeff=big,0xfffe=little"
+
+// isOrderMarkSpec reports whether an endian= value names a field rather than a
+// byte order.
+func isOrderMarkSpec(v string) bool {
+	if _, err := parseEndianValue(v); err == nil {
+		return false
+	}
+	v = strings.TrimSpace(v)
+	return v != "" && (v[0] == '_' || unicode.IsUpper(rune(v[0])))
+}
+
+// joinOrderMarkValues rejoins the V=ORDER pairs of an endian=Field:… mapping,
+// which the option splitter separates at their commas.
+func joinOrderMarkValues(opts []string) []string {
+	var out []string
+	for _, o := range opts {
+		t := strings.TrimSpace(o)
+		if len(out) > 0 && t != "" && t[0] >= '0' && t[0] <= '9' && strings.Contains(t, "=") {
+			last := strings.TrimSpace(out[len(out)-1])
+			if strings.HasPrefix(last, "endian=") && strings.Contains(last, ":") {
+				out[len(out)-1] += "," + o
+				continue
+			}
 		}
+		out = append(out, o)
 	}
-	return eo, encoding, nil
+	return out
+}
+
+// parseOrderMark parses "Field" or "Field:V=ORDER,V=ORDER", ORDER one of big,
+// little or inverse and V an integer literal.
+func parseOrderMark(spec string) (*orderMark, error) {
+	field, mapping := strings.TrimSpace(spec), defaultOrderMarks
+	if i := strings.IndexByte(field, ':'); i >= 0 {
+		field, mapping = strings.TrimSpace(field[:i]), field[i+1:]
+	}
+	m := &orderMark{field: field}
+	for _, pair := range strings.Split(mapping, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("endian=%s: %q is not a VALUE=ORDER pair", field, strings.TrimSpace(pair))
+		}
+		key := strings.ReplaceAll(strings.TrimSpace(kv[0]), "_", "")
+		num, err := strconv.ParseUint(key, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("endian=%s: invalid mark value %q", field, strings.TrimSpace(kv[0]))
+		}
+		e, err := parseEndianValue(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("endian=%s: %w", field, err)
+		}
+		v := orderMarkValue{num: num, endian: e}
+		if h := strings.ToLower(key); strings.HasPrefix(h, "0x") && len(h)%2 == 0 {
+			v.raw, _ = hex.DecodeString(h[2:])
+		}
+		m.values = append(m.values, v)
+	}
+	return m, nil
+}
+
+// checkField validates the mark field's type: an integer, or a byte array,
+// byte slice or string.
+func (m *orderMark) checkField(t reflect.Type) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.String:
+		return nil
+	case reflect.Array, reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return nil
+		}
+	}
+	return fmt.Errorf("endian=%s: the field must be an integer, a byte array or slice, or a string, not %s", m.field, t)
+}
+
+// resolve returns the byte order the mark field of strc selects; order is the
+// one in effect before the mark (the base of endian=inverse).
+func (m *orderMark) resolve(ms *Marshaler, order ByteOrder, strc reflect.Value) (ByteOrder, error) {
+	v := derefValue(strc.Field(m.index))
+	var num uint64
+	var raw []byte
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		num = uint64(v.Int())
+		if bits := v.Type().Bits(); bits < 64 {
+			num &= 1<<bits - 1
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		num = v.Uint()
+	case reflect.String:
+		raw = []byte(v.String())
+	case reflect.Array, reflect.Slice:
+		raw = make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(raw), v)
+	default:
+		return order, fmt.Errorf("endian=%s: the field has no value", m.field)
+	}
+	for _, mv := range m.values {
+		if raw == nil && mv.num == num || raw != nil && mv.raw != nil && bytes.Equal(mv.raw, raw) {
+			return ms.resolveOrder(order, mv.endian)
+		}
+	}
+	if raw != nil {
+		return order, fmt.Errorf("endian=%s: byte-order mark 0x%x matches no byte order", m.field, raw)
+	}
+	return order, fmt.Errorf("endian=%s: byte-order mark %#x matches no byte order", m.field, num)
+}
+
+// switchOrder returns the order for field index fieldIdx of strc, the next
+// field to process: the mark's order once the mark field is behind. applied
+// records that it was resolved, so that happens once per struct.
+func (m *orderMark) switchOrder(ms *Marshaler, order ByteOrder, strc reflect.Value, fieldIdx int, applied *bool) (ByteOrder, error) {
+	if m == nil || *applied || fieldIdx <= m.index {
+		return order, nil
+	}
+	*applied = true
+	return m.resolve(ms, order, strc)
+}
+
+// orderDetect is a struct-level `endian=detect:Field` declaration: the struct
+// is in whichever byte order its magic number, the first integer const= field,
+// reads back as the constant in (pcap's 0xa1b2c3d4). Field, a ByteOrder field
+// tagged `binary:"-"`, records the detected order, and encoding the value
+// again reproduces it.
+type orderDetect struct {
+	index       int    // struct field index of the magic field
+	value       int64  // the const= value of the magic field
+	big, little []byte // the magic as encoded in each order
+	record      string // name of the field recording the order
+	recordIndex int    // struct field index of record
+}
+
+// byteOrderType is the type of a field that records a detected byte order.
+var byteOrderType = reflect.TypeOf((*ByteOrder)(nil)).Elem()
+
+// bind finds the magic field of st among fields and validates the declaration.
+func (d *orderDetect) bind(st reflect.Type, fields []structFieldMetadata) error {
+	var magic *structFieldMetadata
+	for i := range fields {
+		if f := &fields[i]; !f.ignore && !f.unexported && f.hasConst && !f.constIsBytes {
+			magic = f
+			break
+		}
+	}
+	if magic == nil {
+		return fmt.Errorf("endian=detect: %s has no integer const= field to detect the byte order from", st.Name())
+	}
+	ft := st.Field(magic.index).Type
+	switch ft.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return fmt.Errorf("endian=detect: the magic field %s must be an integer, not %s", magic.name, ft)
+	}
+	if magic.endian != endianNone {
+		return fmt.Errorf("endian=detect: the magic field %s must not declare its own endian=", magic.name)
+	}
+	et := magic.encodeType
+	if et == Any {
+		et, _ = getNaturalType(reflect.Zero(ft))
+	}
+	width := et.ByteSize()
+	if k := et.iKind(); width < 2 || k != intKind && k != uintKind && k != bitmapKind {
+		return fmt.Errorf("endian=detect: the magic field %s must be a multi-byte integer, not %s", magic.name, et)
+	}
+	d.index, d.value = magic.index, magic.constInt
+	d.big, d.little = make([]byte, width), make([]byte, width)
+	for i := 0; i < width; i++ {
+		b := byte(uint64(d.value) >> (8 * uint(i)))
+		d.big[width-1-i], d.little[i] = b, b
+	}
+	if bytes.Equal(d.big, d.little) {
+		return fmt.Errorf("endian=detect: the magic %s of field %s reads the same in both byte orders", magic.constExpr, magic.name)
+	}
+	f, ok := st.FieldByName(d.record)
+	if !ok || len(f.Index) != 1 || f.Type != byteOrderType || f.Tag.Get(tagName) != "-" {
+		return fmt.Errorf("endian=detect:%s: %s needs a field %s of type binarystruct.ByteOrder tagged `binary:\"-\"`", d.record, st.Name(), d.record)
+	}
+	d.recordIndex = f.Index[0]
+	return nil
+}
+
+// isMagic reports whether struct field index fieldIdx is the magic field.
+func (d *orderDetect) isMagic(fieldIdx int) bool {
+	return d != nil && d.index == fieldIdx
+}
+
+// read decodes the magic field of strc from r and returns the byte order it
+// was written in. The magic field is set to its constant and the record field
+// to the order.
+func (d *orderDetect) read(r io.Reader, strc reflect.Value) (n int, order ByteOrder, err error) {
+	buf := make([]byte, len(d.big))
+	if n, err = io.ReadFull(r, buf); err != nil {
+		return n, nil, err
+	}
+	switch {
+	case bytes.Equal(buf, d.big):
+		order = BigEndian
+	case bytes.Equal(buf, d.little):
+		order = LittleEndian
+	default:
+		return n, nil, fmt.Errorf("endian=detect: magic 0x%x is 0x%x in neither byte order: %w", buf, d.big, ErrValidationError)
+	}
+	if fv := strc.Field(d.index); fv.CanInt() {
+		fv.SetInt(d.value)
+	} else {
+		fv.SetUint(uint64(d.value))
+	}
+	strc.Field(d.recordIndex).Set(reflect.ValueOf(&order).Elem())
+	return n, order, nil
+}
+
+// writeOrder returns the order for encoding field index fieldIdx of strc and
+// the fields after it: the recorded order from the magic field on, when one is
+// recorded.
+func (d *orderDetect) writeOrder(order ByteOrder, strc reflect.Value, fieldIdx int) ByteOrder {
+	if !d.isMagic(fieldIdx) {
+		return order
+	}
+	if rec := strc.Field(d.recordIndex); !rec.IsNil() {
+		return rec.Interface().(ByteOrder)
+	}
+	return order
 }
 
 // getStructMetadata builds or retrieves cached metadata for the struct type.
@@ -828,6 +1840,8 @@ func getStructMetadata(structType reflect.Type) (*structMetadata, error) {
 	// Struct-level byte order / default encoding: own* come from a blank `_`
 	// sentinel field; inherited* from value-embedded structs that declare them.
 	ownEndian := endianNone
+	var ownMark *orderMark
+	var ownDetect *orderDetect
 	var inheritedEndians []endianOverride
 	ownEncoding := ""
 	var inheritedEncodings []string
@@ -841,12 +1855,18 @@ func getStructMetadata(structType reflect.Type) (*structMetadata, error) {
 		// excluded from the layout — it is metadata, not an encoded field.
 		if field.Name == "_" && fKind == reflect.Struct && fType.NumField() == 0 {
 			if tagStr := field.Tag.Get(tagName); tagStr != "" {
-				eo, enc, err := parseStructSentinel(tagStr)
+				eo, mark, detect, enc, err := parseStructSentinel(tagStr)
 				if err != nil {
 					return nil, err
 				}
 				if eo != endianNone {
-					ownEndian = eo
+					ownEndian, ownMark, ownDetect = eo, nil, nil
+				}
+				if mark != nil {
+					ownMark, ownDetect = mark, nil
+				}
+				if detect != nil {
+					ownMark, ownDetect = nil, detect
 				}
 				if enc != "" {
 					ownEncoding = enc
@@ -921,8 +1941,7 @@ func getStructMetadata(structType reflect.Type) (*structMetadata, error) {
 		}
 
 		meta.hasTag = true
-		m := mTag.FindStringSubmatch(tags[0])
-		typeTag := m[2]
+		dimRun, typeTag, bufLenExpr := parseTypeTag(tags[0])
 		parsedType := Any
 		if typeTag != "" {
 			parsedType = typeByName(typeTag)
@@ -935,20 +1954,21 @@ func getStructMetadata(structType reflect.Type) (*structMetadata, error) {
 			continue
 		}
 
-		dims := parseArrayDims(m[1])
+		dims := parseArrayDims(dimRun)
 		meta.isArray = len(dims) > 0
 		if meta.isArray {
 			meta.arrayDimExprs = dims
 			meta.arrayLenExpr = dims[0] // outermost, for back-compat
 		}
 
-		if m[4] != "" {
-			meta.bufLenExpr = m[4]
-		}
+		meta.bufLenExpr = bufLenExpr
 
 		// parse options
+		hasFill, hasPadchar, hasTrim := false, false, false
 		for idx := 1; idx < len(tags); idx++ {
-			t := strings.Split(tags[idx], "=")
+			// Only the first '=' ends the key: a value, such as a check= rule,
+			// may contain more and is kept verbatim.
+			t := strings.SplitN(tags[idx], "=", 2)
 			for j := 0; j < len(t); j++ {
 				t[j] = strings.TrimSpace(t[j])
 			}
@@ -961,16 +1981,11 @@ func getStructMetadata(structType reflect.Type) (*structMetadata, error) {
 				}
 			case "endian":
 				if len(t) > 1 {
-					switch strings.ToLower(t[1]) {
-					case "big":
-						meta.endian = endianBig
-					case "little":
-						meta.endian = endianLittle
-					case "inverse":
-						meta.endian = endianInverse
-					default:
-						return nil, fmt.Errorf("unknown endian value: %s on field %s", t[1], field.Name)
+					e, err := parseEndianValue(t[1])
+					if err != nil {
+						return nil, fmt.Errorf("%w on field %s", err, field.Name)
 					}
+					meta.endian = e
 				} else {
 					return nil, fmt.Errorf("missing value for endian tag on field %s", field.Name)
 				}
@@ -987,14 +2002,14 @@ func getStructMetadata(structType reflect.Type) (*structMetadata, error) {
 				}
 			case "valueof":
 				if len(t) > 1 {
-					meta.valueofExpr = strings.Join(t[1:], "=")
+					meta.valueofExpr = t[1]
 				} else {
 					return nil, fmt.Errorf("missing value for valueof tag on field %s", field.Name)
 				}
 			case "const":
 				if len(t) > 1 {
 					meta.hasConst = true
-					meta.constExpr = strings.Join(t[1:], "=")
+					meta.constExpr = t[1]
 				} else {
 					return nil, fmt.Errorf("missing value for const tag on field %s", field.Name)
 				}
@@ -1007,6 +2022,7 @@ func getStructMetadata(structType reflect.Type) (*structMetadata, error) {
 					}
 					minStr := strings.TrimSpace(bounds[0])
 					maxStr := strings.TrimSpace(bounds[1])
+					meta.rangeExpr = minStr + ".." + maxStr
 					if minStr != "" {
 						minVal, errParse := parseRangeBound(minStr)
 						if errParse != nil {
@@ -1038,10 +2054,70 @@ func getStructMetadata(structType reflect.Type) (*structMetadata, error) {
 				} else {
 					return nil, fmt.Errorf("missing value for match tag on field %s", field.Name)
 				}
+			case "enum":
+				if len(t) > 1 {
+					e, err := parseFieldEnum(t[1])
+					if err != nil {
+						return nil, fmt.Errorf("%w on field %s", err, field.Name)
+					}
+					meta.enum = e
+				} else {
+					return nil, fmt.Errorf("missing value for enum tag on field %s", field.Name)
+				}
+			case "check":
+				if len(t) > 1 {
+					meta.checkExpr = t[1]
+				} else {
+					return nil, fmt.Errorf("missing value for check tag on field %s", field.Name)
+				}
+			case "default":
+				if len(t) > 1 {
+					meta.defaultExpr = t[1]
+				} else {
+					return nil, fmt.Errorf("missing value for default tag on field %s", field.Name)
+				}
+			case "omitdefault":
+				meta.omitDefault = true
+			case "fill", "padchar": // padchar= is fill= for a string
+				if len(t) > 1 {
+					if hasFill || hasPadchar {
+						return nil, fmt.Errorf("field %s: %w", field.Name, errFillPadchar)
+					}
+					b, err := parseFillByte(t[1])
+					if err != nil {
+						return nil, fmt.Errorf("%w on field %s", err, field.Name)
+					}
+					meta.option.fill = b
+					hasFill, hasPadchar = t[0] == "fill", t[0] == "padchar"
+				} else {
+					return nil, fmt.Errorf("missing value for %s tag on field %s", t[0], field.Name)
+				}
+			case "reserved":
+				meta.option.reserved = true
+			case "trim":
+				if len(t) > 1 {
+					m, err := parseTrimMode(t[1])
+					if err != nil {
+						return nil, fmt.Errorf("%w on field %s", err, field.Name)
+					}
+					meta.option.trim = m
+					hasTrim = true
+				} else {
+					return nil, fmt.Errorf("missing value for trim tag on field %s", field.Name)
+				}
 			default:
 				return nil, fmt.Errorf("unknown tag %s on field %s", t[0], field.Name)
 			}
 		}
+		if meta.option.reserved && meta.encodeType != Pad {
+			return nil, fmt.Errorf("field %s: reserved is only allowed on pad", field.Name)
+		}
+		if hasFill && meta.encodeType != Pad && meta.encodeType.iKind() != stringKind {
+			return nil, fmt.Errorf("field %s: fill is only allowed on pad and string types", field.Name)
+		}
+		if (hasPadchar || hasTrim) && meta.encodeType.iKind() != stringKind {
+			return nil, fmt.Errorf("field %s: padchar and trim are only allowed on string types", field.Name)
+		}
 
 		if meta.hasTag {
 			if meta.encodeType != Any {
@@ -1086,6 +2162,12 @@ func getStructMetadata(structType reflect.Type) (*structMetadata, error) {
 				meta.option.codec = meta.codec
 			}
 
+			for _, e := range append([]string{meta.bufLenExpr}, meta.arrayDimExprs...) {
+				if e != "" && exprUsesParam(e, "remaining") {
+					meta.sizedByInput = true
+				}
+			}
+
 			// Decode-side size expressions must be arithmetic only: reject
 			// bytelen()/count() in [arrayLen] and buf_len.
 			for _, e := range []string{meta.arrayLenExpr, meta.bufLenExpr} {
@@ -1150,14 +2232,78 @@ func getStructMetadata(structType reflect.Type) (*structMetadata, error) {
 						}
 					}
 				}
-				for _, r := range refs {
-					if _, ok := structType.FieldByName(r); !ok {
+				for i, r := range refs {
+					if isOuterRef(r) || strings.HasPrefix(r, "$") {
+						refs[i] = "" // resolved in the enclosing struct or on the Marshaler at encode time
+						continue
+					}
+					if _, ok := structType.FieldByName(fieldPathRoot(r)); !ok {
 						return nil, fmt.Errorf("field %s: valueof references unknown field %s", field.Name, r)
 					}
+					refs[i] = fieldPathRoot(r) // cycles run through top-level fields
 				}
 				valueofRefs[field.Name] = refs
 			}
 
+			if meta.enum != nil {
+				switch meta.naturalType.iKind() {
+				case intKind, uintKind, bitmapKind:
+				default:
+					return nil, fmt.Errorf("field %s: enum requires an integer/bitmap field type, got %s", field.Name, meta.naturalType)
+				}
+			}
+
+			// A check= rule runs right after its field is decoded, so it may
+			// only use that field and the ones before it.
+			if meta.checkExpr != "" {
+				refs, fns, errRef := exprReferences(meta.checkExpr)
+				if errRef != nil {
+					return nil, fmt.Errorf("field %s: invalid check expression: %w", field.Name, errRef)
+				}
+				if len(fns) > 0 {
+					return nil, fmt.Errorf("field %s: functions (bytelen/count) are not allowed in check expressions", field.Name)
+				}
+				for _, r := range refs {
+					if r == "$remaining" {
+						meta.checkUsesRemaining = true
+					}
+					if isOuterRef(r) || strings.HasPrefix(r, "$") {
+						continue
+					}
+					sf, ok := structType.FieldByName(fieldPathRoot(r))
+					if !ok {
+						return nil, fmt.Errorf("field %s: check references unknown field %s", field.Name, r)
+					}
+					if sf.Index[0] > i {
+						return nil, fmt.Errorf("field %s: check references %s, which is decoded later; put the rule on the last field it uses", field.Name, r)
+					}
+				}
+			}
+
+			// A default is the field's value when the input ends before it;
+			// omitdefault drops a trailing field equal to it on encode.
+			if meta.defaultExpr != "" {
+				if meta.hasConst || meta.valueofExpr != "" {
+					return nil, fmt.Errorf("field %s: default cannot be combined with const or valueof", field.Name)
+				}
+				if k := field.Type.Kind(); k == reflect.Ptr || k == reflect.Interface {
+					return nil, fmt.Errorf("field %s: default is not allowed on a pointer or interface field; an omitted one is nil", field.Name)
+				}
+				v, errDef := parseDefault(meta.defaultExpr, field.Type)
+				if errDef != nil {
+					return nil, fmt.Errorf("field %s: %w", field.Name, errDef)
+				}
+				meta.defaultValue = v
+			}
+			if meta.omitDefault {
+				if meta.defaultExpr == "" {
+					return nil, fmt.Errorf("field %s: omitdefault requires a default", field.Name)
+				}
+				if !meta.omittable || meta.omittableExpr != "" {
+					return nil, fmt.Errorf("field %s: omitdefault requires a plain omittable field (one that ends at the end of the input)", field.Name)
+				}
+			}
+
 			// Validate and resolve const: emit-on-encode + validate-on-decode
 			// of a fixed value. Target is an integer/bitmap or a raw byte
 			// sequence ([N]byte / string(N)); the byte form uses a hex blob.
@@ -1247,7 +2393,28 @@ func getStructMetadata(structType reflect.Type) (*structMetadata, error) {
 		}
 	}
 
-	meta := &structMetadata{fields: fields, endian: structEndian, defaultEncoding: structEncoding}
+	if ownMark != nil {
+		f, ok := structType.FieldByName(ownMark.field)
+		if !ok || len(f.Index) != 1 {
+			return nil, fmt.Errorf("endian=%s: %s has no field named %s", ownMark.field, structType.Name(), ownMark.field)
+		}
+		if err := ownMark.checkField(f.Type); err != nil {
+			return nil, err
+		}
+		ownMark.index = f.Index[0]
+	}
+	if ownDetect != nil {
+		if err := ownDetect.bind(structType, fields); err != nil {
+			return nil, err
+		}
+	}
+
+	meta := &structMetadata{fields: fields, endian: structEndian, defaultEncoding: structEncoding, orderMark: ownMark, orderDetect: ownDetect}
+	for _, f := range fields {
+		if f.defaultValue.IsValid() {
+			meta.hasDefaults = true
+		}
+	}
 	structMetadataCache.Store(structType, meta)
 	return meta, nil
 }
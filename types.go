@@ -68,10 +68,7 @@ func resolveByteOrder(order ByteOrder, endian endianOverride) ByteOrder {
 	case endianLittle:
 		return LittleEndian
 	case endianInverse:
-		if order == BigEndian {
-			return LittleEndian
-		}
-		return BigEndian
+		return inverseOrder(order)
 	default:
 		return order
 	}
@@ -135,6 +132,8 @@ const (
 	// Pad is padding zero bytes. Original value is ignored.
 	// May be postfixed by '(size)' to set number of bytes.
 	// e.g.) `binary:"pad(0x8)"`
+	// The fill= option sets another byte, and reserved checks the bytes on decode.
+	// e.g.) `binary:"pad(4),fill=0xff,reserved"`
 	Pad
 
 	// Values with Ignore tag are ignored. `binary:"ignore"`
@@ -191,6 +190,9 @@ const (
 	endianBig
 	endianLittle
 	endianInverse
+	// endianNamed and above are byte orders registered on a Marshaler by name;
+	// see namedEndian and Marshaler.AddByteOrder.
+	endianNamed
 )
 
 type typeOption struct {
@@ -200,8 +202,33 @@ type typeOption struct {
 	dims          []int          // all array dimensions for multidimensional tags (`[4][2]int8` → [4 2]); len>1 means nested arrays. arrayLen mirrors dims[0]. nil/len<=1 = ordinary 1-D.
 	bufLen        int            // tagged field is a string or a padding of length bufLen: `binary:"STRINGTYPE(buflen)"`
 	encoding      string         // string encoding of the field: `binary:"string,encoding=ENC"`
-	endian        endianOverride // byte order override: `binary:"...,endian=big|little|inverse"`
+	endian        endianOverride // byte order override: `binary:"...,endian=big|little|inverse|NAME"`
 	codec         string         // custom codec name: `binary:"...,codec=Codec_Name"`
+	fill          byte           // the byte a padding or a string's unused buffer is filled with: `binary:"pad(4),fill=0xff"`
+	reserved      bool           // a padding must hold its fill byte on decode: `binary:"pad(4),reserved"`
+	trim          trimMode       // the padding a decoded string drops: `binary:"string(8),padchar=0x20,trim=both"`
+}
+
+// trimMode is the padding a sized string drops on decode.
+type trimMode uint8
+
+const (
+	trimRight trimMode = iota // the padding after the string (default)
+	trimNone                  // none: the whole buffer, or what the length prefix counts
+	trimBoth                  // the padding after the string and before it
+)
+
+// parseTrimMode parses the value of a trim= option.
+func parseTrimMode(s string) (trimMode, error) {
+	switch s {
+	case "right":
+		return trimRight, nil
+	case "none":
+		return trimNone, nil
+	case "both":
+		return trimBoth, nil
+	}
+	return trimRight, fmt.Errorf("invalid trim value %q: must be right, none or both", s)
 }
 
 func getITypeFromRType(rt reflect.Type) (it eType) {
@@ -383,7 +410,7 @@ func decodeFunc(srcType eType, destRType reflect.Type) (bytesz int, decoder func
 func buildDecodeFunc(srcType eType, destRType reflect.Type) (bytesz int, decoder func(reflect.Value, uint64) error) {
 
 	printerr := func(v interface{}, t reflect.Value) error {
-		return fmt.Errorf("value %v not fit in type %v", v, t.Type())
+		return fmt.Errorf("value %v not fit in type %v: %w", v, t.Type(), ErrValueOverflow)
 	}
 
 	// get destination size
@@ -570,7 +597,7 @@ func buildDecodeFunc(srcType eType, destRType reflect.Type) (bytesz int, decoder
 func buildEncodeFunc(srcRType reflect.Type, destType eType) func(reflect.Value) (uint64, int, error) {
 
 	printErrNotFit := func(v interface{}, t eType) error {
-		return fmt.Errorf("value %v not fit in %s", v, t)
+		return fmt.Errorf("value %v not fit in %s: %w", v, t, ErrValueOverflow)
 	}
 
 	// get destination size
@@ -9,6 +9,7 @@ import (
 
 	"io"
 	"reflect"
+	"strconv"
 )
 
 var (
@@ -24,6 +25,10 @@ var (
 	// validation constraint failed
 	ErrValidationError = errors.New("validation failed")
 
+	// a value does not fit its binary encoding, such as a string longer than its
+	// fixed size or a slice longer than its declared array length
+	ErrValueOverflow = errors.New("value overflow")
+
 	// no byte order is available to encode/decode a multi-byte value: the value
 	// declared none (no struct-level or per-field endian=) and the Marshaler has
 	// no Order. Declare it on the struct via a blank _ struct{} field tagged
@@ -33,9 +38,24 @@ var (
 
 // DecodeError is returned when unmarshalling fails, describing the field name and byte offset of the failure.
 type DecodeError struct {
-	Offset int
-	Field  string
+	Offset int    // offset of Field within its struct
+	Field  string // the struct field that failed
 	Err    error
+
+	// The rest describe the innermost field that failed, for logging. Path is
+	// its path from the value decoded, such as "Sections[12].Entries[3].Name"
+	// when the failure is in a struct nested in Field; AbsOffset is its offset
+	// from the start of that value, and FieldOffset its offset within its own
+	// struct. Type and Tag are its Go type and binary tag.
+	Path        string
+	AbsOffset   int
+	FieldOffset int
+	Type        string
+	Tag         string
+	// Expected and Actual describe a failed check (see CheckError): the
+	// constraint, such as "1..100" for range=1..100, and the value decoded.
+	Expected string
+	Actual   string
 }
 
 func (e *DecodeError) Error() string {
@@ -46,6 +66,116 @@ func (e *DecodeError) Unwrap() error {
 	return e.Err
 }
 
+// newDecodeError returns the DecodeError for the failure err of a struct field
+// named field, of Go type goType and binary tag tag, at offset within its
+// struct. When err holds the DecodeError of a struct nested in the field, in
+// array elements or not, the result describes that innermost failure, its Path
+// and AbsOffset extended by the field. The interpreters and generated code
+// report field failures through it.
+func newDecodeError(offset int, field, goType, tag string, err error) *DecodeError {
+	de := &DecodeError{Offset: offset, Field: field, Err: err,
+		Path: field, AbsOffset: offset, FieldOffset: offset, Type: goType, Tag: tag}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		switch x := e.(type) {
+		case *elementError:
+			de.Path += "[" + strconv.Itoa(x.index) + "]"
+			de.AbsOffset += x.offset
+		case *DecodeError:
+			if x.Path != "" { // not a failure without a field, such as Canonical's
+				de.Path += "." + x.Path
+				de.AbsOffset += x.AbsOffset
+				de.FieldOffset, de.Type, de.Tag = x.FieldOffset, x.Type, x.Tag
+				de.Expected, de.Actual = x.Expected, x.Actual
+			}
+			return de
+		case *CheckError:
+			de.Expected, de.Actual = x.Expected, x.Actual
+			return de
+		}
+	}
+	return de
+}
+
+// fieldDecodeError is newDecodeError for a field of a struct type.
+func fieldDecodeError(offset int, f reflect.StructField, err error) *DecodeError {
+	return newDecodeError(offset, f.Name, f.Type.String(), f.Tag.Get("binary"), err)
+}
+
+// CheckError is a failed decode-time check, such as a value out of its range=.
+// Err wraps ErrValidationError; Expected and Actual are copied to the
+// DecodeError that reports it.
+type CheckError struct {
+	Expected string // the constraint: a range, pattern, enum, constant or rule
+	Actual   string // the value decoded
+	Err      error
+}
+
+func (e *CheckError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CheckError) Unwrap() error {
+	return e.Err
+}
+
+// elementError is the failure of the array element index, which starts offset
+// bytes into the array.
+type elementError struct {
+	index, offset int
+	err           error
+}
+
+func (e *elementError) Error() string {
+	return fmt.Sprintf("array index [%d]: %v", e.index, e.err)
+}
+
+func (e *elementError) Unwrap() error {
+	return e.err
+}
+
+// EncodeError is returned when marshalling fails, describing the field name and
+// the output offset, within its struct, of the failure.
+type EncodeError struct {
+	Offset int    // output offset of Field within its struct
+	Field  string // the struct field that failed
+	Err    error
+
+	// Path is the path of the innermost field that failed from the value encoded,
+	// such as "Sections[12].Entries[3].Name", and AbsOffset its output offset from
+	// the start of that value.
+	Path      string
+	AbsOffset int
+}
+
+func (e *EncodeError) Error() string {
+	return fmt.Sprintf("encode error at offset %d (field %s): %v", e.Offset, e.Field, e.Err)
+}
+
+func (e *EncodeError) Unwrap() error {
+	return e.Err
+}
+
+// newEncodeError returns the EncodeError for the failure err of a struct field
+// named field, written at offset within its struct. When err holds the
+// EncodeError of a struct nested in the field, in array elements or not, Path
+// and AbsOffset are extended to that innermost failure. The interpreters and
+// generated code report field failures through it.
+func newEncodeError(offset int, field string, err error) *EncodeError {
+	ee := &EncodeError{Offset: offset, Field: field, Err: err, Path: field, AbsOffset: offset}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		switch x := e.(type) {
+		case *elementError:
+			ee.Path += "[" + strconv.Itoa(x.index) + "]"
+			ee.AbsOffset += x.offset
+		case *EncodeError:
+			ee.Path += "." + x.Path
+			ee.AbsOffset += x.AbsOffset
+			return ee
+		}
+	}
+	return ee
+}
+
 // Unmarshal decodes binary images into a Go value. The Go value must be a writable type such as a slice, a pointer or an interface.
 func Unmarshal(input []byte, govalue interface{}) (n int, err error) {
 	return NewMarshaler().Unmarshal(input, govalue)
@@ -67,31 +197,57 @@ func ReadAs(r io.Reader, tag string, data interface{}) (n int, err error) {
 }
 
 // Marshaler.Unmarshal() decodes binary data into a Go value using the Marshaler's byte order.
+// Under Strict, input left over after the value fails with ErrTrailingData;
+// under Canonical, a value not encoded as the encoder would fails with
+// ErrNonCanonical.
 func (ms *Marshaler) Unmarshal(input []byte, govalue interface{}) (n int, err error) {
 	buf := bytes.NewBuffer(input)
-	return ms.Read(buf, govalue)
+	n, err = ms.Read(buf, govalue)
+	if err == nil && ms.Canonical {
+		err = ms.checkCanonical(input[:n], govalue, "", false)
+	}
+	if err == nil && ms.Strict {
+		err = checkTrailingData(n, len(input))
+	}
+	return
 }
 
 // Marshaler.UnmarshalAs() decodes binary data using the supplied tag and the Marshaler's byte order.
+// Under Strict, input left over after the value fails with ErrTrailingData;
+// under Canonical, a value not encoded as the encoder would fails with
+// ErrNonCanonical.
 func (ms *Marshaler) UnmarshalAs(input []byte, tag string, govalue interface{}) (n int, err error) {
 	buf := bytes.NewBuffer(input)
-	return ms.ReadAs(buf, tag, govalue)
+	n, err = ms.ReadAs(buf, tag, govalue)
+	if err == nil && ms.Canonical {
+		err = ms.checkCanonical(input[:n], govalue, tag, true)
+	}
+	if err == nil && ms.Strict {
+		err = checkTrailingData(n, len(input))
+	}
+	return
 }
 
 // Marshaler.Read() decodes a binary stream into a Go value. The byte order comes
 // from the value's declaration, falling back to the Marshaler's Order field.
 func (ms *Marshaler) Read(r io.Reader, data interface{}) (n int, err error) {
+	ms.beginDecode()
+	defer ms.endDecode(&err)
+	ms.armSalvage(reflect.ValueOf(data))
 	return ms.readValue(r, ms.Order, reflect.ValueOf(data))
 }
 
 // Marshaler.ReadAs() decodes a binary stream using the supplied tag.
 func (ms *Marshaler) ReadAs(r io.Reader, tag string, data interface{}) (n int, err error) {
+	ms.beginDecode()
+	defer ms.endDecode(&err)
 	order := ms.Order
 	v := reflect.ValueOf(data)
 	k := v.Type().Kind()
 	if k == reflect.Ptr || k == reflect.Interface {
 		v, _ = dereferencePointer(v)
 	}
+	ms.armSalvage(v)
 
 	var fieldErr error
 	switch v.Kind() {
@@ -126,7 +282,9 @@ func (ms *Marshaler) readValue(r io.Reader, order ByteOrder, v reflect.Value) (n
 
 // read a value as given type
 func (ms *Marshaler) readMain(r io.Reader, order ByteOrder, v reflect.Value, encodeType eType, option typeOption, parentStruct reflect.Value, fieldIndex int) (n int, err error) {
-	order = resolveByteOrder(order, option.endian)
+	if order, err = ms.resolveOrder(order, option.endian); err != nil {
+		return 0, err
+	}
 
 	if option.codec != "" {
 		codec, ok := ms.codecs[option.codec]
@@ -166,6 +324,9 @@ func (ms *Marshaler) readMain(r io.Reader, order ByteOrder, v reflect.Value, enc
 	// type was a pointer or an interface
 	if option.indirectCount > 0 {
 		for i := 0; i < option.indirectCount; i++ {
+			if !v.IsValid() {
+				return 0, fmt.Errorf("cannot decode into a nil interface of unknown type")
+			}
 			v = v.Elem()
 		}
 	}
@@ -186,7 +347,7 @@ func (ms *Marshaler) readMain(r io.Reader, order ByteOrder, v reflect.Value, enc
 		if l == 0 {
 			l = 1
 		}
-		return skipBytes(r, l)
+		return ms.readPad(r, l, option)
 
 	case Ignore: // ignoring value: `binary:"ignore"`
 		return 0, nil
@@ -206,7 +367,7 @@ func (ms *Marshaler) readMain(r io.Reader, order ByteOrder, v reflect.Value, enc
 		return ms.readStruct(r, order, v)
 
 	case stringKind:
-		return ms.readString(r, order, v, encodeType, option.bufLen, option.encoding)
+		return ms.readString(r, order, v, encodeType, option.bufLen, option.encoding, option.fill, option.trim)
 	}
 
 	err = fmt.Errorf("unknown type %s", encodeType)
@@ -240,47 +401,43 @@ func (ms *Marshaler) readSlice(r io.Reader, order ByteOrder, slice reflect.Value
 		return
 	}
 	arrayLen := option.arrayLen
+	elemSize := int(slice.Type().Elem().Size())
 
-	if slice.IsNil() {
-		if arrayLen == 0 {
+	if arrayLen == 0 {
+		if slice.IsNil() {
 			// No data: return nil
 			return
 		}
-		// make a new slice
-		s := reflect.MakeSlice(slice.Type(), arrayLen, arrayLen)
-		slice.Set(s)
-	} else if arrayLen == 0 {
 		// use existing slice
 		arrayLen = slice.Len()
+	} else if err = ms.limitSlice(arrayLen, arrayLen-slice.Len(), elemSize); err != nil {
+		return
 	}
-	sliceLen := slice.Len()
-	readLen := arrayLen
-	if arrayLen < sliceLen {
-		readLen = sliceLen
-	}
+	readLen := min(arrayLen, slice.Len())
 
-	loadSlice := func(uslice reflect.Value, l int) {
+	// loadSlice reads the elements of uslice, the first of them being element
+	// base of the slice.
+	loadSlice := func(uslice reflect.Value, base int) {
+		start := n
+		elemStart := 0 // offset of the element being read
 		wErr := func(i int, e error) error {
 			if i == 0 && e == io.EOF {
 				// if EOF occurs at the first element, then the whole slice returns EOF
 				return e
 			}
-			return fmt.Errorf("array index [%d]: %w", i, e)
+			return &elementError{index: i, offset: elemStart, err: e}
 		}
 		var m int
+		l := uslice.Len()
 
 		if uslice.Type().Elem().Kind() == reflect.Uint8 &&
 			(elementType == Byte || elementType == Uint8) {
 			// Byte slice. Note that reflect.Int8 is not a byte slice.
-			b := uslice.Bytes()
-			if len(b) < l {
-				panic("invalid slice size")
-			}
-			m, err = io.ReadFull(r, b[:l])
-			if err != nil {
-				return
-			}
+			m, err = io.ReadFull(r, uslice.Bytes())
 			n += m
+			if err == io.EOF && base > 0 {
+				err = io.ErrUnexpectedEOF
+			}
 
 		} else if sz, dec, okBulk := scalarBulkDecodeInfo(uslice.Type().Elem(), elementType, order); okBulk && l > 0 {
 			// Bulk fast path for fixed-width scalar elements: one ReadFull into a
@@ -290,7 +447,10 @@ func (ms *Marshaler) readSlice(r io.Reader, order ByteOrder, slice reflect.Value
 			m, err = io.ReadFull(r, buf)
 			n += m
 			if err != nil {
-				err = wErr(0, err)
+				if err == io.EOF && base > 0 {
+					err = io.ErrUnexpectedEOF
+				}
+				err = wErr(base, err)
 				return
 			}
 			for i := 0; i < l; i++ {
@@ -305,8 +465,16 @@ func (ms *Marshaler) readSlice(r io.Reader, order ByteOrder, slice reflect.Value
 				case 8:
 					u64 = order.Uint64(buf[i*8:])
 				}
-				if e := dec(uslice.Index(i), u64); e != nil {
-					err = wErr(i, e)
+				var e error
+				if ms.Strict {
+					e = strictDecode(uslice.Index(i), elementType, u64)
+				}
+				if e == nil {
+					e = dec(uslice.Index(i), u64)
+				}
+				if e != nil {
+					elemStart = start + i*sz
+					err = wErr(base+i, e)
 					return
 				}
 			}
@@ -314,38 +482,48 @@ func (ms *Marshaler) readSlice(r io.Reader, order ByteOrder, slice reflect.Value
 		} else {
 
 			for i := 0; i < l; i++ {
+				collected := len(ms.collected)
+				elemStart = n
 				if elementType == Any {
 					m, err = ms.readValue(r, order, uslice.Index(i))
 				} else {
 					var o typeOption
 					o.bufLen = option.bufLen     // option may contain inheritable values
 					o.encoding = option.encoding // option may contain inheritable values
+					o.fill, o.trim = option.fill, option.trim
 					m, err = ms.readMain(r, order, uslice.Index(i), elementType, o, reflect.Value{}, -1)
 				}
 				n += m
 				if err != nil {
-					err = wErr(i, err)
+					err = wErr(base+i, err)
 					return
 				}
+				ms.wrapCollected(collected, base+i, wErr)
 			}
 		}
 
 	}
 
-	loadSlice(slice, readLen)
+	loadSlice(slice.Slice(0, readLen), 0)
 	if err != nil {
 		return
 	}
 
-	if readLen < arrayLen {
-		// original slice was too small
-		newLen := arrayLen - readLen
-		newSlice := reflect.MakeSlice(slice.Type(), newLen, newLen)
-		loadSlice(newSlice, newLen)
+	// The elements past the slice's length are allocated as they are read: a
+	// chunk at a time when r does not tell how much input it holds, so that a
+	// corrupt length runs into the end of the input first.
+	for readLen < arrayLen {
+		l := allocAhead(r, arrayLen-readLen, elementType.ByteSize(), elemSize)
+		if slice.Len() == 0 {
+			slice.Set(reflect.MakeSlice(slice.Type(), l, l))
+		} else {
+			slice.Set(reflect.AppendSlice(slice, reflect.MakeSlice(slice.Type(), l, l)))
+		}
+		loadSlice(slice.Slice(readLen, readLen+l), readLen)
 		if err != nil {
 			return
 		}
-		slice.Set(reflect.AppendSlice(slice, newSlice))
+		readLen += l
 	}
 
 	return
@@ -375,6 +553,9 @@ func (ms *Marshaler) readArray(r io.Reader, order ByteOrder, array reflect.Value
 				outerLen = array.Len()
 			}
 			if array.IsNil() || array.Len() < outerLen {
+				if err = ms.limitSlice(outerLen, outerLen, int(array.Type().Elem().Size())); err != nil {
+					return 0, err
+				}
 				array.Set(reflect.MakeSlice(array.Type(), outerLen, outerLen))
 			}
 		case reflect.Array:
@@ -392,7 +573,7 @@ func (ms *Marshaler) readArray(r io.Reader, order ByteOrder, array reflect.Value
 				if i == 0 && err == io.EOF {
 					return n, err
 				}
-				return n, fmt.Errorf("array index [%d]: %w", i, err)
+				return n, &elementError{index: i, offset: n - m, err: err}
 			}
 		}
 		return n, nil
@@ -429,7 +610,7 @@ func (ms *Marshaler) readArray(r io.Reader, order ByteOrder, array reflect.Value
 			sz = 1
 		}
 		sz *= arrayLen
-		return skipBytes(r, sz)
+		return ms.readPad(r, sz, option)
 	}
 
 	var m int
@@ -439,8 +620,16 @@ func (ms *Marshaler) readArray(r io.Reader, order ByteOrder, array reflect.Value
 		// special case 2:
 		// if the value is a string and the encoded type is array of numbers, then
 		//	s string	`binary:[5]int8`	// 5-byte wide string
-		buf := make([]byte, arrayLen)
-		n, err = ms.readSlice(r, order, reflect.ValueOf(buf), elementType, option)
+		if err = ms.limitString(arrayLen); err != nil {
+			return
+		}
+		var buf []byte
+		if elementType == Byte || elementType == Uint8 {
+			buf, n, err = readBytes(r, arrayLen)
+		} else {
+			buf = make([]byte, arrayLen)
+			n, err = ms.readSlice(r, order, reflect.ValueOf(buf), elementType, option)
+		}
 		if err != nil {
 			return
 		}
@@ -464,16 +653,19 @@ func (ms *Marshaler) readArray(r io.Reader, order ByteOrder, array reflect.Value
 		readLen = array.Len()
 	}
 
+	elemStart := 0 // offset of the element being read
 	wErr := func(i int, e error) error {
 		if i == 0 && e == io.EOF {
 			// if EOF occurs at the first element, then the whole slice returns EOF
 			return e
 		}
-		return fmt.Errorf("array index [%d]: %w", i, e)
+		return &elementError{index: i, offset: elemStart, err: e}
 	}
 
 	var v reflect.Value
 	for i := 0; i < readLen; i++ {
+		collected := len(ms.collected)
+		elemStart = n
 		if !destIsArray {
 			v = array
 		} else {
@@ -487,6 +679,7 @@ func (ms *Marshaler) readArray(r io.Reader, order ByteOrder, array reflect.Value
 			var o typeOption
 			o.bufLen = option.bufLen     // option may contain inheritable values
 			o.encoding = option.encoding // option may contain inheritable values
+			o.fill, o.trim = option.fill, option.trim
 			m, err = ms.readMain(r, order, v, elementType, o, reflect.Value{}, -1)
 		}
 		n += m
@@ -494,6 +687,7 @@ func (ms *Marshaler) readArray(r io.Reader, order ByteOrder, array reflect.Value
 			err = wErr(i, err)
 			return
 		}
+		ms.wrapCollected(collected, i, wErr)
 	}
 	if readLen < arrayLen {
 		// skip leftover members
@@ -544,9 +738,22 @@ func (ms *Marshaler) readStruct(r io.Reader, order ByteOrder, strc reflect.Value
 		}
 	}
 
+	return ms.readStructFields(r, order, strc)
+}
+
+// readStructFields reads the fields of strc by their tags.
+func (ms *Marshaler) readStructFields(r io.Reader, order ByteOrder, strc reflect.Value) (n int, err error) {
 	if !safeMode {
 		return ms.unsafeReadStruct(r, order, strc)
 	}
+	sv := ms.startSalvage(strc)
+	defer func() { sv.finish(err) }()
+	if err = ms.enterNested(); err != nil {
+		return 0, err
+	}
+	defer ms.leaveNested()
+	ms.enterStruct(strc, r)
+	defer ms.leaveStruct()
 	typ := strc.Type()
 	meta, err := getStructMetadata(typ)
 	if err != nil {
@@ -554,7 +761,12 @@ func (ms *Marshaler) readStruct(r io.Reader, order ByteOrder, strc reflect.Value
 	}
 	// A struct-level byte order overrides the inherited order for this struct's
 	// fields; per-field endian= still overrides it in turn.
-	order = resolveByteOrder(order, meta.endian)
+	if order, err = ms.resolveOrder(order, meta.endian); err != nil {
+		return 0, err
+	}
+	if meta.hasDefaults && strc.CanSet() {
+		meta.applyDefaults(strc)
+	}
 
 	firstElem := true
 	wErr := func(i int, e error) error { // return a wrapped error
@@ -562,15 +774,12 @@ func (ms *Marshaler) readStruct(r io.Reader, order ByteOrder, strc reflect.Value
 			// If EOF occurs at the first non-ignoring field, then return a raw EOF
 			return e
 		}
-		f := typ.Field(i)
-		return &DecodeError{
-			Offset: n,
-			Field:  f.Name,
-			Err:    e,
-		}
+		return fieldDecodeError(n, typ.Field(i), e)
 	}
 
+	marked := false // whether meta.orderMark has been applied
 	for _, fMeta := range meta.fields {
+		collected := len(ms.collected) // failures of this field gathered under ValidateCollect
 		if fMeta.ignore {
 			continue
 		}
@@ -581,12 +790,27 @@ func (ms *Marshaler) readStruct(r io.Reader, order ByteOrder, strc reflect.Value
 			err = wErr(fMeta.index, fMeta.fieldErr)
 			return
 		}
+		ms.setStructOffset(n)
+		sv.begin(fMeta.index)
+		if order, err = meta.orderMark.switchOrder(ms, order, strc, fMeta.index, &marked); err != nil {
+			return n, wErr(meta.orderMark.index, err)
+		}
+		if meta.orderDetect.isMagic(fMeta.index) {
+			var m int
+			if m, order, err = meta.orderDetect.read(r, strc); err != nil {
+				return n, wErr(fMeta.index, err)
+			}
+			n += m
+			firstElem = false
+			sv.end()
+			continue
+		}
 
 		fieldVal := strc.Field(fMeta.index)
 		fKind := typ.Field(fMeta.index).Type.Kind()
 
 		if fMeta.omittable && fMeta.omittableExpr != "" {
-			limit, errEval := evaluateTagValue(strc, fMeta.omittableExpr)
+			limit, errEval := ms.evalTagValue(strc, fMeta.omittableExpr)
 			if errEval == nil && n >= limit {
 				break
 			}
@@ -627,7 +851,7 @@ func (ms *Marshaler) readStruct(r io.Reader, order ByteOrder, strc reflect.Value
 					// Pre-resolved constant length; skip re-evaluating the expression.
 					option.arrayLen = fMeta.option.arrayLen
 				} else if fMeta.arrayLenExpr != "" {
-					option.arrayLen, err = evaluateTagValue(strc, fMeta.arrayLenExpr)
+					option.arrayLen, err = ms.evalTagValue(strc, fMeta.arrayLenExpr)
 					if err != nil {
 						err = wErr(fMeta.index, err)
 						return
@@ -646,7 +870,7 @@ func (ms *Marshaler) readStruct(r io.Reader, order ByteOrder, strc reflect.Value
 							}
 							continue
 						}
-						option.dims[i], err = evaluateTagValue(strc, d)
+						option.dims[i], err = ms.evalTagValue(strc, d)
 						if err != nil {
 							err = wErr(fMeta.index, err)
 							return
@@ -662,7 +886,7 @@ func (ms *Marshaler) readStruct(r io.Reader, order ByteOrder, strc reflect.Value
 			if fMeta.bufLenConst {
 				option.bufLen = fMeta.option.bufLen
 			} else if fMeta.bufLenExpr != "" {
-				option.bufLen, err = evaluateTagValue(strc, fMeta.bufLenExpr)
+				option.bufLen, err = ms.evalTagValue(strc, fMeta.bufLenExpr)
 				if err != nil {
 					err = wErr(fMeta.index, err)
 					return
@@ -681,6 +905,7 @@ func (ms *Marshaler) readStruct(r io.Reader, order ByteOrder, strc reflect.Value
 			if fMeta.codec != "" {
 				option.codec = fMeta.codec
 			}
+			option.fill, option.reserved, option.trim = fMeta.option.fill, fMeta.option.reserved, fMeta.option.trim
 		}
 
 		if fKind == reflect.Ptr || fKind == reflect.Interface {
@@ -691,6 +916,10 @@ func (ms *Marshaler) readStruct(r io.Reader, order ByteOrder, strc reflect.Value
 
 		var m int
 		m, err = ms.readMain(r, order, v, naturalType, option, strc, fMeta.index)
+		var reservedErr error
+		if reservedFailed(&fMeta, err) {
+			reservedErr, err = err, nil
+		}
 		if err != nil {
 			if fMeta.omittable && (err == io.EOF || err == io.ErrUnexpectedEOF || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)) && m == 0 {
 				if wasNilPtr {
@@ -702,16 +931,25 @@ func (ms *Marshaler) readStruct(r io.Reader, order ByteOrder, strc reflect.Value
 			err = wErr(fMeta.index, err)
 			return
 		}
-		if err = validateField(v, &fMeta); err != nil {
-			err = wErr(fMeta.index, err)
+		ms.wrapCollected(collected, fMeta.index, wErr)
+		if reservedErr != nil {
+			if err = ms.validationFailed(wErr(fMeta.index, reservedErr)); err != nil {
+				return
+			}
+		}
+		if err = ms.validateField(strc, v, &fMeta, wErr); err != nil {
 			return
 		}
 		n += m
 		firstElem = false
+		sv.end()
 	}
 	if err = ms.validateCustomValueofs(order, strc, meta, n, typ); err != nil {
 		return
 	}
+	if err = ms.afterDecode(strc); err != nil {
+		return
+	}
 	return
 }
 
@@ -792,8 +1030,25 @@ func readZ16String(r io.Reader) (str []byte, readsz int, err error) {
 	return
 }
 
+// readZBuffer reads the size-byte buffer of a zstring(N) or z16string(N),
+// returning the bytes before the terminator, unit zero bytes at a multiple of
+// unit. The bytes after the terminator, zeros or fill= bytes, are consumed with
+// the buffer; a buffer with no terminator holds a string that fills it.
+func readZBuffer(r io.Reader, size, unit int) (str []byte, readsz int, err error) {
+	str, readsz, err = readBytes(r, size)
+	if err != nil {
+		return
+	}
+	for i := 0; i+unit <= len(str); i += unit {
+		if bytes.Equal(str[i:i+unit], terminatingZeros[:unit]) {
+			return str[:i], readsz, nil
+		}
+	}
+	return
+}
+
 // read string types
-func (ms *Marshaler) readString(r io.Reader, order ByteOrder, v reflect.Value, encodeType eType, bufLen int, textEncoding string) (n int, err error) {
+func (ms *Marshaler) readString(r io.Reader, order ByteOrder, v reflect.Value, encodeType eType, bufLen int, textEncoding string, fill byte, trim trimMode) (n int, err error) {
 	if textEncoding == "" {
 		textEncoding = ms.DefaultTextEncoding
 	}
@@ -801,7 +1056,17 @@ func (ms *Marshaler) readString(r io.Reader, order ByteOrder, v reflect.Value, e
 	switch encodeType {
 	case Zstring: // zero-terminated byte string
 		var buf []byte
-		buf, n, err = readZString(r)
+		if bufLen > 0 {
+			if err = ms.limitString(bufLen); err != nil {
+				return
+			}
+			buf, n, err = readZBuffer(r, bufLen, 1)
+		} else {
+			buf, n, err = readZString(r)
+			if err == nil {
+				err = ms.limitString(len(buf))
+			}
+		}
 		if err != nil {
 			return
 		}
@@ -823,7 +1088,17 @@ func (ms *Marshaler) readString(r io.Reader, order ByteOrder, v reflect.Value, e
 
 	case Z16string: // zero-terminated UTF16 string
 		var buf []byte
-		buf, n, err = readZ16String(r)
+		if bufLen > 0 {
+			if err = ms.limitString(bufLen); err != nil {
+				return
+			}
+			buf, n, err = readZBuffer(r, bufLen, 2)
+		} else {
+			buf, n, err = readZ16String(r)
+			if err == nil {
+				err = ms.limitString(len(buf))
+			}
+		}
 		if err != nil {
 			return
 		}
@@ -874,16 +1149,36 @@ func (ms *Marshaler) readString(r io.Reader, order ByteOrder, v reflect.Value, e
 		readsz = bufLen
 	}
 
-	buf := make([]byte, readsz)
+	if err = ms.limitString(readsz); err != nil {
+		return
+	}
+	var buf []byte
 	m := 0
 	if readsz > 0 {
-		m, err = io.ReadFull(r, buf)
+		buf, m, err = readBytes(r, readsz)
 		n += m
 		if err != nil {
 			return
 		}
 	}
 
+	// drop the padding of the encoded text before decoding it: a fill other
+	// than zeros, and leading padding under trim=both
+	if fill != 0 || trim != trimRight {
+		if headersz == 0 && trim != trimNone {
+			for ; strlen > 0 && buf[strlen-1] == fill; strlen-- {
+				// empty
+			}
+		}
+		buf = buf[:strlen]
+		if trim == trimBoth {
+			for len(buf) > 0 && buf[0] == fill {
+				buf = buf[1:]
+			}
+			strlen = len(buf)
+		}
+	}
+
 	// process text encoding (before removing terminating zeros)
 	if textEncoding != "" && m > 0 {
 		buf, err = ms.DecodeText(buf, textEncoding)
@@ -894,7 +1189,7 @@ func (ms *Marshaler) readString(r io.Reader, order ByteOrder, v reflect.Value, e
 	}
 
 	// remove terminaing zeros if buffer is larger than actual string
-	for ; strlen > 0 && buf[strlen-1] == 0; strlen-- {
+	for ; trim != trimNone && strlen > 0 && buf[strlen-1] == 0; strlen-- {
 		// empty
 	}
 
@@ -942,33 +1237,87 @@ func (ms *Marshaler) readScalar(r io.Reader, order ByteOrder, v reflect.Value, k
 	if err != nil {
 		return
 	}
+	if ms.Strict {
+		if err = strictDecode(v, k, u64); err != nil {
+			return
+		}
+	}
 	err = dec(v, u64)
 	return
 }
 
-func validateField(v reflect.Value, fMeta *structFieldMetadata) error {
+// validateField checks a decoded field v of strc against its const=, range=,
+// match=, enum= and check= options. Each failure, wrapped by wErr with the
+// field's index, goes through validationFailed, and the first one that stops
+// the decode is returned; under ValidateWarn and ValidateCollect every check
+// runs, so each failure is recorded.
+func (ms *Marshaler) validateField(strc, v reflect.Value, fMeta *structFieldMetadata, wErr func(int, error) error) error {
+	if ms.Validation == ValidateNone {
+		return nil
+	}
+	fail := func(err error) error {
+		return ms.validationFailed(wErr(fMeta.index, err))
+	}
 	if fMeta.hasConst {
 		if err := validateConst(v, fMeta); err != nil {
-			return err
+			if err = fail(err); err != nil {
+				return err
+			}
+		}
+	}
+	if err := ms.validateValues(v, fMeta, fail); err != nil {
+		return err
+	}
+	if fMeta.checkExpr != "" {
+		err := validateCheck(fMeta.checkExpr, func(expr string) (int, error) {
+			return ms.evalTagValue(strc, expr)
+		})
+		if err != nil {
+			return fail(err)
 		}
 	}
-	if !fMeta.hasRange && !fMeta.hasMatch {
+	return nil
+}
+
+// validateValues checks v, or each element of an array or slice v, against the
+// field's range=, match= and enum= options, passing each failure to fail and
+// returning the first error fail returns.
+func (ms *Marshaler) validateValues(v reflect.Value, fMeta *structFieldMetadata, fail func(error) error) error {
+	if !fMeta.hasRange && !fMeta.hasMatch && fMeta.enum == nil {
 		return nil
 	}
-	k := v.Kind()
-	if k == reflect.Slice || k == reflect.Array {
+	if k := v.Kind(); k == reflect.Slice || k == reflect.Array {
 		l := v.Len()
 		for i := 0; i < l; i++ {
-			if err := validateValue(v.Index(i), fMeta); err != nil {
+			if err := ms.validateValue(v.Index(i), fMeta, fail); err != nil {
 				return err
 			}
 		}
 		return nil
 	}
-	return validateValue(v, fMeta)
+	return ms.validateValue(v, fMeta, fail)
+}
+
+// validateCheck evaluates a check= rule with eval.
+func validateCheck(expr string, eval func(string) (int, error)) error {
+	ok, err := eval(expr)
+	if err != nil {
+		return fmt.Errorf("check %q: %w", expr, err)
+	}
+	if ok == 0 {
+		return &CheckError{Expected: expr, Err: fmt.Errorf("check %q failed: %w", expr, ErrValidationError)}
+	}
+	return nil
 }
 
-func validateValue(v reflect.Value, fMeta *structFieldMetadata) error {
+func (ms *Marshaler) validateValue(v reflect.Value, fMeta *structFieldMetadata, fail func(error) error) error {
+	if fMeta.enum != nil {
+		if err := ms.validateEnum(v, fMeta.enum); err != nil {
+			if err = fail(err); err != nil {
+				return err
+			}
+		}
+	}
 	if fMeta.hasRange {
 		var val float64
 		switch v.Kind() {
@@ -979,18 +1328,23 @@ func validateValue(v reflect.Value, fMeta *structFieldMetadata) error {
 		case reflect.Float32, reflect.Float64:
 			val = v.Float()
 		default:
-			return fmt.Errorf("range validation not supported on type %s", v.Type().String())
+			return fail(fmt.Errorf("range validation not supported on type %s", v.Type().String()))
 		}
 		if (fMeta.hasRangeMin && val < fMeta.rangeMin) || (fMeta.hasRangeMax && val > fMeta.rangeMax) {
-			return fmt.Errorf("value %v is out of range [%g, %g]: %w", val, fMeta.rangeMin, fMeta.rangeMax, ErrValidationError)
+			err := fail(&CheckError{Expected: fMeta.rangeExpr, Actual: fmt.Sprint(v.Interface()),
+				Err: fmt.Errorf("value %s is out of range [%g, %g]: %w", ms.enumValueString(v, fMeta), fMeta.rangeMin, fMeta.rangeMax, ErrValidationError)})
+			if err != nil {
+				return err
+			}
 		}
 	}
 	if fMeta.hasMatch {
 		if v.Kind() != reflect.String {
-			return fmt.Errorf("match validation not supported on type %s", v.Type().String())
+			return fail(fmt.Errorf("match validation not supported on type %s", v.Type().String()))
 		}
 		if !fMeta.matchRegexp.MatchString(v.String()) {
-			return fmt.Errorf("value %q does not match pattern %s: %w", v.String(), fMeta.matchPattern, ErrValidationError)
+			return fail(&CheckError{Expected: fMeta.matchPattern, Actual: v.String(),
+				Err: fmt.Errorf("value %q does not match pattern %s: %w", v.String(), fMeta.matchPattern, ErrValidationError)})
 		}
 	}
 	return nil
@@ -1006,22 +1360,34 @@ func validateConst(v reflect.Value, fMeta *structFieldMetadata) error {
 			return fmt.Errorf("const validation not supported on type %s", v.Type().String())
 		}
 		if !bytes.Equal(got, fMeta.constBytes) {
-			return fmt.Errorf("const mismatch: got %#x, want %#x: %w", got, fMeta.constBytes, ErrValidationError)
+			return &CheckError{Expected: fmt.Sprintf("%#x", fMeta.constBytes), Actual: fmt.Sprintf("%#x", got),
+				Err: fmt.Errorf("const mismatch: got %#x, want %#x: %w", got, fMeta.constBytes, ErrValidationError)}
 		}
 		return nil
 	}
+	var err error
 	switch v.Kind() {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		if v.Int() != fMeta.constInt {
-			return fmt.Errorf("const mismatch: got %d, want %d: %w", v.Int(), fMeta.constInt, ErrValidationError)
+			err = fmt.Errorf("const mismatch: got %d, want %d: %w", v.Int(), fMeta.constInt, ErrValidationError)
 		}
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
 		if v.Uint() != uint64(fMeta.constInt) {
-			return fmt.Errorf("const mismatch: got %d, want %d: %w", v.Uint(), uint64(fMeta.constInt), ErrValidationError)
+			err = fmt.Errorf("const mismatch: got %d, want %d: %w", v.Uint(), uint64(fMeta.constInt), ErrValidationError)
 		}
 	default:
 		return fmt.Errorf("const validation not supported on type %s", v.Type().String())
 	}
+	if err != nil {
+		// Both in the field's type, as %#x formats it.
+		want := reflect.New(v.Type()).Elem()
+		if want.CanInt() {
+			want.SetInt(fMeta.constInt)
+		} else {
+			want.SetUint(uint64(fMeta.constInt))
+		}
+		return &CheckError{Expected: fmt.Sprintf("%#x", want.Interface()), Actual: fmt.Sprintf("%#x", v.Interface()), Err: err}
+	}
 	return nil
 }
 
@@ -1034,18 +1400,22 @@ func validateConst(v reflect.Value, fMeta *structFieldMetadata) error {
 // exact regardless of the target field's width, sign, or byte order. The decoded
 // field is never overwritten (valueof stays emit-only, like bytelen/const).
 func (ms *Marshaler) validateCustomValueofs(order ByteOrder, strc reflect.Value, meta *structMetadata, structEnd int, typ reflect.Type) error {
+	if !ms.verifiesChecksums() {
+		return nil
+	}
 	for _, fMeta := range meta.fields {
 		if fMeta.valueofCustomName == "" || fMeta.unexported || fMeta.ignore {
 			continue
 		}
 		mkErr := func(e error) error {
-			return &DecodeError{Offset: structEnd, Field: typ.Field(fMeta.index).Name, Err: e}
+			return fieldDecodeError(structEnd, typ.Field(fMeta.index), e)
 		}
 		computed, err := ms.evalCustomValueof(order, strc, meta, fMeta, true)
 		if err != nil {
 			return mkErr(err)
 		}
-		wantBytes, err := ms.fieldEncodedBytes(order, strc, synthIntValue(strc.Field(fMeta.index), int(computed)), fMeta)
+		want := synthIntValue(strc.Field(fMeta.index), int(computed))
+		wantBytes, err := ms.fieldEncodedBytes(order, strc, want, fMeta)
 		if err != nil {
 			return mkErr(err)
 		}
@@ -1054,7 +1424,11 @@ func (ms *Marshaler) validateCustomValueofs(order ByteOrder, strc reflect.Value,
 			return mkErr(err)
 		}
 		if !bytes.Equal(gotBytes, wantBytes) {
-			return mkErr(fmt.Errorf("valueof %s() mismatch: got %#x, want %#x: %w", fMeta.valueofCustomName, gotBytes, wantBytes, ErrValidationError))
+			e := &CheckError{Expected: fmt.Sprintf("%#x", want.Interface()), Actual: fmt.Sprintf("%#x", strc.Field(fMeta.index).Interface()),
+				Err: fmt.Errorf("valueof %s() mismatch: got %#x, want %#x: %w", fMeta.valueofCustomName, gotBytes, wantBytes, ErrValidationError)}
+			if err := ms.validationFailed(mkErr(e)); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -1114,6 +1488,43 @@ func dereferencePointer(p reflect.Value) (value reflect.Value, indirectCount int
 	return
 }
 
+// readPad reads a padding of sz bytes. Under option.reserved, unless
+// validation is off, each must be option.fill: the first that is not is
+// reported, once all sz bytes are read, as a *CheckError.
+func (ms *Marshaler) readPad(r io.Reader, sz int, option typeOption) (n int, err error) {
+	if !option.reserved || ms.Validation == ValidateNone {
+		return skipBytes(r, sz)
+	}
+	buf := make([]byte, min(sz, 16384))
+	bad, got := -1, byte(0)
+	for n < sz {
+		var m int
+		m, err = io.ReadFull(r, buf[:min(sz-n, len(buf))])
+		for i, b := range buf[:m] {
+			if b != option.fill && bad < 0 {
+				bad, got = n+i, b
+			}
+		}
+		n += m
+		if err != nil {
+			return
+		}
+	}
+	if bad >= 0 {
+		err = &CheckError{Expected: fmt.Sprintf("%#02x", option.fill), Actual: fmt.Sprintf("%#02x", got),
+			Err: fmt.Errorf("reserved byte %d is %#02x, want %#02x: %w", bad, got, option.fill, ErrValidationError)}
+	}
+	return
+}
+
+// reservedFailed reports whether err is the failed check of fMeta, a reserved
+// padding. Read whole, unlike a field failing to read, it is subject to the
+// Validation mode.
+func reservedFailed(fMeta *structFieldMetadata, err error) bool {
+	var ce *CheckError
+	return fMeta.option.reserved && errors.As(err, &ce)
+}
+
 // skip padding bytes
 func skipBytes(r io.Reader, sz int) (n int, err error) {
 	maxBufSize := 16384
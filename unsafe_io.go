@@ -54,6 +54,11 @@ func getITypeFromRKind(k reflect.Kind) eType {
 }
 
 func (ms *Marshaler) unsafeWriteStruct(w io.Writer, order ByteOrder, strc reflect.Value) (n int, err error) {
+	if strc, err = ms.beforeEncode(strc); err != nil {
+		return 0, err
+	}
+	ms.enterStruct(strc, nil)
+	defer ms.leaveStruct()
 	typ := strc.Type()
 	meta, err := getStructMetadata(typ)
 	if err != nil {
@@ -61,7 +66,9 @@ func (ms *Marshaler) unsafeWriteStruct(w io.Writer, order ByteOrder, strc reflec
 	}
 	// A struct-level byte order overrides the inherited order for this struct's
 	// fields; per-field endian= still overrides it in turn.
-	order = resolveByteOrder(order, meta.endian)
+	if order, err = ms.resolveOrder(order, meta.endian); err != nil {
+		return 0, err
+	}
 
 	var base unsafe.Pointer
 	if strc.CanAddr() {
@@ -72,14 +79,18 @@ func (ms *Marshaler) unsafeWriteStruct(w io.Writer, order ByteOrder, strc reflec
 		base = unsafe.Pointer(copyVal.Addr().Pointer())
 	}
 	wErr := func(i int, e error) error {
-		f := typ.Field(i)
-		return fmt.Errorf("field <%s>: %w", f.Name, e)
+		return newEncodeError(n, typ.Field(i).Name, e)
 	}
 	// Write-path size-expression evaluator: resolves referenced valueof fields
 	// to their computed values rather than their ignored Go field values.
 	writeEval := ms.encodeExprEval(order, strc, meta)
+	dropFrom := meta.omitDefaultsFrom(strc) // trailing omitdefault fields left at their defaults
 
-	for _, fMeta := range meta.fields {
+	marked := false // whether meta.orderMark has been applied
+	for fi, fMeta := range meta.fields {
+		if fi >= dropFrom {
+			break
+		}
 		if fMeta.ignore || fMeta.unexported {
 			continue
 		}
@@ -88,10 +99,16 @@ func (ms *Marshaler) unsafeWriteStruct(w io.Writer, order ByteOrder, strc reflec
 			err = wErr(fMeta.index, fMeta.fieldErr)
 			return
 		}
+		fMeta = fMeta.encodeMeta()
+		ms.setStructOffset(n)
+		if order, err = meta.orderMark.switchOrder(ms, order, strc, fMeta.index, &marked); err != nil {
+			return n, wErr(meta.orderMark.index, err)
+		}
+		order = meta.orderDetect.writeOrder(order, strc, fMeta.index)
 
 		// check omittable expr
 		if fMeta.omittable && fMeta.omittableExpr != "" {
-			limit, errEval := evaluateTagValue(strc, fMeta.omittableExpr)
+			limit, errEval := ms.evalTagValue(strc, fMeta.omittableExpr)
 			if errEval == nil && n >= limit {
 				break
 			}
@@ -182,6 +199,11 @@ func (ms *Marshaler) unsafeWriteStruct(w io.Writer, order ByteOrder, strc reflec
 		if fMeta.omittable && isNil {
 			break
 		}
+		if ms.ValidateOnEncode {
+			if err = ms.validateEncode(strc.Field(fMeta.index), &fMeta, writeEval); err != nil {
+				return n, wErr(fMeta.index, err)
+			}
+		}
 
 		// If it's interface, nil, or has custom codec, fall back to reflection
 		if typ.Field(fMeta.index).Type.Kind() == reflect.Interface || fMeta.codec != "" || isNil {
@@ -216,6 +238,7 @@ func (ms *Marshaler) unsafeWriteStruct(w io.Writer, order ByteOrder, strc reflec
 				if fMeta.codec != "" {
 					option.codec = fMeta.codec
 				}
+				option.fill, option.reserved, option.trim = fMeta.option.fill, fMeta.option.reserved, fMeta.option.trim
 			}
 			m, err = ms.writeMain(w, order, fieldVal, naturalType, option, strc, fMeta.index)
 			if err != nil {
@@ -225,7 +248,10 @@ func (ms *Marshaler) unsafeWriteStruct(w io.Writer, order ByteOrder, strc reflec
 			continue
 		}
 
-		fieldOrder := resolveByteOrder(order, fMeta.endian)
+		var fieldOrder ByteOrder
+		if fieldOrder, err = ms.resolveOrder(order, fMeta.endian); err != nil {
+			return n, wErr(fMeta.index, err)
+		}
 
 		// Check if it's a nested struct
 		fieldValType := typ.Field(fMeta.index).Type
@@ -343,7 +369,7 @@ func (ms *Marshaler) unsafeWriteStruct(w io.Writer, order ByteOrder, strc reflec
 				}
 			}
 			var m int
-			m, err = zeroFill(w, l)
+			m, err = padFill(w, l, fMeta.option.fill)
 			if err != nil {
 				return n, wErr(fMeta.index, err)
 			}
@@ -351,9 +377,15 @@ func (ms *Marshaler) unsafeWriteStruct(w io.Writer, order ByteOrder, strc reflec
 			continue
 		}
 
-		// Handle basic scalar fields using unsafe
+		// Handle basic scalar fields using unsafe. A field whose Go type differs
+		// from its wire type is converted, range-checked, by the reflection writer.
 		var m int
-		m, err = ms.unsafeWriteScalar(w, fieldOrder, currPtr, fMeta.encodeType, fieldValType.Kind())
+		if !ms.unsafeScalarOK(fieldValType, fMeta.encodeType) {
+			fieldVal := reflect.NewAt(fieldValType, currPtr).Elem()
+			m, err = ms.writeMain(w, fieldOrder, fieldVal, fMeta.encodeType, fMeta.option, strc, fMeta.index)
+		} else {
+			m, err = ms.unsafeWriteScalar(w, fieldOrder, currPtr, fMeta.encodeType, fieldValType.Kind())
+		}
 		if err != nil {
 			return n, wErr(fMeta.index, err)
 		}
@@ -403,6 +435,14 @@ func (ms *Marshaler) unsafeWriteScalar(w io.Writer, order ByteOrder, ptr unsafe.
 }
 
 func (ms *Marshaler) unsafeReadStruct(r io.Reader, order ByteOrder, strc reflect.Value) (n int, err error) {
+	sv := ms.startSalvage(strc)
+	defer func() { sv.finish(err) }()
+	if err = ms.enterNested(); err != nil {
+		return 0, err
+	}
+	defer ms.leaveNested()
+	ms.enterStruct(strc, r)
+	defer ms.leaveStruct()
 	typ := strc.Type()
 	meta, err := getStructMetadata(typ)
 	if err != nil {
@@ -410,7 +450,12 @@ func (ms *Marshaler) unsafeReadStruct(r io.Reader, order ByteOrder, strc reflect
 	}
 	// A struct-level byte order overrides the inherited order for this struct's
 	// fields; per-field endian= still overrides it in turn.
-	order = resolveByteOrder(order, meta.endian)
+	if order, err = ms.resolveOrder(order, meta.endian); err != nil {
+		return 0, err
+	}
+	if meta.hasDefaults && strc.CanSet() {
+		meta.applyDefaults(strc)
+	}
 
 	var base unsafe.Pointer
 	if strc.CanAddr() {
@@ -425,15 +470,12 @@ func (ms *Marshaler) unsafeReadStruct(r io.Reader, order ByteOrder, strc reflect
 		if firstElem && (errors.Is(e, io.EOF) || errors.Is(e, io.ErrUnexpectedEOF)) {
 			return e
 		}
-		f := typ.Field(i)
-		return &DecodeError{
-			Offset: n,
-			Field:  f.Name,
-			Err:    e,
-		}
+		return fieldDecodeError(n, typ.Field(i), e)
 	}
 
+	marked := false // whether meta.orderMark has been applied
 	for _, fMeta := range meta.fields {
+		collected := len(ms.collected) // failures of this field gathered under ValidateCollect
 		if fMeta.ignore || fMeta.unexported {
 			continue
 		}
@@ -442,10 +484,25 @@ func (ms *Marshaler) unsafeReadStruct(r io.Reader, order ByteOrder, strc reflect
 			err = wErr(fMeta.index, fMeta.fieldErr)
 			return
 		}
+		ms.setStructOffset(n)
+		sv.begin(fMeta.index)
+		if order, err = meta.orderMark.switchOrder(ms, order, strc, fMeta.index, &marked); err != nil {
+			return n, wErr(meta.orderMark.index, err)
+		}
+		if meta.orderDetect.isMagic(fMeta.index) {
+			var m int
+			if m, order, err = meta.orderDetect.read(r, strc); err != nil {
+				return n, wErr(fMeta.index, err)
+			}
+			n += m
+			firstElem = false
+			sv.end()
+			continue
+		}
 
 		// check omittable expr
 		if fMeta.omittable && fMeta.omittableExpr != "" {
-			limit, errEval := evaluateTagValue(strc, fMeta.omittableExpr)
+			limit, errEval := ms.evalTagValue(strc, fMeta.omittableExpr)
 			if errEval == nil && n >= limit {
 				break
 			}
@@ -486,14 +543,14 @@ func (ms *Marshaler) unsafeReadStruct(r io.Reader, order ByteOrder, strc reflect
 				if fMeta.isArray {
 					option.isArray = true
 					if fMeta.arrayLenExpr != "" {
-						option.arrayLen, err = evaluateTagValue(strc, fMeta.arrayLenExpr)
+						option.arrayLen, err = ms.evalTagValue(strc, fMeta.arrayLenExpr)
 						if err != nil {
 							return n, wErr(fMeta.index, err)
 						}
 					}
 				}
 				if fMeta.bufLenExpr != "" {
-					option.bufLen, err = evaluateTagValue(strc, fMeta.bufLenExpr)
+					option.bufLen, err = ms.evalTagValue(strc, fMeta.bufLenExpr)
 					if err != nil {
 						return n, wErr(fMeta.index, err)
 					}
@@ -507,8 +564,13 @@ func (ms *Marshaler) unsafeReadStruct(r io.Reader, order ByteOrder, strc reflect
 				if fMeta.codec != "" {
 					option.codec = fMeta.codec
 				}
+				option.fill, option.reserved, option.trim = fMeta.option.fill, fMeta.option.reserved, fMeta.option.trim
 			}
 			m, err = ms.readMain(r, order, fieldVal, naturalType, option, strc, fMeta.index)
+			var reservedErr error
+			if reservedFailed(&fMeta, err) {
+				reservedErr, err = err, nil
+			}
 			if err != nil {
 				if fMeta.omittable && (err == io.EOF || err == io.ErrUnexpectedEOF) && m == 0 {
 					if wasNilPtr {
@@ -519,15 +581,25 @@ func (ms *Marshaler) unsafeReadStruct(r io.Reader, order ByteOrder, strc reflect
 				}
 				return n, wErr(fMeta.index, err)
 			}
-			if err = validateField(fieldVal, &fMeta); err != nil {
-				return n, wErr(fMeta.index, err)
+			ms.wrapCollected(collected, fMeta.index, wErr)
+			if reservedErr != nil {
+				if err = ms.validationFailed(wErr(fMeta.index, reservedErr)); err != nil {
+					return n, err
+				}
+			}
+			if err = ms.validateField(strc, fieldVal, &fMeta, wErr); err != nil {
+				return n, err
 			}
 			n += m
 			firstElem = false
+			sv.end()
 			continue
 		}
 
-		fieldOrder := resolveByteOrder(order, fMeta.endian)
+		var fieldOrder ByteOrder
+		if fieldOrder, err = ms.resolveOrder(order, fMeta.endian); err != nil {
+			return n, wErr(fMeta.index, err)
+		}
 
 		// Check if it's a nested struct
 		fieldValType := typ.Field(fMeta.index).Type
@@ -549,11 +621,13 @@ func (ms *Marshaler) unsafeReadStruct(r io.Reader, order ByteOrder, strc reflect
 				}
 				return n, wErr(fMeta.index, err)
 			}
-			if err = validateField(structVal, &fMeta); err != nil {
-				return n, wErr(fMeta.index, err)
+			ms.wrapCollected(collected, fMeta.index, wErr)
+			if err = ms.validateField(strc, structVal, &fMeta, wErr); err != nil {
+				return n, err
 			}
 			n += m
 			firstElem = false
+			sv.end()
 			continue
 		}
 
@@ -567,7 +641,7 @@ func (ms *Marshaler) unsafeReadStruct(r io.Reader, order ByteOrder, strc reflect
 				// A constant length is pre-resolved in option; only re-evaluate when
 				// the expression references other fields.
 				if !fMeta.arrayLenConst {
-					option.arrayLen, err = evaluateTagValue(strc, fMeta.arrayLenExpr)
+					option.arrayLen, err = ms.evalTagValue(strc, fMeta.arrayLenExpr)
 					if err != nil {
 						return n, wErr(fMeta.index, err)
 					}
@@ -577,7 +651,7 @@ func (ms *Marshaler) unsafeReadStruct(r io.Reader, order ByteOrder, strc reflect
 				option.arrayLen = sh.Len
 			}
 			if fMeta.bufLenExpr != "" && !fMeta.bufLenConst {
-				option.bufLen, err = evaluateTagValue(strc, fMeta.bufLenExpr)
+				option.bufLen, err = ms.evalTagValue(strc, fMeta.bufLenExpr)
 				if err != nil {
 					return n, wErr(fMeta.index, err)
 				}
@@ -593,7 +667,7 @@ func (ms *Marshaler) unsafeReadStruct(r io.Reader, order ByteOrder, strc reflect
 						}
 						continue
 					}
-					option.dims[i], err = evaluateTagValue(strc, d)
+					option.dims[i], err = ms.evalTagValue(strc, d)
 					if err != nil {
 						return n, wErr(fMeta.index, err)
 					}
@@ -603,11 +677,13 @@ func (ms *Marshaler) unsafeReadStruct(r io.Reader, order ByteOrder, strc reflect
 				if err != nil {
 					return n, wErr(fMeta.index, err)
 				}
-				if err = validateField(fieldVal, &fMeta); err != nil {
-					return n, wErr(fMeta.index, err)
+				ms.wrapCollected(collected, fMeta.index, wErr)
+				if err = ms.validateField(strc, fieldVal, &fMeta, wErr); err != nil {
+					return n, err
 				}
 				n += m
 				firstElem = false
+				sv.end()
 				continue
 			}
 			var ok bool
@@ -626,11 +702,13 @@ func (ms *Marshaler) unsafeReadStruct(r io.Reader, order ByteOrder, strc reflect
 				}
 			}
 			if ok {
-				if err = validateField(fieldVal, &fMeta); err != nil {
-					return n, wErr(fMeta.index, err)
+				ms.wrapCollected(collected, fMeta.index, wErr)
+				if err = ms.validateField(strc, fieldVal, &fMeta, wErr); err != nil {
+					return n, err
 				}
 				n += m
 				firstElem = false
+				sv.end()
 				continue
 			}
 			m, err = ms.readMain(r, order, fieldVal, naturalType, option, strc, fMeta.index)
@@ -644,11 +722,13 @@ func (ms *Marshaler) unsafeReadStruct(r io.Reader, order ByteOrder, strc reflect
 				}
 				return n, wErr(fMeta.index, err)
 			}
-			if err = validateField(fieldVal, &fMeta); err != nil {
-				return n, wErr(fMeta.index, err)
+			ms.wrapCollected(collected, fMeta.index, wErr)
+			if err = ms.validateField(strc, fieldVal, &fMeta, wErr); err != nil {
+				return n, err
 			}
 			n += m
 			firstElem = false
+			sv.end()
 			continue
 		}
 
@@ -659,7 +739,7 @@ func (ms *Marshaler) unsafeReadStruct(r io.Reader, order ByteOrder, strc reflect
 			naturalType := fMeta.naturalType
 			option := fMeta.option
 			if fMeta.bufLenExpr != "" && !fMeta.bufLenConst {
-				option.bufLen, err = evaluateTagValue(strc, fMeta.bufLenExpr)
+				option.bufLen, err = ms.evalTagValue(strc, fMeta.bufLenExpr)
 				if err != nil {
 					return n, wErr(fMeta.index, err)
 				}
@@ -675,11 +755,13 @@ func (ms *Marshaler) unsafeReadStruct(r io.Reader, order ByteOrder, strc reflect
 				}
 				return n, wErr(fMeta.index, err)
 			}
-			if err = validateField(fieldVal, &fMeta); err != nil {
-				return n, wErr(fMeta.index, err)
+			ms.wrapCollected(collected, fMeta.index, wErr)
+			if err = ms.validateField(strc, fieldVal, &fMeta, wErr); err != nil {
+				return n, err
 			}
 			n += m
 			firstElem = false
+			sv.end()
 			continue
 		}
 
@@ -687,13 +769,18 @@ func (ms *Marshaler) unsafeReadStruct(r io.Reader, order ByteOrder, strc reflect
 		if fMeta.encodeType == Pad {
 			l := 1
 			if fMeta.bufLenExpr != "" {
-				l, err = evaluateTagValue(strc, fMeta.bufLenExpr)
+				l, err = ms.evalTagValue(strc, fMeta.bufLenExpr)
 				if err != nil {
 					return n, wErr(fMeta.index, err)
 				}
 			}
 			var m int
-			m, err = skipBytes(r, l)
+			m, err = ms.readPad(r, l, fMeta.option)
+			if reservedFailed(&fMeta, err) {
+				if err = ms.validationFailed(wErr(fMeta.index, err)); err != nil {
+					return n, err
+				}
+			}
 			if err != nil {
 				if fMeta.omittable && (err == io.EOF || err == io.ErrUnexpectedEOF) && m == 0 {
 					err = nil
@@ -703,12 +790,19 @@ func (ms *Marshaler) unsafeReadStruct(r io.Reader, order ByteOrder, strc reflect
 			}
 			n += m
 			firstElem = false
+			sv.end()
 			continue
 		}
 
-		// Handle basic scalar fields using unsafe
+		// Handle basic scalar fields using unsafe, or the reflection reader for a
+		// field whose Go type differs from its wire type.
 		var m int
-		m, err = ms.unsafeReadScalar(r, fieldOrder, currPtr, fMeta.encodeType, fieldValType.Kind())
+		if !ms.unsafeScalarOK(fieldValType, fMeta.encodeType) {
+			fieldVal := reflect.NewAt(fieldValType, currPtr).Elem()
+			m, err = ms.readMain(r, fieldOrder, fieldVal, fMeta.encodeType, fMeta.option, strc, fMeta.index)
+		} else {
+			m, err = ms.unsafeReadScalar(r, fieldOrder, currPtr, fMeta.encodeType, fieldValType.Kind())
+		}
 		if err != nil {
 			if fMeta.omittable && (err == io.EOF || err == io.ErrUnexpectedEOF) && m == 0 {
 				if wasNilPtr {
@@ -719,18 +813,33 @@ func (ms *Marshaler) unsafeReadStruct(r io.Reader, order ByteOrder, strc reflect
 			}
 			return n, wErr(fMeta.index, err)
 		}
-		if err = validateField(strc.Field(fMeta.index), &fMeta); err != nil {
-			return n, wErr(fMeta.index, err)
+		ms.wrapCollected(collected, fMeta.index, wErr)
+		if err = ms.validateField(strc, strc.Field(fMeta.index), &fMeta, wErr); err != nil {
+			return n, err
 		}
 		n += m
 		firstElem = false
+		sv.end()
 	}
 	if err = ms.validateCustomValueofs(order, strc, meta, n, typ); err != nil {
 		return n, err
 	}
+	if err = ms.afterDecode(strc); err != nil {
+		return n, err
+	}
 	return
 }
 
+// unsafeScalarOK reports whether a scalar field of Go type t, encoded as
+// encodeType, can be copied through memory as is: the untagged case, or a wire
+// type of the Go type's own kind and size.
+func (ms *Marshaler) unsafeScalarOK(t reflect.Type, encodeType eType) bool {
+	if encodeType == Any || encodeType == iInvalid {
+		return true
+	}
+	return isCompatibleFastPath(t, encodeType)
+}
+
 func (ms *Marshaler) unsafeReadScalar(r io.Reader, order ByteOrder, ptr unsafe.Pointer, encodeType eType, goKind reflect.Kind) (n int, err error) {
 	k := encodeType
 	if k == Any || k == iInvalid {
@@ -791,11 +900,17 @@ func (ms *Marshaler) unsafeWriteSlice(w io.Writer, fieldOrder ByteOrder, currPtr
 	if sz == 0 {
 		return 0, false, nil
 	}
+	if sz > 1 && !isStandardOrder(fieldOrder) {
+		return 0, false, nil // a custom order is applied element by element
+	}
 
 	var dataPtr unsafe.Pointer
 	var length int
 	if isSlice {
 		sh := (*sliceHeader)(currPtr)
+		if arrayLen > 0 && sh.Len > arrayLen {
+			return 0, true, fmt.Errorf("array too large to fit: len %d, size %d: %w", arrayLen, sh.Len, ErrValueOverflow)
+		}
 		if sh.Len == 0 {
 			if arrayLen > 0 {
 				var m int
@@ -860,12 +975,23 @@ func (ms *Marshaler) unsafeReadSlice(r io.Reader, fieldOrder ByteOrder, currPtr
 	if sz == 0 {
 		return 0, false, nil
 	}
+	if sz > 1 && !isStandardOrder(fieldOrder) {
+		return 0, false, nil // a custom order is applied element by element
+	}
 
 	var dataPtr unsafe.Pointer
 	var length int
 
 	if isSlice {
 		sh := (*sliceHeader)(currPtr)
+		if arrayLen > sh.Len && allocAhead(r, arrayLen, sz, sz) < arrayLen {
+			return 0, false, nil // readSlice allocates it as the input arrives
+		}
+		if arrayLen > 0 {
+			if err = ms.limitSlice(arrayLen, arrayLen-sh.Len, sz); err != nil {
+				return 0, true, err
+			}
+		}
 		if sh.Data == nil {
 			if arrayLen == 0 {
 				return 0, true, nil
@@ -0,0 +1,157 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"errors"
+	"reflect"
+)
+
+// ValidationMode selects the decode-time validation a Marshaler performs: the
+// checks declared by the const=, range=, match=, enum= and check= options, and
+// the recomputation of custom valueof evaluators such as checksums. A struct's
+// ValidateBinary hook is not affected.
+type ValidationMode int
+
+const (
+	// ValidateAll performs every check; a failure aborts decoding. The default.
+	ValidateAll ValidationMode = iota
+	// ValidateNone performs no checks, for trusted input on a hot path.
+	ValidateNone
+	// ValidateSkipChecksums performs every check except recomputing custom
+	// valueof evaluators.
+	ValidateSkipChecksums
+	// ValidateWarn performs every check, but a failure is added to the
+	// Marshaler's Warnings and decoding goes on, for tools that parse damaged
+	// input anyway. Each decode replaces the Warnings of the one before.
+	ValidateWarn
+	// ValidateCollect performs every check, but a failure is recorded and
+	// decoding goes on; the decode then returns all the failures at once, joined
+	// with errors.Join, for a full report on a bad file. An error that stops
+	// decoding still does, and is joined after them.
+	ValidateCollect
+)
+
+// validationFailed applies the Marshaler's Validation mode to err, an error
+// returned by a decode-time check. It returns nil under ValidateNone; under
+// ValidateWarn and ValidateCollect it records err for endDecode, which adds it
+// to Warnings or returns it, returning nil when err wraps ErrValidationError.
+// Otherwise, and on a nil Marshaler, it returns err. The interpreters and
+// generated code report failed checks through it.
+func (ms *Marshaler) validationFailed(err error) error {
+	if ms == nil || err == nil {
+		return err
+	}
+	switch ms.Validation {
+	case ValidateNone:
+		return nil
+	case ValidateWarn, ValidateCollect:
+		if !errors.Is(err, ErrValidationError) {
+			break
+		}
+		if ms.decodeDepth == 0 && ms.Validation == ValidateWarn {
+			ms.Warnings = append(ms.Warnings, warning(err))
+		} else {
+			ms.collected = append(ms.collected, err)
+		}
+		return nil
+	}
+	return err
+}
+
+// warning returns err as an entry of Marshaler.Warnings.
+func warning(err error) *DecodeError {
+	var de *DecodeError
+	if !errors.As(err, &de) {
+		de = &DecodeError{Err: err}
+	}
+	return de
+}
+
+// beginDecode and endDecode bracket a decode. Under ValidateCollect the
+// outermost endDecode replaces *err with the failures recorded since the
+// outermost beginDecode, joined with errors.Join and followed by *err itself
+// when it is not nil; under ValidateWarn it sets Warnings to them. The
+// outermost beginDecode clears Warnings and starts the count of the memory
+// charged to MaxDecodeAlloc. Under Salvage the outermost beginDecode lets the
+// value decoded record its fields, and the outermost endDecode wraps a failure
+// in a *SalvageError. Read and ReadAs call them, as does every
+// generated read method that validates or allocates, so a decode nested in
+// another is reported by the outermost one.
+func (ms *Marshaler) beginDecode() {
+	if ms == nil {
+		return
+	}
+	if ms.decodeDepth == 0 {
+		ms.allocated = 0
+		ms.salvaged = nil
+		ms.salvageArmed, ms.salvageNext = ms.Salvage, ""
+		ms.Warnings = nil
+	}
+	ms.decodeDepth++
+}
+
+// endDecode ends a decode begun by beginDecode; see there.
+func (ms *Marshaler) endDecode(err *error) {
+	if ms == nil || ms.decodeDepth == 0 {
+		return
+	}
+	if ms.decodeDepth--; ms.decodeDepth > 0 {
+		return
+	}
+	if len(ms.collected) > 0 {
+		if ms.Validation == ValidateWarn {
+			for _, e := range ms.collected {
+				ms.Warnings = append(ms.Warnings, warning(e))
+			}
+		} else {
+			*err = errors.Join(append(ms.collected, *err)...)
+		}
+		ms.collected = nil
+	}
+	if ms.Salvage && *err != nil {
+		*err = &SalvageError{Decoded: ms.salvaged, Err: *err}
+		ms.salvaged = nil
+	}
+}
+
+// wrapCollected wraps the failures recorded since mark, those of a nested
+// value, with wrap(i, ·) as an error returned from it would be wrapped, so a
+// collected failure carries the same path as a fatal one.
+func (ms *Marshaler) wrapCollected(mark, i int, wrap func(int, error) error) {
+	for j := mark; j < len(ms.collected); j++ {
+		ms.collected[j] = wrap(i, ms.collected[j])
+	}
+}
+
+// verifiesChecksums reports whether decoding recomputes custom valueof
+// evaluators to verify the decoded values: false under ValidateNone and
+// ValidateSkipChecksums. It is true on a nil Marshaler.
+func (ms *Marshaler) verifiesChecksums() bool {
+	return ms == nil || (ms.Validation != ValidateNone && ms.Validation != ValidateSkipChecksums)
+}
+
+// validateEncode runs a field's range=, match=, enum= and check= options on the
+// value about to be encoded, for Marshaler.ValidateOnEncode, so a producer
+// cannot write what its reader rejects. eval evaluates a check rule as the
+// encoder sees the struct, with valueof fields at their computed values. A const
+// or valueof field is not checked, since its value is not the Go field's; nor is
+// a check that uses $remaining, which has no value while encoding.
+func (ms *Marshaler) validateEncode(v reflect.Value, fMeta *structFieldMetadata, eval func(string) (int, error)) error {
+	if fMeta.hasConst || fMeta.valueofExpr != "" {
+		return nil
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if err := ms.validateValues(v, fMeta, func(err error) error { return err }); err != nil {
+		return err
+	}
+	if fMeta.checkExpr != "" && !fMeta.checkUsesRemaining {
+		return validateCheck(fMeta.checkExpr, eval)
+	}
+	return nil
+}
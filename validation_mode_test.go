@@ -0,0 +1,214 @@
+// Copyright 2026 github.com/mixcode
+
+package binarystruct
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Marshaler.Validation skips decode-time checks or turns failures into warnings.
+
+type modeRecord struct {
+	_     struct{} `binary:"endian=big"`
+	Magic uint16   `binary:"uint16,const=0xcafe"`
+	Kind  uint8    `binary:"uint8,range=1..3"`
+	Name  string   `binary:"string(2),match=^[a-z]+$"`
+	Flags uint8    `binary:"uint8,check=Kind!=2||Flags==0"`
+}
+
+var (
+	modeGood = []byte{0xca, 0xfe, 2, 'o', 'k', 0}
+	modeBad  = []byte{0xbe, 0xef, 2, 'O', 'K', 1}
+)
+
+func TestValidationMode_Fields(t *testing.T) {
+	for _, mode := range []ValidationMode{ValidateAll, ValidateNone, ValidateSkipChecksums, ValidateWarn} {
+		ms := NewMarshaler()
+		ms.Validation = mode
+		if _, err := ms.Unmarshal(modeGood, &modeRecord{}); err != nil || len(ms.Warnings) != 0 {
+			t.Fatalf("mode %d: good input: err = %v, warnings = %v", mode, err, ms.Warnings)
+		}
+		var out modeRecord
+		n, err := ms.Unmarshal(modeBad, &out)
+		switch mode {
+		case ValidateAll, ValidateSkipChecksums:
+			if !errors.Is(err, ErrValidationError) {
+				t.Errorf("mode %d: err = %v, want a validation error", mode, err)
+			}
+			continue
+		}
+		if err != nil || n != len(modeBad) {
+			t.Fatalf("mode %d: n = %d, err = %v", mode, n, err)
+		}
+		if out.Magic != 0xbeef || out.Name != "OK" || out.Flags != 1 {
+			t.Errorf("mode %d: decoded %+v", mode, out)
+		}
+		var fields []string
+		for _, w := range ms.Warnings {
+			if !errors.Is(w, ErrValidationError) {
+				t.Errorf("mode %d: warning %v does not wrap ErrValidationError", mode, w)
+			}
+			fields = append(fields, w.Field)
+		}
+		want := ""
+		if mode == ValidateWarn {
+			want = "Magic Name Flags"
+		}
+		if got := strings.Join(fields, " "); got != want {
+			t.Errorf("mode %d: warnings on %q, want %q", mode, got, want)
+		}
+	}
+}
+
+func TestValidationMode_Checksums(t *testing.T) {
+	blob, err := newCRCMarshaler().Marshal(&crcChunk{Type: "IHDR", Data: []byte{1, 2, 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob[len(blob)-1] ^= 0xff
+	for _, c := range []struct {
+		mode     ValidationMode
+		fails    bool
+		warnings int
+	}{
+		{ValidateAll, true, 0},
+		{ValidateNone, false, 0},
+		{ValidateSkipChecksums, false, 0},
+		{ValidateWarn, false, 1},
+	} {
+		ms := newCRCMarshaler()
+		ms.Validation = c.mode
+		_, err := ms.Unmarshal(blob, &crcChunk{})
+		if (err != nil) != c.fails || len(ms.Warnings) != c.warnings {
+			t.Errorf("mode %d: err = %v, warnings = %v", c.mode, err, ms.Warnings)
+		}
+		if c.warnings > 0 && (ms.Warnings[0].Field != "CRC" || ms.Warnings[0].Offset != len(blob)) {
+			t.Errorf("mode %d: warning %v", c.mode, ms.Warnings[0])
+		}
+	}
+}
+
+// Only failed checks become warnings; other errors still abort.
+func TestValidationMode_WarnKeepsErrors(t *testing.T) {
+	type rec struct {
+		Kind uint8 `binary:"uint8,enum=Missing"`
+	}
+	ms := NewMarshaler()
+	ms.Validation = ValidateWarn
+	if _, err := ms.Unmarshal([]byte{1}, &rec{}); err == nil || !strings.Contains(err.Error(), "unknown enum") {
+		t.Fatalf("err = %v", err)
+	}
+	if len(ms.Warnings) != 0 {
+		t.Errorf("warnings = %v", ms.Warnings)
+	}
+	cg := NewCodegen(nil)
+	if err := cg.ValidationFailed(ErrValidationError); err != ErrValidationError || !cg.VerifiesChecksums() {
+		t.Error("a nil Marshaler must validate everything")
+	}
+}
+
+// ValidateCollect returns every failed check at once, each with the path a fatal
+// error would have; an error that stops decoding is joined after them.
+func TestValidationMode_Collect(t *testing.T) {
+	type file struct {
+		Head  modeRecord
+		Items [2]modeRecord
+	}
+	blob := append(append(append([]byte{}, modeBad...), modeGood...), modeBad...)
+	ms := NewMarshaler()
+	ms.Validation = ValidateCollect
+	var out file
+	n, err := ms.Unmarshal(blob, &out)
+	if n != len(blob) || !errors.Is(err, ErrValidationError) {
+		t.Fatalf("n = %d, err = %v", n, err)
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("err %T is not a joined error", err)
+	}
+	var got []string
+	for _, e := range joined.Unwrap() {
+		var outer, inner *DecodeError
+		if !errors.As(e, &outer) || !errors.As(outer.Err, &inner) {
+			t.Fatalf("failure %v is not a nested DecodeError", e)
+		}
+		got = append(got, fmt.Sprintf("%s@%d/%s@%d", outer.Field, outer.Offset, inner.Field, inner.Offset))
+	}
+	want := "Head@0/Magic@0 Head@0/Name@3 Head@0/Flags@5 Items@6/Magic@0 Items@6/Name@3 Items@6/Flags@5"
+	if s := strings.Join(got, " "); s != want {
+		t.Errorf("failures %s, want %s", s, want)
+	}
+	if !strings.Contains(err.Error(), "array index [1]") || out.Items[1].Name != "OK" {
+		t.Errorf("err = %v, decoded %+v", err, out)
+	}
+
+	// Every check of a field runs, and each failure is collected.
+	type span struct {
+		Total  uint8
+		Offset uint8
+		Size   uint8 `binary:"uint8,range=0..8,check=Offset+Size<=Total"`
+	}
+	_, err = ms.Unmarshal([]byte{10, 5, 9}, &span{})
+	if j, ok := err.(interface{ Unwrap() []error }); !ok || len(j.Unwrap()) != 2 || !strings.Contains(err.Error(), "out of range") || !strings.Contains(err.Error(), "check \"Offset+Size<=Total\" failed") {
+		t.Errorf("range and check: err = %v", err)
+	}
+
+	// The Marshaler starts afresh on the next decode.
+	if _, err := ms.Unmarshal(modeGood, &modeRecord{}); err != nil {
+		t.Errorf("good input after a bad one: %v", err)
+	}
+
+	// Truncated input still aborts, after the failures found so far.
+	_, err = ms.Unmarshal(modeBad[:4], &modeRecord{})
+	if !errors.Is(err, ErrValidationError) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("truncated: err = %v", err)
+	}
+
+	// Custom valueof mismatches are collected too.
+	chunk, err := newCRCMarshaler().Marshal(&crcChunk{Type: "IHDR", Data: []byte{1, 2, 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk[len(chunk)-1] ^= 0xff
+	cms := newCRCMarshaler()
+	cms.Validation = ValidateCollect
+	var de *DecodeError
+	if _, err := cms.Unmarshal(chunk, &crcChunk{}); !errors.As(err, &de) || de.Field != "CRC" {
+		t.Errorf("checksum: err = %v", err)
+	}
+}
+
+type warnInner struct {
+	A uint8 `binary:"uint8,range=1..9"`
+}
+
+type warnOuter struct {
+	Hdr  warnInner
+	Recs [2]warnInner
+}
+
+// A warning in a nested struct or element carries its path, and each decode
+// replaces the Warnings of the one before.
+func TestValidationMode_WarnPath(t *testing.T) {
+	ms := NewMarshaler()
+	ms.Validation = ValidateWarn
+	for i := 0; i < 2; i++ {
+		if _, err := ms.Unmarshal([]byte{0, 1, 0}, &warnOuter{}); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, w := range ms.Warnings {
+			got = append(got, fmt.Sprintf("%s@%d", w.Path, w.AbsOffset))
+		}
+		if strings.Join(got, " ") != "Hdr.A@0 Recs[1].A@2" {
+			t.Errorf("decode %d: warnings %v", i, got)
+		}
+	}
+	if _, err := ms.Unmarshal([]byte{1, 1, 1}, &warnOuter{}); err != nil || len(ms.Warnings) != 0 {
+		t.Errorf("good input: err = %v, warnings = %v", err, ms.Warnings)
+	}
+}
@@ -109,6 +109,21 @@ func TestCustomValueof_UnregisteredEvaluatorErrors(t *testing.T) {
 	}
 }
 
+func TestCustomValueof_ReservedNamePanics(t *testing.T) {
+	// A tag always calls the built-in of a reserved name, so an evaluator
+	// registered under one would silently never run.
+	for _, name := range []string{"bytelen", "count", "align", "min", "max", "abs", "sizeof"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("AddValueOf(%q) did not panic", name)
+				}
+			}()
+			NewMarshaler().AddValueOf(name, func(ValueOfContext) (uint64, error) { return 0, nil })
+		}()
+	}
+}
+
 // padArgChunk's Data is a CONSTANT fixed-length byte slice, so a shorter value is
 // zero-padded to 8 bytes on encode. A custom valueof over it must see those 8
 // encoded bytes.